@@ -29,6 +29,16 @@ if ! go build -o bin/migrate cmd/migrate/main.go; then
     exit 1
 fi
 
+if ! go build -o bin/amimport cmd/amimport/main.go; then
+    echo "❌ Alertmanager 导入工具构建失败"
+    exit 1
+fi
+
+if ! go build -o bin/alertbotctl ./cmd/alertbotctl; then
+    echo "❌ alertbotctl 构建失败"
+    exit 1
+fi
+
 echo "✅ 后端构建成功"
 
 # 构建前端
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+)
+
+var alertCommands = map[string]command{
+	"list":    {"List alerts matching filters", runAlertList},
+	"get":     {"Show one alert", runAlertGet},
+	"watch":   {"Stream alert updates as they happen", runAlertWatch},
+	"ack":     {"Acknowledge alerts by fingerprint", runAlertAck},
+	"resolve": {"Resolve alerts by fingerprint", runAlertResolve},
+	"silence": {"Silence alerts by label matcher or fingerprint", runAlertSilence},
+}
+
+// alertPage is the paginated alert list
+type alertPage struct {
+	Items      []models.Alert `json:"items"`
+	Total      int64          `json:"total"`
+	Page       int            `json:"page"`
+	Size       int            `json:"size"`
+	Pages      int            `json:"pages"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+func alertTable(alerts []models.Alert) *table {
+	t := &table{header: []string{"FINGERPRINT", "STATUS", "SEVERITY", "ALERTNAME", "INSTANCE", "AGE", "SUMMARY"}}
+	for _, a := range alerts {
+		t.add(a.Fingerprint, a.Status, a.Severity, str(a.Labels["alertname"]), str(a.Labels["instance"]),
+			formatAge(a.StartsAt), truncate(str(a.Annotations["summary"]), 60))
+	}
+	return t
+}
+
+func runAlertList(c *client, args []string) error {
+	fs := flag.NewFlagSet("alerts list", flag.ExitOnError)
+	status := fs.String("status", "", "Filter by status (firing, resolved, silenced, acknowledged)")
+	severity := fs.String("severity", "", "Filter by severity")
+	alertname := fs.String("alertname", "", "Filter by alertname")
+	instance := fs.String("instance", "", "Filter by instance")
+	query := fs.String("q", "", `Label matcher expression, e.g. 'team="db",env=~"prod.*"'`)
+	search := fs.String("search", "", "Full-text search over labels and annotations")
+	view := fs.Uint("view", 0, "Apply a saved view by ID")
+	page := fs.Int("page", 1, "Page number")
+	size := fs.Int("size", 50, "Page size")
+	sort := fs.String("sort", "", "Sort field")
+	order := fs.String("order", "", "Sort order (asc or desc)")
+	fs.Parse(args)
+
+	params := url.Values{
+		"page": {strconv.Itoa(*page)},
+		"size": {strconv.Itoa(*size)},
+	}
+	for name, value := range map[string]string{
+		"status":    *status,
+		"severity":  *severity,
+		"alertname": *alertname,
+		"instance":  *instance,
+		"query":     *query,
+		"search":    *search,
+		"sort":      *sort,
+		"order":     *order,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	if *view != 0 {
+		params.Set("view_id", strconv.FormatUint(uint64(*view), 10))
+	}
+
+	var result alertPage
+	if err := c.do(http.MethodGet, "/alerts", params, nil, "", &result); err != nil {
+		return err
+	}
+	if err := render(result, func() *table { return alertTable(result.Items) }); err != nil {
+		return err
+	}
+	if outputFormat == "table" && result.Pages > 1 {
+		fmt.Fprintf(os.Stderr, "Page %d of %d (%d alerts)\n", result.Page, result.Pages, result.Total)
+	}
+	return nil
+}
+
+func runAlertGet(c *client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: alertbotctl alerts get <fingerprint>")
+	}
+	var alert models.Alert
+	if err := c.do(http.MethodGet, "/alerts/"+url.PathEscape(args[0]), nil, nil, "", &alert); err != nil {
+		return err
+	}
+	return render(alert, func() *table {
+		t := &table{}
+		t.add("Fingerprint:", alert.Fingerprint)
+		t.add("Status:", alert.Status)
+		t.add("Severity:", alert.Severity)
+		t.add("Started:", formatTime(alert.StartsAt)+" ("+formatAge(alert.StartsAt)+" ago)")
+		if alert.EndsAt != nil {
+			t.add("Ended:", formatTime(*alert.EndsAt))
+		}
+		t.add("Labels:", formatLabels(alert.Labels))
+		t.add("Annotations:", formatLabels(alert.Annotations))
+		return t
+	})
+}
+
+func runAlertAck(c *client, args []string) error {
+	fs := flag.NewFlagSet("alerts ack", flag.ExitOnError)
+	comment := fs.String("c", "", "Comment")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: alertbotctl alerts ack [-c comment] <fingerprint>...")
+	}
+
+	for _, fp := range fs.Args() {
+		body := map[string]string{"comment": *comment}
+		if err := c.doJSON(http.MethodPut, "/alerts/"+url.PathEscape(fp)+"/ack", nil, body, nil); err != nil {
+			return fmt.Errorf("%s: %w", fp, err)
+		}
+		fmt.Printf("✅ Acknowledged %s\n", fp)
+	}
+	return nil
+}
+
+func runAlertResolve(c *client, args []string) error {
+	fs := flag.NewFlagSet("alerts resolve", flag.ExitOnError)
+	comment := fs.String("c", "Resolved from alertbotctl", "Comment")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: alertbotctl alerts resolve [-c comment] <fingerprint>...")
+	}
+
+	for _, fp := range fs.Args() {
+		body := map[string]string{"comment": *comment}
+		if err := c.doJSON(http.MethodDelete, "/alerts/"+url.PathEscape(fp), nil, body, nil); err != nil {
+			return fmt.Errorf("%s: %w", fp, err)
+		}
+		fmt.Printf("✅ Resolved %s\n", fp)
+	}
+	return nil
+}
+
+func runAlertSilence(c *client, args []string) error {
+	fs := flag.NewFlagSet("alerts silence", flag.ExitOnError)
+	matchers := fs.String("m", "", `Silence every alert matching these matchers, e.g. 'alertname="DiskFull",instance=~"db-.*"'`)
+	duration := fs.String("d", "1h", "Silence duration, e.g. 30m or 2h")
+	comment := fs.String("c", "", "Comment")
+	creator := fs.String("by", currentUser(), "Creator of matcher silences; fingerprint silences are created as the logged-in user")
+	fs.Parse(args)
+
+	if *matchers != "" {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("alerts silence: use either -m or fingerprints, not both")
+		}
+		d, err := time.ParseDuration(*duration)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", *duration, err)
+		}
+		now := time.Now()
+		return createSilence(c, *matchers, now, now.Add(d), *creator, *comment)
+	}
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: alertbotctl alerts silence [-d duration] [-c comment] (-m matchers | <fingerprint>...)")
+	}
+	for _, fp := range fs.Args() {
+		body := map[string]string{"duration": *duration, "comment": *comment}
+		if err := c.doJSON(http.MethodPut, "/alerts/"+url.PathEscape(fp)+"/silence", nil, body, nil); err != nil {
+			return fmt.Errorf("%s: %w", fp, err)
+		}
+		fmt.Printf("🔕 Silenced %s for %s\n", fp, *duration)
+	}
+	return nil
+}
+
+// createSilence creates a silence from a matcher expression. Silences only
+// store equality and regex matchers, so negative matchers are rejected.
+func createSilence(c *client, expr string, startsAt, endsAt time.Time, creator, comment string) error {
+	ms, err := matcher.Parse(expr)
+	if err != nil {
+		return fmt.Errorf("invalid matchers: %w", err)
+	}
+	if len(ms) == 0 {
+		return fmt.Errorf("at least one matcher is required")
+	}
+	encoded, err := matcher.Encode(ms)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"matchers":  encoded["matchers"],
+		"starts_at": startsAt,
+		"ends_at":   endsAt,
+		"creator":   creator,
+		"comment":   comment,
+	}
+	var silence map[string]interface{}
+	if err := c.doJSON(http.MethodPost, "/silences", nil, body, &silence); err != nil {
+		return err
+	}
+	if outputFormat != "table" {
+		return render(silence, nil)
+	}
+	fmt.Printf("🔕 Created silence %v for %s until %s\n", silence["id"], ms, formatTime(endsAt))
+	return nil
+}
+
+// currentUser is the default creator of silences
+func currentUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "alertbotctl"
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"alertbot/internal/models"
+)
+
+var changeMarks = map[models.ConfigChangeAction]string{
+	models.ConfigChangeCreate:    "+",
+	models.ConfigChangeUpdate:    "~",
+	models.ConfigChangeDelete:    "-",
+	models.ConfigChangeUnchanged: "=",
+}
+
+func runApply(c *client, args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	path := fs.String("f", "", "Manifest file or directory (read recursively)")
+	dryRun := fs.Bool("dry-run", false, "Show the planned changes without applying them")
+	prune := fs.Bool("prune", false, "Delete stored objects that are not in the manifests")
+	verbose := fs.Bool("v", false, "Also list unchanged objects")
+	fs.Parse(args)
+
+	if *path == "" {
+		return fmt.Errorf("apply: -f is required")
+	}
+	bundle, err := readManifests(*path)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"dry_run": {strconv.FormatBool(*dryRun)},
+		"prune":   {strconv.FormatBool(*prune)},
+	}
+	var report models.ApplyReport
+	if err := c.do(http.MethodPost, "/config/apply", query, bundle, "application/yaml", &report); err != nil {
+		return err
+	}
+
+	counts := make(map[models.ConfigChangeAction]int)
+	for _, change := range report.Changes {
+		counts[change.Action]++
+		if change.Action == models.ConfigChangeUnchanged && !*verbose {
+			continue
+		}
+		line := fmt.Sprintf("%s %s/%s", changeMarks[change.Action], change.Kind, change.Name)
+		if len(change.Fields) > 0 {
+			line += " (" + strings.Join(change.Fields, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+
+	summary := fmt.Sprintf("%d to create, %d to update, %d to delete, %d unchanged",
+		counts[models.ConfigChangeCreate], counts[models.ConfigChangeUpdate], counts[models.ConfigChangeDelete], counts[models.ConfigChangeUnchanged])
+	if report.DryRun {
+		fmt.Printf("ℹ️  Dry run: %s\n", summary)
+	} else {
+		fmt.Printf("✅ Applied: %s\n", strings.NewReplacer("to create", "created", "to update", "updated", "to delete", "deleted").Replace(summary))
+	}
+	return nil
+}
+
+// readManifests concatenates a manifest file, or every .yaml and .yml file
+// under a directory in lexical order, into one YAML stream
+func readManifests(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := filepath.Ext(p)
+			if !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no .yaml or .yml files in %s", path)
+		}
+	}
+
+	var bundle bytes.Buffer
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		bundle.WriteString("---\n")
+		bundle.Write(data)
+		bundle.WriteString("\n")
+	}
+	return bundle.Bytes(), nil
+}
+
+func runExport(c *client, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "-", "Output file (- for stdout)")
+	fs.Parse(args)
+
+	var result struct {
+		Manifests string   `json:"manifests"`
+		Env       []string `json:"env"`
+	}
+	if err := c.do(http.MethodGet, "/config/export", nil, nil, "", &result); err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		fmt.Print(result.Manifests)
+	} else if err := os.WriteFile(*out, []byte(result.Manifests), 0644); err != nil {
+		return err
+	}
+	if len(result.Env) > 0 {
+		fmt.Fprintf(os.Stderr, "ℹ️  Secrets were replaced by references; set these variables on the server before applying: %s\n", strings.Join(result.Env, ", "))
+	}
+	return nil
+}
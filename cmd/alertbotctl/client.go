@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client calls the alertbot HTTP API
+type client struct {
+	baseURL string
+	apiKey  string
+	token   string
+	http    *http.Client
+}
+
+// apiResponse is the standard response envelope
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+	Error   *struct {
+		Code    string          `json:"code"`
+		Message string          `json:"message"`
+		Details json.RawMessage `json:"details"`
+	} `json:"error"`
+}
+
+func newClient(baseURL, apiKey, token string) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		token:   token,
+		http:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// do sends a request to an /api/v1 path and decodes the envelope's data into
+// out when it is not nil
+func (c *client) do(method, path string, query url.Values, body []byte, contentType string, out interface{}) error {
+	u := c.baseURL + "/api/v1" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var envelope apiResponse
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("%s %s: unexpected response (HTTP %d): %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if !envelope.Success || resp.StatusCode >= 400 {
+		if envelope.Error != nil {
+			return fmt.Errorf("%s (HTTP %d)", envelope.Error.Message, resp.StatusCode)
+		}
+		return fmt.Errorf("request failed (HTTP %d): %s", resp.StatusCode, envelope.Message)
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		return json.Unmarshal(envelope.Data, out)
+	}
+	return nil
+}
+
+// doJSON is do with a JSON-encoded request body; a nil body sends none
+func (c *client) doJSON(method, path string, query url.Values, body, out interface{}) error {
+	if body == nil {
+		return c.do(method, path, query, nil, "", out)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(method, path, query, data, "application/json", out)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the alertbotctl config file. Flags and environment variables
+// take precedence over it.
+type config struct {
+	Server string `yaml:"server,omitempty"`
+	APIKey string `yaml:"api_key,omitempty"`
+	// Token is a JWT used where the API key is not accepted, such as the
+	// WebSocket stream. It is written by the login command.
+	Token  string `yaml:"token,omitempty"`
+	Output string `yaml:"output,omitempty"`
+}
+
+// defaultConfigPath is $ALERTBOT_CONFIG, or alertbotctl/config.yaml under
+// the user config directory (~/.config on Linux)
+func defaultConfigPath() string {
+	if path := os.Getenv("ALERTBOT_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "alertbotctl", "config.yaml")
+}
+
+// loadConfig reads the config file at path. A missing file yields an empty
+// config.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes cfg to path, readable only by the owner since it holds
+// credentials
+func saveConfig(path string, cfg *config) error {
+	if path == "" {
+		return fmt.Errorf("no config file path; set ALERTBOT_CONFIG or -config")
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,132 @@
+// Command alertbotctl manages an alertbot server through its HTTP API.
+//
+// The server URL, API key and output format are read from flags, then the
+// ALERTBOT_* environment variables, then the config file (see config.go).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is an alertbotctl subcommand
+type command struct {
+	summary string
+	run     func(c *client, args []string) error
+}
+
+var commands = map[string]command{
+	"alerts":   {"List, watch, acknowledge, resolve and silence alerts", group("alerts", alertCommands)},
+	"silences": {"List, create and expire silences", group("silences", silenceCommands)},
+	"rules":    {"List, show, delete and test routing rules", group("rules", ruleCommands)},
+	"channels": {"List, show, delete and test notification channels", group("channels", channelCommands)},
+	"stats":    {"Show alert and notification statistics", runStats},
+	"login":    {"Log in and store a token in the config file", runLogin},
+	"apply":    {"Apply YAML manifests from a file or directory", runApply},
+	"export":   {"Export the stored configuration as YAML manifests", runExport},
+}
+
+// Global settings resolved in main
+var (
+	configPath   string
+	fileConfig   *config
+	outputFormat string
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: alertbotctl [-server URL] [-api-key KEY] [-o table|json|yaml] <command> [flags]")
+	printCommands(commands)
+	fmt.Fprintln(os.Stderr, "\nGlobal flags:")
+	flag.PrintDefaults()
+}
+
+func printCommands(cmds map[string]command) {
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, cmds[name].summary)
+	}
+}
+
+// group dispatches to one of a command group's subcommands
+func group(name string, cmds map[string]command) func(c *client, args []string) error {
+	return func(c *client, args []string) error {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+			fmt.Fprintf(os.Stderr, "Usage: alertbotctl %s <command> [flags]\n", name)
+			printCommands(cmds)
+			return nil
+		}
+		cmd, ok := cmds[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown command %q for %s", args[0], name)
+		}
+		return cmd.run(c, args[1:])
+	}
+}
+
+func main() {
+	flag.StringVar(&configPath, "config", defaultConfigPath(), "Config file (env ALERTBOT_CONFIG)")
+	server := flag.String("server", "", "alertbot server URL (env ALERTBOT_URL, default http://localhost:8080)")
+	apiKey := flag.String("api-key", "", "API key sent as X-API-Key (env ALERTBOT_API_KEY)")
+	token := flag.String("token", "", "JWT for the WebSocket stream (env ALERTBOT_TOKEN)")
+	output := flag.String("o", "", "Output format: table, json or yaml (env ALERTBOT_OUTPUT, default table)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	fileConfig, err = loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	outputFormat = resolve(*output, "ALERTBOT_OUTPUT", fileConfig.Output, "table")
+	switch outputFormat {
+	case "table", "json", "yaml":
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Unknown output format %q (use table, json or yaml)\n", outputFormat)
+		os.Exit(2)
+	}
+
+	c := newClient(
+		resolve(*server, "ALERTBOT_URL", fileConfig.Server, "http://localhost:8080"),
+		resolve(*apiKey, "ALERTBOT_API_KEY", fileConfig.APIKey, ""),
+		resolve(*token, "ALERTBOT_TOKEN", fileConfig.Token, ""),
+	)
+	if err := cmd.run(c, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// resolve returns the first of the flag value, the environment variable,
+// the config file value and the fallback that is set
+func resolve(flagValue, env, fileValue, fallback string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	if fileValue != "" {
+		return fileValue
+	}
+	return fallback
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+var silenceCommands = map[string]command{
+	"list":   {"List silences", runSilenceList},
+	"create": {"Create a silence from label matchers", runSilenceCreate},
+	"expire": {"Expire (delete) silences by ID", runSilenceExpire},
+}
+
+var ruleCommands = map[string]command{
+	"list":   {"List routing rules", runRuleList},
+	"get":    {"Show one routing rule", runRuleGet},
+	"delete": {"Delete routing rules by ID", runRuleDelete},
+	"test":   {"Test routing rules against a sample alert file", runRuleTest},
+}
+
+var channelCommands = map[string]command{
+	"list":   {"List notification channels", runChannelList},
+	"get":    {"Show one notification channel", runChannelGet},
+	"delete": {"Delete notification channels by ID", runChannelDelete},
+	"test":   {"Send a test notification through a channel", runChannelTest},
+}
+
+// silence is a silence as listed by the API, with its computed status
+type silence struct {
+	ID        uint         `json:"id"`
+	Matchers  models.JSONB `json:"matchers"`
+	StartsAt  time.Time    `json:"starts_at"`
+	EndsAt    time.Time    `json:"ends_at"`
+	Creator   string       `json:"creator"`
+	Comment   string       `json:"comment"`
+	CreatedAt time.Time    `json:"created_at"`
+	Status    string       `json:"status"`
+}
+
+func runSilenceList(c *client, args []string) error {
+	fs := flag.NewFlagSet("silences list", flag.ExitOnError)
+	all := fs.Bool("a", false, "Include expired silences")
+	fs.Parse(args)
+
+	var result struct {
+		Items []silence `json:"items"`
+		Total int       `json:"total"`
+	}
+	if err := c.do(http.MethodGet, "/silences", nil, nil, "", &result); err != nil {
+		return err
+	}
+	if !*all {
+		active := result.Items[:0]
+		for _, s := range result.Items {
+			if s.Status != "expired" {
+				active = append(active, s)
+			}
+		}
+		result.Items, result.Total = active, len(active)
+	}
+
+	return render(result, func() *table {
+		t := &table{header: []string{"ID", "STATUS", "MATCHERS", "ENDS", "CREATOR", "COMMENT"}}
+		for _, s := range result.Items {
+			t.add(strconv.FormatUint(uint64(s.ID), 10), s.Status, silenceMatchers(s.Matchers),
+				formatTime(s.EndsAt), s.Creator, truncate(s.Comment, 40))
+		}
+		return t
+	})
+}
+
+func silenceMatchers(data models.JSONB) string {
+	ms, err := matcher.Decode(data)
+	if err != nil {
+		return "-"
+	}
+	return ms.String()
+}
+
+func runSilenceCreate(c *client, args []string) error {
+	fs := flag.NewFlagSet("silences create", flag.ExitOnError)
+	matchers := fs.String("m", "", `Label matchers, e.g. 'alertname="DiskFull",instance=~"db-.*"' (required)`)
+	duration := fs.Duration("d", time.Hour, "Duration from the start time")
+	start := fs.String("start", "", "Start time in RFC 3339 (default now)")
+	end := fs.String("end", "", "End time in RFC 3339; overrides -d")
+	comment := fs.String("c", "", "Comment")
+	creator := fs.String("by", currentUser(), "Creator")
+	fs.Parse(args)
+
+	if *matchers == "" {
+		return fmt.Errorf("silences create: -m is required")
+	}
+	startsAt := time.Now()
+	if *start != "" {
+		t, err := time.Parse(time.RFC3339, *start)
+		if err != nil {
+			return fmt.Errorf("invalid -start: %w", err)
+		}
+		startsAt = t
+	}
+	endsAt := startsAt.Add(*duration)
+	if *end != "" {
+		t, err := time.Parse(time.RFC3339, *end)
+		if err != nil {
+			return fmt.Errorf("invalid -end: %w", err)
+		}
+		endsAt = t
+	}
+	return createSilence(c, *matchers, startsAt, endsAt, *creator, *comment)
+}
+
+func runSilenceExpire(c *client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: alertbotctl silences expire <id>...")
+	}
+	return deleteByID(c, "/silences/", "silence", args)
+}
+
+// deleteByID deletes the objects with the given IDs under prefix
+func deleteByID(c *client, prefix, kind string, ids []string) error {
+	for _, id := range ids {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return fmt.Errorf("invalid %s ID %q", kind, id)
+		}
+		if err := c.do(http.MethodDelete, prefix+id, nil, nil, "", nil); err != nil {
+			return fmt.Errorf("%s %s: %w", kind, id, err)
+		}
+		fmt.Printf("🗑️  Deleted %s %s\n", kind, id)
+	}
+	return nil
+}
+
+func listRules(c *client) ([]models.RoutingRule, error) {
+	var result struct {
+		Items []models.RoutingRule `json:"items"`
+	}
+	if err := c.do(http.MethodGet, "/rules", nil, nil, "", &result); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result.Items, func(i, j int) bool {
+		return result.Items[i].Priority > result.Items[j].Priority
+	})
+	return result.Items, nil
+}
+
+// ruleConditions renders conditions as matchers when possible
+func ruleConditions(conditions models.JSONB) string {
+	if ms, ok := engine.MatchersFromConditions(conditions); ok {
+		return ms.String()
+	}
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		return "-"
+	}
+	return string(data)
+}
+
+func ruleChannels(receivers models.JSONB) string {
+	ids, _ := receivers["channels"].([]interface{})
+	if len(ids) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func runRuleList(c *client, args []string) error {
+	rules, err := listRules(c)
+	if err != nil {
+		return err
+	}
+	return render(rules, func() *table {
+		t := &table{header: []string{"ID", "NAME", "PRIORITY", "ENABLED", "CONDITIONS", "CHANNELS"}}
+		for _, r := range rules {
+			t.add(strconv.FormatUint(uint64(r.ID), 10), r.Name, strconv.Itoa(r.Priority), strconv.FormatBool(r.Enabled),
+				truncate(ruleConditions(r.Conditions), 60), ruleChannels(r.Receivers))
+		}
+		return t
+	})
+}
+
+func runRuleGet(c *client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: alertbotctl rules get <id>")
+	}
+	var rule models.RoutingRule
+	if err := c.do(http.MethodGet, "/rules/"+args[0], nil, nil, "", &rule); err != nil {
+		return err
+	}
+	return render(rule, func() *table {
+		t := &table{}
+		t.add("ID:", strconv.FormatUint(uint64(rule.ID), 10))
+		t.add("Name:", rule.Name)
+		t.add("Description:", rule.Description)
+		t.add("Priority:", strconv.Itoa(rule.Priority))
+		t.add("Enabled:", strconv.FormatBool(rule.Enabled))
+		t.add("Conditions:", ruleConditions(rule.Conditions))
+		t.add("Channels:", ruleChannels(rule.Receivers))
+		return t
+	})
+}
+
+func runRuleDelete(c *client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: alertbotctl rules delete <id>...")
+	}
+	return deleteByID(c, "/rules/", "rule", args)
+}
+
+// sampleAlert is an alert file for rules test, in YAML or JSON
+type sampleAlert struct {
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+	Status      string            `yaml:"status"`
+}
+
+// ruleTestResult is the outcome of testing one rule
+type ruleTestResult struct {
+	ID       uint   `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	Enabled  bool   `json:"enabled"`
+	Matched  bool   `json:"matched"`
+	Channels string `json:"channels,omitempty"`
+}
+
+func runRuleTest(c *client, args []string) error {
+	fs := flag.NewFlagSet("rules test", flag.ExitOnError)
+	file := fs.String("f", "", "Sample alert file with labels and annotations (required)")
+	matchers := fs.String("m", "", "Test these label matchers instead of stored rules")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("usage: alertbotctl rules test -f alert.yaml [-m matchers | <rule-id>...]")
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	var sample sampleAlert
+	if err := yaml.Unmarshal(data, &sample); err != nil {
+		return fmt.Errorf("invalid sample alert %s: %w", *file, err)
+	}
+	if len(sample.Labels) == 0 {
+		return fmt.Errorf("sample alert %s has no labels", *file)
+	}
+	alert := models.Alert{
+		Labels:      models.JSONB{},
+		Annotations: models.JSONB{},
+		Status:      sample.Status,
+		Severity:    sample.Labels["severity"],
+		StartsAt:    time.Now(),
+	}
+	for k, v := range sample.Labels {
+		alert.Labels[k] = v
+	}
+	for k, v := range sample.Annotations {
+		alert.Annotations[k] = v
+	}
+	if alert.Status == "" {
+		alert.Status = "firing"
+	}
+
+	var rules []models.RoutingRule
+	switch {
+	case *matchers != "":
+		ms, err := matcher.Parse(*matchers)
+		if err != nil {
+			return fmt.Errorf("invalid matchers: %w", err)
+		}
+		rules = []models.RoutingRule{{Name: ms.String(), Conditions: engine.ConditionsFromMatchers(ms), Enabled: true}}
+	case fs.NArg() > 0:
+		for _, id := range fs.Args() {
+			var rule models.RoutingRule
+			if err := c.do(http.MethodGet, "/rules/"+id, nil, nil, "", &rule); err != nil {
+				return fmt.Errorf("rule %s: %w", id, err)
+			}
+			rules = append(rules, rule)
+		}
+	default:
+		if rules, err = listRules(c); err != nil {
+			return err
+		}
+	}
+
+	results := make([]ruleTestResult, 0, len(rules))
+	for _, rule := range rules {
+		var outcome struct {
+			Matched bool `json:"matched"`
+		}
+		body := map[string]interface{}{"conditions": rule.Conditions, "sample_alert": alert}
+		if err := c.doJSON(http.MethodPost, "/rules/test", nil, body, &outcome); err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		results = append(results, ruleTestResult{
+			ID:       rule.ID,
+			Name:     rule.Name,
+			Priority: rule.Priority,
+			Enabled:  rule.Enabled,
+			Matched:  outcome.Matched,
+			Channels: ruleChannels(rule.Receivers),
+		})
+	}
+
+	return render(results, func() *table {
+		t := &table{header: []string{"ID", "NAME", "PRIORITY", "ENABLED", "MATCHED", "CHANNELS"}}
+		for _, r := range results {
+			mark := "no"
+			if r.Matched {
+				mark = "✅ yes"
+			}
+			t.add(strconv.FormatUint(uint64(r.ID), 10), r.Name, strconv.Itoa(r.Priority), strconv.FormatBool(r.Enabled), mark, r.Channels)
+		}
+		return t
+	})
+}
+
+func runChannelList(c *client, args []string) error {
+	var result struct {
+		Items []models.NotificationChannel `json:"items"`
+		Total int                          `json:"total"`
+	}
+	if err := c.do(http.MethodGet, "/channels", nil, nil, "", &result); err != nil {
+		return err
+	}
+	return render(result, func() *table {
+		t := &table{header: []string{"ID", "NAME", "TYPE", "ENABLED"}}
+		for _, ch := range result.Items {
+			t.add(strconv.FormatUint(uint64(ch.ID), 10), ch.Name, ch.Type, strconv.FormatBool(ch.Enabled))
+		}
+		return t
+	})
+}
+
+func runChannelGet(c *client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: alertbotctl channels get <id>")
+	}
+	var channel models.NotificationChannel
+	if err := c.do(http.MethodGet, "/channels/"+args[0], nil, nil, "", &channel); err != nil {
+		return err
+	}
+	return render(channel, func() *table {
+		t := &table{}
+		t.add("ID:", strconv.FormatUint(uint64(channel.ID), 10))
+		t.add("Name:", channel.Name)
+		t.add("Type:", channel.Type)
+		t.add("Enabled:", strconv.FormatBool(channel.Enabled))
+		keys := make([]string, 0, len(channel.Config))
+		for key := range channel.Config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			t.add("  "+key+":", str(channel.Config[key]))
+		}
+		return t
+	})
+}
+
+func runChannelDelete(c *client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: alertbotctl channels delete <id>...")
+	}
+	return deleteByID(c, "/channels/", "channel", args)
+}
+
+func runChannelTest(c *client, args []string) error {
+	fs := flag.NewFlagSet("channels test", flag.ExitOnError)
+	message := fs.String("m", "Test notification from alertbotctl", "Message to send")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: alertbotctl channels test [-m message] <id>")
+	}
+
+	var result map[string]interface{}
+	if err := c.doJSON(http.MethodPost, "/channels/"+fs.Arg(0)+"/test", nil, map[string]string{"message": *message}, &result); err != nil {
+		return err
+	}
+	if outputFormat != "table" {
+		return render(result, nil)
+	}
+	fmt.Printf("✅ Test notification sent through %v (%v)\n", result["channel"], result["type"])
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"alertbot/internal/models"
+)
+
+func runStats(c *client, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	since := fs.Duration("since", 24*time.Hour, "Time range ending now")
+	groupBy := fs.String("by", "severity", "Group alerts by severity, status, alertname or instance")
+	fs.Parse(args)
+
+	now := time.Now()
+	params := url.Values{
+		"start_time": {now.Add(-*since).Format(time.RFC3339)},
+		"end_time":   {now.Format(time.RFC3339)},
+	}
+
+	var result struct {
+		Alerts        models.Stats `json:"alerts"`
+		Notifications struct {
+			TotalSent    int     `json:"total_sent"`
+			TotalSuccess int     `json:"total_success"`
+			TotalFailed  int     `json:"total_failed"`
+			SuccessRate  float64 `json:"success_rate"`
+			Channels     []struct {
+				ChannelName string `json:"channel_name"`
+				ChannelType string `json:"channel_type"`
+				Sent        int    `json:"sent"`
+				Failed      int    `json:"failed"`
+			} `json:"channels"`
+		} `json:"notifications"`
+	}
+
+	alertParams := url.Values{"group_by": {*groupBy}}
+	for k, v := range params {
+		alertParams[k] = v
+	}
+	if err := c.do(http.MethodGet, "/stats/alerts", alertParams, nil, "", &result.Alerts); err != nil {
+		return err
+	}
+	if err := c.do(http.MethodGet, "/stats/notifications", params, nil, "", &result.Notifications); err != nil {
+		return err
+	}
+
+	return render(result, func() *table {
+		a, n := result.Alerts, result.Notifications
+		t := &table{}
+		t.add("Alerts:", strconv.Itoa(a.TotalAlerts),
+			fmt.Sprintf("%d firing, %d resolved", a.FiringAlerts, a.ResolvedAlerts))
+		for _, g := range a.Groups {
+			t.add("  "+*groupBy+"="+g.Key, strconv.Itoa(g.Count), fmt.Sprintf("%.1f%%", g.Percentage))
+		}
+		t.add("Notifications:", strconv.Itoa(n.TotalSent),
+			fmt.Sprintf("%d failed, %.2f%% success", n.TotalFailed, n.SuccessRate))
+		for _, ch := range n.Channels {
+			t.add("  "+ch.ChannelName+" ("+ch.ChannelType+")", strconv.Itoa(ch.Sent), fmt.Sprintf("%d failed", ch.Failed))
+		}
+		return t
+	})
+}
+
+func runLogin(c *client, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	username := fs.String("u", currentUser(), "Username")
+	fs.Parse(args)
+
+	password := os.Getenv("ALERTBOT_PASSWORD")
+	if password == "" {
+		fmt.Fprintf(os.Stderr, "Password for %s: ", *username)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	body := map[string]string{"username": *username, "password": password}
+	if err := c.doJSON(http.MethodPost, "/auth/login", nil, body, &result); err != nil {
+		return err
+	}
+
+	fileConfig.Token = result.Token
+	if fileConfig.Server == "" {
+		fileConfig.Server = c.baseURL
+	}
+	if err := saveConfig(configPath, fileConfig); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Logged in as %s; token stored in %s (expires %s)\n", *username, configPath, formatTime(result.ExpiresAt))
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"alertbot/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamMessage is a message from the alert stream
+type streamMessage struct {
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp time.Time       `json:"timestamp"`
+	Epoch     string          `json:"epoch,omitempty"`
+	Seq       uint64          `json:"seq,omitempty"`
+}
+
+// streamPosition is the last event seen, to resume from after reconnecting
+type streamPosition struct {
+	epoch string
+	seq   uint64
+}
+
+func runAlertWatch(c *client, args []string) error {
+	fs := flag.NewFlagSet("alerts watch", flag.ExitOnError)
+	query := fs.String("q", "", "Label matcher expression")
+	status := fs.String("status", "", "Only alerts with this status")
+	severity := fs.String("severity", "", "Only alerts with this severity")
+	types := fs.String("types", "", "Comma-separated event types, e.g. alert_created,alert_resolved")
+	view := fs.Uint("view", 0, "Subscribe to a saved view by ID")
+	snapshot := fs.Bool("snapshot", true, "Print the currently matching alerts first")
+	fs.Parse(args)
+
+	if c.token == "" {
+		return fmt.Errorf("the alert stream needs a token; run 'alertbotctl login' or set ALERTBOT_TOKEN")
+	}
+
+	subscribe := map[string]interface{}{"type": "subscribe", "id": "alertbotctl", "snapshot": *snapshot}
+	if *view != 0 {
+		subscribe["view_id"] = *view
+	} else {
+		filters := map[string]interface{}{}
+		for name, value := range map[string]string{"query": *query, "status": *status, "severity": *severity} {
+			if value != "" {
+				filters[name] = value
+			}
+		}
+		if *types != "" {
+			filters["types"] = strings.Split(*types, ",")
+		}
+		subscribe["filters"] = filters
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	// Reconnect after connection loss, resuming from the last event seen
+	// so no events are missed
+	var last streamPosition
+	for {
+		err := c.watch(subscribe, &last, interrupt)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*fatalStreamError); ok {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Stream disconnected: %v; reconnecting in 5s\n", err)
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(5 * time.Second):
+		}
+		if last.seq > 0 {
+			subscribe["epoch"] = last.epoch
+			subscribe["last_seq"] = last.seq
+		}
+	}
+}
+
+// fatalStreamError ends watch instead of reconnecting
+type fatalStreamError struct {
+	msg string
+}
+
+func (e *fatalStreamError) Error() string {
+	return e.msg
+}
+
+// watch streams one connection until it fails or the user interrupts it,
+// in which case it returns nil
+func (c *client) watch(subscribe map[string]interface{}, last *streamPosition, interrupt chan os.Signal) error {
+	wsURL := "ws" + strings.TrimPrefix(c.baseURL, "http") + "/api/v1/ws/alerts"
+	header := http.Header{"Authorization": {"Bearer " + c.token}}
+
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return &fatalStreamError{fmt.Sprintf("stream rejected the token (HTTP %d); run 'alertbotctl login' again", resp.StatusCode)}
+		}
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(subscribe); err != nil {
+		return err
+	}
+
+	messages := make(chan streamMessage)
+	failed := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			var message streamMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				failed <- err
+				return
+			}
+			select {
+			case messages <- message:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-interrupt:
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return nil
+		case err := <-failed:
+			return err
+		case message := <-messages:
+			// Sequence numbers restart in a new epoch, after a server
+			// restart or on another replica
+			if message.Seq > 0 && (message.Epoch != last.epoch || message.Seq > last.seq) {
+				last.epoch, last.seq = message.Epoch, message.Seq
+			}
+			if err := printStreamMessage(message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// printStreamMessage prints alert events and snapshots; other messages
+// are only shown in JSON and YAML output
+func printStreamMessage(message streamMessage) error {
+	if message.Type == "subscription_error" {
+		var data struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(message.Data, &data)
+		return &fatalStreamError{"subscription rejected: " + data.Error}
+	}
+
+	switch outputFormat {
+	case "json":
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case "yaml":
+		data, err := toYAML(message)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n%s", data)
+		return nil
+	}
+
+	switch {
+	case message.Type == "snapshot":
+		var data struct {
+			Alerts    []models.Alert `json:"alerts"`
+			Total     int64          `json:"total"`
+			Truncated bool           `json:"truncated"`
+		}
+		if err := json.Unmarshal(message.Data, &data); err != nil {
+			return err
+		}
+		if err := render(nil, func() *table { return alertTable(data.Alerts) }); err != nil {
+			return err
+		}
+		if data.Truncated {
+			fmt.Printf("… %d more alerts match\n", data.Total-int64(len(data.Alerts)))
+		}
+		fmt.Println("--- watching for updates (Ctrl-C to stop)")
+	case message.Type == "resync_required":
+		fmt.Println("--- missed updates while disconnected, reloading")
+	case strings.HasPrefix(message.Type, "alert_"):
+		var data struct {
+			Action string        `json:"action"`
+			Alert  *models.Alert `json:"alert"`
+		}
+		if err := json.Unmarshal(message.Data, &data); err != nil || data.Alert == nil {
+			return nil
+		}
+		a := data.Alert
+		fmt.Printf("%s  %-12s %-8s %-8s %s %s %s\n", message.Timestamp.Local().Format("15:04:05"), data.Action,
+			a.Status, a.Severity, a.Fingerprint, str(a.Labels["alertname"]), formatLabels(a.Labels))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"alertbot/internal/config"
+	"alertbot/internal/repository"
+	"alertbot/internal/service"
+	"alertbot/pkg/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	// Parse command line flags
+	file := flag.String("f", "", "Alertmanager config file to import")
+	dryRun := flag.Bool("dry-run", false, "Print the planned changes without applying them")
+	export := flag.String("export", "", "Write the current configuration as alertmanager.yml to this file (- for stdout)")
+	flag.Parse()
+
+	if (*file == "") == (*export == "") {
+		fmt.Fprintln(os.Stderr, "Usage: amimport -f alertmanager.yml [-dry-run] | amimport -export alertmanager.yml")
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize logger
+	log := logger.New(cfg.Logger)
+
+	// Connect to database
+	db, err := repository.NewDatabase(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// The server's rule engine is not reachable from here; running servers
+	// pick up imported routing rules on restart
+	svc := service.NewAlertmanagerService(repository.NewRepositories(db), nil, log)
+	ctx := context.Background()
+
+	if *export != "" {
+		data, issues, err := svc.Export(ctx)
+		if err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		if *export == "-" {
+			os.Stdout.Write(data)
+		} else if err := os.WriteFile(*export, data, 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", *export, err)
+		}
+		for _, issue := range issues {
+			fmt.Fprintf(os.Stderr, "⚠️  %s: %s\n", issue.Path, issue.Message)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+	report, err := svc.Import(ctx, data, *dryRun)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+	if *dryRun {
+		fmt.Println("ℹ️  Dry run, nothing was changed")
+		return
+	}
+	fmt.Println("✅ Import completed successfully! Restart alertbot servers to load the new routing rules.")
+}
@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"text/tabwriter"
 
 	"alertbot/internal/config"
+	"alertbot/internal/fingerprint"
 	"alertbot/internal/migration"
 	"alertbot/internal/repository"
 	"alertbot/pkg/logger"
@@ -13,11 +16,44 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const usage = `Usage: migrate [flags] [command]
+
+Commands:
+  status      List migrations and whether they are applied
+  up [N]      Apply the next N pending migrations (default: all)
+  down [N]    Revert the last N applied migrations (default: 1)
+  to <id>     Apply or revert migrations until <id> is the last applied
+
+Flags:
+`
+
 func main() {
 	// Parse command line flags
 	drop := flag.Bool("drop", false, "Drop all tables before migrating")
+	rekey := flag.Bool("rekey-fingerprints", false, "Recompute alert fingerprints after changing the fingerprint config")
+	dryRun := flag.Bool("dry-run", false, "Print the SQL of up, down and to instead of running it")
+	force := flag.Bool("force", false, "Proceed even if applied migrations were modified")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	command, arg := "up", ""
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+	}
+	if flag.NArg() > 1 {
+		arg = flag.Arg(1)
+	}
+	switch {
+	case flag.NArg() > 2,
+		command != "status" && command != "up" && command != "down" && command != "to",
+		command == "to" && arg == "":
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -35,9 +71,21 @@ func main() {
 
 	// Create migrator
 	migrator := migration.NewMigrator(db, log)
+	migrator.SetFingerprinter(fingerprint.New(cfg.Fingerprint.IncludeLabels, cfg.Fingerprint.ExcludeLabels))
+	migrator.SetForce(*force)
+	if *dryRun {
+		migrator.SetDryRun(os.Stdout)
+	}
+
+	if command == "status" {
+		if err := printStatus(migrator); err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		return
+	}
 
 	// Drop tables if requested
-	if *drop {
+	if *drop && !*dryRun {
 		log.Warn("Dropping all database tables")
 		if err := migrator.DropAll(); err != nil {
 			log.Fatalf("Failed to drop tables: %v", err)
@@ -46,12 +94,66 @@ func main() {
 	}
 
 	// Run migrations
-	log.Info("Starting database migration")
-	if err := migrator.Migrate(); err != nil {
+	log.WithField("command", command).Info("Starting database migration")
+	switch command {
+	case "up":
+		err = migrator.Up(count(arg, 0))
+	case "down":
+		err = migrator.Down(count(arg, 1))
+	case "to":
+		err = migrator.To(arg)
+	}
+	if err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
+	if *dryRun {
+		return
+	}
+
+	// Migration 005 re-keys once; later fingerprint config changes need the flag
+	if *rekey {
+		log.Info("Re-keying alert fingerprints")
+		if err := migrator.RekeyFingerprints(); err != nil {
+			log.Fatalf("Fingerprint re-key failed: %v", err)
+		}
+	}
 
 	log.Info("Database migration completed successfully")
 	fmt.Println("✅ Migration completed successfully!")
 }
 
+
+// count parses the optional N argument of up and down
+func count(arg string, fallback int) int {
+	if arg == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		fmt.Fprintf(os.Stderr, "invalid number of migrations: %q\n", arg)
+		os.Exit(2)
+	}
+	return n
+}
+
+func printStatus(migrator *migration.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATE\tAPPLIED AT\tREVERSIBLE\tDESCRIPTION")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		reversible := "no"
+		if s.Reversible {
+			reversible = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.ID, s.State, appliedAt, reversible, s.Description)
+	}
+	return w.Flush()
+}
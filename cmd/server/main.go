@@ -10,7 +10,12 @@ import (
 	"time"
 
 	"alertbot/internal/api"
+	"alertbot/internal/cluster"
 	"alertbot/internal/config"
+	"alertbot/internal/events"
+	"alertbot/internal/fingerprint"
+	"alertbot/internal/middleware"
+	"alertbot/internal/migration"
 	"alertbot/internal/monitor"
 	"alertbot/internal/monitoring"
 	"alertbot/internal/repository"
@@ -35,10 +40,39 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Refuse to start when stored alerts were fingerprinted differently, since
+	// new alerts would not deduplicate against them
+	migrator := migration.NewMigrator(db, log)
+	migrator.SetFingerprinter(fingerprint.New(cfg.Fingerprint.IncludeLabels, cfg.Fingerprint.ExcludeLabels))
+	if err := migrator.CheckFingerprintStrategy(); err != nil {
+		log.Fatalf("Fingerprint strategy check failed: %v", err)
+	}
+
 	repos := repository.NewRepositories(db)
+
+	// Join the other replicas; only the leader runs singleton jobs
+	node := cluster.NewNode(db, repos, cfg.Cluster, log)
+	node.Start(context.Background())
 	
+	// Initialize the event bus shared by WebSocket and SSE streams
+	eventBus := events.NewBus(log, events.DefaultHistorySize)
+
 	// Initialize WebSocket hub
-	hub := websocket.NewHub(log)
+	hub := websocket.NewHub(log, eventBus)
+	hub.SetViewResolver(repos.SavedView.GetByID)
+	hub.SetAlertLister(repos.Alert.List)
+
+	// Share streaming events with the other replicas
+	var broker events.Broker = events.NewLocalBroker(eventBus)
+	if node.Enabled() && cfg.Cluster.EventBus == "postgres" {
+		pgBroker, err := events.NewPostgresBroker(repository.PostgresDSN(cfg.Database), db, repos.Cluster, eventBus, node.ID(), websocket.DecodeEvent, log)
+		if err != nil {
+			log.WithError(err).Error("Failed to start Postgres event bus, events stay on this replica")
+		} else {
+			broker = pgBroker
+		}
+	}
+	hub.SetBroker(broker)
 	go hub.Run()
 
 	// Initialize system monitor
@@ -47,7 +81,9 @@ func main() {
 	
 	// Initialize monitoring services
 	monitoringService := monitoring.NewMonitoringService(repos, log, db)
+	monitoringService.SetLeaderCheck(node.IsLeader)
 	backgroundMonitor := monitoring.NewBackgroundMonitor(repos, db, log)
+	backgroundMonitor.SetLeaderCheck(node.IsLeader)
 	
 	// Start monitoring services
 	if err := monitoringService.Start(context.Background()); err != nil {
@@ -63,13 +99,37 @@ func main() {
 		Logger:       log,
 		Config:       cfg,
 		WebSocketHub: hub,
+		Cluster:      node,
 	})
 
+	hub.SetRevocationChecker(services.Auth.IsRevoked)
+
+	// Ingest received alerts in the background
+	if services.Ingestion != nil {
+		services.Ingestion.Start(context.Background())
+	}
+
+	// Restore alerts once their silences end
+	silenceScheduler := service.NewSilenceScheduler(services.Alert, log, service.DefaultSilenceCheckInterval)
+	silenceScheduler.SetLeaderCheck(node.IsLeader)
+	silenceScheduler.Start(context.Background())
+
+	// Raise alerts for heartbeats that stop pinging
+	heartbeatScheduler := service.NewHeartbeatScheduler(services.Heartbeat, log, service.DefaultHeartbeatCheckInterval)
+	heartbeatScheduler.SetLeaderCheck(node.IsLeader)
+	heartbeatScheduler.Start(context.Background())
+
 	if cfg.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := api.NewRouter(services, log, hub, cfg, monitoringService, backgroundMonitor)
+	if cfg.RateLimit.Enabled {
+		if err := middleware.ConfigureRateLimiter(cfg, db, log); err != nil {
+			log.WithError(err).Error("Failed to configure rate limiter, limiting per replica in memory")
+		}
+	}
+
+	router := api.NewRouter(services, log, hub, cfg, monitoringService, backgroundMonitor, node)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
@@ -88,17 +148,26 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down server...")
-	
-	// Stop monitoring services
-	monitoringService.Stop()
-	backgroundMonitor.Stop()
 
+	// Stop receiving alerts, then ingest the queued ones while the
+	// services they need are still running
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if services.Ingestion != nil {
+		services.Ingestion.Stop()
+	}
+
+	// Stop monitoring services
+	monitoringService.Stop()
+	backgroundMonitor.Stop()
+	silenceScheduler.Stop()
+	heartbeatScheduler.Stop()
+	broker.Close()
+	node.Stop()
 
 	log.Info("Server exited gracefully")
 }
\ No newline at end of file
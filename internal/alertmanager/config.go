@@ -0,0 +1,149 @@
+// Package alertmanager translates between an Alertmanager configuration file
+// and alertbot routing rules, notification channels, inhibition rules and
+// alert group rules. Only the subset of alertmanager.yml that alertbot can
+// represent is modelled; everything else is reported as unsupported.
+package alertmanager
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the subset of alertmanager.yml understood by the importer
+type Config struct {
+	Global            *GlobalConfig  `yaml:"global,omitempty"`
+	Route             *Route         `yaml:"route,omitempty"`
+	Receivers         []Receiver     `yaml:"receivers,omitempty"`
+	InhibitRules      []InhibitRule  `yaml:"inhibit_rules,omitempty"`
+	TimeIntervals     []TimeInterval `yaml:"time_intervals,omitempty"`
+	MuteTimeIntervals []TimeInterval `yaml:"mute_time_intervals,omitempty"`
+	Templates         []string       `yaml:"templates,omitempty"`
+}
+
+// GlobalConfig holds defaults inherited by receivers
+type GlobalConfig struct {
+	ResolveTimeout   string `yaml:"resolve_timeout,omitempty"`
+	SMTPFrom         string `yaml:"smtp_from,omitempty"`
+	SMTPSmarthost    string `yaml:"smtp_smarthost,omitempty"`
+	SMTPAuthUsername string `yaml:"smtp_auth_username,omitempty"`
+	SMTPAuthPassword string `yaml:"smtp_auth_password,omitempty"`
+	SMTPRequireTLS   *bool  `yaml:"smtp_require_tls,omitempty"`
+	SlackAPIURL      string `yaml:"slack_api_url,omitempty"`
+
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+// Route is a node of the routing tree
+type Route struct {
+	Receiver            string            `yaml:"receiver,omitempty"`
+	GroupBy             []string          `yaml:"group_by,omitempty"`
+	Continue            bool              `yaml:"continue,omitempty"`
+	Match               map[string]string `yaml:"match,omitempty"`
+	MatchRE             map[string]string `yaml:"match_re,omitempty"`
+	Matchers            []string          `yaml:"matchers,omitempty"`
+	GroupWait           string            `yaml:"group_wait,omitempty"`
+	GroupInterval       string            `yaml:"group_interval,omitempty"`
+	RepeatInterval      string            `yaml:"repeat_interval,omitempty"`
+	MuteTimeIntervals   []string          `yaml:"mute_time_intervals,omitempty"`
+	ActiveTimeIntervals []string          `yaml:"active_time_intervals,omitempty"`
+	Routes              []*Route          `yaml:"routes,omitempty"`
+}
+
+// Receiver is a named set of notification integrations
+type Receiver struct {
+	Name            string           `yaml:"name"`
+	EmailConfigs    []EmailConfig    `yaml:"email_configs,omitempty"`
+	SlackConfigs    []SlackConfig    `yaml:"slack_configs,omitempty"`
+	TelegramConfigs []TelegramConfig `yaml:"telegram_configs,omitempty"`
+	WebhookConfigs  []WebhookConfig  `yaml:"webhook_configs,omitempty"`
+
+	// Integrations alertbot has no channel type for (pagerduty_configs, ...)
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+type EmailConfig struct {
+	SendResolved *bool  `yaml:"send_resolved,omitempty"`
+	To           string `yaml:"to"`
+	From         string `yaml:"from,omitempty"`
+	Smarthost    string `yaml:"smarthost,omitempty"`
+	AuthUsername string `yaml:"auth_username,omitempty"`
+	AuthPassword string `yaml:"auth_password,omitempty"`
+	RequireTLS   *bool  `yaml:"require_tls,omitempty"`
+
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+type SlackConfig struct {
+	SendResolved *bool  `yaml:"send_resolved,omitempty"`
+	APIURL       string `yaml:"api_url,omitempty"`
+	Channel      string `yaml:"channel,omitempty"`
+	Username     string `yaml:"username,omitempty"`
+	IconEmoji    string `yaml:"icon_emoji,omitempty"`
+	IconURL      string `yaml:"icon_url,omitempty"`
+
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+type TelegramConfig struct {
+	SendResolved *bool  `yaml:"send_resolved,omitempty"`
+	BotToken     string `yaml:"bot_token,omitempty"`
+	ChatID       int64  `yaml:"chat_id,omitempty"`
+	ParseMode    string `yaml:"parse_mode,omitempty"`
+
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+type WebhookConfig struct {
+	SendResolved *bool  `yaml:"send_resolved,omitempty"`
+	URL          string `yaml:"url,omitempty"`
+
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+// InhibitRule mutes target alerts while a source alert fires
+type InhibitRule struct {
+	SourceMatch    map[string]string `yaml:"source_match,omitempty"`
+	SourceMatchRE  map[string]string `yaml:"source_match_re,omitempty"`
+	SourceMatchers []string          `yaml:"source_matchers,omitempty"`
+	TargetMatch    map[string]string `yaml:"target_match,omitempty"`
+	TargetMatchRE  map[string]string `yaml:"target_match_re,omitempty"`
+	TargetMatchers []string          `yaml:"target_matchers,omitempty"`
+	Equal          []string          `yaml:"equal,omitempty"`
+}
+
+// TimeInterval is kept only to name it in the unsupported report
+type TimeInterval struct {
+	Name string `yaml:"name"`
+}
+
+// Parse decodes an alertmanager.yml document
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid alertmanager config: %w", err)
+	}
+	if cfg.Route == nil {
+		return nil, fmt.Errorf("invalid alertmanager config: missing route")
+	}
+	if cfg.Route.Receiver == "" {
+		return nil, fmt.Errorf("invalid alertmanager config: root route has no receiver")
+	}
+
+	names := make(map[string]bool, len(cfg.Receivers))
+	for _, r := range cfg.Receivers {
+		if r.Name == "" {
+			return nil, fmt.Errorf("invalid alertmanager config: receiver without name")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("invalid alertmanager config: duplicate receiver %q", r.Name)
+		}
+		names[r.Name] = true
+	}
+	return &cfg, nil
+}
+
+// Marshal encodes a config as YAML
+func Marshal(cfg *Config) ([]byte, error) {
+	return yaml.Marshal(cfg)
+}
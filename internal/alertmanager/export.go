@@ -0,0 +1,391 @@
+package alertmanager
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"alertbot/internal/models"
+)
+
+// BlackholeReceiver is the root receiver of exported configs; every routing
+// rule becomes a child route, so unmatched alerts notify nobody as in alertbot
+const BlackholeReceiver = "blackhole"
+
+type exporter struct {
+	cfg       *Config
+	issues    []Issue
+	channels  map[uint]*models.NotificationChannel
+	receivers map[string]bool
+}
+
+// Export translates alertbot objects into an Alertmanager config, e.g. to
+// roll back after an import
+func Export(channels []models.NotificationChannel, rules []models.RoutingRule, inhibitions []models.InhibitionRule, groupRules []models.AlertGroupRule) (*Config, []Issue) {
+	e := &exporter{
+		cfg: &Config{
+			Route:     &Route{Receiver: BlackholeReceiver},
+			Receivers: []Receiver{{Name: BlackholeReceiver}},
+		},
+		channels:  make(map[uint]*models.NotificationChannel, len(channels)),
+		receivers: map[string]bool{BlackholeReceiver: true},
+	}
+	for i := range channels {
+		e.channels[channels[i].ID] = &channels[i]
+	}
+
+	e.exportGroupRules(groupRules)
+
+	sorted := append([]models.RoutingRule{}, rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	for i := range sorted {
+		e.exportRule(&sorted[i])
+	}
+
+	for i := range inhibitions {
+		e.exportInhibition(&inhibitions[i])
+	}
+	return e.cfg, e.issues
+}
+
+func (e *exporter) issue(path, format string, args ...interface{}) {
+	e.issues = append(e.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// exportGroupRules puts the highest priority catch-all group rule on the
+// root route; rules with matchers have no route of their own to live on
+func (e *exporter) exportGroupRules(rules []models.AlertGroupRule) {
+	sorted := append([]models.AlertGroupRule{}, rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	rootSet := false
+	for _, rule := range sorted {
+		path := "alert_group_rules/" + rule.Name
+		if !rule.Enabled {
+			continue
+		}
+		items, _ := rule.Matchers["matchers"].([]interface{})
+		if len(items) > 0 || rootSet {
+			e.issue(path, "only one catch-all group rule can be exported, as the root route's grouping")
+			continue
+		}
+
+		e.cfg.Route.GroupBy = stringList(rule.GroupBy["labels"])
+		e.cfg.Route.GroupWait = formatDuration(rule.GroupWait)
+		e.cfg.Route.GroupInterval = formatDuration(rule.GroupInterval)
+		e.cfg.Route.RepeatInterval = formatDuration(rule.RepeatInterval)
+		rootSet = true
+	}
+}
+
+func (e *exporter) exportRule(rule *models.RoutingRule) {
+	path := "routing_rules/" + rule.Name
+	if !rule.Enabled {
+		e.issue(path, "disabled rule is not exported")
+		return
+	}
+
+	receiver := e.exportReceiver(rule, path)
+	matcherSets, logic := e.ruleMatchers(rule.Conditions, path)
+	if logic == "or" && len(matcherSets) > 1 {
+		e.issue(path, "'or' conditions are exported as one route per matcher")
+	}
+
+	for _, matchers := range matcherSets {
+		e.cfg.Route.Routes = append(e.cfg.Route.Routes, &Route{
+			Receiver: receiver,
+			Matchers: matchers,
+			Continue: true,
+		})
+	}
+}
+
+// exportReceiver returns the receiver for a rule's channels, creating it on
+// first use. Imported rules keep their original receiver name.
+func (e *exporter) exportReceiver(rule *models.RoutingRule, path string) string {
+	var ids []uint
+	for _, v := range anyList(rule.Receivers["channels"]) {
+		if id, ok := toUint(v); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	name, _ := rule.Receivers["receiver"].(string)
+	if name == "" {
+		if len(ids) == 1 && e.channels[ids[0]] != nil {
+			name = strings.TrimPrefix(e.channels[ids[0]].Name, NamePrefix)
+		} else {
+			name = strings.TrimPrefix(rule.Name, NamePrefix)
+		}
+	}
+	if e.receivers[name] {
+		return name
+	}
+	e.receivers[name] = true
+
+	receiver := Receiver{Name: name}
+	for _, id := range ids {
+		channel := e.channels[id]
+		if channel == nil {
+			e.issue(path, "channel %d does not exist", id)
+			continue
+		}
+		e.exportChannel(&receiver, channel)
+	}
+	e.cfg.Receivers = append(e.cfg.Receivers, receiver)
+	return name
+}
+
+func (e *exporter) exportChannel(r *Receiver, channel *models.NotificationChannel) {
+	path := "notification_channels/" + channel.Name
+	if !channel.Enabled {
+		e.issue(path, "disabled channel is not exported")
+		return
+	}
+	config := channel.Config
+
+	switch models.NotificationChannelType(channel.Type) {
+	case models.ChannelTypeEmail:
+		smarthost := configString(config, "smtp_host")
+		if port := configString(config, "smtp_port"); port != "" {
+			smarthost += ":" + port
+		}
+		requireTLS := configBool(config, "use_starttls") || configBool(config, "use_tls")
+		r.EmailConfigs = append(r.EmailConfigs, EmailConfig{
+			To:           strings.Join(stringList(config["to"]), ", "),
+			From:         configString(config, "from"),
+			Smarthost:    smarthost,
+			AuthUsername: configString(config, "username"),
+			AuthPassword: configString(config, "password"),
+			RequireTLS:   &requireTLS,
+		})
+		if len(stringList(config["cc"])) > 0 || len(stringList(config["bcc"])) > 0 {
+			e.issue(path, "cc and bcc recipients are not exported")
+		}
+
+	case models.ChannelTypeSlack:
+		r.SlackConfigs = append(r.SlackConfigs, SlackConfig{
+			APIURL:    configString(config, "webhook_url"),
+			Channel:   configString(config, "channel"),
+			Username:  configString(config, "username"),
+			IconEmoji: configString(config, "icon_emoji"),
+			IconURL:   configString(config, "icon_url"),
+		})
+
+	case models.ChannelTypeTelegram:
+		chatID, err := strconv.ParseInt(configString(config, "chat_id"), 10, 64)
+		if err != nil {
+			e.issue(path, "chat_id is not numeric; channel is not exported")
+			return
+		}
+		r.TelegramConfigs = append(r.TelegramConfigs, TelegramConfig{
+			BotToken: configString(config, "bot_token"),
+			ChatID:   chatID,
+		})
+
+	case models.ChannelTypeDingTalk, models.ChannelTypeWeChatWork:
+		r.WebhookConfigs = append(r.WebhookConfigs, WebhookConfig{URL: configString(config, "webhook_url")})
+		e.issue(path, "%s robots do not accept Alertmanager's webhook payload without an adapter", channel.Type)
+
+	default:
+		e.issue(path, "%s channels have no Alertmanager equivalent", channel.Type)
+	}
+}
+
+// ruleMatchers converts rule conditions into Alertmanager matcher lists. With
+// 'and' logic there is one list; with 'or' logic one per matcher.
+func (e *exporter) ruleMatchers(conditions models.JSONB, path string) ([][]string, string) {
+	logic, _ := conditions["logic"].(string)
+	logic = strings.ToLower(logic)
+
+	type condition struct {
+		name, value, operator string
+		isRegex               bool
+	}
+	var conds []condition
+	if items, ok := conditions["matchers"].([]interface{}); ok {
+		for _, item := range items {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			c := condition{operator: "equals"}
+			c.name, _ = obj["name"].(string)
+			c.value, _ = obj["value"].(string)
+			c.isRegex, _ = obj["is_regex"].(bool)
+			if op, ok := obj["operator"].(string); ok && op != "" {
+				c.operator = strings.ToLower(op)
+			}
+			conds = append(conds, c)
+		}
+	} else {
+		for _, name := range mapKeys(conditions) {
+			if name == "logic" {
+				continue
+			}
+			c := condition{name: name, operator: "equals"}
+			switch v := conditions[name].(type) {
+			case []interface{}:
+				c.value = strings.Join(stringList(v), "|")
+				c.operator = "in"
+			default:
+				c.value = fmt.Sprint(v)
+			}
+			conds = append(conds, c)
+		}
+	}
+
+	var matchers []string
+	for _, c := range conds {
+		var op, value string
+		switch c.operator {
+		case "equals", "eq":
+			op, value = "=", c.value
+			if c.isRegex {
+				op, value = "=~", unanchor(c.value)
+			}
+		case "not_equals", "ne":
+			op, value = "!=", c.value
+			if c.isRegex {
+				op, value = "!~", unanchor(c.value)
+			}
+		case "regex":
+			op, value = "=~", unanchor(c.value)
+		case "not_regex":
+			op, value = "!~", unanchor(c.value)
+		case "contains":
+			op, value = "=~", ".*"+regexp.QuoteMeta(c.value)+".*"
+		case "not_contains":
+			op, value = "!~", ".*"+regexp.QuoteMeta(c.value)+".*"
+		case "in", "not_in":
+			quoted := strings.Split(c.value, "|")
+			for i, v := range quoted {
+				quoted[i] = regexp.QuoteMeta(strings.TrimSpace(v))
+			}
+			op, value = "=~", strings.Join(quoted, "|")
+			if c.operator == "not_in" {
+				op = "!~"
+			}
+		default:
+			e.issue(path, "operator %s on %s has no Alertmanager equivalent and is dropped", c.operator, c.name)
+			continue
+		}
+		matchers = append(matchers, fmt.Sprintf("%s%s%q", c.name, op, value))
+	}
+
+	if logic == "or" {
+		sets := make([][]string, len(matchers))
+		for i, m := range matchers {
+			sets[i] = []string{m}
+		}
+		return sets, logic
+	}
+	return [][]string{matchers}, logic
+}
+
+func (e *exporter) exportInhibition(rule *models.InhibitionRule) {
+	path := "inhibition_rules/" + rule.Name
+	if !rule.Enabled {
+		e.issue(path, "disabled rule is not exported")
+		return
+	}
+	if rule.Duration > 0 {
+		e.issue(path, "duration is not supported by Alertmanager and is dropped")
+	}
+	e.cfg.InhibitRules = append(e.cfg.InhibitRules, InhibitRule{
+		SourceMatchers: storedMatchers(rule.SourceMatchers),
+		TargetMatchers: storedMatchers(rule.TargetMatchers),
+		Equal:          stringList(rule.EqualLabels["labels"]),
+	})
+}
+
+// storedMatchers renders the stored matcher JSONB as matcher strings
+func storedMatchers(data models.JSONB) []string {
+	var out []string
+	for _, item := range anyList(data["matchers"]) {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := obj["name"].(string)
+		value, _ := obj["value"].(string)
+		op := "="
+		if isRegex, _ := obj["is_regex"].(bool); isRegex {
+			op = "=~"
+		}
+		out = append(out, fmt.Sprintf("%s%s%q", name, op, value))
+	}
+	return out
+}
+
+// formatDuration renders seconds in the largest whole Alertmanager unit
+func formatDuration(seconds int) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d == 0:
+		return "0s"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+func anyList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func stringList(v interface{}) []string {
+	var out []string
+	switch list := v.(type) {
+	case []interface{}:
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+	case []string:
+		out = append(out, list...)
+	}
+	return out
+}
+
+func toUint(v interface{}) (uint, bool) {
+	switch n := v.(type) {
+	case float64:
+		return uint(n), n >= 0
+	case int:
+		return uint(n), n >= 0
+	case uint:
+		return n, true
+	}
+	return 0, false
+}
+
+func configString(config models.JSONB, key string) string {
+	switch v := config[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	}
+	return ""
+}
+
+func configBool(config models.JSONB, key string) bool {
+	b, _ := config[key].(bool)
+	return b
+}
@@ -0,0 +1,200 @@
+package alertmanager
+
+import (
+	"encoding/json"
+	"testing"
+
+	"alertbot/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// store gives imported objects IDs and resolves rule channels, then passes
+// them through JSON as the JSONB columns do
+func store(t *testing.T, result *Result) ([]models.NotificationChannel, []models.RoutingRule, []models.InhibitionRule, []models.AlertGroupRule) {
+	channels := append([]models.NotificationChannel(nil), result.Channels...)
+	ids := make(map[string]uint, len(channels))
+	for i := range channels {
+		channels[i].ID = uint(i + 1)
+		ids[channels[i].Name] = channels[i].ID
+	}
+
+	rules := make([]models.RoutingRule, len(result.RoutingRules))
+	for i, imported := range result.RoutingRules {
+		rules[i] = imported.Rule
+		channelIDs := make([]interface{}, len(imported.Channels))
+		for j, name := range imported.Channels {
+			channelIDs[j] = ids[name]
+		}
+		rules[i].Receivers = models.JSONB{"channels": channelIDs, "receiver": imported.Receiver}
+	}
+
+	return jsonCopy(t, channels), jsonCopy(t, rules), jsonCopy(t, result.InhibitionRules), jsonCopy(t, result.GroupRules)
+}
+
+func jsonCopy[T any](t *testing.T, v T) T {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out T
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	imported := importTestConfig(t)
+	cfg, issues := Export(store(t, imported))
+
+	data, err := Marshal(cfg)
+	require.NoError(t, err)
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	again := Import(parsed)
+
+	// Every receiver keeps its name, so channels come back unchanged
+	assert.ElementsMatch(t, imported.Channels, again.Channels)
+	assert.Equal(t, imported.InhibitionRules, again.InhibitionRules)
+
+	// The routing tree is flattened, but alerts reach the same channels
+	for _, tt := range routingCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, notified(t, again.RoutingRules, tt.labels))
+		})
+	}
+
+	// Only the catch-all group rule has a route to live on
+	require.Len(t, again.GroupRules, 1)
+	root := again.GroupRules[0]
+	assert.Equal(t, imported.GroupRules[0].GroupBy, root.GroupBy)
+	assert.Equal(t, imported.GroupRules[0].GroupWait, root.GroupWait)
+	assert.Equal(t, imported.GroupRules[0].GroupInterval, root.GroupInterval)
+	assert.Equal(t, imported.GroupRules[0].RepeatInterval, root.RepeatInterval)
+
+	paths := make(map[string]bool)
+	for _, issue := range issues {
+		paths[issue.Path] = true
+	}
+	assert.True(t, paths["alert_group_rules/am:root/0"])
+	assert.True(t, paths["notification_channels/am:web-webhook-2"])
+}
+
+func TestExportRuleMatchers(t *testing.T) {
+	item := func(name, operator, value string, isRegex bool) interface{} {
+		return map[string]interface{}{"name": name, "operator": operator, "value": value, "is_regex": isRegex}
+	}
+
+	tests := []struct {
+		name       string
+		conditions models.JSONB
+		want       [][]string
+		issues     int
+	}{
+		{
+			name:       "equality",
+			conditions: models.JSONB{"logic": "and", "matchers": []interface{}{item("team", "equals", "db", false), item("env", "not_equals", "dev", false)}},
+			want:       [][]string{{`team="db"`, `env!="dev"`}},
+		},
+		{
+			name:       "anchored regex",
+			conditions: models.JSONB{"logic": "and", "matchers": []interface{}{item("env", "equals", "^(?:prod|staging)$", true)}},
+			want:       [][]string{{`env=~"prod|staging"`}},
+		},
+		{
+			name:       "unanchored regex matches anywhere",
+			conditions: models.JSONB{"logic": "and", "matchers": []interface{}{item("instance", "regex", "db-", false)}},
+			want:       [][]string{{`instance=~".*(?:db-).*"`}},
+		},
+		{
+			name:       "contains",
+			conditions: models.JSONB{"logic": "and", "matchers": []interface{}{item("summary", "contains", "disk.full", false), item("summary", "not_contains", "test", false)}},
+			want:       [][]string{{`summary=~".*disk\\.full.*"`, `summary!~".*test.*"`}},
+		},
+		{
+			name:       "in",
+			conditions: models.JSONB{"logic": "and", "matchers": []interface{}{item("severity", "in", "critical| page", false), item("env", "not_in", "dev|test", false)}},
+			want:       [][]string{{`severity=~"critical|page"`, `env!~"dev|test"`}},
+		},
+		{
+			name:       "or logic",
+			conditions: models.JSONB{"logic": "or", "matchers": []interface{}{item("team", "equals", "db", false), item("team", "equals", "web", false)}},
+			want:       [][]string{{`team="db"`}, {`team="web"`}},
+			issues:     1,
+		},
+		{
+			name:       "legacy map",
+			conditions: models.JSONB{"severity": []interface{}{"critical", "warning"}, "team": "db"},
+			want:       [][]string{{`severity=~"critical|warning"`, `team="db"`}},
+		},
+		{
+			name:       "unknown operator",
+			conditions: models.JSONB{"logic": "and", "matchers": []interface{}{item("team", "starts_with", "d", false)}},
+			want:       [][]string{nil},
+			issues:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, issues := Export(nil, []models.RoutingRule{{Name: "rule", Conditions: tt.conditions, Enabled: true}}, nil, nil)
+			var got [][]string
+			for _, route := range cfg.Route.Routes {
+				assert.True(t, route.Continue)
+				got = append(got, route.Matchers)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Len(t, issues, tt.issues)
+		})
+	}
+}
+
+func TestExportSkipsDisabled(t *testing.T) {
+	channels := []models.NotificationChannel{
+		{ID: 1, Name: "ops", Type: string(models.ChannelTypeSlack), Config: models.JSONB{"webhook_url": "https://hooks"}, Enabled: false},
+		{ID: 2, Name: "sms", Type: string(models.ChannelTypeSMS), Enabled: true},
+	}
+	rules := []models.RoutingRule{
+		{Name: "on", Enabled: true, Priority: 1, Conditions: models.JSONB{"team": "db"}, Receivers: models.JSONB{"channels": []interface{}{1.0, 2.0, 3.0}}},
+		{Name: "off", Enabled: false, Priority: 2},
+	}
+	inhibitions := []models.InhibitionRule{{Name: "off", Enabled: false}}
+
+	cfg, issues := Export(channels, rules, inhibitions, nil)
+	require.Len(t, cfg.Route.Routes, 1)
+	require.Len(t, cfg.Receivers, 2)
+	assert.Equal(t, BlackholeReceiver, cfg.Receivers[0].Name)
+	assert.Equal(t, "on", cfg.Receivers[1].Name)
+	assert.Empty(t, cfg.Receivers[1].SlackConfigs)
+	assert.Empty(t, cfg.InhibitRules)
+
+	paths := make(map[string]bool)
+	for _, issue := range issues {
+		paths[issue.Path] = true
+	}
+	assert.Equal(t, map[string]bool{
+		"routing_rules/off":         true,
+		"routing_rules/on":          true,
+		"notification_channels/ops": true,
+		"notification_channels/sms": true,
+		"inhibition_rules/off":      true,
+	}, paths)
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "0s"},
+		{45, "45s"},
+		{300, "5m"},
+		{5400, "90m"},
+		{14400, "4h"},
+		{172800, "2d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatDuration(tt.seconds))
+		})
+	}
+}
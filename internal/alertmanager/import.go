@@ -0,0 +1,519 @@
+package alertmanager
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+)
+
+// Alertmanager's defaults for route timing
+const (
+	defaultGroupWait      = 30 * time.Second
+	defaultGroupInterval  = 5 * time.Minute
+	defaultRepeatInterval = 4 * time.Hour
+)
+
+// NamePrefix marks objects created by the importer so a re-import updates
+// them in place
+const NamePrefix = "am:"
+
+// Issue is a construct that was skipped or translated with changed behaviour
+type Issue = models.ConfigIssue
+
+// RoutingRule is an imported routing rule and the channels it notifies,
+// named because channel IDs are only known once channels are stored
+type RoutingRule struct {
+	Rule     models.RoutingRule
+	Receiver string
+	Channels []string
+}
+
+// Result is the alertbot translation of an Alertmanager config
+type Result struct {
+	Channels        []models.NotificationChannel
+	RoutingRules    []RoutingRule
+	InhibitionRules []models.InhibitionRule
+	GroupRules      []models.AlertGroupRule
+	Unsupported     []Issue
+}
+
+type converter struct {
+	cfg       *Config
+	result    *Result
+	receivers map[string][]string // Receiver name to channel names
+	rules     []RoutingRule       // In Alertmanager evaluation order
+}
+
+// routeSettings are the values a route inherits from its parent
+type routeSettings struct {
+	receiver       string
+	groupBy        []string
+	groupWait      string
+	groupInterval  string
+	repeatInterval string
+}
+
+// Import translates an Alertmanager config into alertbot objects
+func Import(cfg *Config) *Result {
+	c := &converter{
+		cfg:       cfg,
+		result:    &Result{},
+		receivers: make(map[string][]string),
+	}
+
+	c.importGlobal()
+	for i := range cfg.Receivers {
+		c.importReceiver(&cfg.Receivers[i])
+	}
+
+	root := routeSettings{
+		receiver:       cfg.Route.Receiver,
+		groupWait:      defaultGroupWait.String(),
+		groupInterval:  defaultGroupInterval.String(),
+		repeatInterval: defaultRepeatInterval.String(),
+	}
+	c.importRoute(cfg.Route, "root", nil, root, true)
+
+	// Earlier rules in evaluation order get higher priority
+	for i := range c.rules {
+		c.rules[i].Rule.Priority = len(c.rules) - i
+	}
+	c.result.RoutingRules = c.rules
+
+	for i := range cfg.InhibitRules {
+		c.importInhibitRule(&cfg.InhibitRules[i], i)
+	}
+
+	for _, ti := range cfg.TimeIntervals {
+		c.issue("time_intervals/"+ti.Name, "time intervals are not supported; routes referring to them notify at all times")
+	}
+	for _, ti := range cfg.MuteTimeIntervals {
+		c.issue("mute_time_intervals/"+ti.Name, "time intervals are not supported; routes referring to them notify at all times")
+	}
+	if len(cfg.Templates) > 0 {
+		c.issue("templates", "notification templates are not imported; alertbot templates are used")
+	}
+
+	return c.result
+}
+
+func (c *converter) issue(path, format string, args ...interface{}) {
+	c.result.Unsupported = append(c.result.Unsupported, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *converter) importGlobal() {
+	if c.cfg.Global == nil {
+		return
+	}
+	if keys := mapKeys(c.cfg.Global.Other); len(keys) > 0 {
+		c.issue("global", "settings not supported: %s", strings.Join(keys, ", "))
+	}
+}
+
+func (c *converter) importReceiver(r *Receiver) {
+	path := "receivers/" + r.Name
+	total := len(r.EmailConfigs) + len(r.SlackConfigs) + len(r.TelegramConfigs) + len(r.WebhookConfigs)
+	index := 0
+
+	add := func(kind string, channelType models.NotificationChannelType, config models.JSONB, sendResolved *bool, other map[string]interface{}) {
+		index++
+		name := NamePrefix + r.Name
+		if total > 1 {
+			name = fmt.Sprintf("%s%s-%s-%d", NamePrefix, r.Name, kind, index)
+		}
+		itemPath := fmt.Sprintf("%s/%s_configs/%d", path, kind, index)
+		if sendResolved != nil && !*sendResolved {
+			c.issue(itemPath, "send_resolved: false is ignored; alertbot always sends resolved notifications")
+		}
+		if keys := mapKeys(other); len(keys) > 0 {
+			c.issue(itemPath, "settings not supported: %s", strings.Join(keys, ", "))
+		}
+
+		c.result.Channels = append(c.result.Channels, models.NotificationChannel{
+			Name:    name,
+			Type:    string(channelType),
+			Config:  config,
+			Enabled: true,
+		})
+		c.receivers[r.Name] = append(c.receivers[r.Name], name)
+	}
+
+	for _, e := range r.EmailConfigs {
+		add("email", models.ChannelTypeEmail, c.emailConfig(e), e.SendResolved, e.Other)
+	}
+	for _, s := range r.SlackConfigs {
+		apiURL := s.APIURL
+		if apiURL == "" && c.cfg.Global != nil {
+			apiURL = c.cfg.Global.SlackAPIURL
+		}
+		config := models.JSONB{"webhook_url": apiURL, "channel": s.Channel}
+		setIfNotEmpty(config, "username", s.Username)
+		setIfNotEmpty(config, "icon_emoji", s.IconEmoji)
+		setIfNotEmpty(config, "icon_url", s.IconURL)
+		add("slack", models.ChannelTypeSlack, config, s.SendResolved, s.Other)
+	}
+	for _, t := range r.TelegramConfigs {
+		config := models.JSONB{"bot_token": t.BotToken, "chat_id": strconv.FormatInt(t.ChatID, 10)}
+		add("telegram", models.ChannelTypeTelegram, config, t.SendResolved, t.Other)
+	}
+	for i, w := range r.WebhookConfigs {
+		channelType, ok := webhookChannelType(w.URL)
+		if !ok {
+			total--
+			c.issue(fmt.Sprintf("%s/webhook_configs/%d", path, i+1), "generic webhooks are not supported; only DingTalk and WeChat Work robot URLs are imported")
+			continue
+		}
+		add("webhook", channelType, models.JSONB{"webhook_url": w.URL}, w.SendResolved, w.Other)
+	}
+
+	for _, key := range mapKeys(r.Other) {
+		c.issue(path+"/"+key, "integration is not supported by alertbot")
+	}
+	if len(c.receivers[r.Name]) == 0 {
+		c.issue(path, "receiver has no supported integrations; routes to it will not notify")
+	}
+}
+
+func (c *converter) emailConfig(e EmailConfig) models.JSONB {
+	g := c.cfg.Global
+	if g == nil {
+		g = &GlobalConfig{}
+	}
+
+	from := firstNonEmpty(e.From, g.SMTPFrom)
+	smarthost := firstNonEmpty(e.Smarthost, g.SMTPSmarthost)
+	host, port := smarthost, 25
+	if h, p, ok := strings.Cut(smarthost, ":"); ok {
+		host = h
+		if n, err := strconv.Atoi(p); err == nil {
+			port = n
+		}
+	}
+	requireTLS := true
+	if e.RequireTLS != nil {
+		requireTLS = *e.RequireTLS
+	} else if g.SMTPRequireTLS != nil {
+		requireTLS = *g.SMTPRequireTLS
+	}
+
+	var to []interface{}
+	for _, addr := range strings.Split(e.To, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+
+	return models.JSONB{
+		"smtp_host":    host,
+		"smtp_port":    port,
+		"username":     firstNonEmpty(e.AuthUsername, g.SMTPAuthUsername),
+		"password":     firstNonEmpty(e.AuthPassword, g.SMTPAuthPassword),
+		"from":         from,
+		"to":           to,
+		"use_starttls": requireTLS,
+	}
+}
+
+// webhookChannelType recognises robot webhooks alertbot has channel types for
+func webhookChannelType(rawURL string) (models.NotificationChannelType, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	switch u.Hostname() {
+	case "oapi.dingtalk.com":
+		return models.ChannelTypeDingTalk, true
+	case "qyapi.weixin.qq.com":
+		return models.ChannelTypeWeChatWork, true
+	}
+	return "", false
+}
+
+// importRoute flattens a route into routing rules. Alertmanager stops at the
+// first matching route unless continue is set, while alertbot notifies every
+// matching rule, so earlier siblings and children are negated where a single
+// matcher makes that possible.
+func (c *converter) importRoute(r *Route, path string, inherited matcher.Matchers, parent routeSettings, isRoot bool) {
+	own, err := routeMatchers(r.Match, r.MatchRE, r.Matchers)
+	if err != nil {
+		c.issue(path, "route skipped: %v", err)
+		return
+	}
+	conds := append(append(matcher.Matchers{}, inherited...), own...)
+
+	settings := parent
+	if r.Receiver != "" {
+		settings.receiver = r.Receiver
+	}
+	if r.GroupBy != nil {
+		settings.groupBy = r.GroupBy
+	}
+	settings.groupWait = firstNonEmpty(r.GroupWait, settings.groupWait)
+	settings.groupInterval = firstNonEmpty(r.GroupInterval, settings.groupInterval)
+	settings.repeatInterval = firstNonEmpty(r.RepeatInterval, settings.repeatInterval)
+
+	if len(r.MuteTimeIntervals) > 0 || len(r.ActiveTimeIntervals) > 0 {
+		c.issue(path, "time intervals are ignored; the route notifies at all times")
+	}
+	if isRoot || r.GroupBy != nil || r.GroupWait != "" || r.GroupInterval != "" || r.RepeatInterval != "" {
+		c.importGroupRule(path, conds, settings)
+	}
+
+	// Children are evaluated first; each one that stops evaluation is
+	// negated for later siblings and for this route's own fallback rule
+	var stops matcher.Matchers
+	fallthroughBlocked := false
+	for i, child := range r.Routes {
+		childPath := fmt.Sprintf("%s/%d", path, i)
+		if fallthroughBlocked {
+			c.issue(childPath, "route is unreachable in Alertmanager after a catch-all sibling but is imported")
+		}
+		c.importRoute(child, childPath, append(append(matcher.Matchers{}, conds...), stops...), settings, false)
+		if child.Continue {
+			continue
+		}
+
+		childOwn, err := routeMatchers(child.Match, child.MatchRE, child.Matchers)
+		switch {
+		case err != nil:
+		case len(childOwn) == 0:
+			fallthroughBlocked = true
+		case len(childOwn) == 1:
+			stops = append(stops, negate(childOwn[0]))
+		default:
+			c.issue(childPath, "route has several matchers and cannot be excluded from later routes; alerts it handles may also notify through %s", path)
+		}
+	}
+
+	if fallthroughBlocked {
+		return
+	}
+
+	rule := models.RoutingRule{
+		Name:        NamePrefix + path,
+		Description: fmt.Sprintf("Imported from Alertmanager route %s (receiver %s)", path, settings.receiver),
+		Conditions:  engine.ConditionsFromMatchers(append(append(matcher.Matchers{}, conds...), stops...)),
+		Enabled:     true,
+	}
+	c.rules = append(c.rules, RoutingRule{
+		Rule:     rule,
+		Receiver: settings.receiver,
+		Channels: c.receivers[settings.receiver],
+	})
+}
+
+func (c *converter) importGroupRule(path string, conds matcher.Matchers, s routeSettings) {
+	if len(s.groupBy) == 0 {
+		c.issue(path, "group_by is empty; alertbot requires grouping labels, so no group rule is created")
+		return
+	}
+	for _, label := range s.groupBy {
+		if label == "..." {
+			c.issue(path, "group_by: ['...'] is not supported; no group rule is created")
+			return
+		}
+	}
+
+	var positive matcher.Matchers
+	for _, m := range conds {
+		if m.Type == matcher.MatchEqual || m.Type == matcher.MatchRegexp {
+			positive = append(positive, m)
+		}
+	}
+	if len(positive) < len(conds) {
+		c.issue(path, "negative matchers are dropped from the group rule")
+	}
+	encoded, _ := matcher.Encode(positive)
+
+	labels := make([]interface{}, len(s.groupBy))
+	for i, l := range s.groupBy {
+		labels[i] = l
+	}
+
+	c.result.GroupRules = append(c.result.GroupRules, models.AlertGroupRule{
+		Name:           NamePrefix + path,
+		Description:    fmt.Sprintf("Imported from Alertmanager route %s", path),
+		GroupBy:        models.JSONB{"labels": labels},
+		GroupWait:      c.seconds(path, "group_wait", s.groupWait, 0, 3600),
+		GroupInterval:  c.seconds(path, "group_interval", s.groupInterval, 60, 86400),
+		RepeatInterval: c.seconds(path, "repeat_interval", s.repeatInterval, 300, 604800),
+		Matchers:       models.JSONB(encoded),
+		Priority:       len(conds),
+		Enabled:        true,
+	})
+}
+
+// seconds converts an Alertmanager duration, clamping it to alertbot's range
+func (c *converter) seconds(path, field, value string, min, max int) int {
+	d, err := ParseDuration(value)
+	if err != nil {
+		c.issue(path, "invalid %s %q", field, value)
+		return min
+	}
+	secs := int(d / time.Second)
+	if secs < min || secs > max {
+		clamped := secs
+		if clamped < min {
+			clamped = min
+		} else {
+			clamped = max
+		}
+		c.issue(path, "%s %s is outside alertbot's range and was set to %ds", field, value, clamped)
+		return clamped
+	}
+	return secs
+}
+
+func (c *converter) importInhibitRule(r *InhibitRule, i int) {
+	path := fmt.Sprintf("inhibit_rules/%d", i)
+
+	source, err := routeMatchers(r.SourceMatch, r.SourceMatchRE, r.SourceMatchers)
+	if err != nil {
+		c.issue(path, "rule skipped: %v", err)
+		return
+	}
+	target, err := routeMatchers(r.TargetMatch, r.TargetMatchRE, r.TargetMatchers)
+	if err != nil {
+		c.issue(path, "rule skipped: %v", err)
+		return
+	}
+	if len(source) == 0 || len(target) == 0 {
+		c.issue(path, "rule skipped: alertbot requires both source and target matchers")
+		return
+	}
+
+	sourceJSON, err := matcher.Encode(source)
+	if err != nil {
+		c.issue(path, "rule skipped: negative source matchers are not supported")
+		return
+	}
+	targetJSON, err := matcher.Encode(target)
+	if err != nil {
+		c.issue(path, "rule skipped: negative target matchers are not supported")
+		return
+	}
+
+	equal := make([]interface{}, len(r.Equal))
+	for j, l := range r.Equal {
+		equal[j] = l
+	}
+
+	c.result.InhibitionRules = append(c.result.InhibitionRules, models.InhibitionRule{
+		Name:           fmt.Sprintf("%s%s", NamePrefix, path),
+		Description:    fmt.Sprintf("Imported from Alertmanager: %s inhibits %s", source, target),
+		SourceMatchers: models.JSONB(sourceJSON),
+		TargetMatchers: models.JSONB(targetJSON),
+		EqualLabels:    models.JSONB{"labels": equal},
+		Enabled:        true,
+	})
+}
+
+// routeMatchers combines the legacy match/match_re maps with matcher strings
+func routeMatchers(match, matchRE map[string]string, exprs []string) (matcher.Matchers, error) {
+	var ms matcher.Matchers
+	for _, name := range sortedKeys(match) {
+		m, err := matcher.New(matcher.MatchEqual, name, match[name])
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	for _, name := range sortedKeys(matchRE) {
+		m, err := matcher.New(matcher.MatchRegexp, name, matchRE[name])
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	for _, expr := range exprs {
+		parsed, err := matcher.Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, parsed...)
+	}
+	return ms, nil
+}
+
+func negate(m *matcher.Matcher) *matcher.Matcher {
+	opposite := map[matcher.Type]matcher.Type{
+		matcher.MatchEqual:     matcher.MatchNotEqual,
+		matcher.MatchNotEqual:  matcher.MatchEqual,
+		matcher.MatchRegexp:    matcher.MatchNotRegexp,
+		matcher.MatchNotRegexp: matcher.MatchRegexp,
+	}
+	n, _ := matcher.New(opposite[m.Type], m.Name, m.Value)
+	return n
+}
+
+var anchoredPattern = regexp.MustCompile(`^\^\(\?:(.*)\)\$$`)
+
+// unanchor reverses the anchoring added by engine.ConditionsFromMatchers;
+// unanchored rule patterns match anywhere
+func unanchor(pattern string) string {
+	if m := anchoredPattern.FindStringSubmatch(pattern); m != nil {
+		return m[1]
+	}
+	return ".*(?:" + pattern + ").*"
+}
+
+// ParseDuration parses Alertmanager durations, which also allow d, w and y units
+func ParseDuration(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+		'y': 365 * 24 * time.Hour,
+	}
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func mapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func setIfNotEmpty(m models.JSONB, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
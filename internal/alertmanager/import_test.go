@@ -0,0 +1,313 @@
+package alertmanager
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig exercises receivers of every supported kind, nested routes
+// with and without continue, and inhibit rules
+const testConfig = `
+global:
+  smtp_from: alertbot@example.com
+  smtp_smarthost: smtp.example.com:587
+  smtp_auth_username: alertbot
+  smtp_auth_password: secret
+  slack_api_url: https://hooks.slack.com/services/T000/B000/XXX
+  resolve_timeout: 5m
+
+route:
+  receiver: ops
+  group_by: [alertname, cluster]
+  group_wait: 30s
+  group_interval: 5m
+  repeat_interval: 4h
+  routes:
+    - matchers: ['team="db"']
+      receiver: dba
+      group_by: [alertname, instance]
+      routes:
+        - matchers: ['severity="critical"']
+          receiver: dba-pager
+          continue: true
+    - match_re:
+        service: "web|api"
+      receiver: web
+    - matchers: ['env="staging"', 'severity="info"']
+      receiver: ops
+
+receivers:
+  - name: ops
+    email_configs:
+      - to: ops@example.com, sre@example.com
+  - name: dba
+    slack_configs:
+      - channel: '#dba'
+        username: alertbot
+  - name: dba-pager
+    telegram_configs:
+      - bot_token: "123:abc"
+        chat_id: -100123
+  - name: web
+    email_configs:
+      - to: web@example.com
+        require_tls: false
+    webhook_configs:
+      - url: https://oapi.dingtalk.com/robot/send?access_token=abc
+
+inhibit_rules:
+  - source_matchers: ['severity="critical"']
+    target_matchers: ['severity=~"warning|info"']
+    equal: [alertname, instance]
+`
+
+// notified returns the channels the routing rules send an alert to, the
+// way alertbot routes: every matching rule notifies
+func notified(t *testing.T, rules []RoutingRule, labels map[string]string) []string {
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		ms, ok := engine.MatchersFromConditions(rule.Rule.Conditions)
+		require.True(t, ok, rule.Rule.Name)
+		if ms.Matches(labels) {
+			for _, channel := range rule.Channels {
+				seen[channel] = true
+			}
+		}
+	}
+	channels := make([]string, 0, len(seen))
+	for channel := range seen {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// routingCases are alerts and the channels Alertmanager notifies for
+// testConfig
+var routingCases = []struct {
+	name   string
+	labels map[string]string
+	want   []string
+}{
+	{"default receiver", map[string]string{"alertname": "Watchdog"}, []string{"am:ops"}},
+	{"team route", map[string]string{"team": "db", "severity": "warning"}, []string{"am:dba"}},
+	{"continue child and parent", map[string]string{"team": "db", "severity": "critical"}, []string{"am:dba", "am:dba-pager"}},
+	{"regex route", map[string]string{"service": "api"}, []string{"am:web-email-1", "am:web-webhook-2"}},
+	{"regex route is anchored", map[string]string{"service": "api-gateway"}, []string{"am:ops"}},
+	{"first match wins", map[string]string{"team": "db", "service": "web"}, []string{"am:dba"}},
+	{"several matchers", map[string]string{"env": "staging", "severity": "info"}, []string{"am:ops"}},
+}
+
+func importTestConfig(t *testing.T) *Result {
+	cfg, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+	return Import(cfg)
+}
+
+func TestImportChannels(t *testing.T) {
+	result := importTestConfig(t)
+
+	channels := make(map[string]models.NotificationChannel, len(result.Channels))
+	for _, channel := range result.Channels {
+		channels[channel.Name] = channel
+		assert.True(t, channel.Enabled, channel.Name)
+	}
+	require.Len(t, channels, 5)
+
+	assert.Equal(t, string(models.ChannelTypeEmail), channels["am:ops"].Type)
+	assert.Equal(t, models.JSONB{
+		"smtp_host":    "smtp.example.com",
+		"smtp_port":    587,
+		"username":     "alertbot",
+		"password":     "secret",
+		"from":         "alertbot@example.com",
+		"to":           []interface{}{"ops@example.com", "sre@example.com"},
+		"use_starttls": true,
+	}, channels["am:ops"].Config)
+	assert.Equal(t, false, channels["am:web-email-1"].Config["use_starttls"])
+
+	assert.Equal(t, models.JSONB{
+		"webhook_url": "https://hooks.slack.com/services/T000/B000/XXX",
+		"channel":     "#dba",
+		"username":    "alertbot",
+	}, channels["am:dba"].Config)
+	assert.Equal(t, models.JSONB{"bot_token": "123:abc", "chat_id": "-100123"}, channels["am:dba-pager"].Config)
+	assert.Equal(t, string(models.ChannelTypeDingTalk), channels["am:web-webhook-2"].Type)
+}
+
+func TestImportRoutes(t *testing.T) {
+	result := importTestConfig(t)
+
+	// Rules are listed in Alertmanager evaluation order, highest priority first
+	for i := 1; i < len(result.RoutingRules); i++ {
+		assert.Greater(t, result.RoutingRules[i-1].Rule.Priority, result.RoutingRules[i].Rule.Priority)
+	}
+	for _, rule := range result.RoutingRules {
+		assert.True(t, strings.HasPrefix(rule.Rule.Name, NamePrefix), rule.Rule.Name)
+	}
+
+	for _, tt := range routingCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, notified(t, result.RoutingRules, tt.labels))
+		})
+	}
+}
+
+func TestImportGroupRules(t *testing.T) {
+	result := importTestConfig(t)
+
+	rules := make(map[string]models.AlertGroupRule, len(result.GroupRules))
+	for _, rule := range result.GroupRules {
+		rules[rule.Name] = rule
+	}
+	require.Len(t, rules, 2)
+
+	root := rules["am:root"]
+	assert.Equal(t, models.JSONB{"labels": []interface{}{"alertname", "cluster"}}, root.GroupBy)
+	assert.Equal(t, 30, root.GroupWait)
+	assert.Equal(t, 300, root.GroupInterval)
+	assert.Equal(t, 14400, root.RepeatInterval)
+	assert.Equal(t, 0, root.Priority)
+
+	// The child inherits timing and narrows the matchers
+	db := rules["am:root/0"]
+	assert.Equal(t, models.JSONB{"labels": []interface{}{"alertname", "instance"}}, db.GroupBy)
+	assert.Equal(t, 30, db.GroupWait)
+	assert.Equal(t, 1, db.Priority)
+	assert.Equal(t, models.JSONB{"matchers": []interface{}{
+		map[string]interface{}{"name": "team", "value": "db", "is_regex": false},
+	}}, db.Matchers)
+}
+
+func TestImportInhibitRules(t *testing.T) {
+	result := importTestConfig(t)
+
+	require.Len(t, result.InhibitionRules, 1)
+	rule := result.InhibitionRules[0]
+	assert.Equal(t, "am:inhibit_rules/0", rule.Name)
+	assert.Equal(t, models.JSONB{"matchers": []interface{}{
+		map[string]interface{}{"name": "severity", "value": "critical", "is_regex": false},
+	}}, rule.SourceMatchers)
+	assert.Equal(t, models.JSONB{"matchers": []interface{}{
+		map[string]interface{}{"name": "severity", "value": "warning|info", "is_regex": true},
+	}}, rule.TargetMatchers)
+	assert.Equal(t, models.JSONB{"labels": []interface{}{"alertname", "instance"}}, rule.EqualLabels)
+}
+
+func TestImportUnsupported(t *testing.T) {
+	cfg, err := Parse([]byte(`
+global:
+  pagerduty_url: https://events.pagerduty.com
+route:
+  receiver: pager
+  group_by: ['...']
+  routes:
+    - matchers: ['team="db"']
+      receiver: pager
+      mute_time_intervals: [nights]
+      group_by: [alertname]
+      repeat_interval: 30d
+    - matchers: ['team=~"db(']
+      receiver: pager
+receivers:
+  - name: pager
+    pagerduty_configs:
+      - service_key: abc
+    webhook_configs:
+      - url: https://example.com/hook
+        send_resolved: false
+inhibit_rules:
+  - source_matchers: ['severity!="info"']
+    target_matchers: ['severity="info"']
+  - target_matchers: ['severity="info"']
+time_intervals:
+  - name: nights
+templates: ['/etc/alertmanager/*.tmpl']
+`))
+	require.NoError(t, err)
+	result := Import(cfg)
+
+	assert.Empty(t, result.Channels)
+	assert.Empty(t, result.InhibitionRules)
+	require.Len(t, result.GroupRules, 1)
+	assert.Equal(t, 604800, result.GroupRules[0].RepeatInterval)
+
+	paths := make(map[string]string)
+	for _, issue := range result.Unsupported {
+		paths[issue.Path] += issue.Message + "\n"
+	}
+	for _, path := range []string{
+		"global",
+		"receivers/pager",
+		"receivers/pager/pagerduty_configs",
+		"receivers/pager/webhook_configs/1",
+		"root",
+		"root/0",
+		"root/1",
+		"inhibit_rules/0",
+		"inhibit_rules/1",
+		"time_intervals/nights",
+		"templates",
+	} {
+		assert.Contains(t, paths, path)
+	}
+	assert.Contains(t, paths["root/0"], "repeat_interval 30d is outside alertbot's range")
+	assert.Contains(t, paths["root/0"], "time intervals are ignored")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"not yaml", "route: ["},
+		{"no route", "receivers: [{name: ops}]"},
+		{"no root receiver", "route: {group_by: [alertname]}"},
+		{"unnamed receiver", "route: {receiver: ops}\nreceivers: [{email_configs: []}]"},
+		{"duplicate receiver", "route: {receiver: ops}\nreceivers: [{name: ops}, {name: ops}]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		{"30s", 30 * time.Second, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"2d", 48 * time.Hour, false},
+		{"1w", 7 * 24 * time.Hour, false},
+		{"1y", 365 * 24 * time.Hour, false},
+		{"", 0, true},
+		{"xd", 0, true},
+		{"5", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDuration(tt.value)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package api
 
 import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
 	"alertbot/internal/models"
 	"alertbot/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AlertHandler struct {
@@ -30,17 +36,75 @@ func (h *AlertHandler) ReceiveAlerts(c *gin.Context) {
 		return
 	}
 
-	err := h.services.Alert.ReceiveAlerts(c.Request.Context(), alerts)
-	if err != nil {
-		h.response.InternalServerError(c, "Failed to process alerts", err.Error())
+	if err := service.ValidatePrometheusAlerts(alerts); err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+			return
+		}
+		h.response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	if queue := h.services.Ingestion; queue != nil {
+		h.enqueueAlerts(c, queue, alerts)
+		return
+	}
+
+	results := h.services.Alert.IngestAlerts(c.Request.Context(), alerts)
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Action]++
+	}
+
+	// Prometheus resends the batch only on server errors, so fail the
+	// request when no alert was stored
+	if counts["failed"] == len(results) {
+		h.response.InternalServerError(c, "Failed to process alerts", results)
 		return
 	}
 
+	message := "Alerts processed successfully"
+	if counts["failed"] > 0 {
+		message = "Alerts processed with errors"
+	}
 	h.response.Success(c, gin.H{
 		"received":   len(alerts),
-		"processed":  len(alerts),
-		"duplicates": 0,
-	}, "Alerts processed successfully")
+		"processed":  len(alerts) - counts["failed"],
+		"created":    counts["created"],
+		"updated":    counts["updated"],
+		"duplicates": counts["deduplicated"],
+		"dropped":    counts["dropped"],
+		"failed":     counts["failed"],
+		"results":    results,
+	}, message)
+}
+
+// enqueueAlerts queues the alerts for ingestion and answers 202. A full
+// queue answers 429 and a stopped one 503, both with Retry-After. A batch
+// larger than the queue answers 413, since retrying it cannot succeed.
+func (h *AlertHandler) enqueueAlerts(c *gin.Context, queue *service.IngestionQueue, alerts []models.PrometheusAlert) {
+	err := queue.Enqueue(alerts)
+	if errors.Is(err, service.ErrIngestionBatchTooLarge) {
+		h.response.PayloadTooLarge(c, err.Error())
+		return
+	}
+	if err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(queue.RetryAfter().Seconds()))))
+		if errors.Is(err, service.ErrIngestionQueueFull) {
+			h.response.RateLimited(c, err.Error())
+		} else {
+			h.response.ServiceUnavailable(c, err.Error())
+		}
+		return
+	}
+
+	h.response.SuccessWithStatus(c, http.StatusAccepted, gin.H{
+		"received": len(alerts),
+		"queued":   len(alerts),
+		"depth":    queue.Depth(),
+	}, "Alerts queued for processing")
 }
 
 func (h *AlertHandler) ListAlerts(c *gin.Context) {
@@ -49,8 +113,25 @@ func (h *AlertHandler) ListAlerts(c *gin.Context) {
 		return
 	}
 
+	// Merge the saved view so the list matches what the live stream shows
+	if filters.ViewID != 0 {
+		if err := h.services.SavedView.ApplyView(c.Request.Context(), &filters, requestUser(c, "")); err != nil {
+			if errors.Is(err, service.ErrViewForbidden) {
+				h.response.Forbidden(c, err.Error())
+				return
+			}
+			h.response.NotFound(c, "Saved view")
+			return
+		}
+	}
+
 	alerts, total, err := h.services.Alert.ListAlerts(c.Request.Context(), filters)
 	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			h.response.BadRequest(c, validationErr.Message, gin.H{"field": validationErr.Field})
+			return
+		}
 		h.response.InternalServerError(c, "Failed to retrieve alerts", err.Error())
 		return
 	}
@@ -63,8 +144,18 @@ func (h *AlertHandler) ListAlerts(c *gin.Context) {
 	if size == 0 {
 		size = 20
 	}
+	if size > 100 {
+		size = 100
+	}
 
-	h.response.Paginated(c, alerts, total, page, size, "Alerts retrieved successfully")
+	// Hand out a cursor whenever the page is full so clients can switch
+	// to keyset pagination for the following pages
+	nextCursor := ""
+	if len(alerts) == size {
+		nextCursor = models.CursorFor(alerts[len(alerts)-1], filters.Sort).Encode()
+	}
+
+	h.response.PaginatedWithCursor(c, alerts, total, page, size, nextCursor, "Alerts retrieved successfully")
 }
 
 func (h *AlertHandler) GetAlert(c *gin.Context) {
@@ -99,13 +190,13 @@ func (h *AlertHandler) SilenceAlert(c *gin.Context) {
 		return
 	}
 
-	err := h.services.Alert.SilenceAlert(c.Request.Context(), fingerprint, req.Duration, req.Comment)
+	silence, err := h.services.Alert.SilenceAlert(c.Request.Context(), fingerprint, req.Duration, req.Comment, authenticatedUser(c))
 	if err != nil {
-		h.response.InternalServerError(c, "Failed to silence alert", err.Error())
+		h.handleSilenceError(c, err, "Failed to silence alert")
 		return
 	}
 
-	h.response.Success(c, nil, "Alert silenced successfully")
+	h.response.Success(c, silence, "Alert silenced successfully")
 }
 
 func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
@@ -173,18 +264,33 @@ func (h *AlertHandler) BatchSilenceAlerts(c *gin.Context) {
 		return
 	}
 
-	err := h.services.Alert.BatchSilenceAlerts(c.Request.Context(), req.Fingerprints, req.Duration, req.Comment)
+	silences, err := h.services.Alert.BatchSilenceAlerts(c.Request.Context(), req.Fingerprints, req.Duration, req.Comment, authenticatedUser(c))
 	if err != nil {
-		h.response.InternalServerError(c, "Failed to batch silence alerts", err.Error())
+		h.handleSilenceError(c, err, "Failed to batch silence alerts")
 		return
 	}
 
 	h.response.Success(c, gin.H{
 		"processed": len(req.Fingerprints),
+		"silenced":  len(silences),
 		"action":    "silenced",
+		"silences":  silences,
 	}, "Alerts silenced successfully")
 }
 
+// handleSilenceError maps silence errors to responses
+func (h *AlertHandler) handleSilenceError(c *gin.Context, err error, message string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		h.response.NotFound(c, "Alert")
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
+
 func (h *AlertHandler) BatchAcknowledgeAlerts(c *gin.Context) {
 	var req struct {
 		Fingerprints []string `json:"fingerprints" binding:"required"`
@@ -296,63 +402,55 @@ func (h *AlertHandler) GetAlertRelations(c *gin.Context) {
 	h.response.Success(c, relations, "Alert relations retrieved successfully")
 }
 
-// UpdateDeduplicationConfig updates the deduplication engine configuration
+// UpdateDeduplicationConfig stores the deduplication configuration and applies it
 func (h *AlertHandler) UpdateDeduplicationConfig(c *gin.Context) {
 	var config models.DeduplicationConfig
 	if !h.response.BindAndValidate(c, &config) {
 		return
 	}
 
-	// Validate configuration values
-	if config.DeduplicationWindow <= 0 {
-		h.response.BadRequest(c, "Deduplication window must be greater than 0", nil)
+	err := h.services.Alert.UpdateDeduplicationConfig(c.Request.Context(), config)
+	if err != nil {
+		h.handleDeduplicationError(c, err, "Failed to update deduplication configuration")
 		return
 	}
 
-	if config.CorrelationWindow <= 0 {
-		h.response.BadRequest(c, "Correlation window must be greater than 0", nil)
+	h.response.Success(c, config, "Deduplication configuration updated successfully")
+}
+
+// GetDeduplicationConfig retrieves the current deduplication configuration
+func (h *AlertHandler) GetDeduplicationConfig(c *gin.Context) {
+	config, err := h.services.Alert.GetDeduplicationConfig(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to retrieve deduplication configuration", err.Error())
 		return
 	}
 
-	if config.MaxRelatedAlerts < 1 {
-		h.response.BadRequest(c, "Max related alerts must be at least 1", nil)
+	h.response.Success(c, config, "Deduplication configuration retrieved successfully")
+}
+
+// DryRunDeduplication replays recent alerts against a proposed configuration
+func (h *AlertHandler) DryRunDeduplication(c *gin.Context) {
+	var req models.DeduplicationDryRunRequest
+	if !h.response.BindAndValidate(c, &req) {
 		return
 	}
 
-	err := h.services.Alert.UpdateDeduplicationConfig(c.Request.Context(), config)
+	result, err := h.services.Alert.DryRunDeduplication(c.Request.Context(), req)
 	if err != nil {
-		h.response.InternalServerError(c, "Failed to update deduplication configuration", err.Error())
+		h.handleDeduplicationError(c, err, "Failed to run deduplication dry run")
 		return
 	}
 
-	h.response.Success(c, config, "Deduplication configuration updated successfully")
+	h.response.Success(c, result, "Deduplication dry run completed")
 }
 
-// GetDeduplicationConfig retrieves the current deduplication configuration
-func (h *AlertHandler) GetDeduplicationConfig(c *gin.Context) {
-	// Return the default/current configuration
-	// This could be enhanced to store configuration in database
-	defaultConfig := models.DeduplicationConfig{
-		DeduplicationWindow:     5 * 60000000000,  // 5 minutes in nanoseconds
-		CorrelationWindow:      30 * 60000000000,  // 30 minutes in nanoseconds
-		MaxRelatedAlerts:       10,
-		EnableTimeBasedDedup:   true,
-		EnableContentBasedDedup: true,
-		EnableCorrelation:      true,
-		IgnoreLabels: []string{
-			"__name__",
-			"__tmp_",
-			"timestamp",
-			"receive_timestamp",
-		},
-		CorrelationLabels: []string{
-			"instance",
-			"job",
-			"service",
-			"cluster",
-			"node",
-		},
-	}
-
-	h.response.Success(c, defaultConfig, "Deduplication configuration retrieved successfully")
+// handleDeduplicationError maps deduplication errors to responses
+func (h *AlertHandler) handleDeduplicationError(c *gin.Context, err error, message string) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		h.response.BadRequest(c, validationErr.Message, gin.H{"field": validationErr.Field})
+		return
+	}
+	h.response.InternalServerError(c, message, err.Error())
 }
\ No newline at end of file
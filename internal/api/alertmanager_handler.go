@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"alertbot/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAlertmanagerConfigBody bounds the size of an uploaded alertmanager.yml
+const maxAlertmanagerConfigBody = 4 << 20
+
+type AlertmanagerHandler struct {
+	services *service.Services
+	response *ResponseHelper
+}
+
+func NewAlertmanagerHandler(services *service.Services) *AlertmanagerHandler {
+	return &AlertmanagerHandler{
+		services: services,
+		response: NewResponseHelper(),
+	}
+}
+
+// ImportConfig translates an alertmanager.yml request body into routing
+// rules, channels, inhibition rules and group rules. With dry_run=true the
+// planned changes are returned without being applied.
+func (h *AlertmanagerHandler) ImportConfig(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAlertmanagerConfigBody+1))
+	if err != nil {
+		h.response.BadRequest(c, "Failed to read request body", err.Error())
+		return
+	}
+	if len(body) > maxAlertmanagerConfigBody {
+		h.response.BadRequest(c, "Alertmanager config is too large", nil)
+		return
+	}
+
+	report, err := h.services.Alertmanager.Import(c.Request.Context(), body, dryRun)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+			return
+		}
+		h.response.InternalServerError(c, "Failed to import Alertmanager config", err.Error())
+		return
+	}
+
+	message := "Alertmanager config imported successfully"
+	if dryRun {
+		message = "Alertmanager config import planned"
+	}
+	h.response.Success(c, report, message)
+}
+
+// ExportConfig renders the current configuration as alertmanager.yml. The
+// YAML is returned as is with format=yaml, otherwise wrapped with the
+// constructs that could not be exported.
+func (h *AlertmanagerHandler) ExportConfig(c *gin.Context) {
+	data, issues, err := h.services.Alertmanager.Export(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to export Alertmanager config", err.Error())
+		return
+	}
+
+	if c.Query("format") == "yaml" {
+		c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+		return
+	}
+	h.response.Success(c, gin.H{
+		"config":      string(data),
+		"unsupported": issues,
+	}, "Alertmanager config exported successfully")
+}
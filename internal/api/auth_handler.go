@@ -1,9 +1,11 @@
 package api
 
 import (
+	"errors"
 	"time"
 
 	"alertbot/internal/service"
+	"alertbot/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -35,90 +37,121 @@ type LoginResponse struct {
 
 // UserInfo represents user information
 type UserInfo struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	ID       uint     `json:"id"`
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Teams    []string `json:"teams,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
 }
 
-// Login handles user authentication (placeholder implementation)
+// Login authenticates the user and issues a signed JWT
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if !h.response.BindAndValidate(c, &req) {
 		return
 	}
 
-	// Placeholder authentication logic
-	// In a real implementation, you would:
-	// 1. Hash the password and compare with stored hash
-	// 2. Check user existence in database
-	// 3. Generate JWT token with proper claims
-	// 4. Set appropriate token expiration
-
-	// For demo purposes, accept specific credentials
-	if req.Username == "admin" && req.Password == "admin123" {
-		// Generate a placeholder token (in real implementation, use proper JWT)
-		token := "demo-jwt-token-" + time.Now().Format("20060102150405")
-		expiresAt := time.Now().Add(24 * time.Hour)
-
-		response := LoginResponse{
-			Token:     token,
-			ExpiresAt: expiresAt,
-			User: UserInfo{
-				ID:       1,
-				Username: "admin",
-				Role:     "admin",
-			},
+	token, claims, err := h.services.Auth.Login(c.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			h.response.Unauthorized(c, "Invalid username or password")
+			return
 		}
-
-		h.response.Success(c, response, "Login successful")
-	} else {
-		h.response.Unauthorized(c, "Invalid username or password")
+		h.response.InternalServerError(c, "Failed to log in", err.Error())
+		return
 	}
+
+	h.response.Success(c, LoginResponse{
+		Token:     token,
+		ExpiresAt: claims.ExpiresAt.Time,
+		User:      userInfoFromClaims(claims),
+	}, "Login successful")
 }
 
-// Logout handles user logout (placeholder implementation)
+// Logout revokes the current token; open WebSocket sessions using it are closed
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// In a real implementation, you might:
-	// 1. Invalidate the token (add to blacklist)
-	// 2. Clear any server-side sessions
-	// 3. Log the logout event
+	claims, ok := currentClaims(c)
+	if !ok {
+		h.response.Success(c, nil, "Logout successful")
+		return
+	}
+
+	if err := h.services.Auth.RevokeToken(c.Request.Context(), claims); err != nil {
+		h.response.InternalServerError(c, "Failed to revoke token", err.Error())
+		return
+	}
 
 	h.response.Success(c, nil, "Logout successful")
 }
 
-// RefreshToken refreshes an existing token (placeholder implementation)
+// RefreshToken issues a new token and revokes the current one
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	// In a real implementation, you would:
-	// 1. Validate the existing token
-	// 2. Check if it's eligible for refresh
-	// 3. Generate a new token with extended expiration
-	// 4. Return the new token
+	claims, ok := currentClaims(c)
+	if !ok {
+		h.response.Unauthorized(c, "Valid token required")
+		return
+	}
 
-	// For demo purposes
-	token := "refreshed-demo-jwt-token-" + time.Now().Format("20060102150405")
-	expiresAt := time.Now().Add(24 * time.Hour)
+	token, fresh, err := h.services.Auth.RefreshToken(c.Request.Context(), claims)
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to refresh token", err.Error())
+		return
+	}
 
-	response := gin.H{
+	h.response.Success(c, gin.H{
 		"token":      token,
-		"expires_at": expiresAt,
+		"expires_at": fresh.ExpiresAt.Time,
+	}, "Token refreshed successfully")
+}
+
+// GetProfile returns the current user's profile
+func (h *AuthHandler) GetProfile(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		h.response.Unauthorized(c, "Valid token required")
+		return
 	}
 
-	h.response.Success(c, response, "Token refreshed successfully")
+	h.response.Success(c, userInfoFromClaims(claims), "Profile retrieved successfully")
 }
 
-// GetProfile returns the current user's profile (placeholder implementation)
-func (h *AuthHandler) GetProfile(c *gin.Context) {
-	// In a real implementation, you would:
-	// 1. Extract user information from JWT token
-	// 2. Fetch additional user details from database
-	// 3. Return user profile information
-
-	// For demo purposes, return mock user info
-	userInfo := UserInfo{
-		ID:       1,
-		Username: "admin",
-		Role:     "admin",
+// IssueStreamTicket exchanges the bearer token for a short-lived, single-use
+// ticket that browsers pass as ?ticket= when opening a WebSocket or SSE stream
+func (h *AuthHandler) IssueStreamTicket(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		h.response.Unauthorized(c, "Valid token required")
+		return
 	}
 
-	h.response.Success(c, userInfo, "Profile retrieved successfully")
-}
\ No newline at end of file
+	ticket, expiresAt, err := h.services.Auth.IssueTicket(c.Request.Context(), claims)
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to issue ticket", err.Error())
+		return
+	}
+
+	h.response.Success(c, gin.H{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	}, "Ticket issued successfully")
+}
+
+// currentClaims returns the claims set by the JWT middleware
+func currentClaims(c *gin.Context) (*utils.Claims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*utils.Claims)
+	return claims, ok
+}
+
+func userInfoFromClaims(claims *utils.Claims) UserInfo {
+	return UserInfo{
+		ID:       claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+		Teams:    claims.Teams,
+		Scope:    claims.Scope,
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"alertbot/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxManifestBody bounds the size of an applied manifest bundle
+const maxManifestBody = 8 << 20
+
+type ConfigHandler struct {
+	services *service.Services
+	response *ResponseHelper
+}
+
+func NewConfigHandler(services *service.Services) *ConfigHandler {
+	return &ConfigHandler{
+		services: services,
+		response: NewResponseHelper(),
+	}
+}
+
+// ApplyConfig applies a YAML manifest bundle from the request body. With
+// dry_run=true the planned changes are returned without being applied; with
+// prune=true stored objects missing from the bundle are deleted.
+func (h *ConfigHandler) ApplyConfig(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	prune, _ := strconv.ParseBool(c.DefaultQuery("prune", "false"))
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxManifestBody+1))
+	if err != nil {
+		h.response.BadRequest(c, "Failed to read request body", err.Error())
+		return
+	}
+	if len(body) > maxManifestBody {
+		h.response.BadRequest(c, "Manifest bundle is too large", nil)
+		return
+	}
+
+	report, err := h.services.Config.Apply(c.Request.Context(), body, dryRun, prune)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+			return
+		}
+		h.response.InternalServerError(c, "Failed to apply configuration", err.Error())
+		return
+	}
+
+	message := "Configuration applied successfully"
+	if dryRun {
+		message = "Configuration changes planned"
+	}
+	h.response.Success(c, report, message)
+}
+
+// ExportConfig returns the stored configuration as a manifest bundle. The
+// YAML is returned as is with format=yaml, otherwise wrapped with the
+// environment variables its secret references need.
+func (h *ConfigHandler) ExportConfig(c *gin.Context) {
+	data, envVars, err := h.services.Config.Export(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to export configuration", err.Error())
+		return
+	}
+
+	if c.Query("format") == "yaml" {
+		c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+		return
+	}
+	if envVars == nil {
+		envVars = []string{}
+	}
+	h.response.Success(c, gin.H{
+		"manifests": string(data),
+		"env":       envVars,
+	}, "Configuration exported successfully")
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"alertbot/internal/events"
+	"alertbot/internal/service"
+	websocketPkg "alertbot/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Interval between SSE keep-alive comments
+	sseHeartbeatPeriod = 15 * time.Second
+
+	// How often an open stream re-checks token expiry and revocation
+	sseSessionCheckPeriod = 15 * time.Second
+
+	// Buffered events per SSE client before the stream is dropped
+	sseBufferSize = 256
+)
+
+// EventStreamHandler serves the Server-Sent Events alternative to the WebSocket stream
+type EventStreamHandler struct {
+	services *service.Services
+	logger   *logrus.Logger
+	bus      *events.Bus
+	response *ResponseHelper
+}
+
+func NewEventStreamHandler(services *service.Services, logger *logrus.Logger, bus *events.Bus) *EventStreamHandler {
+	return &EventStreamHandler{
+		services: services,
+		logger:   logger,
+		bus:      bus,
+		response: NewResponseHelper(),
+	}
+}
+
+// StreamEvents streams bus events as text/event-stream. It accepts the same
+// filters as a WebSocket subscription (query, status, severity, types or
+// view_id) and resumes from the Last-Event-ID header.
+func (h *EventStreamHandler) StreamEvents(c *gin.Context) {
+	claims, err := authenticateStream(c, h.services.Auth)
+	if err != nil {
+		h.response.Unauthorized(c, err.Error())
+		return
+	}
+
+	session, err := newStreamSession(claims)
+	if err != nil {
+		h.response.Forbidden(c, err.Error())
+		return
+	}
+
+	sub, err := h.subscriptionFromQuery(c, session)
+	if err != nil {
+		h.response.BadRequest(c, "Invalid stream filters", err.Error())
+		return
+	}
+	var subs []*websocketPkg.Subscription
+	if sub != nil {
+		subs = append(subs, sub)
+	}
+
+	lastEpoch, lastEventID, hasLastEventID, err := parseLastEventID(c)
+	if err != nil {
+		h.response.BadRequest(c, "Invalid Last-Event-ID", err.Error())
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		h.response.InternalServerError(c, "Streaming is not supported", nil)
+		return
+	}
+
+	// Subscribe before replaying so nothing published in between is lost
+	listener := h.bus.Subscribe(sseBufferSize, true)
+	defer listener.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	flusher.Flush()
+
+	h.logger.WithFields(logrus.Fields{
+		"username":      session.Username,
+		"last_event_id": events.FormatEventID(lastEpoch, lastEventID),
+	}).Info("SSE client connected")
+
+	var lastSent uint64
+	send := func(message *websocketPkg.Message) bool {
+		matched, ok := websocketPkg.Route(session, subs, message)
+		if !ok {
+			return true
+		}
+		out := *message
+		out.Subscriptions = matched
+		if err := writeSSE(c.Writer, &out); err != nil {
+			return false
+		}
+		flusher.Flush()
+		if message.Seq > lastSent {
+			lastSent = message.Seq
+		}
+		return true
+	}
+
+	if hasLastEventID {
+		missed, ok := h.bus.Since(lastEpoch, lastEventID)
+		if ok {
+			for _, event := range missed {
+				if !send(websocketPkg.MessageFromEvent(event)) {
+					return
+				}
+			}
+		} else {
+			send(&websocketPkg.Message{
+				Type: "resync_required",
+				Data: map[string]interface{}{
+					"last_epoch": lastEpoch,
+					"last_seq":   lastEventID,
+					"epoch":      h.bus.Epoch(),
+					"seq":        h.bus.CurrentSeq(),
+				},
+				Timestamp: time.Now(),
+			})
+			h.sendSnapshots(c, session, subs, send)
+		}
+	} else if c.Query("snapshot") != "false" {
+		h.sendSnapshots(c, session, subs, send)
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatPeriod)
+	defer heartbeat.Stop()
+	sessionCheck := time.NewTicker(sseSessionCheckPeriod)
+	defer sessionCheck.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case event, ok := <-listener.C:
+			if !ok {
+				// Too slow to keep up; the client reconnects with Last-Event-ID
+				h.logger.WithField("username", session.Username).Warn("SSE client fell behind, closing stream")
+				return
+			}
+			// Skip events already delivered during replay
+			if event.Seq <= lastSent {
+				continue
+			}
+			if !send(websocketPkg.MessageFromEvent(event)) {
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case now := <-sessionCheck.C:
+			reason := ""
+			switch {
+			case session.Expired(now):
+				reason = "token expired"
+			case h.services.Auth.IsRevoked(session.TokenID):
+				reason = "token revoked"
+			}
+			if reason != "" {
+				send(&websocketPkg.Message{
+					Type:      "session_ended",
+					Data:      map[string]interface{}{"reason": reason},
+					Timestamp: time.Now(),
+				})
+				return
+			}
+		}
+	}
+}
+
+// subscriptionFromQuery builds the stream filter from query parameters
+func (h *EventStreamHandler) subscriptionFromQuery(c *gin.Context, session *websocketPkg.Session) (*websocketPkg.Subscription, error) {
+	if viewParam := c.Query("view_id"); viewParam != "" {
+		viewID, err := strconv.ParseUint(viewParam, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid view_id")
+		}
+		view, err := h.services.SavedView.GetView(c.Request.Context(), uint(viewID), session.Username)
+		if err != nil {
+			return nil, fmt.Errorf("saved view %d is not available", viewID)
+		}
+		return websocketPkg.SubscriptionFromView("default", view)
+	}
+
+	filters := map[string]interface{}{}
+	for _, key := range []string{"query", "status", "severity"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if types := c.Query("types"); types != "" {
+		items := []interface{}{}
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				items = append(items, t)
+			}
+		}
+		filters["types"] = items
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return websocketPkg.NewSubscription("default", filters)
+}
+
+// sendSnapshots sends the alerts currently matching each subscription
+func (h *EventStreamHandler) sendSnapshots(c *gin.Context, session *websocketPkg.Session, subs []*websocketPkg.Subscription, send func(*websocketPkg.Message) bool) {
+	if len(subs) == 0 {
+		subs = []*websocketPkg.Subscription{{ID: "default"}}
+	}
+
+	for _, sub := range subs {
+		seq := h.bus.CurrentSeq()
+		filters := session.Restrict(sub.AlertFilters())
+		filters.Size = 100
+
+		alerts, total, err := h.services.Alert.ListAlerts(c.Request.Context(), filters)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to load SSE snapshot")
+			continue
+		}
+
+		send(&websocketPkg.Message{
+			Type: "snapshot",
+			Data: map[string]interface{}{
+				"subscription_id": sub.ID,
+				"alerts":          alerts,
+				"total":           total,
+				"truncated":       total > int64(len(alerts)),
+				"epoch":           h.bus.Epoch(),
+				"seq":             seq,
+			},
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// parseLastEventID reads the resume epoch and sequence number from the
+// Last-Event-ID header, or from the last_event_id query parameter for the
+// first EventSource request
+func parseLastEventID(c *gin.Context) (string, uint64, bool, error) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return "", 0, false, nil
+	}
+
+	epoch, seq, err := events.ParseEventID(value)
+	if err != nil {
+		return "", 0, false, err
+	}
+	return epoch, seq, true, nil
+}
+
+// writeSSE writes a message as a single SSE event. Bus events carry their
+// epoch and sequence number as the event id so browsers resume automatically.
+func writeSSE(w gin.ResponseWriter, message *websocketPkg.Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if message.Seq > 0 {
+		fmt.Fprintf(&b, "id: %s\n", events.FormatEventID(message.Epoch, message.Seq))
+	}
+	fmt.Fprintf(&b, "event: %s\n", message.Type)
+	fmt.Fprintf(&b, "data: %s\n\n", payload)
+
+	_, err = w.WriteString(b.String())
+	return err
+}
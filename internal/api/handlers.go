@@ -1,11 +1,15 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"alertbot/internal/models"
 	"alertbot/internal/service"
 	websocketPkg "alertbot/internal/websocket"
+	"alertbot/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -132,17 +136,32 @@ type WebSocketHandler struct {
 	services *service.Services
 	logger   *logrus.Logger
 	hub      *websocketPkg.Hub
+	response *ResponseHelper
 }
 
 func NewWebSocketHandler(services *service.Services, logger *logrus.Logger, hub *websocketPkg.Hub) *WebSocketHandler {
 	return &WebSocketHandler{
 		services: services,
 		logger:   logger,
-		hub:      websocketPkg.NewHub(logger),
+		hub:      hub,
+		response: NewResponseHelper(),
 	}
 }
 
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	// Authenticate before upgrading so unauthenticated clients get a plain 401
+	claims, err := authenticateStream(c, h.services.Auth)
+	if err != nil {
+		h.response.Unauthorized(c, err.Error())
+		return
+	}
+
+	session, err := newStreamSession(claims)
+	if err != nil {
+		h.response.Forbidden(c, err.Error())
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
@@ -157,22 +176,54 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Get user information from context (set by auth middleware)
-	userID, _ := c.Get("user_id")
-	username, _ := c.Get("username")
-	role, _ := c.Get("role")
-
 	// Create and start new client
-	client := h.hub.NewClient(conn, getUserID(userID), getUserString(username), getUserString(role))
+	client := h.hub.NewClient(conn, session)
 	client.Start()
 
 	h.logger.WithFields(logrus.Fields{
-		"client_id": client.GetInfo()["id"],
-		"user_id":   getUserID(userID),
-		"username":  getUserString(username),
+		"client_id":  client.GetInfo()["id"],
+		"user_id":    session.UserID,
+		"username":   session.Username,
+		"expires_at": session.ExpiresAt,
 	}).Info("WebSocket client connected")
 }
 
+// authenticateStream accepts either a bearer token in the Authorization
+// header or a short-lived ticket in the "ticket" query parameter, since
+// browsers cannot set headers on WebSocket and EventSource requests
+func authenticateStream(c *gin.Context, auth service.AuthService) (*utils.Claims, error) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return nil, fmt.Errorf("invalid authorization header format")
+		}
+		claims, err := auth.ParseToken(c.Request.Context(), parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid or expired token")
+		}
+		return claims, nil
+	}
+
+	if ticket := c.Query("ticket"); ticket != "" {
+		claims, err := auth.RedeemTicket(c.Request.Context(), ticket)
+		if err != nil {
+			return nil, fmt.Errorf("invalid or expired ticket")
+		}
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("authentication required")
+}
+
+// newStreamSession converts token claims into a streaming session
+func newStreamSession(claims *utils.Claims) (*websocketPkg.Session, error) {
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return websocketPkg.NewSession(claims.UserID, claims.Username, claims.Role, claims.ID, expiresAt, claims.Teams, claims.Scope)
+}
+
 func getUserID(userID interface{}) uint {
 	if id, ok := userID.(uint); ok {
 		return id
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"alertbot/internal/models"
+	"alertbot/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultHeartbeatGrace is the grace period in seconds when none is given
+const defaultHeartbeatGrace = 60
+
+type HeartbeatHandler struct {
+	services *service.Services
+	response *ResponseHelper
+}
+
+func NewHeartbeatHandler(services *service.Services) *HeartbeatHandler {
+	return &HeartbeatHandler{
+		services: services,
+		response: NewResponseHelper(),
+	}
+}
+
+// HeartbeatRequest represents the request body for creating or updating a heartbeat
+type HeartbeatRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	Description string           `json:"description"`
+	Interval    int              `json:"interval" binding:"required"` // Seconds
+	Grace       *int             `json:"grace"`                       // Seconds, default 60
+	Matchers    []SilenceMatcher `json:"matchers"`                    // e.g. alertname="Watchdog"
+	Labels      models.JSONB     `json:"labels"`
+	Enabled     *bool            `json:"enabled"`
+}
+
+func (r *HeartbeatRequest) toModel() *models.Heartbeat {
+	heartbeat := &models.Heartbeat{
+		Name:        r.Name,
+		Description: r.Description,
+		Interval:    r.Interval,
+		Grace:       defaultHeartbeatGrace,
+		Labels:      r.Labels,
+		Enabled:     true,
+	}
+	if r.Grace != nil {
+		heartbeat.Grace = *r.Grace
+	}
+	if r.Enabled != nil {
+		heartbeat.Enabled = *r.Enabled
+	}
+	if len(r.Matchers) > 0 {
+		matchers := make([]interface{}, len(r.Matchers))
+		for i, m := range r.Matchers {
+			matchers[i] = gin.H{
+				"name":     m.Name,
+				"value":    m.Value,
+				"is_regex": m.IsRegex,
+			}
+		}
+		heartbeat.Matchers = models.JSONB{"matchers": matchers}
+	}
+	return heartbeat
+}
+
+// ListHeartbeats retrieves all heartbeats with their status
+func (h *HeartbeatHandler) ListHeartbeats(c *gin.Context) {
+	heartbeats, err := h.services.Heartbeat.ListHeartbeats(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to retrieve heartbeats", err.Error())
+		return
+	}
+
+	h.response.Success(c, gin.H{
+		"items": heartbeats,
+		"total": len(heartbeats),
+	}, "Heartbeats retrieved successfully")
+}
+
+// CreateHeartbeat creates a heartbeat monitor
+func (h *HeartbeatHandler) CreateHeartbeat(c *gin.Context) {
+	var req HeartbeatRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	heartbeat := req.toModel()
+	if err := h.services.Heartbeat.CreateHeartbeat(c.Request.Context(), heartbeat); err != nil {
+		h.handleError(c, err, "Failed to create heartbeat")
+		return
+	}
+
+	h.response.SuccessWithStatus(c, http.StatusCreated, heartbeat, "Heartbeat created successfully")
+}
+
+// GetHeartbeat retrieves a heartbeat
+func (h *HeartbeatHandler) GetHeartbeat(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	heartbeat, err := h.services.Heartbeat.GetHeartbeat(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve heartbeat")
+		return
+	}
+
+	h.response.Success(c, heartbeat, "Heartbeat retrieved successfully")
+}
+
+// UpdateHeartbeat replaces a heartbeat's settings
+func (h *HeartbeatHandler) UpdateHeartbeat(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req HeartbeatRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	heartbeat, err := h.services.Heartbeat.UpdateHeartbeat(c.Request.Context(), id, req.toModel())
+	if err != nil {
+		h.handleError(c, err, "Failed to update heartbeat")
+		return
+	}
+
+	h.response.Success(c, heartbeat, "Heartbeat updated successfully")
+}
+
+// DeleteHeartbeat deletes a heartbeat and resolves its alert if it was missed
+func (h *HeartbeatHandler) DeleteHeartbeat(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.services.Heartbeat.DeleteHeartbeat(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "Failed to delete heartbeat")
+		return
+	}
+
+	h.response.Success(c, nil, "Heartbeat deleted successfully")
+}
+
+// PingHeartbeat records a ping, e.g. from a cron job's last step
+func (h *HeartbeatHandler) PingHeartbeat(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	heartbeat, err := h.services.Heartbeat.Ping(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "Failed to record heartbeat ping")
+		return
+	}
+
+	h.response.Success(c, heartbeat, "Heartbeat ping recorded")
+}
+
+// handleError maps service errors to HTTP responses
+func (h *HeartbeatHandler) handleError(c *gin.Context, err error, message string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		h.response.NotFound(c, "Heartbeat")
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"alertbot/internal/models"
+	"alertbot/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type IncidentHandler struct {
+	services *service.Services
+	response *ResponseHelper
+}
+
+func NewIncidentHandler(services *service.Services) *IncidentHandler {
+	return &IncidentHandler{
+		services: services,
+		response: NewResponseHelper(),
+	}
+}
+
+// IncidentRequest represents the request body for opening an incident by hand
+type IncidentRequest struct {
+	Title    string   `json:"title" binding:"required"`
+	Severity string   `json:"severity"`
+	Owner    string   `json:"owner"`
+	Alerts   []string `json:"alerts"` // Fingerprints of alerts to attach
+}
+
+// IncidentNoteRequest represents the request body for adding a note
+type IncidentNoteRequest struct {
+	Content string `json:"content" binding:"required"`
+	Author  string `json:"author"`
+}
+
+// IncidentAlertRequest represents the request body for attaching an alert
+type IncidentAlertRequest struct {
+	Fingerprint string `json:"fingerprint" binding:"required"`
+}
+
+// ListIncidents retrieves incidents filtered by status and owner
+func (h *IncidentHandler) ListIncidents(c *gin.Context) {
+	var filters models.IncidentFilters
+	if !h.response.BindQueryAndValidate(c, &filters) {
+		return
+	}
+
+	incidents, total, err := h.services.Incident.ListIncidents(c.Request.Context(), filters)
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve incidents")
+		return
+	}
+
+	page := filters.Page
+	if page == 0 {
+		page = 1
+	}
+	size := filters.Size
+	if size == 0 {
+		size = 20
+	}
+
+	h.response.Paginated(c, incidents, total, page, size, "Incidents retrieved successfully")
+}
+
+// CreateIncident opens an incident and attaches the given alerts
+func (h *IncidentHandler) CreateIncident(c *gin.Context) {
+	var req IncidentRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	incident := &models.Incident{
+		Title:    req.Title,
+		Severity: req.Severity,
+		Owner:    req.Owner,
+	}
+	if err := h.services.Incident.CreateIncident(c.Request.Context(), incident, req.Alerts, requestUser(c, "")); err != nil {
+		h.handleError(c, err, "Failed to create incident")
+		return
+	}
+
+	h.response.SuccessWithStatus(c, http.StatusCreated, incident, "Incident created successfully")
+}
+
+// GetIncident retrieves an incident with its alerts and notes
+func (h *IncidentHandler) GetIncident(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	incident, err := h.services.Incident.GetIncident(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve incident")
+		return
+	}
+
+	h.response.Success(c, incident, "Incident retrieved successfully")
+}
+
+// UpdateIncident changes an incident's title, status, severity or owner
+func (h *IncidentHandler) UpdateIncident(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.IncidentUpdate
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	incident, err := h.services.Incident.UpdateIncident(c.Request.Context(), id, req, requestUser(c, ""))
+	if err != nil {
+		h.handleError(c, err, "Failed to update incident")
+		return
+	}
+
+	h.response.Success(c, incident, "Incident updated successfully")
+}
+
+// DeleteIncident deletes an incident; its alerts are kept
+func (h *IncidentHandler) DeleteIncident(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.services.Incident.DeleteIncident(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "Failed to delete incident")
+		return
+	}
+
+	h.response.Success(c, nil, "Incident deleted successfully")
+}
+
+// AttachAlert adds an alert to an open incident
+func (h *IncidentHandler) AttachAlert(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req IncidentAlertRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	incident, err := h.services.Incident.AttachAlert(c.Request.Context(), id, req.Fingerprint)
+	if err != nil {
+		h.handleError(c, err, "Failed to attach alert")
+		return
+	}
+
+	h.response.Success(c, incident, "Alert attached successfully")
+}
+
+// AddNote adds a note to an incident
+func (h *IncidentHandler) AddNote(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req IncidentNoteRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	note, err := h.services.Incident.AddNote(c.Request.Context(), id, requestUser(c, req.Author), req.Content)
+	if err != nil {
+		h.handleError(c, err, "Failed to add note")
+		return
+	}
+
+	h.response.SuccessWithStatus(c, http.StatusCreated, note, "Note added successfully")
+}
+
+// GetTimeline returns the incident's alert history, attachments and notes in order
+func (h *IncidentHandler) GetTimeline(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	timeline, err := h.services.Incident.GetTimeline(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve incident timeline")
+		return
+	}
+
+	h.response.Success(c, gin.H{
+		"items": timeline,
+		"total": len(timeline),
+	}, "Incident timeline retrieved successfully")
+}
+
+// handleError maps service errors to HTTP responses
+func (h *IncidentHandler) handleError(c *gin.Context, err error, message string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		h.response.NotFound(c, "Incident")
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
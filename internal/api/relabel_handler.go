@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"alertbot/internal/models"
+	"alertbot/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// maxRelabelBody bounds the size of an uploaded relabel config list
+const maxRelabelBody = 1 << 20
+
+type RelabelHandler struct {
+	services *service.Services
+	response *ResponseHelper
+}
+
+func NewRelabelHandler(services *service.Services) *RelabelHandler {
+	return &RelabelHandler{
+		services: services,
+		response: NewResponseHelper(),
+	}
+}
+
+// GetRelabelConfigs returns the relabel configs, as YAML when format=yaml
+func (h *RelabelHandler) GetRelabelConfigs(c *gin.Context) {
+	list, err := h.services.Relabel.GetConfigs(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to retrieve relabel configs", err.Error())
+		return
+	}
+
+	if c.Query("format") == "yaml" {
+		c.YAML(http.StatusOK, list)
+		return
+	}
+	h.response.Success(c, list, "Relabel configs retrieved successfully")
+}
+
+// UpdateRelabelConfigs replaces the relabel configs. The body is YAML when
+// the content type mentions yaml and JSON otherwise; besides a document
+// with a configs list it may be a bare list, as copied from a Prometheus
+// relabel_configs section.
+func (h *RelabelHandler) UpdateRelabelConfigs(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRelabelBody+1))
+	if err != nil {
+		h.response.BadRequest(c, "Failed to read request body", err.Error())
+		return
+	}
+	if len(body) > maxRelabelBody {
+		h.response.BadRequest(c, "Relabel configs are too large", nil)
+		return
+	}
+
+	unmarshal := json.Unmarshal
+	if strings.Contains(c.ContentType(), "yaml") {
+		unmarshal = yaml.Unmarshal
+	}
+	list := models.DefaultRelabelConfigList()
+	if err := unmarshal(body, list); err != nil {
+		if listErr := unmarshal(body, &list.Configs); listErr != nil {
+			h.response.BadRequest(c, "Invalid relabel configs", err.Error())
+			return
+		}
+	}
+
+	if err := h.services.Relabel.UpdateConfigs(c.Request.Context(), list); err != nil {
+		h.handleRelabelError(c, err, "Failed to update relabel configs")
+		return
+	}
+
+	h.response.Success(c, list, "Relabel configs updated successfully")
+}
+
+// TestRelabelConfigs shows label sets before and after relabeling, using
+// the configs in the request or the stored ones
+func (h *RelabelHandler) TestRelabelConfigs(c *gin.Context) {
+	var req models.RelabelTestRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	results, err := h.services.Relabel.Test(c.Request.Context(), req)
+	if err != nil {
+		h.handleRelabelError(c, err, "Failed to test relabel configs")
+		return
+	}
+
+	h.response.Success(c, results, "Relabel configs tested successfully")
+}
+
+// handleRelabelError maps relabel errors to responses
+func (h *RelabelHandler) handleRelabelError(c *gin.Context, err error, message string) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+		return
+	}
+	h.response.InternalServerError(c, message, err.Error())
+}
@@ -32,6 +32,9 @@ type PaginatedData struct {
 	Page  int         `json:"page"`
 	Size  int         `json:"size"`
 	Pages int         `json:"pages"`
+
+	// NextCursor is set when more rows are available via keyset pagination
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // ResponseHelper provides helper methods for standardized API responses
@@ -84,6 +87,25 @@ func (r *ResponseHelper) Paginated(c *gin.Context, items interface{}, total int6
 	r.Success(c, data, message)
 }
 
+// PaginatedWithCursor sends a paginated response that also carries a keyset cursor
+func (r *ResponseHelper) PaginatedWithCursor(c *gin.Context, items interface{}, total int64, page, size int, nextCursor, message string) {
+	pages := int((total + int64(size) - 1) / int64(size))
+	if pages < 1 {
+		pages = 1
+	}
+
+	data := PaginatedData{
+		Items:      items,
+		Total:      total,
+		Page:       page,
+		Size:       size,
+		Pages:      pages,
+		NextCursor: nextCursor,
+	}
+
+	r.Success(c, data, message)
+}
+
 // Error sends an error response
 func (r *ResponseHelper) Error(c *gin.Context, statusCode int, code, message string, details interface{}) {
 	response := APIResponse{
@@ -128,6 +150,11 @@ func (r *ResponseHelper) Conflict(c *gin.Context, message string) {
 	r.Error(c, http.StatusConflict, "CONFLICT", message, nil)
 }
 
+// PayloadTooLarge sends a 413 Request Entity Too Large response
+func (r *ResponseHelper) PayloadTooLarge(c *gin.Context, message string) {
+	r.Error(c, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", message, nil)
+}
+
 // ValidationError sends a 422 Unprocessable Entity response
 func (r *ResponseHelper) ValidationError(c *gin.Context, message string, details interface{}) {
 	r.Error(c, http.StatusUnprocessableEntity, "VALIDATION_ERROR", message, details)
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"alertbot/internal/cluster"
 	"alertbot/internal/config"
 	"alertbot/internal/middleware"
 	"alertbot/internal/monitoring"
@@ -12,7 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket.Hub, cfg *config.Config, monitoringService *monitoring.MonitoringService, backgroundMonitor *monitoring.BackgroundMonitor) *gin.Engine {
+func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket.Hub, cfg *config.Config, monitoringService *monitoring.MonitoringService, backgroundMonitor *monitoring.BackgroundMonitor, node *cluster.Node) *gin.Engine {
 	router := gin.New()
 
 	// 全局中间件 - 顺序很重要
@@ -37,11 +38,38 @@ func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket
 	
 	// Alertmanager compatibility endpoints (for direct Prometheus integration)
 	router.GET("/api/v1/status", func(c *gin.Context) {
+		clusterStatus := gin.H{
+			"status": "ready",
+			"name":   "alertbot",
+		}
+		if node != nil {
+			members, err := node.Members()
+			if err != nil {
+				logger.WithError(err).Warn("Failed to list cluster members")
+				members = nil
+			}
+			peers := make([]gin.H, 0, len(members))
+			for _, member := range members {
+				if member.Leader {
+					clusterStatus["leader"] = member.NodeID
+				}
+				peers = append(peers, gin.H{
+					"name":       member.NodeID,
+					"address":    member.Address,
+					"hostname":   member.Hostname,
+					"leader":     member.Leader,
+					"self":       member.Self,
+					"startedAt":  member.StartedAt,
+					"lastSeenAt": member.LastSeenAt,
+				})
+			}
+			clusterStatus["name"] = node.ID()
+			clusterStatus["enabled"] = node.Enabled()
+			clusterStatus["peers"] = peers
+		}
+
 		c.JSON(200, gin.H{
-			"cluster": gin.H{
-				"status": "ready",
-				"name":   "alertbot",
-			},
+			"cluster": clusterStatus,
 			"versionInfo": gin.H{
 				"version":   "1.0.0",
 				"revision":  "alertbot",
@@ -52,6 +80,12 @@ func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket
 		})
 	})
 
+	// 认证中间件，已注销的令牌会被拒绝
+	requireAuth := middleware.JWTAuth(cfg, services.Auth.IsRevoked)
+	optionalAuth := middleware.OptionalJWTAuth(cfg, services.Auth.IsRevoked)
+	// 按用户限流，需放在认证中间件之后
+	userLimit := middleware.UserRateLimit(cfg, logger)
+
 	// API v1路由组
 	v1 := router.Group("/api/v1")
 	{
@@ -60,24 +94,27 @@ func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/login", authHandler.Login)
-			auth.POST("/logout", authHandler.Logout)
-			auth.POST("/refresh", authHandler.RefreshToken)
-			auth.GET("/profile", authHandler.GetProfile) // In real implementation, this would require JWT auth
+			auth.POST("/logout", optionalAuth, userLimit, authHandler.Logout)
+			auth.POST("/refresh", requireAuth, userLimit, authHandler.RefreshToken)
+			auth.GET("/profile", requireAuth, userLimit, authHandler.GetProfile)
+			auth.POST("/ws-ticket", requireAuth, userLimit, authHandler.IssueStreamTicket)
 		}
 		// 告警相关路由
 		alertHandler := NewAlertHandler(services)
+		topologyHandler := NewTopologyHandler(services)
 		alerts := v1.Group("/alerts")
 		{
 			alerts.POST("", alertHandler.ReceiveAlerts)
-			alerts.GET("", alertHandler.ListAlerts)
+			alerts.GET("", optionalAuth, userLimit, alertHandler.ListAlerts)
 			alerts.GET("/:fingerprint", alertHandler.GetAlert)
-			alerts.PUT("/:fingerprint/silence", alertHandler.SilenceAlert)
+			alerts.PUT("/:fingerprint/silence", requireAuth, userLimit, alertHandler.SilenceAlert)
 			alerts.PUT("/:fingerprint/ack", alertHandler.AcknowledgeAlert)
 			alerts.DELETE("/:fingerprint", alertHandler.ResolveAlert)
 			alerts.GET("/:fingerprint/history", alertHandler.GetAlertHistory)
 			alerts.GET("/:fingerprint/relations", alertHandler.GetAlertRelations)
+			alerts.GET("/:fingerprint/root-cause", topologyHandler.GetRootCause)
 			// 批量操作路由
-			alerts.PUT("/batch/silence", alertHandler.BatchSilenceAlerts)
+			alerts.PUT("/batch/silence", requireAuth, userLimit, alertHandler.BatchSilenceAlerts)
 			alerts.PUT("/batch/ack", alertHandler.BatchAcknowledgeAlerts)
 			alerts.DELETE("/batch/resolve", alertHandler.BatchResolveAlerts)
 		}
@@ -87,6 +124,39 @@ func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket
 		{
 			deduplication.GET("/config", alertHandler.GetDeduplicationConfig)
 			deduplication.PUT("/config", alertHandler.UpdateDeduplicationConfig)
+			deduplication.POST("/dry-run", alertHandler.DryRunDeduplication)
+		}
+		
+		// 依赖拓扑路由
+		topology := v1.Group("/topology")
+		{
+			topology.GET("", topologyHandler.GetTopology)
+			topology.PUT("", topologyHandler.UpdateTopology)
+		}
+		
+		// 标签重写配置路由
+		relabelHandler := NewRelabelHandler(services)
+		relabelConfigs := v1.Group("/relabel-configs")
+		{
+			relabelConfigs.GET("", relabelHandler.GetRelabelConfigs)
+			relabelConfigs.PUT("", relabelHandler.UpdateRelabelConfigs)
+			relabelConfigs.POST("/test", relabelHandler.TestRelabelConfigs)
+		}
+		
+		// Alertmanager 配置导入导出路由
+		alertmanagerHandler := NewAlertmanagerHandler(services)
+		alertmanager := v1.Group("/alertmanager", requireAuth, userLimit, middleware.RequireRole("admin"))
+		{
+			alertmanager.POST("/import", alertmanagerHandler.ImportConfig)
+			alertmanager.GET("/export", alertmanagerHandler.ExportConfig)
+		}
+		
+		// 声明式配置路由
+		configHandler := NewConfigHandler(services)
+		configs := v1.Group("/config", requireAuth, userLimit, middleware.RequireRole("admin"))
+		{
+			configs.POST("/apply", configHandler.ApplyConfig)
+			configs.GET("/export", configHandler.ExportConfig)
 		}
 		
 		// 告警历史路由
@@ -130,6 +200,18 @@ func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket
 			silences.POST("/test", silenceHandler.TestSilence) // Added test endpoint
 		}
 
+		// 心跳监控路由
+		heartbeatHandler := NewHeartbeatHandler(services)
+		heartbeats := v1.Group("/heartbeats")
+		{
+			heartbeats.GET("", heartbeatHandler.ListHeartbeats)
+			heartbeats.POST("", heartbeatHandler.CreateHeartbeat)
+			heartbeats.GET("/:id", heartbeatHandler.GetHeartbeat)
+			heartbeats.PUT("/:id", heartbeatHandler.UpdateHeartbeat)
+			heartbeats.DELETE("/:id", heartbeatHandler.DeleteHeartbeat)
+			heartbeats.POST("/:id/ping", heartbeatHandler.PingHeartbeat)
+		}
+
 		// 抑制规则相关路由
 		inhibitionHandler := NewInhibitionHandler(services)
 		inhibitions := v1.Group("/inhibitions")
@@ -161,6 +243,31 @@ func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket
 			groupRules.POST("/test", groupHandler.TestAlertGroupRule)
 		}
 
+		// 保存视图相关路由
+		viewHandler := NewSavedViewHandler(services)
+		views := v1.Group("/views", optionalAuth, userLimit)
+		{
+			views.GET("", viewHandler.ListViews)
+			views.POST("", requireAuth, viewHandler.CreateView)
+			views.GET("/:id", viewHandler.GetView)
+			views.PUT("/:id", requireAuth, viewHandler.UpdateView)
+			views.DELETE("/:id", requireAuth, viewHandler.DeleteView)
+		}
+
+		// 事件（关联告警）相关路由
+		incidentHandler := NewIncidentHandler(services)
+		incidents := v1.Group("/incidents", optionalAuth, userLimit)
+		{
+			incidents.GET("", incidentHandler.ListIncidents)
+			incidents.POST("", incidentHandler.CreateIncident)
+			incidents.GET("/:id", incidentHandler.GetIncident)
+			incidents.PUT("/:id", incidentHandler.UpdateIncident)
+			incidents.DELETE("/:id", incidentHandler.DeleteIncident)
+			incidents.POST("/:id/alerts", incidentHandler.AttachAlert)
+			incidents.POST("/:id/notes", incidentHandler.AddNote)
+			incidents.GET("/:id/timeline", incidentHandler.GetTimeline)
+		}
+
 		// 统计相关路由
 		statsHandler := NewStatsHandler(services)
 		stats := v1.Group("/stats")
@@ -212,7 +319,11 @@ func NewRouter(services *service.Services, logger *logrus.Logger, hub *websocket
 
 		// WebSocket路由
 		wsHandler := NewWebSocketHandler(services, logger, hub)
-		v1.GET("/ws/alerts", middleware.OptionalJWTAuth(cfg), wsHandler.HandleWebSocket)
+		v1.GET("/ws/alerts", wsHandler.HandleWebSocket) // 认证在处理器内完成（Header 或 ticket）
+
+		// SSE 路由，与 WebSocket 共享事件总线
+		eventsHandler := NewEventStreamHandler(services, logger, hub.Bus())
+		v1.GET("/events", eventsHandler.StreamEvents)
 	}
 
 	// Prometheus v2 API兼容路由
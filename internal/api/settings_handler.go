@@ -40,11 +40,15 @@ type PrometheusSettings struct {
 	EvaluationInterval string `json:"evaluation_interval"`
 }
 
+// NotificationSettings limits each channel to RateLimit notifications per
+// RateLimitPer (a duration, default "1m") with bursts of RateLimitBurst
 type NotificationSettings struct {
-	MaxRetries    int `json:"max_retries" binding:"min=0,max=10"`
-	RetryInterval int `json:"retry_interval" binding:"min=1,max=3600"`
-	RateLimit     int `json:"rate_limit" binding:"min=1,max=1000"`
-	BatchSize     int `json:"batch_size" binding:"min=1,max=100"`
+	MaxRetries     int    `json:"max_retries" binding:"min=0,max=10"`
+	RetryInterval  int    `json:"retry_interval" binding:"min=1,max=3600"`
+	RateLimit      int    `json:"rate_limit" binding:"min=1,max=1000"`
+	RateLimitPer   string `json:"rate_limit_per"`
+	RateLimitBurst int    `json:"rate_limit_burst" binding:"omitempty,min=1,max=1000"`
+	BatchSize      int    `json:"batch_size" binding:"min=1,max=100"`
 }
 
 // GetSystemSettings retrieves system settings
@@ -191,10 +195,12 @@ func (h *SettingsHandler) GetNotificationSettings(c *gin.Context) {
 
 	// Convert to response format
 	settings := NotificationSettings{
-		MaxRetries:    config.MaxRetries,
-		RetryInterval: config.RetryInterval,
-		RateLimit:     config.RateLimit,
-		BatchSize:     config.BatchSize,
+		MaxRetries:     config.MaxRetries,
+		RetryInterval:  config.RetryInterval,
+		RateLimit:      config.RateLimit,
+		RateLimitPer:   config.RateLimitPer,
+		RateLimitBurst: config.RateLimitBurst,
+		BatchSize:      config.BatchSize,
 	}
 
 	h.response.Success(c, settings, "Notification settings retrieved successfully")
@@ -210,16 +216,20 @@ func (h *SettingsHandler) UpdateNotificationSettings(c *gin.Context) {
 
 	// Convert to model
 	config := &models.NotificationConfig{
-		MaxRetries:    settings.MaxRetries,
-		RetryInterval: settings.RetryInterval,
-		RateLimit:     settings.RateLimit,
-		BatchSize:     settings.BatchSize,
+		MaxRetries:     settings.MaxRetries,
+		RetryInterval:  settings.RetryInterval,
+		RateLimit:      settings.RateLimit,
+		RateLimitPer:   settings.RateLimitPer,
+		RateLimitBurst: settings.RateLimitBurst,
+		BatchSize:      settings.BatchSize,
 	}
 
 	if err := h.settings.UpdateNotificationConfig(config); err != nil {
 		h.response.BadRequest(c, "Failed to update notification settings", err.Error())
 		return
 	}
+	settings.RateLimitPer = config.RateLimitPer
+	settings.RateLimitBurst = config.RateLimitBurst
 	
 	h.response.Success(c, settings, "Notification settings updated successfully")
 }
\ No newline at end of file
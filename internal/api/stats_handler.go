@@ -1,6 +1,7 @@
 package api
 
 import (
+	"alertbot/internal/models"
 	"alertbot/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -86,16 +87,7 @@ func (h *StatsHandler) GetSystemStats(c *gin.Context) {
 	// Note: In a production system, you might want to cache these counts
 	
 	// Count alerts
-	_, totalAlerts, err := h.services.Alert.ListAlerts(c.Request.Context(), struct {
-		Status    string `json:"status" form:"status"`
-		Severity  string `json:"severity" form:"severity"`
-		AlertName string `json:"alertname" form:"alertname"`
-		Instance  string `json:"instance" form:"instance"`
-		Page      int    `json:"page" form:"page"`
-		Size      int    `json:"size" form:"size"`
-		Sort      string `json:"sort" form:"sort"`
-		Order     string `json:"order" form:"order"`
-	}{Size: 1})
+	_, totalAlerts, err := h.services.Alert.ListAlerts(c.Request.Context(), models.AlertFilters{Size: 1})
 	
 	if err != nil {
 		h.response.InternalServerError(c, "Failed to get alert count", err.Error())
@@ -146,16 +138,7 @@ func (h *StatsHandler) GetHealthStatus(c *gin.Context) {
 	checks := gin.H{}
 
 	// Check database connectivity
-	_, _, err := h.services.Alert.ListAlerts(c.Request.Context(), struct {
-		Status    string `json:"status" form:"status"`
-		Severity  string `json:"severity" form:"severity"`
-		AlertName string `json:"alertname" form:"alertname"`
-		Instance  string `json:"instance" form:"instance"`
-		Page      int    `json:"page" form:"page"`
-		Size      int    `json:"size" form:"size"`
-		Sort      string `json:"sort" form:"sort"`
-		Order     string `json:"order" form:"order"`
-	}{Size: 1})
+	_, _, err := h.services.Alert.ListAlerts(c.Request.Context(), models.AlertFilters{Size: 1})
 
 	if err != nil {
 		checks["database"] = gin.H{
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"alertbot/internal/models"
+	"alertbot/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// maxTopologyBody bounds the size of an uploaded dependency graph
+const maxTopologyBody = 4 << 20
+
+type TopologyHandler struct {
+	services *service.Services
+	response *ResponseHelper
+}
+
+func NewTopologyHandler(services *service.Services) *TopologyHandler {
+	return &TopologyHandler{
+		services: services,
+		response: NewResponseHelper(),
+	}
+}
+
+// GetTopology returns the dependency graph, as YAML when format=yaml
+func (h *TopologyHandler) GetTopology(c *gin.Context) {
+	graph, err := h.services.Topology.GetGraph(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to retrieve topology", err.Error())
+		return
+	}
+
+	if c.Query("format") == "yaml" {
+		c.YAML(http.StatusOK, graph)
+		return
+	}
+	h.response.Success(c, graph, "Topology retrieved successfully")
+}
+
+// UpdateTopology replaces the dependency graph. The body is YAML when the
+// content type mentions yaml and JSON otherwise.
+func (h *TopologyHandler) UpdateTopology(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTopologyBody+1))
+	if err != nil {
+		h.response.BadRequest(c, "Failed to read request body", err.Error())
+		return
+	}
+	if len(body) > maxTopologyBody {
+		h.response.BadRequest(c, "Topology is too large", nil)
+		return
+	}
+
+	graph := models.DefaultTopologyGraph()
+	if strings.Contains(c.ContentType(), "yaml") {
+		err = yaml.Unmarshal(body, graph)
+	} else {
+		err = json.Unmarshal(body, graph)
+	}
+	if err != nil {
+		h.response.BadRequest(c, "Invalid topology document", err.Error())
+		return
+	}
+
+	if err := h.services.Topology.UpdateGraph(c.Request.Context(), graph); err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+			return
+		}
+		h.response.InternalServerError(c, "Failed to update topology", err.Error())
+		return
+	}
+
+	h.response.Success(c, graph, "Topology updated successfully")
+}
+
+// GetRootCause returns the suspected root cause of an alert
+func (h *TopologyHandler) GetRootCause(c *gin.Context) {
+	fingerprint := c.Param("fingerprint")
+
+	analysis, err := h.services.Topology.RootCause(c.Request.Context(), fingerprint)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.response.NotFound(c, "Alert")
+			return
+		}
+		h.response.InternalServerError(c, "Failed to analyze root cause", err.Error())
+		return
+	}
+
+	h.response.Success(c, analysis, "Root cause analysis completed")
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"alertbot/internal/models"
+	"alertbot/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SavedViewHandler struct {
+	services *service.Services
+	response *ResponseHelper
+}
+
+func NewSavedViewHandler(services *service.Services) *SavedViewHandler {
+	return &SavedViewHandler{
+		services: services,
+		response: NewResponseHelper(),
+	}
+}
+
+// SavedViewRequest represents the request body for creating or updating saved views
+type SavedViewRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	Shared   bool     `json:"shared"`
+	Query    string   `json:"query"`
+	Status   string   `json:"status"`
+	Severity string   `json:"severity"`
+	Sort     string   `json:"sort"`
+	Order    string   `json:"order"`
+	Columns  []string `json:"columns"`
+}
+
+// ListViews returns the caller's views and all shared views; anonymous
+// callers see only shared views
+func (h *SavedViewHandler) ListViews(c *gin.Context) {
+	views, err := h.services.SavedView.ListViews(c.Request.Context(), authenticatedUser(c))
+	if err != nil {
+		h.response.InternalServerError(c, "Failed to retrieve saved views", err.Error())
+		return
+	}
+
+	h.response.Success(c, gin.H{
+		"items": views,
+		"total": len(views),
+	}, "Saved views retrieved successfully")
+}
+
+// CreateView creates a new saved view owned by the authenticated caller
+func (h *SavedViewHandler) CreateView(c *gin.Context) {
+	var req SavedViewRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	view := req.toModel()
+	view.Owner = authenticatedUser(c)
+
+	if err := h.services.SavedView.CreateView(c.Request.Context(), view); err != nil {
+		h.handleError(c, err, "Failed to create saved view")
+		return
+	}
+
+	h.response.SuccessWithStatus(c, http.StatusCreated, view, "Saved view created successfully")
+}
+
+// GetView retrieves a saved view by ID
+func (h *SavedViewHandler) GetView(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	view, err := h.services.SavedView.GetView(c.Request.Context(), id, authenticatedUser(c))
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve saved view")
+		return
+	}
+
+	h.response.Success(c, view, "Saved view retrieved successfully")
+}
+
+// UpdateView updates a saved view; only the owner may change it
+func (h *SavedViewHandler) UpdateView(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req SavedViewRequest
+	if !h.response.BindAndValidate(c, &req) {
+		return
+	}
+
+	view, err := h.services.SavedView.UpdateView(c.Request.Context(), id, authenticatedUser(c), req.toModel())
+	if err != nil {
+		h.handleError(c, err, "Failed to update saved view")
+		return
+	}
+
+	h.response.Success(c, view, "Saved view updated successfully")
+}
+
+// DeleteView deletes a saved view; only the owner may delete it
+func (h *SavedViewHandler) DeleteView(c *gin.Context) {
+	id, ok := h.response.ParseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.services.SavedView.DeleteView(c.Request.Context(), id, authenticatedUser(c)); err != nil {
+		h.handleError(c, err, "Failed to delete saved view")
+		return
+	}
+
+	h.response.Success(c, nil, "Saved view deleted successfully")
+}
+
+// handleError maps service errors to HTTP responses
+func (h *SavedViewHandler) handleError(c *gin.Context, err error, message string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		h.response.ValidationError(c, validationErr.Message, gin.H{"field": validationErr.Field})
+	case errors.Is(err, service.ErrViewForbidden):
+		h.response.Forbidden(c, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		h.response.NotFound(c, "Saved view")
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
+
+func (r SavedViewRequest) toModel() *models.SavedView {
+	columns := make([]interface{}, len(r.Columns))
+	for i, column := range r.Columns {
+		columns[i] = column
+	}
+
+	return &models.SavedView{
+		Name:     r.Name,
+		Shared:   r.Shared,
+		Query:    r.Query,
+		Status:   r.Status,
+		Severity: r.Severity,
+		Sort:     r.Sort,
+		Order:    r.Order,
+		Columns:  models.JSONB{"columns": columns},
+	}
+}
+
+// authenticatedUser returns the username from the request's JWT claims, or
+// an empty string when the request is not authenticated. Ownership must
+// never be taken from the request itself.
+func authenticatedUser(c *gin.Context) string {
+	if username, ok := c.Get("username"); ok {
+		return getUserString(username)
+	}
+	return ""
+}
+
+// requestUser returns the authenticated username, falling back to the
+// supplied value when the request is not authenticated
+func requestUser(c *gin.Context, fallback string) string {
+	if username, ok := c.Get("username"); ok {
+		if name := getUserString(username); name != "" {
+			return name
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,357 @@
+// Package cluster coordinates alertbot replicas that share one Postgres
+// database: membership, leader election for singleton jobs and claims
+// that let only one replica send each notification.
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/metrics"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// leaderLockID is the Postgres advisory lock key held by the leader
+const leaderLockID int64 = 0x616c657274626f75 // "alertbou"
+
+const (
+	// DefaultHeartbeatInterval is how often membership is refreshed and
+	// leadership checked
+	DefaultHeartbeatInterval = 10 * time.Second
+	// DefaultNotificationDedupWindow is how long a notification claim keeps
+	// other replicas from sending the same notification
+	DefaultNotificationDedupWindow = 30 * time.Second
+
+	// memberTimeout is how many heartbeats a member may miss before it is
+	// no longer listed; the leader removes members gone ten times as long
+	memberTimeout = 3
+
+	// eventPayloadRetention is how long large streaming events stay
+	// available for other replicas to fetch
+	eventPayloadRetention = 5 * time.Minute
+)
+
+// Member is a replica as shown in the cluster status
+type Member struct {
+	models.ClusterMember
+	Self bool `json:"self"`
+}
+
+// Node is this replica's view of the cluster. With clustering disabled,
+// or on a database without advisory locks, it runs standalone: it is
+// always the leader and every notification claim succeeds.
+type Node struct {
+	db     *gorm.DB
+	repos  *repository.Repositories
+	logger *logrus.Logger
+
+	id          string
+	hostname    string
+	address     string
+	startedAt   time.Time
+	interval    time.Duration
+	dedupWindow time.Duration
+	standalone  bool
+
+	// conn holds the leader lock while this node leads
+	conn   *sql.Conn
+	leader bool
+	mu     sync.RWMutex
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	runMu    sync.Mutex
+}
+
+func NewNode(db *gorm.DB, repos *repository.Repositories, cfg config.Cluster, logger *logrus.Logger) *Node {
+	hostname, _ := os.Hostname()
+	name := cfg.NodeName
+	if name == "" {
+		name = hostname
+	}
+
+	n := &Node{
+		db:          db,
+		repos:       repos,
+		logger:      logger,
+		id:          fmt.Sprintf("%s-%s", name, uuid.NewString()[:8]),
+		hostname:    hostname,
+		address:     cfg.AdvertiseAddress,
+		startedAt:   time.Now(),
+		interval:    time.Duration(cfg.HeartbeatInterval) * time.Second,
+		dedupWindow: time.Duration(cfg.NotificationDedupWindow) * time.Second,
+		standalone:  !cfg.Enabled,
+		stopChan:    make(chan struct{}),
+	}
+	if n.interval <= 0 {
+		n.interval = DefaultHeartbeatInterval
+	}
+	if n.dedupWindow <= 0 {
+		n.dedupWindow = DefaultNotificationDedupWindow
+	}
+	if cfg.Enabled && db.Dialector.Name() != repository.DialectPostgres {
+		logger.WithField("driver", db.Dialector.Name()).Warn("Clustering needs Postgres, running standalone")
+		n.standalone = true
+	}
+	if n.standalone {
+		n.leader = true
+		metrics.UpdateClusterLeader(true)
+	}
+	return n
+}
+
+// ID identifies this replica in the cluster
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Enabled reports whether the node coordinates with other replicas
+func (n *Node) Enabled() bool {
+	return !n.standalone
+}
+
+// IsLeader reports whether this replica runs singleton jobs
+func (n *Node) IsLeader() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.leader
+}
+
+// Start joins the cluster and campaigns for leadership until Stop is
+// called or the context is cancelled
+func (n *Node) Start(ctx context.Context) {
+	n.runMu.Lock()
+	defer n.runMu.Unlock()
+
+	if n.running || n.standalone {
+		return
+	}
+
+	n.logger.WithFields(logrus.Fields{
+		"node_id":  n.id,
+		"interval": n.interval,
+	}).Info("Joining cluster")
+	n.running = true
+
+	n.wg.Add(1)
+	go n.run(ctx)
+}
+
+// Stop gives up leadership and leaves the cluster
+func (n *Node) Stop() {
+	n.runMu.Lock()
+	defer n.runMu.Unlock()
+
+	if !n.running {
+		return
+	}
+
+	n.logger.WithField("node_id", n.id).Info("Leaving cluster")
+	close(n.stopChan)
+	n.wg.Wait()
+	n.running = false
+
+	n.resign()
+	if err := n.repos.Cluster.DeleteMember(n.id); err != nil {
+		n.logger.WithError(err).Warn("Failed to remove cluster membership")
+	}
+}
+
+func (n *Node) run(ctx context.Context) {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	n.tick(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.stopChan:
+			return
+		case <-ticker.C:
+			n.tick(ctx)
+		}
+	}
+}
+
+// tick checks leadership, then refreshes this node's membership
+func (n *Node) tick(ctx context.Context) {
+	n.campaign(ctx)
+	leader := n.IsLeader()
+
+	now := time.Now()
+	member := &models.ClusterMember{
+		NodeID:     n.id,
+		Hostname:   n.hostname,
+		Address:    n.address,
+		Leader:     leader,
+		StartedAt:  n.startedAt,
+		LastSeenAt: now,
+	}
+	if err := n.repos.Cluster.UpsertMember(member); err != nil {
+		n.logger.WithError(err).Warn("Failed to refresh cluster membership")
+	}
+
+	if leader {
+		if err := n.repos.Cluster.DeleteMembersNotSeenSince(now.Add(-10 * memberTimeout * n.interval)); err != nil {
+			n.logger.WithError(err).Warn("Failed to remove departed cluster members")
+		}
+		if err := n.repos.Cluster.DeleteClaimsBefore(now.Add(-2 * n.dedupWindow)); err != nil {
+			n.logger.WithError(err).Warn("Failed to remove old notification claims")
+		}
+		if err := n.repos.Cluster.DeleteEventPayloadsBefore(now.Add(-eventPayloadRetention)); err != nil {
+			n.logger.WithError(err).Warn("Failed to remove old event payloads")
+		}
+	}
+
+	if members, err := n.repos.Cluster.ListMembers(now.Add(-memberTimeout * n.interval)); err == nil {
+		metrics.UpdateClusterMembers(float64(len(members)))
+	}
+}
+
+// campaign keeps the leader lock if this node holds it, or tries to take
+// it. The lock belongs to a dedicated session, so Postgres releases it
+// when that session dies and another replica takes over.
+func (n *Node) campaign(ctx context.Context) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn != nil {
+		if err := n.conn.PingContext(ctx); err == nil {
+			return
+		}
+		n.logger.WithField("node_id", n.id).Warn("Lost leader lock connection, no longer leader")
+		n.conn.Close()
+		n.conn = nil
+		n.setLeader(false)
+	}
+
+	sqlDB, err := n.db.DB()
+	if err != nil {
+		return
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		n.logger.WithError(err).Warn("Failed to reserve connection for leader election")
+		return
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", leaderLockID).Scan(&locked); err != nil || !locked {
+		if err != nil {
+			n.logger.WithError(err).Warn("Failed to campaign for cluster leadership")
+		}
+		conn.Close()
+		return
+	}
+
+	n.conn = conn
+	n.setLeader(true)
+	n.logger.WithField("node_id", n.id).Info("Elected cluster leader")
+}
+
+// resign releases the leader lock
+func (n *Node) resign() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		return
+	}
+	if _, err := n.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", leaderLockID); err != nil {
+		n.logger.WithError(err).Warn("Failed to release leader lock")
+	}
+	n.conn.Close()
+	n.conn = nil
+	n.setLeader(false)
+}
+
+// setLeader must be called with mu held
+func (n *Node) setLeader(leader bool) {
+	n.leader = leader
+	metrics.UpdateClusterLeader(leader)
+}
+
+// Members lists the live replicas, or only this one when standalone
+func (n *Node) Members() ([]Member, error) {
+	if n.standalone {
+		return []Member{{
+			ClusterMember: models.ClusterMember{
+				NodeID:     n.id,
+				Hostname:   n.hostname,
+				Address:    n.address,
+				Leader:     true,
+				StartedAt:  n.startedAt,
+				LastSeenAt: time.Now(),
+			},
+			Self: true,
+		}}, nil
+	}
+
+	records, err := n.repos.Cluster.ListMembers(time.Now().Add(-memberTimeout * n.interval))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]Member, len(records))
+	for i, record := range records {
+		members[i] = Member{ClusterMember: record, Self: record.NodeID == n.id}
+	}
+	return members, nil
+}
+
+// ClaimNotification reports whether this replica should send the
+// notification identified by key. Once a replica claims a key, the others
+// skip it for the dedup window. Claim errors fall back to sending, since
+// a duplicate notification beats a lost one.
+func (n *Node) ClaimNotification(key string) bool {
+	if n.standalone {
+		return true
+	}
+
+	now := time.Now()
+	claimed, err := n.repos.Cluster.ClaimNotification(&models.NotificationClaim{
+		ClaimKey:  key,
+		NodeID:    n.id,
+		ClaimedAt: now,
+	}, now.Add(-n.dedupWindow))
+	switch {
+	case err != nil:
+		n.logger.WithError(err).WithField("claim_key", key).Warn("Failed to claim notification, sending anyway")
+		metrics.RecordNotificationClaim("error")
+		return true
+	case claimed:
+		metrics.RecordNotificationClaim("claimed")
+	default:
+		metrics.RecordNotificationClaim("taken")
+	}
+	return claimed
+}
+
+// ReleaseNotification gives up a claim taken by ClaimNotification, so a
+// failed send can be retried here or by another replica within the dedup
+// window.
+func (n *Node) ReleaseNotification(key string) {
+	if n.standalone {
+		return
+	}
+
+	if err := n.repos.Cluster.ReleaseClaim(key, n.id); err != nil {
+		n.logger.WithError(err).WithField("claim_key", key).Warn("Failed to release notification claim")
+		return
+	}
+	metrics.RecordNotificationClaim("released")
+}
@@ -12,6 +12,12 @@ type Config struct {
 	JWT       JWT        `mapstructure:"jwt"`
 	RateLimit RateLimit  `mapstructure:"rate_limit"`
 	Security  Security   `mapstructure:"security"`
+
+	Fingerprint Fingerprint `mapstructure:"fingerprint"`
+	Cluster     Cluster     `mapstructure:"cluster"`
+	Pipeline    Pipeline    `mapstructure:"pipeline"`
+	Ingestion   Ingestion   `mapstructure:"ingestion"`
+	Enrichment  Enrichment  `mapstructure:"enrichment"`
 }
 
 type Server struct {
@@ -22,6 +28,8 @@ type Server struct {
 }
 
 type Database struct {
+	Driver          string `mapstructure:"driver"` // postgres or sqlite
+	Path            string `mapstructure:"path"`   // SQLite database file, or :memory:
 	Host            string `mapstructure:"host"`
 	Port            int    `mapstructure:"port"`
 	User            string `mapstructure:"user"`
@@ -50,6 +58,89 @@ type RateLimit struct {
 	Enabled bool `mapstructure:"enabled"`
 	RPS     int  `mapstructure:"rps"`
 	Burst   int  `mapstructure:"burst"`
+
+	// Where token buckets are kept: memory (per replica), postgres or redis
+	Backend  string `mapstructure:"backend"`
+	RedisURL string `mapstructure:"redis_url"` // e.g. redis://localhost:6379/0
+
+	// Per authenticated user, across all endpoints
+	UserRPS   int `mapstructure:"user_rps"`
+	UserBurst int `mapstructure:"user_burst"`
+
+	// Per client IP limits for matching endpoints, first match wins
+	Endpoints []RateLimitEndpoint `mapstructure:"endpoints"`
+
+	// Global cap on outbound notifications across all channels
+	NotificationRPS   int `mapstructure:"notification_rps"`
+	NotificationBurst int `mapstructure:"notification_burst"`
+}
+
+// Pipeline orders the stages received alerts go through. Stages left out
+// are disabled; normalize and store are required.
+type Pipeline struct {
+	Stages []string `mapstructure:"stages"`
+}
+
+// Ingestion queues received alerts so the receive endpoints answer before
+// the alerts are processed. With Async off they are processed inline.
+type Ingestion struct {
+	Async      bool `mapstructure:"async"`
+	Workers    int  `mapstructure:"workers"`     // batches ingested at once
+	MaxDepth   int  `mapstructure:"max_depth"`   // alerts waiting in the queue
+	RetryAfter int  `mapstructure:"retry_after"` // seconds, sent when the queue refuses a batch
+}
+
+// Enrichment adds labels and annotations to received alerts before they
+// are deduplicated and routed. Rules apply in order, so a rule can look up
+// labels an earlier one added.
+type Enrichment struct {
+	Rules []EnrichmentRule `mapstructure:"rules"`
+}
+
+// EnrichmentRule looks up a record for alerts matching Match, in a CSV or
+// YAML table or over HTTP, and copies its fields onto the alert. Labels and
+// Annotations map the name to set to the record field.
+type EnrichmentRule struct {
+	Name        string            `mapstructure:"name"`
+	Match       string            `mapstructure:"match"`       // Label matchers; empty matches every alert
+	Table       string            `mapstructure:"table"`       // CSV or YAML file with a column per key label
+	KeyLabels   []string          `mapstructure:"key_labels"`  // Labels whose values select the table row
+	URL         string            `mapstructure:"url"`         // HTTP lookup; {label} is replaced by the label's value
+	Headers     map[string]string `mapstructure:"headers"`     // Sent with HTTP lookups, e.g. authorization
+	Timeout     int               `mapstructure:"timeout"`     // Milliseconds per HTTP lookup
+	CacheTTL    int               `mapstructure:"cache_ttl"`   // Seconds HTTP results, including misses, are cached
+	Labels      map[string]string `mapstructure:"labels"`      // Label to set => record field
+	Annotations map[string]string `mapstructure:"annotations"` // Annotation to set => record field
+	Overwrite   bool              `mapstructure:"overwrite"`   // Replace values the alert already has
+}
+
+// RateLimitEndpoint limits requests to a path. Path segments of "*" match
+// any value; an empty method matches every method.
+type RateLimitEndpoint struct {
+	Path   string `mapstructure:"path"`
+	Method string `mapstructure:"method"`
+	RPS    int    `mapstructure:"rps"`
+	Burst  int    `mapstructure:"burst"`
+}
+
+// Fingerprint selects the labels that identify an alert. With no include
+// list every label is used, which matches Alertmanager's fingerprints.
+// Patterns ending in "*" match a label name prefix.
+type Fingerprint struct {
+	IncludeLabels []string `mapstructure:"include_labels"`
+	ExcludeLabels []string `mapstructure:"exclude_labels"`
+}
+
+// Cluster runs several replicas against one Postgres database. One replica
+// is elected leader to run singleton jobs, and notifications are claimed
+// so that only one replica sends each.
+type Cluster struct {
+	Enabled                 bool   `mapstructure:"enabled"`
+	NodeName                string `mapstructure:"node_name"`                 // Defaults to the hostname
+	AdvertiseAddress        string `mapstructure:"advertise_address"`         // Shown to peers, e.g. http://10.0.0.5:8080
+	HeartbeatInterval       int    `mapstructure:"heartbeat_interval"`        // Seconds between membership updates and leader checks
+	NotificationDedupWindow int    `mapstructure:"notification_dedup_window"` // Seconds a notification claim blocks other replicas
+	EventBus                string `mapstructure:"event_bus"`                 // local or postgres; how streaming events reach other replicas
 }
 
 type Security struct {
@@ -57,6 +148,19 @@ type Security struct {
 	PasswordMinLen int      `mapstructure:"password_min_len"`
 	HTTPSOnly      bool     `mapstructure:"https_only"`
 	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	Users          []User   `mapstructure:"users"`
+	TicketTTL      int      `mapstructure:"ticket_ttl"` // WebSocket ticket lifetime in seconds
+}
+
+// User is a locally configured account. Teams and Scope limit which alerts
+// the user can see on streaming endpoints; admins see everything.
+type User struct {
+	ID           uint     `mapstructure:"id"`
+	Username     string   `mapstructure:"username"`
+	PasswordHash string   `mapstructure:"password_hash"` // bcrypt hash
+	Role         string   `mapstructure:"role"`
+	Teams        []string `mapstructure:"teams"`
+	Scope        string   `mapstructure:"scope"` // Label matcher expression, e.g. env="prod"
 }
 
 func Load() (*Config, error) {
@@ -71,6 +175,8 @@ func Load() (*Config, error) {
 	viper.SetDefault("server.write_timeout", 10)
 	viper.SetDefault("server.idle_timeout", 60)
 	
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("database.path", "alertbot.db")
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", 5432)
 	viper.SetDefault("database.user", "alertbot")
@@ -94,11 +200,40 @@ func Load() (*Config, error) {
 	viper.SetDefault("rate_limit.enabled", true)
 	viper.SetDefault("rate_limit.rps", 100)
 	viper.SetDefault("rate_limit.burst", 200)
+	viper.SetDefault("rate_limit.backend", "memory")
+	viper.SetDefault("rate_limit.user_rps", 50)
+	viper.SetDefault("rate_limit.user_burst", 100)
+	viper.SetDefault("rate_limit.endpoints", []map[string]interface{}{
+		{"path": "/api/v1/alerts", "rps": 200, "burst": 400},
+		{"path": "/api/v1/channels/*/test", "rps": 5, "burst": 10},
+		{"path": "/api/v1/auth/login", "rps": 10, "burst": 20},
+	})
+	viper.SetDefault("rate_limit.notification_rps", 30)
+	viper.SetDefault("rate_limit.notification_burst", 100)
 	
 	viper.SetDefault("security.bcrypt_cost", 12)
 	viper.SetDefault("security.password_min_len", 8)
 	viper.SetDefault("security.https_only", false)
 	viper.SetDefault("security.trusted_proxies", []string{})
+	viper.SetDefault("security.ticket_ttl", 30)
+
+	viper.SetDefault("fingerprint.include_labels", []string{})
+	viper.SetDefault("fingerprint.exclude_labels", []string{})
+
+	viper.SetDefault("pipeline.stages", []string{
+		"normalize", "heartbeat", "enrich", "dedup", "silence", "inhibit",
+		"store", "group", "route", "notify", "broadcast",
+	})
+
+	viper.SetDefault("ingestion.async", true)
+	viper.SetDefault("ingestion.workers", 4)
+	viper.SetDefault("ingestion.max_depth", 20000)
+	viper.SetDefault("ingestion.retry_after", 10)
+
+	viper.SetDefault("cluster.enabled", false)
+	viper.SetDefault("cluster.heartbeat_interval", 10)
+	viper.SetDefault("cluster.notification_dedup_window", 30)
+	viper.SetDefault("cluster.event_bus", "postgres")
 
 	viper.AutomaticEnv()
 
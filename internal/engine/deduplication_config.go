@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+)
+
+// deduplicationConfigRefresh bounds how stale a replica's deduplication
+// config may be after another replica updated it
+const deduplicationConfigRefresh = 30 * time.Second
+
+// DeduplicationOverride replaces parts of the config for matching alerts
+type DeduplicationOverride struct {
+	Name      string
+	AlertName string
+	Matchers  matcher.Matchers
+
+	DeduplicationWindow time.Duration
+	CorrelationWindow   time.Duration
+	IgnoreLabels        []string
+	CorrelationLabels   []string
+}
+
+// Matches reports whether the override applies to the alert
+func (o *DeduplicationOverride) Matches(alert *models.Alert) bool {
+	if o.AlertName != "" {
+		if name, _ := alert.Labels["alertname"].(string); name != o.AlertName {
+			return false
+		}
+	}
+	return o.Matchers.MatchesJSONB(alert.Labels)
+}
+
+// NewDeduplicationConfig validates stored settings and compiles override matchers
+func NewDeduplicationConfig(m *models.DeduplicationConfig) (DeduplicationConfig, error) {
+	config := DeduplicationConfig{
+		DeduplicationWindow:     m.DeduplicationWindow,
+		IgnoreLabels:            m.IgnoreLabels,
+		CorrelationLabels:       m.CorrelationLabels,
+		CorrelationWindow:       m.CorrelationWindow,
+		MaxRelatedAlerts:        m.MaxRelatedAlerts,
+		EnableTimeBasedDedup:    m.EnableTimeBasedDedup,
+		EnableContentBasedDedup: m.EnableContentBasedDedup,
+		EnableCorrelation:       m.EnableCorrelation,
+	}
+
+	for i, o := range m.Overrides {
+		if o.AlertName == "" && o.Matchers == "" {
+			return config, fmt.Errorf("override %d must set alertname or matchers", i)
+		}
+		if o.DeduplicationWindow < 0 || o.CorrelationWindow < 0 {
+			return config, fmt.Errorf("override %d has a negative window", i)
+		}
+		ms, err := matcher.Parse(o.Matchers)
+		if err != nil {
+			return config, fmt.Errorf("override %d has invalid matchers: %w", i, err)
+		}
+
+		name := o.Name
+		if name == "" {
+			name = fmt.Sprintf("override-%d", i)
+		}
+		config.Overrides = append(config.Overrides, DeduplicationOverride{
+			Name:                name,
+			AlertName:           o.AlertName,
+			Matchers:            ms,
+			DeduplicationWindow: o.DeduplicationWindow,
+			CorrelationWindow:   o.CorrelationWindow,
+			IgnoreLabels:        o.IgnoreLabels,
+			CorrelationLabels:   o.CorrelationLabels,
+		})
+	}
+
+	return config, nil
+}
+
+// ForAlert returns the effective config for an alert: the global settings with
+// the first matching override applied
+func (c DeduplicationConfig) ForAlert(alert *models.Alert) DeduplicationConfig {
+	effective := c
+	effective.Overrides = nil
+
+	for i := range c.Overrides {
+		o := &c.Overrides[i]
+		if !o.Matches(alert) {
+			continue
+		}
+
+		effective.AppliedOverride = o.Name
+		if o.DeduplicationWindow > 0 {
+			effective.DeduplicationWindow = o.DeduplicationWindow
+		}
+		if o.CorrelationWindow > 0 {
+			effective.CorrelationWindow = o.CorrelationWindow
+		}
+		if o.IgnoreLabels != nil {
+			effective.IgnoreLabels = o.IgnoreLabels
+		}
+		if o.CorrelationLabels != nil {
+			effective.CorrelationLabels = o.CorrelationLabels
+		}
+		break
+	}
+
+	return effective
+}
+
+// currentConfig returns the active config, reloading it from the settings
+// table when the local copy is older than deduplicationConfigRefresh
+func (de *DeduplicationEngine) currentConfig() DeduplicationConfig {
+	de.mu.RLock()
+	config, loadedAt := de.config, de.loadedAt
+	de.mu.RUnlock()
+
+	if time.Since(loadedAt) < deduplicationConfigRefresh {
+		return config
+	}
+	return de.reloadConfig()
+}
+
+// reloadConfig reads the stored config; on failure the current one is kept
+func (de *DeduplicationEngine) reloadConfig() DeduplicationConfig {
+	de.mu.Lock()
+	defer de.mu.Unlock()
+
+	// Record the attempt even on failure so the database is not queried per alert
+	de.loadedAt = time.Now()
+
+	if de.repo == nil || de.repo.Settings == nil {
+		return de.config
+	}
+
+	stored, err := de.repo.Settings.GetDeduplicationConfig()
+	if err != nil {
+		de.logger.WithError(err).Warn("Failed to load deduplication config, keeping current")
+		return de.config
+	}
+	config, err := NewDeduplicationConfig(stored)
+	if err != nil {
+		de.logger.WithError(err).Error("Stored deduplication config is invalid, keeping current")
+		return de.config
+	}
+
+	de.config = config
+	return config
+}
@@ -2,13 +2,12 @@ package engine
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"alertbot/internal/fingerprint"
 	"alertbot/internal/metrics"
 	"alertbot/internal/models"
 	"alertbot/internal/repository"
@@ -20,7 +19,13 @@ import (
 type DeduplicationEngine struct {
 	repo   *repository.Repositories
 	logger *logrus.Logger
-	config DeduplicationConfig
+
+	mu       sync.RWMutex
+	config   DeduplicationConfig
+	loadedAt time.Time
+
+	topology      *TopologyEngine
+	fingerprinter *fingerprint.Strategy
 }
 
 // DeduplicationConfig holds configuration for deduplication
@@ -48,6 +53,12 @@ type DeduplicationConfig struct {
 	
 	// Enable alert correlation
 	EnableCorrelation bool
+	
+	// Scoped overrides, the first matching one applies
+	Overrides []DeduplicationOverride
+	
+	// Name of the override applied by ForAlert, empty for the global config
+	AppliedOverride string
 }
 
 // DeduplicationResult contains the result of deduplication check
@@ -57,7 +68,9 @@ type DeduplicationResult struct {
 	RelatedAlerts    []*models.Alert
 	DeduplicationKey string
 	CorrelationKey   string
+	Override         string // Name of the applied override, if any
 	Action           string // "create", "update", "ignore"
+	Correlated       bool   // Correlation is enabled for the alert
 }
 
 // CorrelationRule defines how alerts should be correlated
@@ -70,37 +83,31 @@ type CorrelationRule struct {
 }
 
 func NewDeduplicationEngine(repo *repository.Repositories, logger *logrus.Logger) *DeduplicationEngine {
-	config := DeduplicationConfig{
-		DeduplicationWindow:     5 * time.Minute,  // 5 minutes window for duplicates
-		CorrelationWindow:      30 * time.Minute, // 30 minutes window for correlation
-		MaxRelatedAlerts:       10,
-		EnableTimeBasedDedup:   true,
-		EnableContentBasedDedup: true,
-		EnableCorrelation:      true,
-		IgnoreLabels: []string{
-			"__name__",
-			"__tmp_",
-			"timestamp",
-			"receive_timestamp",
-		},
-		CorrelationLabels: []string{
-			"instance",
-			"job",
-			"service",
-			"cluster",
-			"node",
-		},
-	}
+	// The built-in defaults are always valid
+	config, _ := NewDeduplicationConfig(models.DefaultDeduplicationConfig())
 
-	return &DeduplicationEngine{
-		repo:   repo,
-		logger: logger,
-		config: config,
+	de := &DeduplicationEngine{
+		repo:          repo,
+		logger:        logger,
+		config:        config,
+		fingerprinter: fingerprint.Default(),
 	}
+	de.reloadConfig()
+	return de
 }
 
 // ProcessAlert performs deduplication and correlation for an incoming alert
 func (de *DeduplicationEngine) ProcessAlert(ctx context.Context, alert *models.Alert) (*DeduplicationResult, error) {
+	idx, err := de.NewIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	return de.ProcessAlertWithIndex(ctx, alert, idx)
+}
+
+// ProcessAlertWithIndex performs deduplication and correlation against the
+// alerts in idx
+func (de *DeduplicationEngine) ProcessAlertWithIndex(ctx context.Context, alert *models.Alert, idx *DeduplicationIndex) (*DeduplicationResult, error) {
 	start := time.Now()
 	defer func() {
 		metrics.RecordDeduplicationDuration(time.Since(start).Seconds())
@@ -110,12 +117,15 @@ func (de *DeduplicationEngine) ProcessAlert(ctx context.Context, alert *models.A
 		RelatedAlerts: make([]*models.Alert, 0),
 	}
 
+	config := de.currentConfig().ForAlert(alert)
+	result.Override = config.AppliedOverride
+
 	// Generate deduplication key
-	dedupKey := de.generateDeduplicationKey(alert)
+	dedupKey := de.generateDeduplicationKey(alert, config)
 	result.DeduplicationKey = dedupKey
 
 	// Generate correlation key
-	corrKey := de.generateCorrelationKey(alert)
+	corrKey := de.generateCorrelationKey(alert, config)
 	result.CorrelationKey = corrKey
 
 	de.logger.WithFields(logrus.Fields{
@@ -125,22 +135,17 @@ func (de *DeduplicationEngine) ProcessAlert(ctx context.Context, alert *models.A
 	}).Debug("Processing alert for deduplication and correlation")
 
 	// Check for existing duplicate
-	if de.config.EnableTimeBasedDedup || de.config.EnableContentBasedDedup {
-		existingAlert, err := de.findDuplicate(ctx, alert, dedupKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check for duplicates: %w", err)
-		}
-
-		if existingAlert != nil {
+	if config.EnableTimeBasedDedup || config.EnableContentBasedDedup {
+		if existingAlert := de.findDuplicate(idx, alert, dedupKey, config); existingAlert != nil {
 			result.IsDuplicate = true
 			result.ExistingAlert = existingAlert
 			result.Action = de.determineUpdateAction(alert, existingAlert)
 			
 			// Record duplicate metrics
-			if de.config.EnableTimeBasedDedup {
+			if config.EnableTimeBasedDedup {
 				metrics.RecordDeduplicationDuplicate("time_based")
 			}
-			if de.config.EnableContentBasedDedup {
+			if config.EnableContentBasedDedup {
 				metrics.RecordDeduplicationDuplicate("content_based")
 			}
 			
@@ -152,23 +157,20 @@ func (de *DeduplicationEngine) ProcessAlert(ctx context.Context, alert *models.A
 	}
 
 	// Find related alerts for correlation
-	if de.config.EnableCorrelation {
-		relatedAlerts, err := de.findRelatedAlerts(ctx, alert, corrKey)
-		if err != nil {
-			de.logger.WithError(err).Warn("Failed to find related alerts")
-		} else {
-			result.RelatedAlerts = relatedAlerts
-			
-			if len(relatedAlerts) > 0 {
-				// Record correlation metrics
-				for range relatedAlerts {
-					metrics.RecordDeduplicationCorrelation()
-				}
-				
-				de.logger.WithFields(logrus.Fields{
-					"related_count": len(relatedAlerts),
-				}).Info("Found related alerts for correlation")
+	result.Correlated = config.EnableCorrelation
+	if config.EnableCorrelation {
+		relatedAlerts := de.findRelatedAlerts(idx, alert, corrKey, config)
+		result.RelatedAlerts = relatedAlerts
+
+		if len(relatedAlerts) > 0 {
+			// Record correlation metrics
+			for range relatedAlerts {
+				metrics.RecordDeduplicationCorrelation()
 			}
+
+			de.logger.WithFields(logrus.Fields{
+				"related_count": len(relatedAlerts),
+			}).Info("Found related alerts for correlation")
 		}
 	}
 
@@ -183,112 +185,40 @@ func (de *DeduplicationEngine) ProcessAlert(ctx context.Context, alert *models.A
 	return result, nil
 }
 
-// generateDeduplicationKey creates a key for identifying duplicate alerts
-func (de *DeduplicationEngine) generateDeduplicationKey(alert *models.Alert) string {
-	// Start with alert name and critical labels
-	var keyParts []string
-
-	// Add alertname (most important for deduplication)
-	if alertName, exists := alert.Labels["alertname"]; exists {
-		if name, ok := alertName.(string); ok {
-			keyParts = append(keyParts, fmt.Sprintf("alertname=%s", name))
-		}
-	}
-
-	// Add instance information
-	if instance, exists := alert.Labels["instance"]; exists {
-		if inst, ok := instance.(string); ok {
-			keyParts = append(keyParts, fmt.Sprintf("instance=%s", inst))
-		}
-	}
-
-	// Add job information
-	if job, exists := alert.Labels["job"]; exists {
-		if j, ok := job.(string); ok {
-			keyParts = append(keyParts, fmt.Sprintf("job=%s", j))
-		}
-	}
-
-	// Add severity for more specific deduplication
-	keyParts = append(keyParts, fmt.Sprintf("severity=%s", alert.Severity))
-
-	// Add status to differentiate firing/resolved
-	keyParts = append(keyParts, fmt.Sprintf("status=%s", alert.Status))
-
-	// Add other significant labels (excluding ignored ones)
-	var otherLabels []string
-	for key, value := range alert.Labels {
-		if de.shouldIgnoreLabel(key) {
-			continue
-		}
-		
-		// Skip already processed labels
-		if key == "alertname" || key == "instance" || key == "job" {
-			continue
-		}
-		
-		if str, ok := value.(string); ok {
-			otherLabels = append(otherLabels, fmt.Sprintf("%s=%s", key, str))
-		}
+// generateDeduplicationKey creates a key for identifying duplicate alerts.
+// It is the alert fingerprint under the configured strategy, with labels
+// starting with one of the ignored prefixes excluded as well.
+func (de *DeduplicationEngine) generateDeduplicationKey(alert *models.Alert, config DeduplicationConfig) string {
+	ignored := make([]string, 0, len(config.IgnoreLabels))
+	for _, prefix := range config.IgnoreLabels {
+		ignored = append(ignored, prefix+"*")
 	}
-	
-	// Sort for consistent key generation
-	sort.Strings(otherLabels)
-	keyParts = append(keyParts, otherLabels...)
-
-	// Create hash of the key for consistent length
-	keyString := strings.Join(keyParts, "|")
-	hash := sha256.Sum256([]byte(keyString))
-	return hex.EncodeToString(hash[:])[:16] // Use first 16 characters
+	return de.fingerprinter.Without(ignored...).FingerprintJSONB(alert.Labels)
 }
 
-// generateCorrelationKey creates a key for finding related alerts
-func (de *DeduplicationEngine) generateCorrelationKey(alert *models.Alert) string {
-	var keyParts []string
-
-	// Use configured correlation labels
-	for _, label := range de.config.CorrelationLabels {
-		if value, exists := alert.Labels[label]; exists {
-			if str, ok := value.(string); ok {
-				keyParts = append(keyParts, fmt.Sprintf("%s=%s", label, str))
-			}
+// generateCorrelationKey creates a key for finding related alerts from the
+// configured correlation labels and severity
+func (de *DeduplicationEngine) generateCorrelationKey(alert *models.Alert, config DeduplicationConfig) string {
+	labels := make(map[string]string, len(config.CorrelationLabels)+1)
+	for _, label := range config.CorrelationLabels {
+		if value, ok := alert.Labels[label].(string); ok {
+			labels[label] = value
 		}
 	}
 
 	// Add severity for similar-impact correlation
-	keyParts = append(keyParts, fmt.Sprintf("severity=%s", alert.Severity))
-
-	if len(keyParts) == 0 {
-		// Fallback to basic correlation
-		keyParts = append(keyParts, "default")
-	}
+	labels["__severity__"] = alert.Severity
 
-	// Sort for consistent key generation
-	sort.Strings(keyParts)
-	keyString := strings.Join(keyParts, "|")
-	
-	hash := sha256.Sum256([]byte(keyString))
-	return hex.EncodeToString(hash[:])[:12] // Shorter key for correlation
+	return fingerprint.Sum(labels)
 }
 
-// findDuplicate searches for existing duplicate alerts
-func (de *DeduplicationEngine) findDuplicate(ctx context.Context, alert *models.Alert, dedupKey string) (*models.Alert, error) {
+// findDuplicate searches the indexed firing alerts for a duplicate
+func (de *DeduplicationEngine) findDuplicate(idx *DeduplicationIndex, alert *models.Alert, dedupKey string, config DeduplicationConfig) *models.Alert {
 	// Build time window for search
-	timeWindowStart := alert.StartsAt.Add(-de.config.DeduplicationWindow)
-	timeWindowEnd := alert.StartsAt.Add(de.config.DeduplicationWindow)
-
-	// Search for alerts with similar characteristics
-	filters := models.AlertFilters{
-		Status: "firing", // Only check against firing alerts
-		Size:   100,      // Limit search scope
-	}
+	timeWindowStart := alert.StartsAt.Add(-config.DeduplicationWindow)
+	timeWindowEnd := alert.StartsAt.Add(config.DeduplicationWindow)
 
-	alerts, _, err := de.repo.Alert.List(filters)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, existingAlert := range alerts {
+	for _, existingAlert := range idx.firing {
 		// Skip if outside time window
 		if existingAlert.StartsAt.Before(timeWindowStart) || existingAlert.StartsAt.After(timeWindowEnd) {
 			continue
@@ -300,39 +230,29 @@ func (de *DeduplicationEngine) findDuplicate(ctx context.Context, alert *models.
 		}
 
 		// Check if deduplication keys match
-		existingDedupKey := de.generateDeduplicationKey(&existingAlert)
-		if existingDedupKey == dedupKey {
-			return &existingAlert, nil
+		if idx.dedupKey(de, existingAlert, config) == dedupKey {
+			return existingAlert
 		}
 
 		// Additional similarity checks
-		if de.config.EnableContentBasedDedup && de.areAlertsSimilar(alert, &existingAlert) {
-			return &existingAlert, nil
+		if config.EnableContentBasedDedup && de.areAlertsSimilar(alert, existingAlert) {
+			return existingAlert
 		}
 	}
 
-	return nil, nil
+	return nil
 }
 
-// findRelatedAlerts searches for alerts that might be related/correlated
-func (de *DeduplicationEngine) findRelatedAlerts(ctx context.Context, alert *models.Alert, corrKey string) ([]*models.Alert, error) {
+// findRelatedAlerts searches the indexed recent alerts for alerts that
+// might be related/correlated
+func (de *DeduplicationEngine) findRelatedAlerts(idx *DeduplicationIndex, alert *models.Alert, corrKey string, config DeduplicationConfig) []*models.Alert {
 	// Build time window for correlation search
-	timeWindowStart := alert.StartsAt.Add(-de.config.CorrelationWindow)
-	timeWindowEnd := alert.StartsAt.Add(de.config.CorrelationWindow)
-
-	// Search for alerts in correlation window
-	filters := models.AlertFilters{
-		Size: 200, // Broader search for correlation
-	}
-
-	alerts, _, err := de.repo.Alert.List(filters)
-	if err != nil {
-		return nil, err
-	}
+	timeWindowStart := alert.StartsAt.Add(-config.CorrelationWindow)
+	timeWindowEnd := alert.StartsAt.Add(config.CorrelationWindow)
 
 	var relatedAlerts []*models.Alert
-	
-	for _, existingAlert := range alerts {
+
+	for _, existingAlert := range idx.recent {
 		// Skip if outside time window
 		if existingAlert.StartsAt.Before(timeWindowStart) || existingAlert.StartsAt.After(timeWindowEnd) {
 			continue
@@ -344,17 +264,17 @@ func (de *DeduplicationEngine) findRelatedAlerts(ctx context.Context, alert *mod
 		}
 
 		// Check correlation
-		if de.areAlertsCorrelated(alert, &existingAlert, corrKey) {
-			relatedAlerts = append(relatedAlerts, &existingAlert)
-			
+		if de.areAlertsCorrelated(alert, existingAlert, corrKey, idx.correlationKey(de, existingAlert, config)) {
+			relatedAlerts = append(relatedAlerts, existingAlert)
+
 			// Limit number of related alerts
-			if len(relatedAlerts) >= de.config.MaxRelatedAlerts {
+			if len(relatedAlerts) >= config.MaxRelatedAlerts {
 				break
 			}
 		}
 	}
 
-	return relatedAlerts, nil
+	return relatedAlerts
 }
 
 // areAlertsSimilar checks if two alerts are similar enough to be considered duplicates
@@ -392,10 +312,7 @@ func (de *DeduplicationEngine) areAlertsSimilar(alert1, alert2 *models.Alert) bo
 }
 
 // areAlertsCorrelated checks if alerts should be correlated
-func (de *DeduplicationEngine) areAlertsCorrelated(alert1, alert2 *models.Alert, corrKey string) bool {
-	// Generate correlation key for the second alert
-	corrKey2 := de.generateCorrelationKey(alert2)
-	
+func (de *DeduplicationEngine) areAlertsCorrelated(alert1, alert2 *models.Alert, corrKey, corrKey2 string) bool {
 	// Basic correlation: same correlation key
 	if corrKey == corrKey2 {
 		return true
@@ -414,18 +331,38 @@ func (de *DeduplicationEngine) areAlertsCorrelated(alert1, alert2 *models.Alert,
 	return de.checkCausalRelation(alert1, alert2)
 }
 
-// checkCausalRelation checks if alerts might have a causal relationship
+// SetTopology makes causal correlation follow the configured dependency graph
+func (de *DeduplicationEngine) SetTopology(topology *TopologyEngine) {
+	de.topology = topology
+}
+
+// SetFingerprinter sets the strategy duplicate keys are derived from, so
+// they select the same labels as alert fingerprints
+func (de *DeduplicationEngine) SetFingerprinter(strategy *fingerprint.Strategy) {
+	de.fingerprinter = strategy
+}
+
+// causalPatterns are infrastructure dependency patterns: the labels an
+// alert of each parent type shares with the alerts it may cause
+var causalPatterns = map[string][]string{
+	"node":     {"instance", "job"},
+	"service":  {"instance"},
+	"database": {"service", "instance"},
+	"network":  {"instance", "cluster"},
+}
+
+// checkCausalRelation checks if alerts might have a causal relationship.
+// With a dependency graph configured, alerts are related when either one's
+// node depends on the other's; otherwise alert name patterns are used.
 func (de *DeduplicationEngine) checkCausalRelation(alert1, alert2 *models.Alert) bool {
-	// Infrastructure dependency patterns
-	patterns := map[string][]string{
-		"node":     {"instance", "job"},
-		"service":  {"instance"},
-		"database": {"service", "instance"},
-		"network":  {"instance", "cluster"},
+	if de.topology != nil {
+		if topology := de.topology.Current(); !topology.Empty() {
+			return topology.DependsOn(alert2, alert1) || topology.DependsOn(alert1, alert2)
+		}
 	}
 
 	// Check if alerts follow dependency patterns
-	for parentType, childLabels := range patterns {
+	for parentType, childLabels := range causalPatterns {
 		if de.alertMatchesType(alert1, parentType) {
 			for _, childLabel := range childLabels {
 				if value1, exists1 := alert1.Labels[childLabel].(string); exists1 {
@@ -484,16 +421,6 @@ func (de *DeduplicationEngine) determineUpdateAction(newAlert, existingAlert *mo
 	return "ignore"
 }
 
-// shouldIgnoreLabel checks if a label should be ignored in deduplication
-func (de *DeduplicationEngine) shouldIgnoreLabel(label string) bool {
-	for _, ignoreLabel := range de.config.IgnoreLabels {
-		if strings.HasPrefix(label, ignoreLabel) {
-			return true
-		}
-	}
-	return false
-}
-
 // stringSimilarity calculates basic string similarity (Jaccard similarity)
 func (de *DeduplicationEngine) stringSimilarity(s1, s2 string) float64 {
 	if s1 == s2 {
@@ -543,6 +470,9 @@ func (de *DeduplicationEngine) stringSimilarity(s1, s2 string) float64 {
 
 // UpdateDeduplicationConfig updates the deduplication configuration
 func (de *DeduplicationEngine) UpdateDeduplicationConfig(config DeduplicationConfig) {
+	de.mu.Lock()
 	de.config = config
+	de.loadedAt = time.Now()
+	de.mu.Unlock()
 	de.logger.Info("Deduplication configuration updated")
 }
\ No newline at end of file
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"alertbot/internal/models"
+)
+
+const (
+	// indexFiringAlerts and indexRecentAlerts bound how many recent alerts
+	// an incoming alert is compared with for duplicates and correlation
+	indexFiringAlerts = 100
+	indexRecentAlerts = 200
+)
+
+// DeduplicationIndex holds the recent alerts that incoming alerts are
+// deduplicated and correlated against. A batch loads it once instead of
+// querying per alert; alerts added while the batch is processed are seen by
+// the alerts after them.
+type DeduplicationIndex struct {
+	firing []*models.Alert
+	recent []*models.Alert
+
+	// keys caches the deduplication and correlation keys of indexed alerts
+	// per override, severity and status
+	keys map[*models.Alert]map[string]string
+}
+
+// NewIndex loads the most recent alerts from the database
+func (de *DeduplicationEngine) NewIndex(ctx context.Context) (*DeduplicationIndex, error) {
+	firing, _, err := de.repo.Alert.List(models.AlertFilters{
+		Status: string(models.AlertStatusFiring),
+		Size:   indexFiringAlerts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load firing alerts: %w", err)
+	}
+
+	idx := &DeduplicationIndex{
+		firing: alertPointers(firing),
+		keys:   make(map[*models.Alert]map[string]string),
+	}
+
+	// Correlation is best effort, an index without recent alerts only
+	// finds no related ones
+	recent, _, err := de.repo.Alert.List(models.AlertFilters{Size: indexRecentAlerts})
+	if err != nil {
+		de.logger.WithError(err).Warn("Failed to load recent alerts for correlation")
+	}
+	idx.recent = alertPointers(recent)
+
+	return idx, nil
+}
+
+// Add makes an incoming alert visible to the alerts processed after it.
+// Like the database queries, the index keeps only the newest alerts.
+func (idx *DeduplicationIndex) Add(alert *models.Alert) {
+	if alert.Status == string(models.AlertStatusFiring) {
+		idx.firing = prependAlert(idx.firing, alert, indexFiringAlerts)
+	}
+	idx.recent = prependAlert(idx.recent, alert, indexRecentAlerts)
+}
+
+// dedupKey returns the deduplication key of an indexed alert under config
+func (idx *DeduplicationIndex) dedupKey(de *DeduplicationEngine, alert *models.Alert, config DeduplicationConfig) string {
+	return idx.cachedKey("dedup", alert, config, de.generateDeduplicationKey)
+}
+
+// correlationKey returns the correlation key of an indexed alert under config
+func (idx *DeduplicationIndex) correlationKey(de *DeduplicationEngine, alert *models.Alert, config DeduplicationConfig) string {
+	return idx.cachedKey("correlation", alert, config, de.generateCorrelationKey)
+}
+
+func (idx *DeduplicationIndex) cachedKey(kind string, alert *models.Alert, config DeduplicationConfig, generate func(*models.Alert, DeduplicationConfig) string) string {
+	cacheKey := kind + "\x00" + config.AppliedOverride + "\x00" + alert.Severity + "\x00" + alert.Status
+
+	keys, ok := idx.keys[alert]
+	if !ok {
+		keys = make(map[string]string)
+		idx.keys[alert] = keys
+	}
+	if key, ok := keys[cacheKey]; ok {
+		return key
+	}
+
+	key := generate(alert, config)
+	keys[cacheKey] = key
+	return key
+}
+
+func alertPointers(alerts []models.Alert) []*models.Alert {
+	pointers := make([]*models.Alert, len(alerts))
+	for i := range alerts {
+		pointers[i] = &alerts[i]
+	}
+	return pointers
+}
+
+func prependAlert(alerts []*models.Alert, alert *models.Alert, limit int) []*models.Alert {
+	if len(alerts) < limit {
+		alerts = append(alerts, nil)
+	}
+	copy(alerts[1:], alerts)
+	alerts[0] = alert
+	return alerts
+}
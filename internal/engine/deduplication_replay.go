@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"sort"
+
+	"alertbot/internal/models"
+)
+
+// replayGroup is an alert kept during replay and the alerts merged into it
+type replayGroup struct {
+	alert  *models.Alert
+	config DeduplicationConfig
+	group  models.DeduplicationReplayGroup
+}
+
+// Config returns the active deduplication config
+func (de *DeduplicationEngine) Config() DeduplicationConfig {
+	return de.currentConfig()
+}
+
+// Replay simulates deduplication of the given alerts in start order under
+// config, without touching stored alerts. Repeated fingerprints are treated as
+// re-sends of the same alert rather than duplicates, as on ingestion.
+func (de *DeduplicationEngine) Replay(alerts []*models.Alert, config DeduplicationConfig) models.DeduplicationReplay {
+	ordered := make([]*models.Alert, len(alerts))
+	copy(ordered, alerts)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].StartsAt.Before(ordered[j].StartsAt)
+	})
+
+	result := models.DeduplicationReplay{Groups: []models.DeduplicationReplayGroup{}}
+	seen := make(map[string]bool, len(ordered))
+	var kept []*replayGroup
+
+	for _, alert := range ordered {
+		if seen[alert.Fingerprint] {
+			continue
+		}
+		seen[alert.Fingerprint] = true
+
+		effective := config.ForAlert(alert)
+		key := de.generateDeduplicationKey(alert, effective)
+
+		if effective.EnableTimeBasedDedup || effective.EnableContentBasedDedup {
+			if existing := de.replayDuplicateOf(kept, alert, key, effective); existing != nil {
+				existing.group.Duplicates = append(existing.group.Duplicates, alert.Fingerprint)
+				result.Duplicates++
+				continue
+			}
+		}
+
+		alertName, _ := alert.Labels["alertname"].(string)
+		kept = append(kept, &replayGroup{
+			alert:  alert,
+			config: effective,
+			group: models.DeduplicationReplayGroup{
+				Fingerprint:      alert.Fingerprint,
+				AlertName:        alertName,
+				DeduplicationKey: key,
+				Override:         effective.AppliedOverride,
+			},
+		})
+	}
+
+	for _, g := range kept {
+		if len(g.group.Duplicates) > 0 {
+			result.Groups = append(result.Groups, g.group)
+		}
+	}
+	return result
+}
+
+// replayDuplicateOf mirrors findDuplicate against the alerts kept so far
+func (de *DeduplicationEngine) replayDuplicateOf(kept []*replayGroup, alert *models.Alert, key string, config DeduplicationConfig) *replayGroup {
+	windowStart := alert.StartsAt.Add(-config.DeduplicationWindow)
+	windowEnd := alert.StartsAt.Add(config.DeduplicationWindow)
+
+	for _, g := range kept {
+		existing := g.alert
+		if existing.Status != string(models.AlertStatusFiring) {
+			continue
+		}
+		if existing.StartsAt.Before(windowStart) || existing.StartsAt.After(windowEnd) {
+			continue
+		}
+		if de.generateDeduplicationKey(existing, config) == key {
+			return g
+		}
+		if config.EnableContentBasedDedup && de.areAlertsSimilar(alert, existing) {
+			return g
+		}
+	}
+	return nil
+}
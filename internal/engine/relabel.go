@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"alertbot/internal/relabel"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// relabelRefresh bounds how stale a replica's relabel configs may be after
+// another replica updated them
+const relabelRefresh = 30 * time.Second
+
+// RelabelEngine serves the stored relabel configs
+type RelabelEngine struct {
+	repo   *repository.Repositories
+	logger *logrus.Logger
+
+	mu       sync.RWMutex
+	rules    relabel.Rules
+	loadedAt time.Time
+}
+
+// NewRelabelEngine creates a relabel engine and loads the stored configs
+func NewRelabelEngine(repo *repository.Repositories, logger *logrus.Logger) *RelabelEngine {
+	re := &RelabelEngine{
+		repo:   repo,
+		logger: logger,
+	}
+	re.reload()
+	return re
+}
+
+// Current returns the active rules, reloading them from the settings table
+// when the local copy is older than relabelRefresh
+func (re *RelabelEngine) Current() relabel.Rules {
+	re.mu.RLock()
+	rules, loadedAt := re.rules, re.loadedAt
+	re.mu.RUnlock()
+
+	if time.Since(loadedAt) < relabelRefresh {
+		return rules
+	}
+	return re.reload()
+}
+
+// Set replaces the active rules after they were stored
+func (re *RelabelEngine) Set(rules relabel.Rules) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.rules = rules
+	re.loadedAt = time.Now()
+}
+
+// reload reads the stored configs; on failure the current rules are kept
+func (re *RelabelEngine) reload() relabel.Rules {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+
+	// Record the attempt even on failure so the database is not queried per alert
+	re.loadedAt = time.Now()
+
+	if re.repo == nil || re.repo.Settings == nil {
+		return re.rules
+	}
+
+	stored, err := re.repo.Settings.GetRelabelConfigs()
+	if err != nil {
+		re.logger.WithError(err).Warn("Failed to load relabel configs, keeping current")
+		return re.rules
+	}
+	rules, err := relabel.Compile(stored.Configs)
+	if err != nil {
+		re.logger.WithError(err).Error("Stored relabel configs are invalid, keeping current")
+		return re.rules
+	}
+
+	re.rules = rules
+	return rules
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"alertbot/internal/errors"
+	"alertbot/internal/matcher"
 	"alertbot/internal/models"
 	"alertbot/internal/recovery"
 	"alertbot/internal/repository"
@@ -212,6 +213,82 @@ func (re *RuleEngine) parseConditions(conditionsJSON models.JSONB) (*RuleConditi
 	return condition, nil
 }
 
+// ConditionsFromMatchers encodes label matchers in the conditions format.
+// Rule regexes are not anchored, so matcher regexes are anchored here to keep
+// their full-match semantics.
+func ConditionsFromMatchers(ms matcher.Matchers) models.JSONB {
+	items := make([]interface{}, 0, len(ms))
+	for _, m := range ms {
+		item := map[string]interface{}{
+			"name":     m.Name,
+			"value":    m.Value,
+			"is_regex": false,
+			"operator": "equals",
+		}
+		switch m.Type {
+		case matcher.MatchNotEqual:
+			item["operator"] = "not_equals"
+		case matcher.MatchRegexp:
+			item["is_regex"] = true
+			item["value"] = matcher.Anchor(m.Value)
+		case matcher.MatchNotRegexp:
+			item["is_regex"] = true
+			item["value"] = matcher.Anchor(m.Value)
+			item["operator"] = "not_equals"
+		}
+		items = append(items, item)
+	}
+	return models.JSONB{"matchers": items, "logic": "and"}
+}
+
+// MatchersFromConditions reverses ConditionsFromMatchers. It reports false
+// for conditions using operators or logic that label matchers cannot express.
+func MatchersFromConditions(conditions models.JSONB) (matcher.Matchers, bool) {
+	items, ok := conditions["matchers"].([]interface{})
+	if !ok || len(conditions) != 2 || conditions["logic"] != "and" {
+		return nil, false
+	}
+
+	ms := make(matcher.Matchers, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, _ := obj["name"].(string)
+		value, _ := obj["value"].(string)
+		isRegex, _ := obj["is_regex"].(bool)
+		operator, _ := obj["operator"].(string)
+
+		var t matcher.Type
+		switch {
+		case operator == "equals" && !isRegex:
+			t = matcher.MatchEqual
+		case operator == "not_equals" && !isRegex:
+			t = matcher.MatchNotEqual
+		case operator == "equals":
+			t = matcher.MatchRegexp
+		case operator == "not_equals":
+			t = matcher.MatchNotRegexp
+		default:
+			return nil, false
+		}
+		if isRegex {
+			if !strings.HasPrefix(value, "^(?:") || !strings.HasSuffix(value, ")$") {
+				return nil, false
+			}
+			value = value[len("^(?:") : len(value)-len(")$")]
+		}
+
+		m, err := matcher.New(t, name, value)
+		if err != nil {
+			return nil, false
+		}
+		ms = append(ms, m)
+	}
+	return ms, true
+}
+
 // evaluateConditions evaluates rule conditions against an alert
 func (re *RuleEngine) evaluateConditions(condition *RuleCondition, alert *models.Alert) (bool, error) {
 	if len(condition.Matchers) == 0 {
@@ -0,0 +1,355 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// topologyRefresh bounds how stale a replica's dependency graph may be after
+// another replica updated it
+const topologyRefresh = 30 * time.Second
+
+// activeAlertStatuses are the statuses an alert can be a root cause in
+var activeAlertStatuses = []string{
+	string(models.AlertStatusFiring),
+	string(models.AlertStatusAcknowledged),
+	string(models.AlertStatusSilenced),
+}
+
+type topologyNode struct {
+	name      string
+	matchers  matcher.Matchers
+	dependsOn []string
+}
+
+// Topology is a validated dependency graph with compiled node matchers
+type Topology struct {
+	nodes            map[string]*topologyNode
+	order            []string // Declaration order, for deterministic matching
+	SuppressSymptoms bool
+}
+
+// NewTopology validates a stored graph: node names must be unique,
+// dependencies must name known nodes and the graph must be acyclic
+func NewTopology(g *models.TopologyGraph) (*Topology, error) {
+	t := &Topology{
+		nodes:            make(map[string]*topologyNode, len(g.Nodes)),
+		SuppressSymptoms: g.SuppressSymptoms,
+	}
+
+	for i, n := range g.Nodes {
+		if n.Name == "" {
+			return nil, fmt.Errorf("node %d has no name", i)
+		}
+		if _, exists := t.nodes[n.Name]; exists {
+			return nil, fmt.Errorf("node %q is defined twice", n.Name)
+		}
+
+		expr := n.Matchers
+		if expr == "" {
+			expr = fmt.Sprintf("service=%q", n.Name)
+		}
+		ms, err := matcher.Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("node %q has invalid matchers: %w", n.Name, err)
+		}
+
+		t.nodes[n.Name] = &topologyNode{
+			name:      n.Name,
+			matchers:  ms,
+			dependsOn: n.DependsOn,
+		}
+		t.order = append(t.order, n.Name)
+	}
+
+	for _, name := range t.order {
+		for _, dep := range t.nodes[name].dependsOn {
+			if dep == name {
+				return nil, fmt.Errorf("node %q depends on itself", name)
+			}
+			if _, ok := t.nodes[dep]; !ok {
+				return nil, fmt.Errorf("node %q depends on unknown node %q", name, dep)
+			}
+		}
+	}
+
+	if cycle := t.findCycle(); cycle != nil {
+		return nil, fmt.Errorf("dependency cycle: %v", cycle)
+	}
+	return t, nil
+}
+
+// findCycle returns the nodes of a dependency cycle, or nil
+func (t *Topology) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(t.nodes))
+	var stack []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range t.nodes[name].dependsOn {
+			switch state[dep] {
+			case visiting:
+				for i, n := range stack {
+					if n == dep {
+						return append(append([]string{}, stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range t.order {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// Empty reports whether no graph is configured
+func (t *Topology) Empty() bool {
+	return t == nil || len(t.nodes) == 0
+}
+
+// NodesFor returns the nodes whose matchers select the alert
+func (t *Topology) NodesFor(alert *models.Alert) []string {
+	if t.Empty() {
+		return nil
+	}
+	var names []string
+	for _, name := range t.order {
+		if t.nodes[name].matchers.MatchesJSONB(alert.Labels) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// upstream walks dependencies breadth-first from the start nodes and returns
+// each reachable dependency's distance and its predecessor on a shortest path
+func (t *Topology) upstream(start []string) (map[string]int, map[string]string) {
+	dist := make(map[string]int)
+	prev := make(map[string]string)
+	visited := make(map[string]bool, len(start))
+	queue := make([]string, 0, len(start))
+	for _, name := range start {
+		visited[name] = true
+		queue = append(queue, name)
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, dep := range t.nodes[name].dependsOn {
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			dist[dep] = dist[name] + 1 // Start nodes are absent, i.e. 0
+			prev[dep] = name
+			queue = append(queue, dep)
+		}
+	}
+	return dist, prev
+}
+
+// DependsOn reports whether a node of alert depends, directly or
+// transitively, on a node of upstream
+func (t *Topology) DependsOn(alert, upstream *models.Alert) bool {
+	nodes := t.NodesFor(alert)
+	if len(nodes) == 0 {
+		return false
+	}
+	dist, _ := t.upstream(nodes)
+	for _, name := range t.NodesFor(upstream) {
+		if _, ok := dist[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Analyze finds the active alerts on nodes the alert depends on. The root
+// cause is the candidate with no active alerts further upstream; among
+// several, the most distant and then the earliest wins.
+func (t *Topology) Analyze(alert *models.Alert, active []models.Alert) *models.RootCauseAnalysis {
+	analysis := &models.RootCauseAnalysis{
+		Alert:      alert,
+		Nodes:      t.NodesFor(alert),
+		Candidates: []models.RootCauseCandidate{},
+	}
+	if len(analysis.Nodes) == 0 {
+		return analysis
+	}
+
+	dist, prev := t.upstream(analysis.Nodes)
+	activeNodes := make(map[string]bool)
+	for i := range active {
+		candidate := &active[i]
+		if candidate.Fingerprint == alert.Fingerprint {
+			continue
+		}
+
+		// Attribute the alert to its most upstream node
+		best, bestDist := "", 0
+		for _, name := range t.NodesFor(candidate) {
+			if d, ok := dist[name]; ok && d > bestDist {
+				best, bestDist = name, d
+			}
+		}
+		if best == "" {
+			continue
+		}
+		activeNodes[best] = true
+		analysis.Candidates = append(analysis.Candidates, models.RootCauseCandidate{
+			Alert:    candidate,
+			Node:     best,
+			Distance: bestDist,
+		})
+	}
+
+	sort.SliceStable(analysis.Candidates, func(i, j int) bool {
+		a, b := analysis.Candidates[i], analysis.Candidates[j]
+		if a.Distance != b.Distance {
+			return a.Distance > b.Distance
+		}
+		return a.Alert.StartsAt.Before(b.Alert.StartsAt)
+	})
+
+	for i := range analysis.Candidates {
+		c := &analysis.Candidates[i]
+		if t.hasActiveUpstream(c.Node, activeNodes) {
+			continue
+		}
+		analysis.RootCause = c
+		analysis.IsSymptom = true
+		for name := c.Node; name != ""; name = prev[name] {
+			analysis.Path = append([]string{name}, analysis.Path...)
+		}
+		break
+	}
+	return analysis
+}
+
+// hasActiveUpstream reports whether any dependency of node has an active alert
+func (t *Topology) hasActiveUpstream(node string, activeNodes map[string]bool) bool {
+	dist, _ := t.upstream([]string{node})
+	for name := range dist {
+		if activeNodes[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// TopologyEngine serves the stored dependency graph and root cause analysis
+type TopologyEngine struct {
+	repo   *repository.Repositories
+	logger *logrus.Logger
+
+	mu       sync.RWMutex
+	topology *Topology
+	loadedAt time.Time
+}
+
+// NewTopologyEngine creates a topology engine and loads the stored graph
+func NewTopologyEngine(repo *repository.Repositories, logger *logrus.Logger) *TopologyEngine {
+	te := &TopologyEngine{
+		repo:     repo,
+		logger:   logger,
+		topology: &Topology{SuppressSymptoms: true},
+	}
+	te.reload()
+	return te
+}
+
+// Current returns the active graph, reloading it from the settings table
+// when the local copy is older than topologyRefresh
+func (te *TopologyEngine) Current() *Topology {
+	te.mu.RLock()
+	topology, loadedAt := te.topology, te.loadedAt
+	te.mu.RUnlock()
+
+	if time.Since(loadedAt) < topologyRefresh {
+		return topology
+	}
+	return te.reload()
+}
+
+// Set replaces the active graph after it was stored
+func (te *TopologyEngine) Set(t *Topology) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.topology = t
+	te.loadedAt = time.Now()
+}
+
+// reload reads the stored graph; on failure the current one is kept
+func (te *TopologyEngine) reload() *Topology {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	// Record the attempt even on failure so the database is not queried per alert
+	te.loadedAt = time.Now()
+
+	if te.repo == nil || te.repo.Settings == nil {
+		return te.topology
+	}
+
+	stored, err := te.repo.Settings.GetTopologyGraph()
+	if err != nil {
+		te.logger.WithError(err).Warn("Failed to load topology graph, keeping current")
+		return te.topology
+	}
+	topology, err := NewTopology(stored)
+	if err != nil {
+		te.logger.WithError(err).Error("Stored topology graph is invalid, keeping current")
+		return te.topology
+	}
+
+	te.topology = topology
+	return topology
+}
+
+// Analyze explains the alert against the currently active alerts
+func (te *TopologyEngine) Analyze(ctx context.Context, alert *models.Alert) (*models.RootCauseAnalysis, error) {
+	topology := te.Current()
+	if topology.Empty() {
+		return topology.Analyze(alert, nil), nil
+	}
+
+	var active []models.Alert
+	for _, status := range activeAlertStatuses {
+		alerts, err := te.repo.Alert.ListByStatus(status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s alerts: %w", status, err)
+		}
+		active = append(active, alerts...)
+	}
+	return topology.Analyze(alert, active), nil
+}
@@ -0,0 +1,200 @@
+// Package enrichment adds context the alert source does not know about,
+// such as owner team, runbook or dashboard links, to received alerts. Rules
+// select alerts by label matchers and look up a record for them in a static
+// table or an HTTP service such as a CMDB.
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"alertbot/internal/config"
+	"alertbot/internal/matcher"
+	"alertbot/internal/metrics"
+	"alertbot/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Record is what a source knows about an alert. Fields are read by name;
+// a dotted name such as owner.team reads nested objects.
+type Record map[string]interface{}
+
+// Field returns the named field as a string
+func (r Record) Field(name string) (string, bool) {
+	value, ok := r[name]
+	if !ok {
+		var current interface{} = r
+		for _, part := range strings.Split(name, ".") {
+			object, isObject := asObject(current)
+			if !isObject {
+				return "", false
+			}
+			if current, ok = object[part]; !ok {
+				return "", false
+			}
+		}
+		value = current
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, v != ""
+	case map[string]interface{}, Record, []interface{}:
+		return "", false
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// asObject returns a nested object of a record. YAML tables decode nested
+// objects as Record, JSON responses as plain maps.
+func asObject(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case Record:
+		return v, true
+	}
+	return nil, false
+}
+
+// Source looks up the record for an alert's labels. A nil record without
+// an error means the source has nothing for the alert.
+type Source interface {
+	Lookup(ctx context.Context, labels map[string]string) (Record, error)
+}
+
+type rule struct {
+	name        string
+	matchers    matcher.Matchers
+	source      Source
+	labels      map[string]string
+	annotations map[string]string
+	overwrite   bool
+}
+
+// Enricher applies enrichment rules to alerts. It is safe for concurrent use.
+type Enricher struct {
+	rules  []*rule
+	logger *logrus.Logger
+}
+
+// New compiles the configured rules. Invalid rules are logged and skipped,
+// so one bad table does not stop the others.
+func New(rules []config.EnrichmentRule, logger *logrus.Logger) *Enricher {
+	e := &Enricher{logger: logger}
+	for i, cfg := range rules {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		r, err := compileRule(cfg)
+		if err != nil {
+			logger.WithError(err).WithField("rule", cfg.Name).Error("Skipping invalid enrichment rule")
+			continue
+		}
+		e.rules = append(e.rules, r)
+	}
+
+	if len(e.rules) > 0 {
+		logger.WithField("rules", len(e.rules)).Info("Alert enrichment configured")
+	}
+	return e
+}
+
+func compileRule(cfg config.EnrichmentRule) (*rule, error) {
+	r := &rule{
+		name:        cfg.Name,
+		labels:      cfg.Labels,
+		annotations: cfg.Annotations,
+		overwrite:   cfg.Overwrite,
+	}
+	if len(r.labels) == 0 && len(r.annotations) == 0 {
+		return nil, errors.New("rule sets no labels or annotations")
+	}
+
+	if strings.TrimSpace(cfg.Match) != "" {
+		matchers, err := matcher.Parse(cfg.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match: %w", err)
+		}
+		r.matchers = matchers
+	}
+
+	var err error
+	switch {
+	case cfg.Table != "" && cfg.URL != "":
+		return nil, errors.New("rule has both a table and a url")
+	case cfg.Table != "":
+		r.source, err = LoadTable(cfg.Table, cfg.KeyLabels)
+	case cfg.URL != "":
+		r.source, err = NewHTTPSource(cfg)
+	default:
+		return nil, errors.New("rule has neither a table nor a url")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Enabled reports whether any rule is configured
+func (e *Enricher) Enabled() bool {
+	return e != nil && len(e.rules) > 0
+}
+
+// Enrich applies every matching rule to the alert in order. A failed
+// lookup skips its rule; the errors are returned together.
+func (e *Enricher) Enrich(ctx context.Context, alert *models.Alert) error {
+	if !e.Enabled() {
+		return nil
+	}
+
+	var errs []error
+	for _, r := range e.rules {
+		labels := matcher.LabelSet(alert.Labels)
+		if r.matchers != nil && !r.matchers.Matches(labels) {
+			continue
+		}
+
+		record, err := r.source.Lookup(ctx, labels)
+		if err != nil {
+			metrics.RecordEnrichmentLookup(r.name, "error")
+			errs = append(errs, fmt.Errorf("enrichment rule %s: %w", r.name, err))
+			continue
+		}
+		if record == nil {
+			metrics.RecordEnrichmentLookup(r.name, "miss")
+			continue
+		}
+
+		metrics.RecordEnrichmentLookup(r.name, "hit")
+		if alert.Labels == nil {
+			alert.Labels = models.JSONB{}
+		}
+		if alert.Annotations == nil {
+			alert.Annotations = models.JSONB{}
+		}
+		r.apply(record, alert.Labels, r.labels)
+		r.apply(record, alert.Annotations, r.annotations)
+	}
+	return errors.Join(errs...)
+}
+
+// apply copies record fields into target under their mapped names
+func (r *rule) apply(record Record, target models.JSONB, fields map[string]string) {
+	for name, field := range fields {
+		value, ok := record.Field(field)
+		if !ok {
+			continue
+		}
+		if existing, set := target[name].(string); set && existing != "" && !r.overwrite {
+			continue
+		}
+		target[name] = value
+	}
+}
@@ -0,0 +1,205 @@
+package enrichment
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEnricher(rules []config.EnrichmentRule) *Enricher {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return New(rules, logger)
+}
+
+func TestRecordField(t *testing.T) {
+	record := Record{
+		"team":      "payments",
+		"tier":      1,
+		"empty":     "",
+		"missing":   nil,
+		"owner":     map[string]interface{}{"oncall": "payments-primary", "contact": map[string]interface{}{"slack": "#payments"}},
+		"owner.raw": "literal",
+		"service":   Record{"tier": "gold"},
+		"tags":      []interface{}{"a"},
+	}
+
+	tests := []struct {
+		field string
+		want  string
+		ok    bool
+	}{
+		{"team", "payments", true},
+		{"tier", "1", true},
+		{"empty", "", false},
+		{"missing", "", false},
+		{"unknown", "", false},
+		{"owner.oncall", "payments-primary", true},
+		{"owner.contact.slack", "#payments", true},
+		{"owner.raw", "literal", true},
+		{"service.tier", "gold", true},
+		{"service", "", false},
+		{"owner", "", false},
+		{"tags", "", false},
+		{"team.name", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			value, ok := record.Field(tt.field)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	table := writeTable(t, "services.yaml", servicesYAML)
+	runbooks := writeTable(t, "runbooks.csv", "alertname,runbook\nDiskFull,https://runbooks/disk\n")
+
+	enricher := newTestEnricher([]config.EnrichmentRule{
+		{
+			Name:        "services",
+			Match:       `env=~"prod|staging"`,
+			Table:       table,
+			KeyLabels:   []string{"service", "env"},
+			Labels:      map[string]string{"team": "team", "tier": "tier"},
+			Annotations: map[string]string{"oncall": "owner.oncall"},
+		},
+		{
+			Name:        "runbooks",
+			Table:       runbooks,
+			KeyLabels:   []string{"alertname"},
+			Annotations: map[string]string{"runbook_url": "runbook"},
+			Overwrite:   true,
+		},
+	})
+	require.True(t, enricher.Enabled())
+
+	tests := []struct {
+		name            string
+		labels          models.JSONB
+		annotations     models.JSONB
+		wantLabels      models.JSONB
+		wantAnnotations models.JSONB
+	}{
+		{
+			name:            "matching rules apply in order",
+			labels:          models.JSONB{"alertname": "DiskFull", "service": "checkout", "env": "prod"},
+			wantLabels:      models.JSONB{"alertname": "DiskFull", "service": "checkout", "env": "prod", "team": "payments", "tier": "1"},
+			wantAnnotations: models.JSONB{"oncall": "payments-primary", "runbook_url": "https://runbooks/disk"},
+		},
+		{
+			name:            "matchers select rules",
+			labels:          models.JSONB{"alertname": "DiskFull", "service": "checkout", "env": "dev"},
+			wantLabels:      models.JSONB{"alertname": "DiskFull", "service": "checkout", "env": "dev"},
+			wantAnnotations: models.JSONB{"runbook_url": "https://runbooks/disk"},
+		},
+		{
+			name:            "existing values are kept",
+			labels:          models.JSONB{"alertname": "HighLatency", "service": "checkout", "env": "prod", "team": "sre"},
+			annotations:     models.JSONB{"oncall": "sre-primary"},
+			wantLabels:      models.JSONB{"alertname": "HighLatency", "service": "checkout", "env": "prod", "team": "sre", "tier": "1"},
+			wantAnnotations: models.JSONB{"oncall": "sre-primary"},
+		},
+		{
+			name:            "overwrite replaces existing values",
+			labels:          models.JSONB{"alertname": "DiskFull"},
+			annotations:     models.JSONB{"runbook_url": "https://old"},
+			wantLabels:      models.JSONB{"alertname": "DiskFull"},
+			wantAnnotations: models.JSONB{"runbook_url": "https://runbooks/disk"},
+		},
+		{
+			name:            "fields missing from the record are skipped",
+			labels:          models.JSONB{"service": "search", "env": "prod"},
+			wantLabels:      models.JSONB{"service": "search", "env": "prod", "team": "discovery"},
+			wantAnnotations: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := &models.Alert{Labels: tt.labels, Annotations: tt.annotations}
+			require.NoError(t, enricher.Enrich(context.Background(), alert))
+			assert.Equal(t, tt.wantLabels, alert.Labels)
+			if tt.wantAnnotations == nil {
+				assert.Empty(t, alert.Annotations)
+			} else {
+				assert.Equal(t, tt.wantAnnotations, alert.Annotations)
+			}
+		})
+	}
+}
+
+func TestEnrichLookupTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	enricher := newTestEnricher([]config.EnrichmentRule{
+		{
+			Name:    "cmdb",
+			URL:     server.URL + "/services/{service}",
+			Timeout: 20,
+			Labels:  map[string]string{"team": "team"},
+		},
+		{
+			Name:        "runbooks",
+			Table:       writeTable(t, "runbooks.csv", "alertname,runbook\nDiskFull,https://runbooks/disk\n"),
+			KeyLabels:   []string{"alertname"},
+			Annotations: map[string]string{"runbook_url": "runbook"},
+		},
+	})
+
+	// The timed out rule is skipped and reported; the others still apply
+	alert := &models.Alert{Labels: models.JSONB{"alertname": "DiskFull", "service": "checkout"}}
+	start := time.Now()
+	err := enricher.Enrich(context.Background(), alert)
+	assert.Less(t, time.Since(start), time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "enrichment rule cmdb")
+	assert.Equal(t, models.JSONB{"alertname": "DiskFull", "service": "checkout"}, alert.Labels)
+	assert.Equal(t, models.JSONB{"runbook_url": "https://runbooks/disk"}, alert.Annotations)
+}
+
+func TestNewSkipsInvalidRules(t *testing.T) {
+	table := writeTable(t, "runbooks.csv", "alertname,runbook\nDiskFull,https://runbooks/disk\n")
+
+	tests := []struct {
+		name string
+		rule config.EnrichmentRule
+	}{
+		{"nothing to set", config.EnrichmentRule{Table: table, KeyLabels: []string{"alertname"}}},
+		{"invalid match", config.EnrichmentRule{Match: `team~"db"`, Table: table, KeyLabels: []string{"alertname"}, Labels: map[string]string{"runbook": "runbook"}}},
+		{"no source", config.EnrichmentRule{Labels: map[string]string{"team": "team"}}},
+		{"two sources", config.EnrichmentRule{Table: table, KeyLabels: []string{"alertname"}, URL: "http://cmdb/{service}", Labels: map[string]string{"team": "team"}}},
+		{"missing table", config.EnrichmentRule{Table: table + ".missing", KeyLabels: []string{"alertname"}, Labels: map[string]string{"team": "team"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enricher := newTestEnricher([]config.EnrichmentRule{tt.rule})
+			assert.False(t, enricher.Enabled())
+			assert.NoError(t, enricher.Enrich(context.Background(), &models.Alert{Labels: models.JSONB{"alertname": "DiskFull"}}))
+		})
+	}
+
+	var none *Enricher
+	assert.False(t, none.Enabled())
+}
@@ -0,0 +1,139 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"alertbot/internal/config"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// DefaultLookupTimeout bounds an HTTP lookup
+	DefaultLookupTimeout = 2 * time.Second
+	// DefaultLookupCacheTTL is how long HTTP lookup results are reused
+	DefaultLookupCacheTTL = 5 * time.Minute
+
+	// maxLookupResponse caps the response body read from a lookup service
+	maxLookupResponse = 1 << 20
+)
+
+// urlPlaceholder matches {label} in a lookup URL
+var urlPlaceholder = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// HTTPSource looks records up in an HTTP service such as a CMDB. The URL's
+// {label} placeholders are filled with the alert's label values; the
+// service answers with a JSON object, or 404 when it has no record.
+type HTTPSource struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	cache   *cache.Cache
+}
+
+// cachedRecord stores a lookup result; Record is nil for a miss
+type cachedRecord struct {
+	Record Record
+}
+
+func NewHTTPSource(cfg config.EnrichmentRule) (*HTTPSource, error) {
+	if _, err := url.Parse(urlPlaceholder.ReplaceAllString(cfg.URL, "x")); err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = DefaultLookupTimeout
+	}
+	ttl := time.Duration(cfg.CacheTTL) * time.Second
+	if ttl <= 0 {
+		ttl = DefaultLookupCacheTTL
+	}
+
+	return &HTTPSource{
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: timeout},
+		cache:   cache.New(ttl, 2*ttl),
+	}, nil
+}
+
+// Lookup fetches the record for the alert, or returns the cached one.
+// Alerts missing a label the URL needs have no record. Failed lookups are
+// not cached, so the next alert tries again.
+func (s *HTTPSource) Lookup(ctx context.Context, labels map[string]string) (Record, error) {
+	target, ok := s.resolve(labels)
+	if !ok {
+		return nil, nil
+	}
+
+	if cached, found := s.cache.Get(target); found {
+		return cached.(cachedRecord).Record, nil
+	}
+
+	record, err := s.fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.SetDefault(target, cachedRecord{Record: record})
+	return record, nil
+}
+
+// resolve fills the URL placeholders with escaped label values
+func (s *HTTPSource) resolve(labels map[string]string) (string, bool) {
+	complete := true
+	target := urlPlaceholder.ReplaceAllStringFunc(s.url, func(placeholder string) string {
+		value, ok := labels[placeholder[1:len(placeholder)-1]]
+		if !ok || value == "" {
+			complete = false
+		}
+		return url.PathEscape(value)
+	})
+	return target, complete
+}
+
+func (s *HTTPSource) fetch(ctx context.Context, target string) (Record, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("lookup returned HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxLookupResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lookup response: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(body, &record); err != nil {
+		return nil, fmt.Errorf("lookup response is not a JSON object: %w", err)
+	}
+	if record == nil {
+		return nil, errors.New("lookup response is null")
+	}
+	return record, nil
+}
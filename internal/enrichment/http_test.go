@@ -0,0 +1,156 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLookupServer serves records for /services/{service}, counting requests
+func newLookupServer(t *testing.T, records map[string]string) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := records[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPSourceLookup(t *testing.T) {
+	server, _ := newLookupServer(t, map[string]string{
+		"/services/checkout":   `{"team": "payments", "owner": {"oncall": "payments-primary"}}`,
+		"/services/a b":        `{"team": "spaces"}`,
+		"/services/null":       `null`,
+		"/services/not-object": `["payments"]`,
+	})
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   Record
+		err    bool
+	}{
+		{"found", map[string]string{"service": "checkout"}, Record{"team": "payments", "owner": map[string]interface{}{"oncall": "payments-primary"}}, false},
+		{"escaped label value", map[string]string{"service": "a b"}, Record{"team": "spaces"}, false},
+		{"not found", map[string]string{"service": "search"}, nil, false},
+		{"missing label", map[string]string{"env": "prod"}, nil, false},
+		{"empty label", map[string]string{"service": ""}, nil, false},
+		{"null response", map[string]string{"service": "null"}, nil, true},
+		{"not an object", map[string]string{"service": "not-object"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewHTTPSource(config.EnrichmentRule{
+				URL:     server.URL + "/services/{service}",
+				Headers: map[string]string{"Authorization": "Bearer token"},
+			})
+			require.NoError(t, err)
+
+			record, err := source.Lookup(context.Background(), tt.labels)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, record)
+		})
+	}
+}
+
+func TestHTTPSourceCache(t *testing.T) {
+	server, requests := newLookupServer(t, map[string]string{
+		"/services/checkout": `{"team": "payments"}`,
+	})
+	source, err := NewHTTPSource(config.EnrichmentRule{
+		URL:     server.URL + "/services/{service}",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	require.NoError(t, err)
+	source.cache = cache.New(50*time.Millisecond, time.Minute)
+	ctx := context.Background()
+
+	// Hits and misses are both cached per resolved URL
+	for i := 0; i < 3; i++ {
+		record, err := source.Lookup(ctx, map[string]string{"service": "checkout"})
+		require.NoError(t, err)
+		assert.Equal(t, Record{"team": "payments"}, record)
+
+		record, err = source.Lookup(ctx, map[string]string{"service": "search"})
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	}
+	assert.EqualValues(t, 2, requests.Load())
+
+	// Expired entries are fetched again
+	time.Sleep(100 * time.Millisecond)
+	_, err = source.Lookup(ctx, map[string]string{"service": "checkout"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, requests.Load())
+}
+
+func TestHTTPSourceErrorsAreNotCached(t *testing.T) {
+	server, requests := newLookupServer(t, nil)
+	source, err := NewHTTPSource(config.EnrichmentRule{URL: server.URL + "/services/{service}"})
+	require.NoError(t, err)
+
+	// Without the header the service answers 401
+	for i := 0; i < 2; i++ {
+		_, err := source.Lookup(context.Background(), map[string]string{"service": "checkout"})
+		assert.Error(t, err)
+	}
+	assert.EqualValues(t, 2, requests.Load())
+}
+
+func TestHTTPSourceTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	source, err := NewHTTPSource(config.EnrichmentRule{URL: server.URL + "/services/{service}", Timeout: 20})
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = source.Lookup(context.Background(), map[string]string{"service": "checkout"})
+	require.Error(t, err)
+	var urlErr *url.Error
+	require.True(t, errors.As(err, &urlErr))
+	assert.True(t, urlErr.Timeout())
+	assert.Less(t, time.Since(start), time.Second)
+
+	// The caller's context bounds the lookup as well
+	source, err = NewHTTPSource(config.EnrichmentRule{URL: server.URL + "/services/{service}"})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = source.Lookup(ctx, map[string]string{"service": "checkout"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,112 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Table is a static lookup table loaded from a file. Each row is a record;
+// the columns named after the key labels select it.
+type Table struct {
+	keyLabels []string
+	rows      map[string]Record
+}
+
+// LoadTable reads a CSV file with a header row, or a YAML list of objects,
+// depending on the file extension
+func LoadTable(path string, keyLabels []string) (*Table, error) {
+	if len(keyLabels) == 0 {
+		return nil, errors.New("table lookup needs key_labels")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table: %w", err)
+	}
+
+	var records []Record
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		records, err = parseCSV(data)
+	case ".yaml", ".yml":
+		records, err = parseYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported table format %q, use .csv, .yaml or .yml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse table %s: %w", path, err)
+	}
+
+	t := &Table{keyLabels: keyLabels, rows: make(map[string]Record, len(records))}
+	for i, record := range records {
+		values := make([]string, len(keyLabels))
+		for j, label := range keyLabels {
+			value, ok := record[label]
+			if !ok {
+				return nil, fmt.Errorf("row %d of %s has no %q column", i+1, path, label)
+			}
+			values[j] = fmt.Sprint(value)
+		}
+		// The first row for a key wins
+		if key := tableKey(values); t.rows[key] == nil {
+			t.rows[key] = record
+		}
+	}
+	return t, nil
+}
+
+// Lookup returns the row for the alert's key label values
+func (t *Table) Lookup(ctx context.Context, labels map[string]string) (Record, error) {
+	values := make([]string, len(t.keyLabels))
+	for i, label := range t.keyLabels {
+		value, ok := labels[label]
+		if !ok {
+			return nil, nil
+		}
+		values[i] = value
+	}
+	return t.rows[tableKey(values)], nil
+}
+
+func tableKey(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+func parseCSV(data []byte) ([]Record, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	records := make([]Record, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(Record, len(header))
+		for i, column := range header {
+			record[strings.TrimSpace(column)] = strings.TrimSpace(row[i])
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func parseYAML(data []byte) ([]Record, error) {
+	var records []Record
+	if err := yaml.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
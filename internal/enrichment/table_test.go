@@ -0,0 +1,125 @@
+package enrichment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTable writes a table file into a temporary directory
+func writeTable(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+const servicesCSV = `service, env, team, tier
+checkout, prod, payments, 1
+checkout, staging, payments, 3
+search, prod, discovery, 2
+checkout, prod, duplicate, 9
+`
+
+const servicesYAML = `
+- service: checkout
+  env: prod
+  team: payments
+  tier: 1
+  owner:
+    oncall: payments-primary
+- service: search
+  env: prod
+  team: discovery
+`
+
+func TestLoadTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		data   string
+		labels map[string]string
+		want   Record
+	}{
+		{
+			name:   "csv",
+			file:   "services.csv",
+			data:   servicesCSV,
+			labels: map[string]string{"service": "search", "env": "prod"},
+			want:   Record{"service": "search", "env": "prod", "team": "discovery", "tier": "2"},
+		},
+		{
+			name:   "csv first row wins",
+			file:   "services.csv",
+			data:   servicesCSV,
+			labels: map[string]string{"service": "checkout", "env": "prod", "instance": "web-1"},
+			want:   Record{"service": "checkout", "env": "prod", "team": "payments", "tier": "1"},
+		},
+		{
+			name:   "csv miss",
+			file:   "services.csv",
+			data:   servicesCSV,
+			labels: map[string]string{"service": "search", "env": "staging"},
+		},
+		{
+			name:   "missing key label",
+			file:   "services.csv",
+			data:   servicesCSV,
+			labels: map[string]string{"service": "search"},
+		},
+		{
+			name:   "yaml",
+			file:   "services.yaml",
+			data:   servicesYAML,
+			labels: map[string]string{"service": "checkout", "env": "prod"},
+			want: Record{"service": "checkout", "env": "prod", "team": "payments", "tier": 1,
+				"owner": Record{"oncall": "payments-primary"}},
+		},
+		{
+			name:   "yml extension",
+			file:   "services.yml",
+			data:   servicesYAML,
+			labels: map[string]string{"service": "search", "env": "prod"},
+			want:   Record{"service": "search", "env": "prod", "team": "discovery"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := LoadTable(writeTable(t, tt.file, tt.data), []string{"service", "env"})
+			require.NoError(t, err)
+
+			record, err := table.Lookup(context.Background(), tt.labels)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, record)
+		})
+	}
+}
+
+func TestLoadTableErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		data      string
+		keyLabels []string
+	}{
+		{"no key labels", "services.csv", servicesCSV, nil},
+		{"unsupported format", "services.json", `[]`, []string{"service"}},
+		{"missing key column", "services.csv", servicesCSV, []string{"service", "region"}},
+		{"ragged csv", "services.csv", "service,team\ncheckout\n", []string{"service"}},
+		{"yaml not a list", "services.yaml", "service: checkout\n", []string{"service"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTable(writeTable(t, tt.file, tt.data), tt.keyLabels)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadTable(filepath.Join(t.TempDir(), "missing.csv"), []string{"service"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package events
+
+import "encoding/json"
+
+// Broker publishes events to the Bus of every replica. Streaming clients
+// only listen to their own replica's Bus, so events must go through the
+// broker to reach clients connected elsewhere.
+type Broker interface {
+	// Publish delivers the event to the local bus and, for a distributed
+	// broker, to the other replicas. An error means only local clients
+	// received it.
+	Publish(eventType string, data interface{}) error
+
+	// Close stops receiving events from other replicas
+	Close() error
+}
+
+// Decoder rebuilds the data of an event received from another replica,
+// so listeners see the same types a local publisher passed in
+type Decoder func(eventType string, data json.RawMessage) (interface{}, error)
+
+// decodeAny is the Decoder used when none is given
+func decodeAny(_ string, data json.RawMessage) (interface{}, error) {
+	var value interface{}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// LocalBroker publishes to the local bus only, for single-replica setups
+type LocalBroker struct {
+	bus *Bus
+}
+
+// NewLocalBroker creates an in-process broker for bus
+func NewLocalBroker(bus *Bus) *LocalBroker {
+	return &LocalBroker{bus: bus}
+}
+
+func (b *LocalBroker) Publish(eventType string, data interface{}) error {
+	b.bus.Publish(eventType, data)
+	return nil
+}
+
+func (b *LocalBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,220 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event is a single message published to streaming clients. Seq is only
+// meaningful within the bus Epoch that assigned it.
+type Event struct {
+	Epoch     string      `json:"epoch"`
+	Seq       uint64      `json:"seq"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// ID returns the resume position of the event as "<epoch>-<seq>"
+func (e *Event) ID() string {
+	return FormatEventID(e.Epoch, e.Seq)
+}
+
+// FormatEventID formats a resume position as "<epoch>-<seq>"
+func FormatEventID(epoch string, seq uint64) string {
+	return epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// ParseEventID parses a resume position formatted by FormatEventID. A bare
+// sequence number, as sent by older clients, yields an empty epoch.
+func ParseEventID(id string) (string, uint64, error) {
+	epoch, seq := "", id
+	if i := strings.LastIndex(id, "-"); i >= 0 {
+		epoch, seq = id[:i], id[i+1:]
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid event id %q", id)
+	}
+	return epoch, n, nil
+}
+
+// Listener receives events published after it subscribed
+type Listener struct {
+	C <-chan *Event
+
+	ch       chan *Event
+	bus      *Bus
+	dropSlow bool
+	closed   bool
+}
+
+// Close unsubscribes the listener
+func (l *Listener) Close() {
+	l.bus.unsubscribe(l)
+}
+
+// Bus fans published events out to listeners and keeps a bounded history
+// so reconnecting clients can resume from a sequence number. Each bus has a
+// random epoch, so positions from a previous process or another replica are
+// recognised instead of being mistaken for local ones.
+type Bus struct {
+	logger *logrus.Logger
+	epoch  string
+
+	mutex       sync.RWMutex
+	seq         uint64
+	history     []*Event
+	historySize int
+	listeners   map[*Listener]struct{}
+}
+
+// DefaultHistorySize is the number of events retained for resume
+const DefaultHistorySize = 1000
+
+// NewBus creates an in-process event bus
+func NewBus(logger *logrus.Logger, historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Bus{
+		logger:      logger,
+		epoch:       newEpoch(),
+		historySize: historySize,
+		listeners:   make(map[*Listener]struct{}),
+	}
+}
+
+// Publish assigns the next sequence number and delivers the event to all listeners.
+// Delivery never blocks: listeners created with dropSlow are closed when their
+// buffer is full, others miss the event.
+func (b *Bus) Publish(eventType string, data interface{}) *Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.seq++
+	event := &Event{
+		Epoch:     b.epoch,
+		Seq:       b.seq,
+		Type:      eventType,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for listener := range b.listeners {
+		select {
+		case listener.ch <- event:
+		default:
+			if listener.dropSlow {
+				b.closeListener(listener)
+			} else {
+				b.logger.WithField("event_type", eventType).Warn("Event listener buffer is full, dropping event")
+			}
+		}
+	}
+
+	return event
+}
+
+// Subscribe registers a listener with the given buffer size. When dropSlow is
+// set the listener is closed instead of silently missing events, so the
+// consumer can reconnect and resume.
+func (b *Bus) Subscribe(buffer int, dropSlow bool) *Listener {
+	ch := make(chan *Event, buffer)
+	listener := &Listener{
+		C:        ch,
+		ch:       ch,
+		bus:      b,
+		dropSlow: dropSlow,
+	}
+
+	b.mutex.Lock()
+	b.listeners[listener] = struct{}{}
+	b.mutex.Unlock()
+
+	return listener
+}
+
+// Epoch returns the identifier of this bus instance
+func (b *Bus) Epoch() string {
+	return b.epoch
+}
+
+// CurrentSeq returns the sequence number of the last published event
+func (b *Bus) CurrentSeq() uint64 {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.seq
+}
+
+// Since returns retained events of the given epoch with a sequence number
+// greater than seq. The boolean is false when the position cannot be
+// resumed from: it belongs to another epoch, lies ahead of this bus, or
+// some of the events after it were already evicted.
+func (b *Bus) Since(epoch string, seq uint64) ([]*Event, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if epoch != b.epoch || seq > b.seq {
+		return nil, false
+	}
+	if seq == b.seq {
+		return nil, true
+	}
+	if len(b.history) == 0 || b.history[0].Seq > seq+1 {
+		return nil, false
+	}
+
+	idx := sort.Search(len(b.history), func(i int) bool {
+		return b.history[i].Seq > seq
+	})
+
+	events := make([]*Event, len(b.history)-idx)
+	copy(events, b.history[idx:])
+	return events, true
+}
+
+// ListenerCount returns the number of active listeners
+func (b *Bus) ListenerCount() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.listeners)
+}
+
+func (b *Bus) unsubscribe(listener *Listener) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.closeListener(listener)
+}
+
+// closeListener removes and closes a listener; callers must hold the mutex
+func (b *Bus) closeListener(listener *Listener) {
+	if listener.closed {
+		return
+	}
+	listener.closed = true
+	delete(b.listeners, listener)
+	close(listener.ch)
+}
+
+// newEpoch returns a random bus identifier
+func newEpoch() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBus(historySize int) *Bus {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewBus(logger, historySize)
+}
+
+func publishN(bus *Bus, n int) *Event {
+	var last *Event
+	for i := 0; i < n; i++ {
+		last = bus.Publish("alert_updated", i)
+	}
+	return last
+}
+
+func TestBusSince(t *testing.T) {
+	bus := newTestBus(3)
+	publishN(bus, 5)
+	epoch := bus.Epoch()
+
+	tests := []struct {
+		name  string
+		epoch string
+		seq   uint64
+		want  []uint64
+		ok    bool
+	}{
+		{"retained", epoch, 3, []uint64{4, 5}, true},
+		{"oldest retained", epoch, 2, []uint64{3, 4, 5}, true},
+		{"up to date", epoch, 5, nil, true},
+		{"evicted", epoch, 1, nil, false},
+		{"ahead of the bus", epoch, 6, nil, false},
+		{"other epoch", "0123456789abcdef", 3, nil, false},
+		{"no epoch", "", 3, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, ok := bus.Since(tt.epoch, tt.seq)
+			assert.Equal(t, tt.ok, ok)
+			var seqs []uint64
+			for _, event := range events {
+				assert.Equal(t, epoch, event.Epoch)
+				seqs = append(seqs, event.Seq)
+			}
+			assert.Equal(t, tt.want, seqs)
+		})
+	}
+}
+
+func TestBusResumeAfterRecreate(t *testing.T) {
+	before := newTestBus(10)
+	last := publishN(before, 5)
+
+	// A restarted process numbers events from 1 again; the old position
+	// must not be taken for one of them
+	after := newTestBus(10)
+	publishN(after, 8)
+	require.NotEqual(t, before.Epoch(), after.Epoch())
+
+	epoch, seq, err := ParseEventID(last.ID())
+	require.NoError(t, err)
+	events, ok := after.Since(epoch, seq)
+	assert.False(t, ok)
+	assert.Empty(t, events)
+
+	// Positions from the new bus resume normally
+	events, ok = after.Since(after.Epoch(), 6)
+	assert.True(t, ok)
+	assert.Len(t, events, 2)
+}
+
+func TestBusResumeOnOtherReplica(t *testing.T) {
+	local, remote := newTestBus(10), newTestBus(10)
+
+	// The remote replica publishes an event the local one also delivers,
+	// under its own numbering
+	publishN(local, 3)
+	sent := remote.Publish("alert_created", "a")
+	received := local.Publish(sent.Type, sent.Data)
+	assert.NotEqual(t, sent.ID(), received.ID())
+
+	epoch, seq, err := ParseEventID(sent.ID())
+	require.NoError(t, err)
+	_, ok := local.Since(epoch, seq)
+	assert.False(t, ok)
+}
+
+func TestParseEventID(t *testing.T) {
+	tests := []struct {
+		id    string
+		epoch string
+		seq   uint64
+		err   bool
+	}{
+		{"0123456789abcdef-42", "0123456789abcdef", 42, false},
+		{"42", "", 42, false},
+		{"epoch-", "", 0, true},
+		{"epoch-x", "", 0, true},
+		{"", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			epoch, seq, err := ParseEventID(tt.id)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.epoch, epoch)
+			assert.Equal(t, tt.seq, seq)
+			if epoch != "" {
+				assert.Equal(t, tt.id, FormatEventID(epoch, seq))
+			}
+		})
+	}
+}
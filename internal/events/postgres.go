@@ -0,0 +1,215 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"alertbot/internal/metrics"
+	"alertbot/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// NotifyChannel is the Postgres notification channel events travel on
+	NotifyChannel = "alertbot_events"
+
+	// maxNotifyPayload keeps notifications below Postgres' 8000 byte limit;
+	// larger events are stored and sent by reference
+	maxNotifyPayload = 7900
+
+	// listenerPingInterval detects a dead listener connection between events
+	listenerPingInterval = 90 * time.Second
+)
+
+// PayloadStore keeps event payloads too large to send inline
+type PayloadStore interface {
+	CreateEventPayload(payload *models.EventPayload) error
+	GetEventPayload(id string) (*models.EventPayload, error)
+}
+
+// envelope is the notification payload. Data is omitted when the event is
+// stored under Ref instead.
+type envelope struct {
+	Origin string          `json:"origin"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Ref    string          `json:"ref,omitempty"`
+}
+
+// PostgresBroker shares events between replicas with LISTEN/NOTIFY. Each
+// replica delivers its own events locally and ignores their echo. Events
+// published while a listener reconnects are not replayed to it. Every
+// replica numbers events in its own bus epoch, so a client that resumes on
+// another replica is told to resync.
+type PostgresBroker struct {
+	bus      *Bus
+	db       *gorm.DB
+	store    PayloadStore
+	listener *pq.Listener
+	origin   string
+	decode   Decoder
+	logger   *logrus.Logger
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewPostgresBroker listens for events on the database at dsn and publishes
+// through db. origin identifies this replica; decode rebuilds remote event
+// data and defaults to plain JSON values.
+func NewPostgresBroker(dsn string, db *gorm.DB, store PayloadStore, bus *Bus, origin string, decode Decoder, logger *logrus.Logger) (*PostgresBroker, error) {
+	if decode == nil {
+		decode = decodeAny
+	}
+
+	b := &PostgresBroker{
+		bus:    bus,
+		db:     db,
+		store:  store,
+		origin: origin,
+		decode: decode,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+
+	b.listener = pq.NewListener(dsn, time.Second, time.Minute, b.onListenerEvent)
+	if err := b.listener.Listen(NotifyChannel); err != nil {
+		b.listener.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %w", NotifyChannel, err)
+	}
+
+	b.wg.Add(1)
+	go b.run()
+
+	logger.WithField("channel", NotifyChannel).Info("Sharing events with other replicas")
+	return b, nil
+}
+
+func (b *PostgresBroker) Publish(eventType string, data interface{}) error {
+	b.bus.Publish(eventType, data)
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	env := envelope{Origin: b.origin, Type: eventType, Data: raw}
+	message, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	payload := "inline"
+	if len(message) > maxNotifyPayload {
+		stored := &models.EventPayload{
+			ID:        uuid.NewString(),
+			Type:      eventType,
+			Payload:   string(raw),
+			CreatedAt: time.Now(),
+		}
+		if err := b.store.CreateEventPayload(stored); err != nil {
+			return fmt.Errorf("failed to store %s event: %w", eventType, err)
+		}
+
+		env.Data, env.Ref = nil, stored.ID
+		if message, err = json.Marshal(env); err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+		}
+		payload = "reference"
+	}
+
+	if err := b.db.Exec("SELECT pg_notify(?, ?)", NotifyChannel, string(message)).Error; err != nil {
+		return fmt.Errorf("failed to notify %s event: %w", eventType, err)
+	}
+	metrics.RecordEventBusMessage("sent", payload)
+	return nil
+}
+
+// Close stops listening and waits for the receive loop to exit
+func (b *PostgresBroker) Close() error {
+	var err error
+	b.once.Do(func() {
+		close(b.done)
+		b.wg.Wait()
+		err = b.listener.Close()
+	})
+	return err
+}
+
+func (b *PostgresBroker) run() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case notification := <-b.listener.Notify:
+			if notification == nil {
+				// Sent after the listener reconnects
+				b.logger.Warn("Event listener reconnected, events from other replicas may have been missed")
+				continue
+			}
+			b.receive(notification.Extra)
+		case <-ticker.C:
+			go b.listener.Ping()
+		}
+	}
+}
+
+// receive publishes an event from another replica to the local bus
+func (b *PostgresBroker) receive(message string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(message), &env); err != nil {
+		b.logger.WithError(err).Warn("Discarding malformed event notification")
+		metrics.RecordEventBusMessage("received", "error")
+		return
+	}
+	if env.Origin == b.origin {
+		return
+	}
+
+	data, payload := env.Data, "inline"
+	if env.Ref != "" {
+		stored, err := b.store.GetEventPayload(env.Ref)
+		if err != nil {
+			b.logger.WithError(err).WithFields(logrus.Fields{
+				"event_type": env.Type,
+				"ref":        env.Ref,
+			}).Warn("Failed to fetch event payload")
+			metrics.RecordEventBusMessage("received", "error")
+			return
+		}
+		data, payload = json.RawMessage(stored.Payload), "reference"
+	}
+
+	value, err := b.decode(env.Type, data)
+	if err != nil {
+		b.logger.WithError(err).WithField("event_type", env.Type).Warn("Failed to decode event from another replica")
+		metrics.RecordEventBusMessage("received", "error")
+		return
+	}
+
+	b.bus.Publish(env.Type, value)
+	metrics.RecordEventBusMessage("received", payload)
+}
+
+func (b *PostgresBroker) onListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		b.logger.WithError(err).Warn("Event listener disconnected")
+	case pq.ListenerEventReconnected:
+		b.logger.Info("Event listener reconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		b.logger.WithError(err).Warn("Event listener failed to reconnect")
+	}
+}
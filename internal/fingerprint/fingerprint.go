@@ -0,0 +1,175 @@
+// Package fingerprint computes alert identities. Fingerprints use the same
+// FNV-64a scheme as Prometheus and Alertmanager, so an alert with all of its
+// labels selected has the ID shown in the Alertmanager UI and API.
+package fingerprint
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// FNV-64a parameters, as used by prometheus/common/model
+	offset64 uint64 = 14695981039346656037
+	prime64  uint64 = 1099511628211
+
+	// separatorByte never occurs in valid UTF-8 label names or values
+	separatorByte byte = 255
+)
+
+// Sum returns the Alertmanager-compatible fingerprint of a label set
+func Sum(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sum := offset64
+	for _, name := range names {
+		sum = hashAdd(sum, name)
+		sum = hashAddByte(sum, separatorByte)
+		sum = hashAdd(sum, labels[name])
+		sum = hashAddByte(sum, separatorByte)
+	}
+	return fmt.Sprintf("%016x", sum)
+}
+
+func hashAdd(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return h
+}
+
+func hashAddByte(h uint64, b byte) uint64 {
+	h ^= uint64(b)
+	h *= prime64
+	return h
+}
+
+// Strategy selects the labels that identify an alert. With no include list
+// every label is used; exclusions are applied afterwards. A pattern ending in
+// "*" matches every label with that prefix.
+type Strategy struct {
+	include []string
+	exclude []string
+}
+
+// New creates a strategy from include and exclude label patterns
+func New(include, exclude []string) *Strategy {
+	return &Strategy{
+		include: clean(include),
+		exclude: clean(exclude),
+	}
+}
+
+// Default returns the strategy that uses every label, matching Alertmanager
+func Default() *Strategy {
+	return &Strategy{}
+}
+
+// Without returns a copy of the strategy that also excludes the given patterns
+func (s *Strategy) Without(patterns ...string) *Strategy {
+	if s == nil {
+		s = Default()
+	}
+	exclude := append(append([]string(nil), s.exclude...), clean(patterns)...)
+	return &Strategy{include: s.include, exclude: exclude}
+}
+
+// Include returns the include patterns, sorted and without duplicates
+func (s *Strategy) Include() []string {
+	if s == nil {
+		return nil
+	}
+	return normalize(s.include)
+}
+
+// Exclude returns the exclude patterns, sorted and without duplicates
+func (s *Strategy) Exclude() []string {
+	if s == nil {
+		return nil
+	}
+	return normalize(s.exclude)
+}
+
+// Equal reports whether two strategies select the same labels. Pattern order
+// and duplicates do not matter; a nil strategy equals Default.
+func (s *Strategy) Equal(other *Strategy) bool {
+	return slices.Equal(s.Include(), other.Include()) && slices.Equal(s.Exclude(), other.Exclude())
+}
+
+// Select returns the labels that take part in the fingerprint
+func (s *Strategy) Select(labels map[string]string) map[string]string {
+	if s == nil || (len(s.include) == 0 && len(s.exclude) == 0) {
+		return labels
+	}
+
+	selected := make(map[string]string, len(labels))
+	for name, value := range labels {
+		if len(s.include) > 0 && !matchAny(s.include, name) {
+			continue
+		}
+		if matchAny(s.exclude, name) {
+			continue
+		}
+		selected[name] = value
+	}
+	return selected
+}
+
+// Fingerprint returns the fingerprint of the selected labels
+func (s *Strategy) Fingerprint(labels map[string]string) string {
+	return Sum(s.Select(labels))
+}
+
+// FingerprintJSONB fingerprints labels stored as JSONB
+func (s *Strategy) FingerprintJSONB(labels map[string]interface{}) string {
+	return s.Fingerprint(LabelSet(labels))
+}
+
+// LabelSet converts JSONB labels into a string map
+func LabelSet(labels map[string]interface{}) map[string]string {
+	set := make(map[string]string, len(labels))
+	for name, value := range labels {
+		if str, ok := value.(string); ok {
+			set[name] = str
+		} else if value != nil {
+			set[name] = fmt.Sprint(value)
+		}
+	}
+	return set
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+func clean(patterns []string) []string {
+	var out []string
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func normalize(patterns []string) []string {
+	out := slices.Clone(patterns)
+	sort.Strings(out)
+	return slices.Compact(out)
+}
@@ -0,0 +1,102 @@
+package fingerprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSumMatchesAlertmanager checks Sum against the values
+// model.LabelSet.Fingerprint() from prometheus/common returns, which
+// Alertmanager shows as alert IDs
+func TestSumMatchesAlertmanager(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{"empty", map[string]string{}, "cbf29ce484222325"},
+		{"nil", nil, "cbf29ce484222325"},
+		{"single label", map[string]string{"alertname": "Watchdog"}, "003b0a0f7d3fa569"},
+		{"several labels", map[string]string{"alertname": "HighCPU", "instance": "web1:9100", "severity": "critical"}, "b9eb3ff1baf3247e"},
+		// Vectors from the prometheus/common signature tests
+		{"upstream pair", map[string]string{"name": "garland, briggs", "fear": "love is not enough"}, "507a62d79ee76c9a"},
+		{"upstream single", map[string]string{"first-label": "first-label-value"}, "476b44f5db3e21f9"},
+		{"upstream double", map[string]string{"first-label": "first-label-value", "second-label": "second-label-value"}, "2c59c33dd8453b1d"},
+		{"upstream triple", map[string]string{"first-label": "first-label-value", "second-label": "second-label-value", "third-label": "third-label-value"}, "c01c58139d52b4b9"},
+		{"empty value", map[string]string{"alertname": "Disk", "mountpoint": "/", "empty": ""}, "d3ffdc345591d7ce"},
+		{"unicode", map[string]string{"alertname": "Ünïcode", "team": "运维"}, "a43c68bc5b8d60a4"},
+		// The separator keeps name and value boundaries apart
+		{"boundary a/bc", map[string]string{"a": "bc"}, "a0a3542c19b900ab"},
+		{"boundary ab/c", map[string]string{"ab": "c"}, "20ba9b3025a8b421"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Sum(tt.labels))
+			assert.Equal(t, tt.want, Default().Fingerprint(tt.labels))
+		})
+	}
+}
+
+func TestStrategySelect(t *testing.T) {
+	labels := map[string]string{
+		"alertname":    "HighCPU",
+		"instance":     "web1:9100",
+		"pod":          "web-7d9f",
+		"k8s_node":     "node-1",
+		"k8s_cluster":  "prod",
+		"severity":     "critical",
+		"generated_by": "ruler",
+	}
+
+	tests := []struct {
+		name     string
+		strategy *Strategy
+		want     map[string]string
+	}{
+		{"default", Default(), labels},
+		{"nil", nil, labels},
+		{"include", New([]string{"alertname", "instance"}, nil), map[string]string{"alertname": "HighCPU", "instance": "web1:9100"}},
+		{"include prefix", New([]string{"alertname", "k8s_*"}, nil), map[string]string{"alertname": "HighCPU", "k8s_node": "node-1", "k8s_cluster": "prod"}},
+		{"exclude", New(nil, []string{"pod", " generated_by "}), map[string]string{"alertname": "HighCPU", "instance": "web1:9100", "k8s_node": "node-1", "k8s_cluster": "prod", "severity": "critical"}},
+		{"include then exclude", New([]string{"alertname", "k8s_*"}, []string{"k8s_node"}), map[string]string{"alertname": "HighCPU", "k8s_cluster": "prod"}},
+		{"without", New([]string{"alertname", "k8s_*"}, nil).Without("k8s_cluster"), map[string]string{"alertname": "HighCPU", "k8s_node": "node-1"}},
+		{"blank patterns", New([]string{"", " "}, []string{""}), labels},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.strategy.Select(labels))
+			assert.Equal(t, Sum(tt.want), tt.strategy.Fingerprint(labels))
+		})
+	}
+}
+
+func TestStrategyEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *Strategy
+		want bool
+	}{
+		{"default and nil", Default(), nil, true},
+		{"default and empty lists", Default(), New([]string{}, []string{" "}), true},
+		{"order and duplicates", New([]string{"b", "a"}, []string{"x", "x"}), New([]string{"a", "b"}, []string{"x"}), true},
+		{"include differs", New([]string{"a"}, nil), New([]string{"a", "b"}, nil), false},
+		{"exclude differs", New(nil, []string{"pod"}), Default(), false},
+		{"include and exclude swapped", New([]string{"a"}, nil), New(nil, []string{"a"}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.a.Equal(tt.b))
+			assert.Equal(t, tt.want, tt.b.Equal(tt.a))
+		})
+	}
+}
+
+func TestFingerprintJSONB(t *testing.T) {
+	labels := map[string]interface{}{"alertname": "HighCPU", "instance": "web1:9100", "severity": "critical", "missing": nil}
+	assert.Equal(t, "b9eb3ff1baf3247e", Default().FingerprintJSONB(labels))
+	assert.Equal(t, map[string]string{"port": "9100"}, LabelSet(map[string]interface{}{"port": 9100}))
+}
@@ -0,0 +1,209 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeys are substrings of channel config keys whose values are exported
+// as environment references rather than in clear
+var secretKeys = []string{"password", "secret", "token", "access_key", "api_key", "webhook_url"}
+
+var nonEnvChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// SecretEnvName is the variable an exported secret refers to, e.g.
+// ALERTBOT_MANIFEST_DBA_SLACK_WEBHOOK_URL for key webhook_url of channel
+// dba-slack
+func SecretEnvName(channel, key string) string {
+	name := strings.Trim(nonEnvChars.ReplaceAllString(strings.ToUpper(channel+"_"+key), "_"), "_")
+	return EnvPrefix + name
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// State is the stored configuration to export
+type State struct {
+	Templates       []models.NotificationTemplate
+	Channels        []models.NotificationChannel
+	RoutingRules    []models.RoutingRule
+	InhibitionRules []models.InhibitionRule
+	GroupRules      []models.AlertGroupRule
+	Silences        []models.Silence // Only named silences are exported
+}
+
+// Export renders the stored configuration as a bundle. Secret channel config
+// values are replaced by ${...} references; the returned names are the
+// variables that must be set to apply the bundle again.
+func Export(state *State) ([]byte, []string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	var envVars []string
+	write := func(kind, name string, spec interface{}) error {
+		doc := Document{APIVersion: APIVersion, Kind: kind, Name: name}
+		if err := doc.Spec.Encode(spec); err != nil {
+			return fmt.Errorf("%s/%s: %w", kind, name, err)
+		}
+		return encoder.Encode(&doc)
+	}
+
+	for _, t := range state.Templates {
+		if err := write(KindNotificationTemplate, t.Name, NotificationTemplateSpec{
+			Description: t.Description,
+			Title:       t.Title,
+			Content:     t.Content,
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	channelNames := make(map[uint]string, len(state.Channels))
+	for _, c := range state.Channels {
+		channelNames[c.ID] = c.Name
+		config := make(map[string]interface{}, len(c.Config))
+		for key, value := range c.Config {
+			if _, ok := value.(string); ok && isSecretKey(key) && value != "" {
+				env := SecretEnvName(c.Name, key)
+				envVars = append(envVars, env)
+				value = "${" + env + "}"
+			}
+			config[key] = value
+		}
+		if err := write(KindNotificationChannel, c.Name, NotificationChannelSpec{
+			Type:    c.Type,
+			Enabled: boolPtr(c.Enabled),
+			Config:  config,
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, r := range state.RoutingRules {
+		spec := RoutingRuleSpec{
+			Description: r.Description,
+			Priority:    r.Priority,
+			Enabled:     boolPtr(r.Enabled),
+			Channels:    []string{},
+		}
+		if ms, ok := engine.MatchersFromConditions(r.Conditions); ok {
+			spec.Matchers = matcherStrings(ms)
+		} else {
+			spec.Conditions = r.Conditions
+		}
+		if ids, ok := r.Receivers["channels"].([]interface{}); ok {
+			for _, id := range ids {
+				if n, ok := id.(float64); ok {
+					if name, ok := channelNames[uint(n)]; ok {
+						spec.Channels = append(spec.Channels, name)
+					}
+				}
+			}
+		}
+		spec.Template, _ = r.Receivers["template"].(string)
+		if err := write(KindRoutingRule, r.Name, spec); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, r := range state.InhibitionRules {
+		if err := write(KindInhibitionRule, r.Name, InhibitionRuleSpec{
+			Description:    r.Description,
+			SourceMatchers: storedMatcherStrings(r.SourceMatchers),
+			TargetMatchers: storedMatcherStrings(r.TargetMatchers),
+			Equal:          jsonbStrings(r.EqualLabels["labels"]),
+			Duration:       r.Duration,
+			Priority:       r.Priority,
+			Enabled:        boolPtr(r.Enabled),
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, r := range state.GroupRules {
+		groupWait, groupInterval, repeatInterval := r.GroupWait, r.GroupInterval, r.RepeatInterval
+		if err := write(KindAlertGroupRule, r.Name, AlertGroupRuleSpec{
+			Description:    r.Description,
+			GroupBy:        jsonbStrings(r.GroupBy["labels"]),
+			GroupWait:      &groupWait,
+			GroupInterval:  &groupInterval,
+			RepeatInterval: &repeatInterval,
+			Matchers:       storedMatcherStrings(r.Matchers),
+			Priority:       r.Priority,
+			Enabled:        boolPtr(r.Enabled),
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range state.Silences {
+		if s.Name == "" {
+			continue
+		}
+		if err := write(KindSilence, s.Name, SilenceSpec{
+			Matchers: storedMatcherStrings(s.Matchers),
+			StartsAt: s.StartsAt.UTC(),
+			EndsAt:   s.EndsAt.UTC(),
+			Creator:  s.Creator,
+			Comment:  s.Comment,
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), envVars, nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func matcherStrings(ms matcher.Matchers) []string {
+	out := make([]string, len(ms))
+	for i, m := range ms {
+		out[i] = m.String()
+	}
+	return out
+}
+
+// storedMatcherStrings renders the stored matcher JSONB as expressions
+func storedMatcherStrings(data models.JSONB) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	ms, err := matcher.Decode(data)
+	if err != nil {
+		return nil
+	}
+	return matcherStrings(ms)
+}
+
+func jsonbStrings(v interface{}) []string {
+	items, _ := v.([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
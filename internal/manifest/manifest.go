@@ -0,0 +1,484 @@
+// Package manifest reads and writes the declarative YAML form of alertbot's
+// configuration: notification channels, templates, routing rules,
+// inhibition rules, alert group rules and silences. A bundle is a stream of
+// YAML documents, each naming one object:
+//
+//	apiVersion: alertbot/v1
+//	kind: RoutingRule
+//	name: database-critical
+//	spec:
+//	  matchers: ['team="db"', 'severity="critical"']
+//	  channels: [dba-slack]
+//
+// Objects are identified by kind and name. Channel config values may refer
+// to environment variables as ${NAME} so secrets stay out of the manifests;
+// only names starting with EnvPrefix are resolved, so a manifest cannot copy
+// the server's own secrets into a channel.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion is the only manifest version
+const APIVersion = "alertbot/v1"
+
+// EnvPrefix starts the name of every environment variable a manifest may
+// refer to
+const EnvPrefix = "ALERTBOT_MANIFEST_"
+
+// Manifest kinds, in the order objects are applied
+const (
+	KindNotificationTemplate = "NotificationTemplate"
+	KindNotificationChannel  = "NotificationChannel"
+	KindRoutingRule          = "RoutingRule"
+	KindInhibitionRule       = "InhibitionRule"
+	KindAlertGroupRule       = "AlertGroupRule"
+	KindSilence              = "Silence"
+)
+
+// Document is one YAML document of a bundle
+type Document struct {
+	APIVersion string    `yaml:"apiVersion"`
+	Kind       string    `yaml:"kind"`
+	Name       string    `yaml:"name"`
+	Spec       yaml.Node `yaml:"spec"`
+}
+
+type NotificationTemplateSpec struct {
+	Description string `yaml:"description,omitempty"`
+	Title       string `yaml:"title,omitempty"`
+	Content     string `yaml:"content"`
+}
+
+type NotificationChannelSpec struct {
+	Type    string                 `yaml:"type"`
+	Enabled *bool                  `yaml:"enabled,omitempty"`
+	Config  map[string]interface{} `yaml:"config"`
+}
+
+// RoutingRuleSpec selects alerts with matchers, or with conditions in the
+// stored rule format for operators matchers cannot express
+type RoutingRuleSpec struct {
+	Description string                 `yaml:"description,omitempty"`
+	Priority    int                    `yaml:"priority,omitempty"`
+	Enabled     *bool                  `yaml:"enabled,omitempty"`
+	Matchers    []string               `yaml:"matchers,omitempty"`
+	Conditions  map[string]interface{} `yaml:"conditions,omitempty"`
+	Channels    []string               `yaml:"channels"`
+	Template    string                 `yaml:"template,omitempty"`
+}
+
+type InhibitionRuleSpec struct {
+	Description    string   `yaml:"description,omitempty"`
+	SourceMatchers []string `yaml:"source_matchers"`
+	TargetMatchers []string `yaml:"target_matchers"`
+	Equal          []string `yaml:"equal,omitempty"`
+	Duration       int      `yaml:"duration,omitempty"`
+	Priority       int      `yaml:"priority,omitempty"`
+	Enabled        *bool    `yaml:"enabled,omitempty"`
+}
+
+// AlertGroupRuleSpec times are in seconds; unset ones take the model defaults
+type AlertGroupRuleSpec struct {
+	Description    string   `yaml:"description,omitempty"`
+	GroupBy        []string `yaml:"group_by"`
+	GroupWait      *int     `yaml:"group_wait,omitempty"`
+	GroupInterval  *int     `yaml:"group_interval,omitempty"`
+	RepeatInterval *int     `yaml:"repeat_interval,omitempty"`
+	Matchers       []string `yaml:"matchers,omitempty"`
+	Priority       int      `yaml:"priority,omitempty"`
+	Enabled        *bool    `yaml:"enabled,omitempty"`
+}
+
+// SilenceSpec is a scheduled silence, e.g. a maintenance window
+type SilenceSpec struct {
+	Matchers []string  `yaml:"matchers"`
+	StartsAt time.Time `yaml:"starts_at"`
+	EndsAt   time.Time `yaml:"ends_at"`
+	Creator  string    `yaml:"creator,omitempty"`
+	Comment  string    `yaml:"comment,omitempty"`
+}
+
+// RoutingRule is a built routing rule with the channels and template it
+// names; they may be defined in the bundle or already stored, and channels
+// are resolved to IDs when the rule is stored
+type RoutingRule struct {
+	Rule     models.RoutingRule
+	Channels []string
+	Template string
+}
+
+// Objects are the models described by a bundle
+type Objects struct {
+	Templates       []models.NotificationTemplate
+	Channels        []models.NotificationChannel
+	RoutingRules    []RoutingRule
+	InhibitionRules []models.InhibitionRule
+	GroupRules      []models.AlertGroupRule
+	Silences        []models.Silence
+}
+
+// Errors collects every problem found in a bundle
+type Errors []string
+
+func (e Errors) Error() string {
+	return strings.Join(e, "; ")
+}
+
+// Parse decodes a bundle. Unknown kinds and spec fields are errors so typos
+// do not silently drop configuration.
+func Parse(data []byte) ([]Document, error) {
+	var docs []Document
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 0; ; i++ {
+		var doc Document
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+		if doc.Kind == "" && doc.Name == "" && doc.Spec.IsZero() {
+			continue // Empty document, e.g. a trailing ---
+		}
+		if doc.APIVersion != "" && doc.APIVersion != APIVersion {
+			return nil, fmt.Errorf("document %d: unsupported apiVersion %q", i+1, doc.APIVersion)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// Build converts documents into models, resolving ${NAME} references in
+// channel config with lookupEnv
+func Build(docs []Document, lookupEnv func(string) (string, bool)) (*Objects, error) {
+	objects := &Objects{}
+	var errs Errors
+	seen := make(map[string]bool, len(docs))
+
+	for _, doc := range docs {
+		ref := doc.Kind + "/" + doc.Name
+		if doc.Name == "" {
+			errs = append(errs, fmt.Sprintf("%s: name is required", doc.Kind))
+			continue
+		}
+		if seen[ref] {
+			errs = append(errs, fmt.Sprintf("%s: defined more than once", ref))
+			continue
+		}
+		seen[ref] = true
+
+		var err error
+		switch doc.Kind {
+		case KindNotificationTemplate:
+			err = objects.addTemplate(doc)
+		case KindNotificationChannel:
+			err = objects.addChannel(doc, lookupEnv)
+		case KindRoutingRule:
+			err = objects.addRoutingRule(doc)
+		case KindInhibitionRule:
+			err = objects.addInhibitionRule(doc)
+		case KindAlertGroupRule:
+			err = objects.addGroupRule(doc)
+		case KindSilence:
+			err = objects.addSilence(doc)
+		default:
+			err = fmt.Errorf("unknown kind")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ref, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return objects, nil
+}
+
+// decodeSpec decodes a spec strictly into out
+func decodeSpec(doc Document, out interface{}) error {
+	if doc.Spec.IsZero() {
+		return fmt.Errorf("spec is required")
+	}
+	data, err := yaml.Marshal(&doc.Spec)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(out)
+}
+
+func enabled(b *bool) bool {
+	return b == nil || *b
+}
+
+func (o *Objects) addTemplate(doc Document) error {
+	var spec NotificationTemplateSpec
+	if err := decodeSpec(doc, &spec); err != nil {
+		return err
+	}
+	if spec.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	o.Templates = append(o.Templates, models.NotificationTemplate{
+		Name:        doc.Name,
+		Description: spec.Description,
+		Title:       spec.Title,
+		Content:     spec.Content,
+	})
+	return nil
+}
+
+var validChannelTypes = map[string]bool{
+	string(models.ChannelTypeDingTalk):   true,
+	string(models.ChannelTypeWeChatWork): true,
+	string(models.ChannelTypeEmail):      true,
+	string(models.ChannelTypeSMS):        true,
+	string(models.ChannelTypeTelegram):   true,
+	string(models.ChannelTypeSlack):      true,
+}
+
+func (o *Objects) addChannel(doc Document, lookupEnv func(string) (string, bool)) error {
+	var spec NotificationChannelSpec
+	if err := decodeSpec(doc, &spec); err != nil {
+		return err
+	}
+	if !validChannelTypes[spec.Type] {
+		return fmt.Errorf("unsupported channel type %q", spec.Type)
+	}
+
+	var missing, forbidden []string
+	config, _ := expandEnv(spec.Config, lookupEnv, &missing, &forbidden).(map[string]interface{})
+	if len(forbidden) > 0 {
+		return fmt.Errorf("environment variables must start with %s: %s", EnvPrefix, strings.Join(forbidden, ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+
+	o.Channels = append(o.Channels, models.NotificationChannel{
+		Name:    doc.Name,
+		Type:    spec.Type,
+		Config:  models.JSONB(config),
+		Enabled: enabled(spec.Enabled),
+	})
+	return nil
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} in every string of a decoded YAML value.
+// Names without EnvPrefix are collected in forbidden and never looked up.
+func expandEnv(v interface{}, lookupEnv func(string) (string, bool), missing, forbidden *[]string) interface{} {
+	switch value := v.(type) {
+	case string:
+		return envRef.ReplaceAllStringFunc(value, func(ref string) string {
+			name := envRef.FindStringSubmatch(ref)[1]
+			if !strings.HasPrefix(name, EnvPrefix) {
+				*forbidden = append(*forbidden, name)
+				return ""
+			}
+			resolved, ok := lookupEnv(name)
+			if !ok {
+				*missing = append(*missing, name)
+			}
+			return resolved
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			out[k] = expandEnv(item, lookupEnv, missing, forbidden)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = expandEnv(item, lookupEnv, missing, forbidden)
+		}
+		return out
+	}
+	return v
+}
+
+func (o *Objects) addRoutingRule(doc Document) error {
+	var spec RoutingRuleSpec
+	if err := decodeSpec(doc, &spec); err != nil {
+		return err
+	}
+	if len(spec.Matchers) > 0 && spec.Conditions != nil {
+		return fmt.Errorf("set either matchers or conditions, not both")
+	}
+
+	conditions := models.JSONB(spec.Conditions)
+	if spec.Conditions == nil {
+		ms, err := matcher.Parse(matcher.Join(spec.Matchers...))
+		if err != nil {
+			return fmt.Errorf("invalid matchers: %w", err)
+		}
+		conditions = engine.ConditionsFromMatchers(ms)
+	}
+
+	o.RoutingRules = append(o.RoutingRules, RoutingRule{
+		Rule: models.RoutingRule{
+			Name:        doc.Name,
+			Description: spec.Description,
+			Conditions:  conditions,
+			Priority:    spec.Priority,
+			Enabled:     enabled(spec.Enabled),
+		},
+		Channels: spec.Channels,
+		Template: spec.Template,
+	})
+	return nil
+}
+
+// storedMatchers parses matcher expressions into the stored JSONB form,
+// which has no negative matchers
+func storedMatchers(exprs []string) (models.JSONB, error) {
+	ms, err := matcher.Parse(matcher.Join(exprs...))
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := matcher.Encode(ms)
+	if err != nil {
+		return nil, err
+	}
+	return models.JSONB(encoded), nil
+}
+
+func stringsJSONB(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func (o *Objects) addInhibitionRule(doc Document) error {
+	var spec InhibitionRuleSpec
+	if err := decodeSpec(doc, &spec); err != nil {
+		return err
+	}
+	if len(spec.SourceMatchers) == 0 || len(spec.TargetMatchers) == 0 {
+		return fmt.Errorf("source_matchers and target_matchers are required")
+	}
+	source, err := storedMatchers(spec.SourceMatchers)
+	if err != nil {
+		return fmt.Errorf("invalid source_matchers: %w", err)
+	}
+	target, err := storedMatchers(spec.TargetMatchers)
+	if err != nil {
+		return fmt.Errorf("invalid target_matchers: %w", err)
+	}
+
+	o.InhibitionRules = append(o.InhibitionRules, models.InhibitionRule{
+		Name:           doc.Name,
+		Description:    spec.Description,
+		SourceMatchers: source,
+		TargetMatchers: target,
+		EqualLabels:    models.JSONB{"labels": stringsJSONB(spec.Equal)},
+		Duration:       spec.Duration,
+		Priority:       spec.Priority,
+		Enabled:        enabled(spec.Enabled),
+	})
+	return nil
+}
+
+func (o *Objects) addGroupRule(doc Document) error {
+	var spec AlertGroupRuleSpec
+	if err := decodeSpec(doc, &spec); err != nil {
+		return err
+	}
+	if len(spec.GroupBy) == 0 {
+		return fmt.Errorf("group_by is required")
+	}
+	matchers, err := storedMatchers(spec.Matchers)
+	if err != nil {
+		return fmt.Errorf("invalid matchers: %w", err)
+	}
+
+	rule := models.AlertGroupRule{
+		Name:           doc.Name,
+		Description:    spec.Description,
+		GroupBy:        models.JSONB{"labels": stringsJSONB(spec.GroupBy)},
+		GroupWait:      10,
+		GroupInterval:  300,
+		RepeatInterval: 3600,
+		Matchers:       matchers,
+		Priority:       spec.Priority,
+		Enabled:        enabled(spec.Enabled),
+	}
+	if spec.GroupWait != nil {
+		rule.GroupWait = *spec.GroupWait
+	}
+	if spec.GroupInterval != nil {
+		rule.GroupInterval = *spec.GroupInterval
+	}
+	if spec.RepeatInterval != nil {
+		rule.RepeatInterval = *spec.RepeatInterval
+	}
+
+	switch {
+	case rule.GroupWait < 0 || rule.GroupWait > 3600:
+		return fmt.Errorf("group_wait must be between 0 and 3600 seconds")
+	case rule.GroupInterval < 60 || rule.GroupInterval > 86400:
+		return fmt.Errorf("group_interval must be between 60 and 86400 seconds")
+	case rule.RepeatInterval < 300 || rule.RepeatInterval > 604800:
+		return fmt.Errorf("repeat_interval must be between 300 and 604800 seconds")
+	}
+
+	o.GroupRules = append(o.GroupRules, rule)
+	return nil
+}
+
+func (o *Objects) addSilence(doc Document) error {
+	var spec SilenceSpec
+	if err := decodeSpec(doc, &spec); err != nil {
+		return err
+	}
+	if len(spec.Matchers) == 0 {
+		return fmt.Errorf("matchers are required")
+	}
+	if spec.StartsAt.IsZero() || spec.EndsAt.IsZero() {
+		return fmt.Errorf("starts_at and ends_at are required")
+	}
+	if !spec.EndsAt.After(spec.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	matchers, err := storedMatchers(spec.Matchers)
+	if err != nil {
+		return fmt.Errorf("invalid matchers: %w", err)
+	}
+	if spec.Creator == "" {
+		spec.Creator = "config"
+	}
+
+	o.Silences = append(o.Silences, models.Silence{
+		Name:     doc.Name,
+		Matchers: matchers,
+		StartsAt: spec.StartsAt.UTC(),
+		EndsAt:   spec.EndsAt.UTC(),
+		Creator:  spec.Creator,
+		Comment:  spec.Comment,
+	})
+	return nil
+}
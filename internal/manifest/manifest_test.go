@@ -0,0 +1,275 @@
+package manifest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"alertbot/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBundle = `
+apiVersion: alertbot/v1
+kind: NotificationTemplate
+name: short
+spec:
+  title: '{{ .Alert.Labels.alertname }}'
+  content: '{{ .Alert.Annotations.summary }}'
+---
+kind: NotificationChannel
+name: dba-slack
+spec:
+  type: slack
+  config:
+    webhook_url: ${ALERTBOT_MANIFEST_SLACK_URL}
+    channel: '#dba'
+---
+kind: NotificationChannel
+name: ops-email
+spec:
+  type: email
+  enabled: false
+  config:
+    smtp_host: smtp.example.com
+    smtp_port: 587
+    password: pw-${ALERTBOT_MANIFEST_SMTP}
+    to: [ops@example.com]
+---
+kind: RoutingRule
+name: database-critical
+spec:
+  priority: 10
+  matchers: ['team="db"', 'severity=~"critical|page"']
+  channels: [dba-slack, ops-email]
+  template: short
+---
+kind: RoutingRule
+name: disk
+spec:
+  conditions:
+    logic: and
+    matchers:
+      - {name: summary, operator: contains, value: disk, is_regex: false}
+  channels: [ops-email]
+---
+kind: InhibitionRule
+name: critical-mutes-warning
+spec:
+  source_matchers: ['severity="critical"']
+  target_matchers: ['severity="warning"']
+  equal: [alertname]
+---
+kind: AlertGroupRule
+name: by-cluster
+spec:
+  group_by: [cluster]
+  group_wait: 30
+  matchers: ['env="prod"']
+---
+kind: Silence
+name: maintenance
+spec:
+  matchers: ['cluster="eu-1"']
+  starts_at: 2026-01-10T22:00:00+01:00
+  ends_at: 2026-01-11T02:00:00+01:00
+---
+`
+
+var testEnv = map[string]string{
+	"ALERTBOT_MANIFEST_SLACK_URL": "https://hooks.slack.com/services/T/B/X",
+	"ALERTBOT_MANIFEST_SMTP":      "secret",
+}
+
+func lookupIn(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func buildBundle(t *testing.T, data string, env map[string]string) *Objects {
+	docs, err := Parse([]byte(data))
+	require.NoError(t, err)
+	objects, err := Build(docs, lookupIn(env))
+	require.NoError(t, err)
+	return objects
+}
+
+func TestBuild(t *testing.T) {
+	objects := buildBundle(t, testBundle, testEnv)
+
+	require.Len(t, objects.Templates, 1)
+	require.Len(t, objects.Channels, 2)
+	assert.Equal(t, models.JSONB{
+		"webhook_url": "https://hooks.slack.com/services/T/B/X",
+		"channel":     "#dba",
+	}, objects.Channels[0].Config)
+	assert.True(t, objects.Channels[0].Enabled)
+	assert.Equal(t, "pw-secret", objects.Channels[1].Config["password"])
+	assert.False(t, objects.Channels[1].Enabled)
+
+	require.Len(t, objects.RoutingRules, 2)
+	critical := objects.RoutingRules[0]
+	assert.Equal(t, []string{"dba-slack", "ops-email"}, critical.Channels)
+	assert.Equal(t, "short", critical.Template)
+	assert.Equal(t, 10, critical.Rule.Priority)
+	assert.Equal(t, "and", critical.Rule.Conditions["logic"])
+
+	require.Len(t, objects.GroupRules, 1)
+	group := objects.GroupRules[0]
+	assert.Equal(t, 30, group.GroupWait)
+	assert.Equal(t, 300, group.GroupInterval)
+	assert.Equal(t, 3600, group.RepeatInterval)
+
+	require.Len(t, objects.Silences, 1)
+	silence := objects.Silences[0]
+	assert.Equal(t, "2026-01-10T21:00:00Z", silence.StartsAt.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, "config", silence.Creator)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		bundle string
+	}{
+		{"not yaml", "kind: [\n"},
+		{"unknown apiVersion", "apiVersion: alertbot/v2\nkind: Silence\nname: x\n"},
+		{"apiVersion in a later document", "kind: Silence\nname: x\n---\napiVersion: v1\nkind: Silence\nname: y\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.bundle))
+			assert.Error(t, err)
+		})
+	}
+
+	docs, err := Parse([]byte("---\n---\nkind: Silence\nname: x\n---\n"))
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+}
+
+func TestBuildErrors(t *testing.T) {
+	channel := func(config string) string {
+		return "kind: NotificationChannel\nname: c\nspec:\n  type: slack\n  config: " + config + "\n"
+	}
+
+	tests := []struct {
+		name   string
+		bundle string
+		want   string
+	}{
+		{"unknown kind", "kind: Receiver\nname: x\nspec: {}\n", "Receiver/x: unknown kind"},
+		{"missing name", "kind: Silence\nspec: {}\n", "Silence: name is required"},
+		{"duplicate", "kind: NotificationTemplate\nname: t\nspec: {content: a}\n---\nkind: NotificationTemplate\nname: t\nspec: {content: b}\n", "defined more than once"},
+		{"missing spec", "kind: NotificationTemplate\nname: t\n", "spec is required"},
+		{"unknown field", "kind: NotificationTemplate\nname: t\nspec: {content: a, body: b}\n", "field body not found"},
+		{"channel type", "kind: NotificationChannel\nname: c\nspec: {type: pager}\n", `unsupported channel type "pager"`},
+		{"env without prefix", channel("{webhook_url: '${DATABASE_PASSWORD}'}"), "must start with ALERTBOT_MANIFEST_: DATABASE_PASSWORD"},
+		{"env not set", channel("{webhook_url: '${ALERTBOT_MANIFEST_UNSET}'}"), "not set: ALERTBOT_MANIFEST_UNSET"},
+		{"matchers and conditions", "kind: RoutingRule\nname: r\nspec: {matchers: ['a=\"b\"'], conditions: {a: b}, channels: []}\n", "either matchers or conditions"},
+		{"invalid matchers", "kind: RoutingRule\nname: r\nspec: {matchers: ['a~\"b\"'], channels: []}\n", "invalid matchers"},
+		{"inhibition without target", "kind: InhibitionRule\nname: i\nspec: {source_matchers: ['a=\"b\"']}\n", "required"},
+		{"negative inhibition matcher", "kind: InhibitionRule\nname: i\nspec: {source_matchers: ['a!=\"b\"'], target_matchers: ['a=\"c\"']}\n", "invalid source_matchers"},
+		{"group without group_by", "kind: AlertGroupRule\nname: g\nspec: {group_wait: 10}\n", "group_by is required"},
+		{"group_wait range", "kind: AlertGroupRule\nname: g\nspec: {group_by: [a], group_wait: 3601}\n", "group_wait must be"},
+		{"group_interval range", "kind: AlertGroupRule\nname: g\nspec: {group_by: [a], group_interval: 59}\n", "group_interval must be"},
+		{"repeat_interval range", "kind: AlertGroupRule\nname: g\nspec: {group_by: [a], repeat_interval: 604801}\n", "repeat_interval must be"},
+		{"silence without times", "kind: Silence\nname: s\nspec: {matchers: ['a=\"b\"']}\n", "starts_at and ends_at"},
+		{"silence ends first", "kind: Silence\nname: s\nspec: {matchers: ['a=\"b\"'], starts_at: 2026-01-02T00:00:00Z, ends_at: 2026-01-01T00:00:00Z}\n", "ends_at must be after"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := Parse([]byte(tt.bundle))
+			require.NoError(t, err)
+			_, err = Build(docs, lookupIn(nil))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	// Every problem is reported at once
+	docs, err := Parse([]byte("kind: Receiver\nname: x\n---\nkind: Silence\nname: s\n"))
+	require.NoError(t, err)
+	_, err = Build(docs, lookupIn(nil))
+	var errs Errors
+	require.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 2)
+}
+
+// stored gives built objects IDs and resolves rule channels and templates
+// the way the config service stores them, passing everything through JSON
+// as the JSONB columns do
+func stored(t *testing.T, objects *Objects) *State {
+	state := &State{
+		Templates:       objects.Templates,
+		Channels:        objects.Channels,
+		InhibitionRules: objects.InhibitionRules,
+		GroupRules:      objects.GroupRules,
+		Silences:        append(objects.Silences, models.Silence{Creator: "admin"}),
+	}
+	ids := make(map[string]uint)
+	for i := range state.Channels {
+		state.Channels[i].ID = uint(i + 1)
+		ids[state.Channels[i].Name] = state.Channels[i].ID
+	}
+	for _, r := range objects.RoutingRules {
+		channels := make([]interface{}, len(r.Channels))
+		for i, name := range r.Channels {
+			channels[i] = ids[name]
+		}
+		rule := r.Rule
+		rule.Receivers = models.JSONB{"channels": channels}
+		if r.Template != "" {
+			rule.Receivers["template"] = r.Template
+		}
+		state.RoutingRules = append(state.RoutingRules, rule)
+	}
+
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+	var out State
+	require.NoError(t, json.Unmarshal(data, &out))
+	return &out
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	objects := buildBundle(t, testBundle, testEnv)
+
+	data, envVars, err := Export(stored(t, objects))
+	require.NoError(t, err)
+
+	// Secrets are exported as references to new variables
+	assert.ElementsMatch(t, []string{
+		"ALERTBOT_MANIFEST_DBA_SLACK_WEBHOOK_URL",
+		"ALERTBOT_MANIFEST_OPS_EMAIL_PASSWORD",
+	}, envVars)
+	assert.NotContains(t, string(data), "pw-secret")
+	assert.NotContains(t, string(data), "hooks.slack.com")
+
+	again := buildBundle(t, string(data), map[string]string{
+		"ALERTBOT_MANIFEST_DBA_SLACK_WEBHOOK_URL": "https://hooks.slack.com/services/T/B/X",
+		"ALERTBOT_MANIFEST_OPS_EMAIL_PASSWORD":    "pw-secret",
+	})
+
+	// Building the export gives back the objects the bundle described; the
+	// unnamed silence is not part of the configuration
+	assert.Equal(t, objects.Templates, again.Templates)
+	require.Len(t, again.Channels, len(objects.Channels))
+	for i := range objects.Channels {
+		objects.Channels[i].ID = 0
+		assert.Equal(t, objects.Channels[i], again.Channels[i])
+	}
+	assert.Equal(t, objects.RoutingRules, again.RoutingRules)
+	assert.Equal(t, objects.InhibitionRules, again.InhibitionRules)
+	assert.Equal(t, objects.GroupRules, again.GroupRules)
+	assert.Equal(t, objects.Silences, again.Silences)
+}
+
+func TestSecretEnvName(t *testing.T) {
+	assert.Equal(t, "ALERTBOT_MANIFEST_DBA_SLACK_WEBHOOK_URL", SecretEnvName("dba-slack", "webhook_url"))
+	assert.Equal(t, "ALERTBOT_MANIFEST_AM_WEB_EMAIL_1_PASSWORD", SecretEnvName("am:web email-1", "password"))
+}
@@ -0,0 +1,380 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is the comparison operator of a label matcher
+type Type string
+
+const (
+	MatchEqual     Type = "="
+	MatchNotEqual  Type = "!="
+	MatchRegexp    Type = "=~"
+	MatchNotRegexp Type = "!~"
+)
+
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Matcher matches a single label against a value using Prometheus semantics
+type Matcher struct {
+	Name  string `json:"name"`
+	Type  Type   `json:"type"`
+	Value string `json:"value"`
+
+	re *regexp.Regexp
+}
+
+// Matchers is a set of matchers that must all match
+type Matchers []*Matcher
+
+// maxRepeat is the largest repetition count Postgres regular expressions
+// accept; Go allows up to 1000
+const maxRepeat = 255
+
+// Anchor wraps a matcher regex so that it must match the whole value, as in
+// Alertmanager. Patterns are anchored the same way in Go and in SQL.
+func Anchor(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
+
+// New creates a matcher and compiles its regular expression if needed.
+// Regular expressions are fully anchored, as in Alertmanager, and limited to
+// the syntax Go and Postgres interpret alike, since label queries also run
+// as SQL.
+func New(t Type, name, value string) (*Matcher, error) {
+	if !labelNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid label name %q", name)
+	}
+
+	m := &Matcher{Name: name, Type: t, Value: value}
+
+	switch t {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile(Anchor(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q for label %s: %w", value, name, err)
+		}
+		if err := checkPortable(value); err != nil {
+			return nil, fmt.Errorf("invalid regex %q for label %s: %w", value, name, err)
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("unsupported match type %q", t)
+	}
+
+	return m, nil
+}
+
+// checkPortable rejects regex syntax that Postgres does not support or reads
+// differently from Go. The pattern must already compile in Go.
+func checkPortable(pattern string) error {
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			switch next := pattern[i]; {
+			case next == 'p' || next == 'P':
+				return fmt.Errorf("unicode classes are not supported")
+			case next == 'Q' || next == 'E':
+				return fmt.Errorf(`\Q...\E quoting is not supported`)
+			case next >= '1' && next <= '9':
+				return fmt.Errorf("octal escapes are not supported")
+			case next == 'x' && i+1 < len(pattern) && pattern[i+1] == '{':
+				return fmt.Errorf(`\x{...} escapes are not supported, use \xHH`)
+			case !inClass && (next == 'b' || next == 'B'):
+				return fmt.Errorf("word boundaries are not supported")
+			case !inClass && (next == 'z' || next == 'C'):
+				return fmt.Errorf(`\%c is not supported`, next)
+			case inClass && (next == 'D' || next == 'S' || next == 'W'):
+				return fmt.Errorf(`\%c is not supported inside brackets`, next)
+			}
+		case inClass:
+			if c == '[' && i+1 < len(pattern) && pattern[i+1] == ':' {
+				// Named classes such as [:alpha:] end at their own ":]"
+				if end := strings.Index(pattern[i+2:], ":]"); end >= 0 {
+					i += end + 3
+				}
+			} else if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+			// A leading ']' is a literal member of the class
+			if strings.HasPrefix(pattern[i+1:], "^]") {
+				i += 2
+			} else if strings.HasPrefix(pattern[i+1:], "]") {
+				i++
+			}
+		case c == '(' && strings.HasPrefix(pattern[i+1:], "?") && !strings.HasPrefix(pattern[i+1:], "?:"):
+			return fmt.Errorf("flags and named groups are not supported")
+		case c == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				continue
+			}
+			for _, bound := range strings.Split(pattern[i+1:i+end], ",") {
+				if n, err := strconv.Atoi(bound); err == nil && n > maxRepeat {
+					return fmt.Errorf("repetition counts above %d are not supported", maxRepeat)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the given label value satisfies the matcher.
+// A missing label is treated as an empty string.
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+// String returns the matcher in PromQL selector form
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%s", m.Name, m.Type, strconv.Quote(m.Value))
+}
+
+// Matches reports whether all matchers match the given label set
+func (ms Matchers) Matches(labels map[string]string) bool {
+	for _, m := range ms {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesJSONB is like Matches but accepts labels as stored in JSONB columns
+func (ms Matchers) MatchesJSONB(labels map[string]interface{}) bool {
+	return ms.Matches(LabelSet(labels))
+}
+
+// String returns the matchers in PromQL selector form
+func (ms Matchers) String() string {
+	parts := make([]string, 0, len(ms))
+	for _, m := range ms {
+		parts = append(parts, m.String())
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// LabelSet converts JSONB labels into a plain string map
+func LabelSet(labels map[string]interface{}) map[string]string {
+	set := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if str, ok := v.(string); ok {
+			set[k] = str
+		} else if v != nil {
+			set[k] = fmt.Sprint(v)
+		}
+	}
+	return set
+}
+
+// FromLabels builds equality matchers for every label in the set, sorted by name
+func FromLabels(labels map[string]string) Matchers {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ms := make(Matchers, 0, len(names))
+	for _, name := range names {
+		if m, err := New(MatchEqual, name, labels[name]); err == nil {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// Join combines several matcher expressions into one that matches when all do.
+// Surrounding braces are dropped and empty expressions are skipped.
+func Join(exprs ...string) string {
+	parts := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		expr = strings.TrimSpace(expr)
+		if strings.HasPrefix(expr, "{") && strings.HasSuffix(expr, "}") {
+			expr = strings.TrimSpace(expr[1 : len(expr)-1])
+		}
+		if expr != "" {
+			parts = append(parts, expr)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse parses a comma-separated list of label matchers such as
+// `team="db",env=~"prod.*"`. Surrounding braces are optional and
+// values may be left unquoted when they contain no commas.
+func Parse(input string) (Matchers, error) {
+	s := strings.TrimSpace(input)
+	if strings.HasPrefix(s, "{") {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unbalanced braces in %q", input)
+		}
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	var ms Matchers
+	for len(s) > 0 {
+		name, rest := scanLabelName(s)
+		if name == "" {
+			return nil, fmt.Errorf("expected label name at %q", s)
+		}
+
+		rest = strings.TrimLeft(rest, " \t")
+		var t Type
+		switch {
+		case strings.HasPrefix(rest, "=~"):
+			t = MatchRegexp
+		case strings.HasPrefix(rest, "!~"):
+			t = MatchNotRegexp
+		case strings.HasPrefix(rest, "!="):
+			t = MatchNotEqual
+		case strings.HasPrefix(rest, "="):
+			t = MatchEqual
+		default:
+			return nil, fmt.Errorf("expected match operator after %q", name)
+		}
+		rest = strings.TrimLeft(rest[len(t):], " \t")
+
+		value, rest, err := scanValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for label %s: %w", name, err)
+		}
+
+		m, err := New(t, name, value)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("expected ',' at %q", rest)
+		}
+		s = strings.TrimLeft(rest[1:], " \t")
+	}
+
+	return ms, nil
+}
+
+// scanLabelName consumes a label name from the start of s
+func scanLabelName(s string) (string, string) {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			i++
+			continue
+		}
+		break
+	}
+	return s[:i], s[i:]
+}
+
+// scanValue consumes a quoted or bare value from the start of s
+func scanValue(s string) (string, string, error) {
+	if s == "" {
+		return "", "", nil
+	}
+
+	quote := s[0]
+	if quote != '"' && quote != '\'' && quote != '`' {
+		end := strings.IndexByte(s, ',')
+		if end < 0 {
+			end = len(s)
+		}
+		return strings.TrimSpace(s[:end]), s[end:], nil
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			raw := s[:i+1]
+			if quote == '\'' {
+				raw = `"` + strings.ReplaceAll(raw[1:i], `"`, `\"`) + `"`
+			}
+			value, err := strconv.Unquote(raw)
+			if err != nil {
+				return "", "", err
+			}
+			return value, s[i+1:], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated quoted string")
+}
+
+// Decode reads the stored JSONB form used by silences and inhibition rules:
+// {"matchers":[{"name":"...","value":"...","is_regex":false}]}
+func Decode(data map[string]interface{}) (Matchers, error) {
+	raw, ok := data["matchers"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("matchers must be a list")
+	}
+
+	ms := make(Matchers, 0, len(raw))
+	for _, item := range raw {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("matcher must be an object")
+		}
+		name, _ := obj["name"].(string)
+		value, _ := obj["value"].(string)
+		isRegex, _ := obj["is_regex"].(bool)
+
+		t := MatchEqual
+		if isRegex {
+			t = MatchRegexp
+		}
+		m, err := New(t, name, value)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// Encode converts equality and regex matchers into the stored JSONB form.
+// Negative matchers have no stored representation and are rejected.
+func Encode(ms Matchers) (map[string]interface{}, error) {
+	items := make([]interface{}, 0, len(ms))
+	for _, m := range ms {
+		if m.Type != MatchEqual && m.Type != MatchRegexp {
+			return nil, fmt.Errorf("matcher %s cannot be stored", m)
+		}
+		items = append(items, map[string]interface{}{
+			"name":     m.Name,
+			"value":    m.Value,
+			"is_regex": m.Type == MatchRegexp,
+		})
+	}
+	return map[string]interface{}{"matchers": items}, nil
+}
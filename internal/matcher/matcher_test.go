@@ -0,0 +1,210 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Matcher
+	}{
+		{"equal", `team="db"`, []Matcher{{Name: "team", Type: MatchEqual, Value: "db"}}},
+		{"not equal", `team!="db"`, []Matcher{{Name: "team", Type: MatchNotEqual, Value: "db"}}},
+		{"regexp", `env=~"prod.*"`, []Matcher{{Name: "env", Type: MatchRegexp, Value: "prod.*"}}},
+		{"not regexp", `env!~"dev|test"`, []Matcher{{Name: "env", Type: MatchNotRegexp, Value: "dev|test"}}},
+		{"several with braces", `{team="db", env=~"prod.*"}`, []Matcher{
+			{Name: "team", Type: MatchEqual, Value: "db"},
+			{Name: "env", Type: MatchRegexp, Value: "prod.*"},
+		}},
+		{"spaces around operator", ` team = "db" `, []Matcher{{Name: "team", Type: MatchEqual, Value: "db"}}},
+		{"empty value", `team=""`, []Matcher{{Name: "team", Type: MatchEqual, Value: ""}}},
+		{"bare value", `team=db,env=prod`, []Matcher{
+			{Name: "team", Type: MatchEqual, Value: "db"},
+			{Name: "env", Type: MatchEqual, Value: "prod"},
+		}},
+		{"comma in quotes", `summary="a, b"`, []Matcher{{Name: "summary", Type: MatchEqual, Value: "a, b"}}},
+		{"escaped quote", `summary="say \"hi\""`, []Matcher{{Name: "summary", Type: MatchEqual, Value: `say "hi"`}}},
+		{"escaped backslash", `path=~"C:\\\\temp"`, []Matcher{{Name: "path", Type: MatchRegexp, Value: `C:\\temp`}}},
+		{"regexp escape", `instance=~"db-\\d+"`, []Matcher{{Name: "instance", Type: MatchRegexp, Value: `db-\d+`}}},
+		{"single quotes", `summary='say "hi"'`, []Matcher{{Name: "summary", Type: MatchEqual, Value: `say "hi"`}}},
+		{"backticks", "instance=~`db-\\d+`", []Matcher{{Name: "instance", Type: MatchRegexp, Value: `db-\d+`}}},
+		{"unicode", `team="运维"`, []Matcher{{Name: "team", Type: MatchEqual, Value: "运维"}}},
+		{"empty", ``, nil},
+		{"empty braces", `{}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, err := Parse(tt.input)
+			require.NoError(t, err)
+			require.Len(t, ms, len(tt.want))
+			for i, want := range tt.want {
+				assert.Equal(t, want.Name, ms[i].Name)
+				assert.Equal(t, want.Type, ms[i].Type)
+				assert.Equal(t, want.Value, ms[i].Value)
+			}
+
+			// The string form parses back to the same matchers
+			again, err := Parse(ms.String())
+			require.NoError(t, err)
+			assert.Equal(t, ms.String(), again.String())
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unbalanced braces", `{team="db"`},
+		{"missing name", `="db"`},
+		{"invalid name", `1team="db"`},
+		{"missing operator", `team`},
+		{"unknown operator", `team~"db"`},
+		{"unterminated quote", `team="db`},
+		{"invalid escape", `team="\q"`},
+		{"missing comma", `team="db" env="prod"`},
+		{"invalid regexp", `env=~"prod("`},
+		{"unsupported regexp", `env=~"(?i)prod"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     Type
+		value string
+		label string
+		want  bool
+	}{
+		{"equal", MatchEqual, "prod", "prod", true},
+		{"equal differs", MatchEqual, "prod", "production", false},
+		{"equal empty matches missing", MatchEqual, "", "", true},
+		{"not equal", MatchNotEqual, "prod", "staging", true},
+		{"not equal same", MatchNotEqual, "prod", "prod", false},
+		{"regexp", MatchRegexp, "prod.*", "production", true},
+		{"regexp is anchored at the start", MatchRegexp, "prod", "preprod", false},
+		{"regexp is anchored at the end", MatchRegexp, "prod", "production", false},
+		{"alternation is anchored", MatchRegexp, "prod|staging", "production", false},
+		{"alternation", MatchRegexp, "prod|staging", "staging", true},
+		{"regexp empty matches missing", MatchRegexp, ".*", "", true},
+		{"not regexp", MatchNotRegexp, "prod|staging", "dev", true},
+		{"not regexp is anchored", MatchNotRegexp, "prod", "production", true},
+		{"not regexp matching", MatchNotRegexp, "prod.*", "production", false},
+		{"escaped metacharacter", MatchRegexp, `db-1\.example`, "db-1xexample", false},
+		{"character class", MatchRegexp, `db-[0-9]+:\d+`, "db-12:9100", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.t, "env", tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Matches(tt.label))
+		})
+	}
+}
+
+func TestMatchersMatches(t *testing.T) {
+	ms, err := Parse(`team="db",env=~"prod|staging",owner=""`)
+	require.NoError(t, err)
+
+	assert.True(t, ms.Matches(map[string]string{"team": "db", "env": "prod"}))
+	assert.False(t, ms.Matches(map[string]string{"team": "db", "env": "prod", "owner": "alice"}))
+	assert.False(t, ms.Matches(map[string]string{"team": "db"}))
+	assert.True(t, ms.MatchesJSONB(map[string]interface{}{"team": "db", "env": "staging", "owner": nil}))
+}
+
+func TestNewRejectsUnportableRegexp(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"plain", `prod-[a-z]+\.example\.com`, true},
+		{"non-capturing group", `(?:a|b)+`, true},
+		{"named class", `[[:alpha:]_]+`, true},
+		{"literal bracket in class", `[]a]|[^]b]`, true},
+		{"escapes in class", `[\d\s\w.-]`, true},
+		{"bounded repeat", `a{2,255}`, true},
+		{"escaped brace", `\{1000\}`, true},
+		{"hex escape", `\x41`, true},
+		{"case-insensitive flag", `(?i)prod`, false},
+		{"scoped flag", `(?i:prod)`, false},
+		{"named group", `(?P<env>prod)`, false},
+		{"unicode class", `\pL+`, false},
+		{"unicode class name", `\p{Greek}`, false},
+		{"quoted literal", `\Q.*\E`, false},
+		{"word boundary", `\bprod\b`, false},
+		{"not word boundary", `\Bprod`, false},
+		{"end of text", `prod\z`, false},
+		{"octal escape", `\101`, false},
+		{"braced hex escape", `\x{41}`, false},
+		{"negated class in brackets", `[\D]`, false},
+		{"large repeat", `a{256}`, false},
+		{"large repeat range", `a{1,1000}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(MatchRegexp, "env", tt.value)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	// Equality matchers take the value literally
+	_, err := New(MatchEqual, "env", `(?i)prod`)
+	assert.NoError(t, err)
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(MatchEqual, "", "db")
+	assert.Error(t, err)
+	_, err = New(MatchEqual, "team-name", "db")
+	assert.Error(t, err)
+	_, err = New(Type("=="), "team", "db")
+	assert.Error(t, err)
+}
+
+func TestAnchor(t *testing.T) {
+	assert.Equal(t, "^(?:prod|staging)$", Anchor("prod|staging"))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	ms, err := Parse(`team="db",env=~"prod.*"`)
+	require.NoError(t, err)
+
+	encoded, err := Encode(ms)
+	require.NoError(t, err)
+	decoded, err := Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, ms.String(), decoded.String())
+
+	negative, err := Parse(`team!="db"`)
+	require.NoError(t, err)
+	_, err = Encode(negative)
+	assert.Error(t, err)
+
+	_, err = Decode(map[string]interface{}{"matchers": "team=db"})
+	assert.Error(t, err)
+}
+
+func TestJoin(t *testing.T) {
+	assert.Equal(t, `team="db",env="prod"`, Join(`{team="db"}`, " ", `{ env="prod" }`, "{}"))
+}
@@ -176,6 +176,71 @@ var (
 		[]string{"client_ip"},
 	)
 
+	RateLimiterErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alertbot_rate_limiter_errors_total",
+			Help: "Total number of rate limit checks that failed and let the request through",
+		},
+		[]string{"backend"},
+	)
+
+	// Ingestion pipeline metrics
+	PipelineStageDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "alertbot_pipeline_stage_duration_seconds",
+			Help:    "Time spent in each alert ingestion stage per batch in seconds",
+			Buckets: []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0, 5.0, 10.0},
+		},
+		[]string{"stage"},
+	)
+
+	PipelineStageErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alertbot_pipeline_stage_errors_total",
+			Help: "Total number of alerts an ingestion stage failed to process",
+		},
+		[]string{"stage"},
+	)
+
+	EnrichmentLookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alertbot_enrichment_lookups_total",
+			Help: "Total number of enrichment lookups by rule and result",
+		},
+		[]string{"rule", "result"}, // hit, miss, error
+	)
+
+	RelabelDroppedAlerts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "alertbot_relabel_dropped_alerts_total",
+			Help: "Total number of received alerts discarded by relabel configs",
+		},
+	)
+
+	// Ingestion queue metrics
+	IngestionQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "alertbot_ingestion_queue_depth",
+			Help: "Number of received alerts waiting to be ingested",
+		},
+	)
+
+	IngestionQueueLag = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "alertbot_ingestion_queue_lag_seconds",
+			Help:    "Time received alerts waited in the ingestion queue in seconds",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1.0, 5.0, 10.0, 30.0, 60.0, 300.0},
+		},
+	)
+
+	IngestionQueueDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alertbot_ingestion_queue_dropped_total",
+			Help: "Total number of received alerts refused by the ingestion queue",
+		},
+		[]string{"reason"}, // full, closed, too_large
+	)
+
 	// Memory and performance metrics
 	MemoryUsage = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -301,6 +366,37 @@ var (
 		},
 		[]string{"job_name"},
 	)
+
+	// Cluster metrics
+	ClusterLeader = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "alertbot_cluster_leader",
+			Help: "Whether this replica is the cluster leader running singleton jobs",
+		},
+	)
+
+	ClusterMembers = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "alertbot_cluster_members",
+			Help: "Number of live replicas in the cluster",
+		},
+	)
+
+	NotificationClaims = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alertbot_notification_claims_total",
+			Help: "Total number of notification claims by result",
+		},
+		[]string{"result"}, // claimed, taken, error, released
+	)
+
+	EventBusMessages = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alertbot_event_bus_messages_total",
+			Help: "Total number of events exchanged with other replicas",
+		},
+		[]string{"direction", "payload"}, // sent/received, inline/reference/error
+	)
 )
 
 // RecordHTTPRequest records HTTP request metrics
@@ -395,6 +491,45 @@ func RecordRateLimitedRequest(clientIP string) {
 	RateLimitedRequests.WithLabelValues(clientIP).Inc()
 }
 
+// RecordPipelineStage records the duration of an ingestion stage over a
+// batch and the number of alerts it failed
+func RecordPipelineStage(stage string, duration float64, failed int) {
+	PipelineStageDuration.WithLabelValues(stage).Observe(duration)
+	if failed > 0 {
+		PipelineStageErrors.WithLabelValues(stage).Add(float64(failed))
+	}
+}
+
+// RecordRateLimiterError records a rate limit backend failure
+func RecordRateLimiterError(backend string) {
+	RateLimiterErrors.WithLabelValues(backend).Inc()
+}
+
+// RecordEnrichmentLookup records the result of an enrichment rule's lookup
+func RecordEnrichmentLookup(rule, result string) {
+	EnrichmentLookups.WithLabelValues(rule, result).Inc()
+}
+
+// RecordRelabelDropped records an alert discarded by relabel configs
+func RecordRelabelDropped() {
+	RelabelDroppedAlerts.Inc()
+}
+
+// UpdateIngestionQueueDepth sets the number of queued alerts
+func UpdateIngestionQueueDepth(depth float64) {
+	IngestionQueueDepth.Set(depth)
+}
+
+// RecordIngestionQueueLag records how long a batch waited in the queue
+func RecordIngestionQueueLag(seconds float64) {
+	IngestionQueueLag.Observe(seconds)
+}
+
+// RecordIngestionQueueDrop records alerts the ingestion queue refused
+func RecordIngestionQueueDrop(reason string, alerts int) {
+	IngestionQueueDropped.WithLabelValues(reason).Add(float64(alerts))
+}
+
 // UpdateMemoryUsage updates memory usage metrics
 func UpdateMemoryUsage(memType string, bytes float64) {
 	MemoryUsage.WithLabelValues(memType).Set(bytes)
@@ -464,4 +599,28 @@ func RecordSecurityEvent(eventType string) {
 func RecordBackgroundJob(jobName, status string, duration float64) {
 	BackgroundJobs.WithLabelValues(jobName, status).Inc()
 	BackgroundJobDuration.WithLabelValues(jobName).Observe(duration)
-}
\ No newline at end of file
+}
+
+// UpdateClusterLeader records whether this replica is the leader
+func UpdateClusterLeader(leader bool) {
+	value := 0.0
+	if leader {
+		value = 1.0
+	}
+	ClusterLeader.Set(value)
+}
+
+// UpdateClusterMembers records the number of live replicas
+func UpdateClusterMembers(count float64) {
+	ClusterMembers.Set(count)
+}
+
+// RecordNotificationClaim records the result of a notification claim
+func RecordNotificationClaim(result string) {
+	NotificationClaims.WithLabelValues(result).Inc()
+}
+
+// RecordEventBusMessage records an event sent to or received from other replicas
+func RecordEventBusMessage(direction, payload string) {
+	EventBusMessages.WithLabelValues(direction, payload).Inc()
+}
@@ -10,7 +10,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func JWTAuth(cfg *config.Config) gin.HandlerFunc {
+// RevocationChecker reports whether the session token with the given ID was revoked
+type RevocationChecker func(tokenID string) bool
+
+func JWTAuth(cfg *config.Config, isRevoked RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -40,6 +43,9 @@ func JWTAuth(cfg *config.Config) gin.HandlerFunc {
 
 		token := parts[1]
 		claims, err := utils.ParseJWT(token, cfg.JWT.Secret)
+		if err == nil && claims.IsTicket() {
+			err = utils.ErrTicketNotAllowed
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -52,14 +58,27 @@ func JWTAuth(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if isRevoked != nil && isRevoked(claims.ID) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "TOKEN_REVOKED",
+					"message": "Token has been revoked",
+				},
+			})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
 
-func OptionalJWTAuth(cfg *config.Config) gin.HandlerFunc {
+func OptionalJWTAuth(cfg *config.Config, isRevoked RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -75,7 +94,7 @@ func OptionalJWTAuth(cfg *config.Config) gin.HandlerFunc {
 
 		token := parts[1]
 		claims, err := utils.ParseJWT(token, cfg.JWT.Secret)
-		if err != nil {
+		if err != nil || claims.IsTicket() || (isRevoked != nil && isRevoked(claims.ID)) {
 			c.Next()
 			return
 		}
@@ -83,6 +102,7 @@ func OptionalJWTAuth(cfg *config.Config) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
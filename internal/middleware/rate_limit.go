@@ -1,152 +1,230 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"alertbot/internal/config"
 	"alertbot/internal/metrics"
 
 	"github.com/gin-gonic/gin"
-	"github.com/patrickmn/go-cache"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/time/rate"
+	"gorm.io/gorm"
 )
 
-// RateLimitRule defines rate limiting rules for different scenarios
+// RateLimitRule defines rate limiting rules for different scenarios. A rule
+// with no RPS does not limit.
 type RateLimitRule struct {
 	RPS   int `json:"rps"`
 	Burst int `json:"burst"`
 }
 
+// EndpointRule limits requests to the endpoints matching Path and Method
+type EndpointRule struct {
+	Path   string `json:"path"`
+	Method string `json:"method,omitempty"`
+	RateLimitRule
+}
+
 // RateLimitConfig holds all rate limiting configurations
 type RateLimitConfig struct {
-	Global        RateLimitRule            `json:"global"`
-	PerUser       RateLimitRule            `json:"per_user"`
-	PerEndpoint   map[string]RateLimitRule `json:"per_endpoint"`
-	Notification  RateLimitRule            `json:"notification"`
-	BurstProtection RateLimitRule          `json:"burst_protection"`
+	Global          RateLimitRule  `json:"global"`
+	PerUser         RateLimitRule  `json:"per_user"`
+	PerEndpoint     []EndpointRule `json:"per_endpoint"`
+	Notification    RateLimitRule  `json:"notification"`
+	BurstProtection RateLimitRule  `json:"burst_protection"`
+}
+
+// LimitResult describes a bucket after a request took tokens from it
+type LimitResult struct {
+	Allowed bool
+	// Limit is the bucket size and Remaining the requests left in it
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until a denied request would be allowed
+	RetryAfter time.Duration
 }
 
-// MemoryRateLimiter implements in-memory rate limiting with multiple strategies
-type MemoryRateLimiter struct {
-	limiters *cache.Cache
-	mu       sync.RWMutex
-	config   RateLimitConfig
-	logger   *logrus.Logger
+// LimiterBackend keeps token buckets. Take removes n tokens from the bucket
+// for key if it holds enough; Refund puts back tokens a request took but
+// did not use, up to the bucket size.
+type LimiterBackend interface {
+	Take(ctx context.Context, key string, rule RateLimitRule, n int) (LimitResult, error)
+	Refund(ctx context.Context, key string, rule RateLimitRule, n int) error
+	Name() string
 }
 
-func NewMemoryRateLimiter(cfg *config.Config, logger *logrus.Logger) *MemoryRateLimiter {
-	// Default rate limit configuration
-	rateLimitConfig := RateLimitConfig{
+// RateLimiter applies the configured rules using a backend shared by every
+// replica, or local to this one for the memory backend
+type RateLimiter struct {
+	backend LimiterBackend
+	config  RateLimitConfig
+	logger  *logrus.Logger
+}
+
+// NewRateLimitConfig builds the rules from the configuration file
+func NewRateLimitConfig(cfg config.RateLimit) RateLimitConfig {
+	endpoints := make([]EndpointRule, 0, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		endpoints = append(endpoints, EndpointRule{
+			Path:          endpoint.Path,
+			Method:        strings.ToUpper(endpoint.Method),
+			RateLimitRule: RateLimitRule{RPS: endpoint.RPS, Burst: endpoint.Burst},
+		})
+	}
+
+	return RateLimitConfig{
 		Global: RateLimitRule{
-			RPS:   cfg.RateLimit.RPS,
-			Burst: cfg.RateLimit.Burst,
+			RPS:   cfg.RPS,
+			Burst: cfg.Burst,
 		},
 		PerUser: RateLimitRule{
-			RPS:   cfg.RateLimit.RPS / 2, // Half of global limit per user
-			Burst: cfg.RateLimit.Burst / 2,
-		},
-		PerEndpoint: map[string]RateLimitRule{
-			"/api/v1/alerts": {
-				RPS:   cfg.RateLimit.RPS * 2, // Alerts endpoint gets higher limit
-				Burst: cfg.RateLimit.Burst * 2,
-			},
-			"/api/v1/channels/*/test": {
-				RPS:   5,  // Test endpoints are limited to prevent abuse
-				Burst: 10,
-			},
-			"/api/v1/auth/login": {
-				RPS:   10, // Login attempts are strictly limited
-				Burst: 20,
-			},
+			RPS:   cfg.UserRPS,
+			Burst: cfg.UserBurst,
 		},
+		PerEndpoint: endpoints,
 		Notification: RateLimitRule{
-			RPS:   30,  // 30 notifications per second
-			Burst: 100, // Allow bursts up to 100
+			RPS:   cfg.NotificationRPS,
+			Burst: cfg.NotificationBurst,
 		},
 		BurstProtection: RateLimitRule{
-			RPS:   cfg.RateLimit.RPS * 5, // 5x normal rate for burst detection
-			Burst: 1,                     // Only allow 1 burst request
+			RPS:   cfg.RPS * 5, // 5x normal rate for burst detection
+			Burst: 1,           // Only allow 1 burst request
 		},
 	}
+}
+
+// NewLimiterBackend creates the backend selected by cfg.Backend. The
+// postgres backend stores buckets through db.
+func NewLimiterBackend(cfg config.RateLimit, db *gorm.DB) (LimiterBackend, error) {
+	switch cfg.Backend {
+	case "", "memory":
+		return NewMemoryRateLimiter(), nil
+	case "postgres":
+		if db == nil || db.Dialector.Name() != "postgres" {
+			return nil, fmt.Errorf("rate limit backend postgres needs a Postgres database")
+		}
+		return NewPostgresRateLimiter(db), nil
+	case "redis":
+		return NewRedisRateLimiter(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("unsupported rate limit backend %q", cfg.Backend)
+	}
+}
 
-	return &MemoryRateLimiter{
-		limiters: cache.New(time.Hour, 10*time.Minute),
-		config:   rateLimitConfig,
-		logger:   logger,
+func NewRateLimiter(cfg config.RateLimit, backend LimiterBackend, logger *logrus.Logger) *RateLimiter {
+	return &RateLimiter{
+		backend: backend,
+		config:  NewRateLimitConfig(cfg),
+		logger:  logger,
 	}
 }
 
-func (rl *MemoryRateLimiter) GetLimiter(key string, rule RateLimitRule) *rate.Limiter {
-	rl.mu.RLock()
-	if limiter, found := rl.limiters.Get(key); found {
-		rl.mu.RUnlock()
-		return limiter.(*rate.Limiter)
+// Take removes n tokens from the bucket for key. Backend errors let the
+// request through, so an unreachable store does not take the API down.
+func (rl *RateLimiter) Take(ctx context.Context, key string, rule RateLimitRule, n int) LimitResult {
+	if rule.RPS <= 0 {
+		return LimitResult{Allowed: true}
 	}
-	rl.mu.RUnlock()
-
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	
-	// Double-check after acquiring write lock
-	if limiter, found := rl.limiters.Get(key); found {
-		return limiter.(*rate.Limiter)
+
+	result, err := rl.backend.Take(ctx, key, rule, n)
+	if err != nil {
+		rl.logger.WithError(err).WithFields(logrus.Fields{
+			"backend": rl.backend.Name(),
+			"key":     key,
+		}).Warn("Rate limit backend failed, allowing request")
+		metrics.RecordRateLimiterError(rl.backend.Name())
+		return LimitResult{Allowed: true}
+	}
+	return result
+}
+
+// Refund puts back n tokens taken from the bucket for key. Backend errors
+// are only logged; the tokens come back as the bucket refills.
+func (rl *RateLimiter) Refund(ctx context.Context, key string, rule RateLimitRule, n int) {
+	if rule.RPS <= 0 {
+		return
 	}
 
-	limiter := rate.NewLimiter(rate.Limit(rule.RPS), rule.Burst)
-	rl.limiters.Set(key, limiter, cache.DefaultExpiration)
-	return limiter
+	if err := rl.backend.Refund(ctx, key, rule, n); err != nil {
+		rl.logger.WithError(err).WithFields(logrus.Fields{
+			"backend": rl.backend.Name(),
+			"key":     key,
+		}).Warn("Rate limit backend failed to refund tokens")
+		metrics.RecordRateLimiterError(rl.backend.Name())
+	}
 }
 
-func (rl *MemoryRateLimiter) Allow(key string, rule RateLimitRule) bool {
-	return rl.GetLimiter(key, rule).Allow()
+func (rl *RateLimiter) Allow(ctx context.Context, key string, rule RateLimitRule) bool {
+	return rl.Take(ctx, key, rule, 1).Allowed
 }
 
 // AllowN checks if n requests can be made
-func (rl *MemoryRateLimiter) AllowN(key string, rule RateLimitRule, n int) bool {
-	return rl.GetLimiter(key, rule).AllowN(time.Now(), n)
+func (rl *RateLimiter) AllowN(ctx context.Context, key string, rule RateLimitRule, n int) bool {
+	return rl.Take(ctx, key, rule, n).Allowed
 }
 
 // GetRuleForEndpoint returns the appropriate rate limit rule for an endpoint
-func (rl *MemoryRateLimiter) GetRuleForEndpoint(path string) RateLimitRule {
-	// Check for exact match first
-	if rule, exists := rl.config.PerEndpoint[path]; exists {
-		return rule
-	}
-	
-	// Check for pattern matches
-	for pattern, rule := range rl.config.PerEndpoint {
-		if matchEndpointPattern(pattern, path) {
-			return rule
+func (rl *RateLimiter) GetRuleForEndpoint(method, path string) RateLimitRule {
+	for _, endpoint := range rl.config.PerEndpoint {
+		if endpoint.Method != "" && endpoint.Method != method {
+			continue
+		}
+		if endpoint.Path == path || matchEndpointPattern(endpoint.Path, path) {
+			return endpoint.RateLimitRule
 		}
 	}
-	
+
 	// Return global rule as default
 	return rl.config.Global
 }
 
 // GetNotificationRule returns the notification rate limit rule
-func (rl *MemoryRateLimiter) GetNotificationRule() RateLimitRule {
+func (rl *RateLimiter) GetNotificationRule() RateLimitRule {
 	return rl.config.Notification
 }
 
 // GetUserRule returns the per-user rate limit rule
-func (rl *MemoryRateLimiter) GetUserRule() RateLimitRule {
+func (rl *RateLimiter) GetUserRule() RateLimitRule {
 	return rl.config.PerUser
 }
 
 // GetBurstProtectionRule returns the burst protection rule
-func (rl *MemoryRateLimiter) GetBurstProtectionRule() RateLimitRule {
+func (rl *RateLimiter) GetBurstProtectionRule() RateLimitRule {
 	return rl.config.BurstProtection
 }
 
-var globalRateLimiter *MemoryRateLimiter
+var globalRateLimiter *RateLimiter
+
+// rateLimitCheck is one bucket a request is counted against
+type rateLimitCheck struct {
+	key       string
+	rule      RateLimitRule
+	limitType string
+}
 
+// ConfigureRateLimiter sets up the limiter used by the rate limit
+// middleware. Without it the middleware keeps limits in memory.
+func ConfigureRateLimiter(cfg *config.Config, db *gorm.DB, logger *logrus.Logger) error {
+	backend, err := NewLimiterBackend(cfg.RateLimit, db)
+	if err != nil {
+		return err
+	}
+	globalRateLimiter = NewRateLimiter(cfg.RateLimit, backend, logger)
+	logger.WithField("backend", backend.Name()).Info("Rate limiter configured")
+	return nil
+}
+
+// RateLimit counts every request against the global and per-endpoint
+// limits of its client IP. The per-user limit needs the authenticated user
+// and is applied by UserRateLimit after the auth middleware.
 func RateLimit(cfg *config.Config, logger *logrus.Logger) gin.HandlerFunc {
 	if !cfg.RateLimit.Enabled {
 		return func(c *gin.Context) {
@@ -156,80 +234,149 @@ func RateLimit(cfg *config.Config, logger *logrus.Logger) gin.HandlerFunc {
 
 	// Initialize rate limiter
 	if globalRateLimiter == nil {
-		globalRateLimiter = NewMemoryRateLimiter(cfg, logger)
+		globalRateLimiter = NewRateLimiter(cfg.RateLimit, NewMemoryRateLimiter(), logger)
 	}
 
 	return func(c *gin.Context) {
 		clientIP := c.ClientIP()
 		path := c.Request.URL.Path
 		method := c.Request.Method
-		
+
 		// Get appropriate rate limit rule for this endpoint
-		rule := globalRateLimiter.GetRuleForEndpoint(path)
-		
-		// Create composite key for rate limiting
-		keys := []string{
-			fmt.Sprintf("global:%s", clientIP),                    // Global per-IP limit
-			fmt.Sprintf("endpoint:%s:%s:%s", method, path, clientIP), // Per-endpoint per-IP limit
+		rule := globalRateLimiter.GetRuleForEndpoint(method, path)
+
+		checks := []rateLimitCheck{
+			{fmt.Sprintf("global:%s", clientIP), globalRateLimiter.config.Global, "global"}, // Global per-IP limit
+			{fmt.Sprintf("endpoint:%s:%s:%s", method, path, clientIP), rule, "endpoint"},    // Per-endpoint per-IP limit
 		}
-		
-		// Add user-based key if user is authenticated
-		if userID, exists := c.Get("user_id"); exists {
-			keys = append(keys, fmt.Sprintf("user:%v", userID))
+		if !applyRateLimits(c, logger, checks) {
+			return
 		}
-		
-		// Check each rate limit
-		for i, key := range keys {
-			var currentRule RateLimitRule
-			var limitType string
-			
-			switch i {
-			case 0: // Global limit
-				currentRule = globalRateLimiter.config.Global
-				limitType = "global"
-			case 1: // Endpoint limit
-				currentRule = rule
-				limitType = "endpoint"
-			case 2: // User limit
-				currentRule = globalRateLimiter.GetUserRule()
-				limitType = "user"
-			}
-			
-			if !globalRateLimiter.Allow(key, currentRule) {
-				c.Header("X-RateLimit-Limit", strconv.Itoa(currentRule.RPS))
-				c.Header("X-RateLimit-Remaining", "0")
-				c.Header("X-RateLimit-Type", limitType)
-				c.Header("Retry-After", "60")
-
-				logger.WithFields(logrus.Fields{
-					"client_ip":   clientIP,
-					"path":        path,
-					"method":      method,
-					"limit_type":  limitType,
-					"rate_limit":  currentRule.RPS,
-				}).Warn("Rate limit exceeded")
-
-				// Record rate limit metric
-				metrics.RecordRateLimitedRequest(clientIP)
-
-				c.JSON(http.StatusTooManyRequests, gin.H{
-					"success": false,
-					"error": gin.H{
-						"code":        "RATE_LIMITED",
-						"message":     fmt.Sprintf("%s rate limit exceeded, please try again later", limitType),
-						"limit_type":  limitType,
-						"retry_after": 60,
-					},
-				})
-				c.Abort()
-				return
-			}
+		c.Next()
+	}
+}
+
+// userRateLimitedKey marks requests already counted against their user, so
+// nested route groups do not count them twice
+const userRateLimitedKey = "user_rate_limited"
+
+// UserRateLimit counts requests against the per-user limit of the user set
+// by JWTAuth or OptionalJWTAuth, so it must run after them. Anonymous
+// requests pass through.
+func UserRateLimit(cfg *config.Config, logger *logrus.Logger) gin.HandlerFunc {
+	if !cfg.RateLimit.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
 		}
+	}
 
+	if globalRateLimiter == nil {
+		globalRateLimiter = NewRateLimiter(cfg.RateLimit, NewMemoryRateLimiter(), logger)
+	}
+
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists || c.GetBool(userRateLimitedKey) {
+			c.Next()
+			return
+		}
+		c.Set(userRateLimitedKey, true)
+
+		checks := []rateLimitCheck{
+			{fmt.Sprintf("user:%v", userID), globalRateLimiter.GetUserRule(), "user"},
+		}
+		if !applyRateLimits(c, logger, checks) {
+			return
+		}
 		c.Next()
 	}
 }
 
+// applyRateLimits takes a token from every bucket in checks. When one of
+// them denies the request, the tokens already taken are refunded so the
+// rejected request uses up no quota, and a 429 response is written.
+// Otherwise the RateLimit-* headers describe the bucket closest to running
+// out, including buckets counted by an earlier middleware.
+func applyRateLimits(c *gin.Context, logger *logrus.Logger, checks []rateLimitCheck) bool {
+	ctx := c.Request.Context()
+
+	var tightest *LimitResult
+	var tightestRule RateLimitRule
+	for i, check := range checks {
+		result := globalRateLimiter.Take(ctx, check.key, check.rule, 1)
+
+		if !result.Allowed {
+			for _, taken := range checks[:i] {
+				globalRateLimiter.Refund(ctx, taken.key, taken.rule, 1)
+			}
+			rejectRateLimited(c, logger, check, result)
+			return false
+		}
+
+		if result.Limit > 0 && (tightest == nil || result.Remaining < tightest.Remaining) {
+			r := result
+			tightest, tightestRule = &r, check.rule
+		}
+	}
+
+	if tightest != nil {
+		remaining, err := strconv.Atoi(c.Writer.Header().Get("RateLimit-Remaining"))
+		if err != nil || tightest.Remaining < remaining {
+			setRateLimitHeaders(c, *tightest, tightestRule)
+		}
+	}
+	return true
+}
+
+// rejectRateLimited answers a request denied by check with 429
+func rejectRateLimited(c *gin.Context, logger *logrus.Logger, check rateLimitCheck, result LimitResult) {
+	clientIP := c.ClientIP()
+
+	setRateLimitHeaders(c, result, check.rule)
+	retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.Header("X-RateLimit-Type", check.limitType)
+
+	logger.WithFields(logrus.Fields{
+		"client_ip":  clientIP,
+		"path":       c.Request.URL.Path,
+		"method":     c.Request.Method,
+		"limit_type": check.limitType,
+		"rate_limit": check.rule.RPS,
+	}).Warn("Rate limit exceeded")
+
+	// Record rate limit metric
+	metrics.RecordRateLimitedRequest(clientIP)
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":        "RATE_LIMITED",
+			"message":     fmt.Sprintf("%s rate limit exceeded, please try again later", check.limitType),
+			"limit_type":  check.limitType,
+			"retry_after": retryAfter,
+		},
+	})
+	c.Abort()
+}
+
+// setRateLimitHeaders sets the RateLimit-* headers from the IETF draft.
+// A token bucket of size burst refills completely in burst/rps seconds,
+// which is reported as the policy window.
+func setRateLimitHeaders(c *gin.Context, result LimitResult, rule RateLimitRule) {
+	window := int(math.Ceil(float64(rule.Burst) / float64(rule.RPS)))
+	if window < 1 {
+		window = 1
+	}
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit, window))
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+}
+
 // NotificationRateLimit provides rate limiting specifically for notifications
 func NotificationRateLimit(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -237,22 +384,22 @@ func NotificationRateLimit(logger *logrus.Logger) gin.HandlerFunc {
 			c.Next()
 			return
 		}
-		
+
 		channelID := c.Param("id")
 		if channelID == "" {
 			c.Next()
 			return
 		}
-		
+
 		key := fmt.Sprintf("notification:%s", channelID)
 		rule := globalRateLimiter.GetNotificationRule()
-		
-		if !globalRateLimiter.Allow(key, rule) {
+
+		if !globalRateLimiter.Allow(c.Request.Context(), key, rule) {
 			logger.WithFields(logrus.Fields{
 				"channel_id": channelID,
 				"rate_limit": rule.RPS,
 			}).Warn("Notification rate limit exceeded")
-			
+
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"success": false,
 				"error": gin.H{
@@ -263,7 +410,7 @@ func NotificationRateLimit(logger *logrus.Logger) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		
+
 		c.Next()
 	}
 }
@@ -275,18 +422,18 @@ func BurstProtection(logger *logrus.Logger) gin.HandlerFunc {
 			c.Next()
 			return
 		}
-		
+
 		clientIP := c.ClientIP()
 		key := fmt.Sprintf("burst:%s", clientIP)
 		rule := globalRateLimiter.GetBurstProtectionRule()
-		
+
 		// Check if this looks like a burst attack (many requests in short time)
-		if !globalRateLimiter.AllowN(key, rule, 10) { // 10 requests threshold
+		if !globalRateLimiter.AllowN(c.Request.Context(), key, rule, 10) { // 10 requests threshold
 			logger.WithFields(logrus.Fields{
 				"client_ip": clientIP,
 				"path":      c.Request.URL.Path,
 			}).Warn("Potential burst attack detected")
-			
+
 			// Temporarily block this IP
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"success": false,
@@ -298,7 +445,7 @@ func BurstProtection(logger *logrus.Logger) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		
+
 		c.Next()
 	}
 }
@@ -307,11 +454,11 @@ func BurstProtection(logger *logrus.Logger) gin.HandlerFunc {
 func matchEndpointPattern(pattern, path string) bool {
 	patternParts := strings.Split(pattern, "/")
 	pathParts := strings.Split(path, "/")
-	
+
 	if len(patternParts) != len(pathParts) {
 		return false
 	}
-	
+
 	for i, part := range patternParts {
 		if part == "*" {
 			continue // Wildcard matches anything
@@ -320,6 +467,70 @@ func matchEndpointPattern(pattern, path string) bool {
 			return false
 		}
 	}
-	
+
 	return true
-}
\ No newline at end of file
+}
+
+// Backends keep each bucket as its theoretical arrival time (TAT), the
+// Unix time in seconds at which the bucket is full again. A request for n
+// tokens moves the TAT n intervals later and is allowed if that stays
+// within one bucket's worth of the current time (GCRA).
+
+// gcraParams returns the seconds per token and the seconds a full bucket
+// holds for rule
+func gcraParams(rule RateLimitRule) (interval, tolerance float64) {
+	burst := rule.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	interval = 1 / float64(rule.RPS)
+	return interval, interval * float64(burst)
+}
+
+// gcraTake returns the TAT after taking n tokens from a bucket with the
+// given TAT, and whether the request is allowed
+func gcraTake(rule RateLimitRule, n int, now, tat float64) (float64, bool) {
+	interval, tolerance := gcraParams(rule)
+	if tat < now {
+		tat = now
+	}
+	newTat := tat + float64(n)*interval
+	if newTat-now > tolerance {
+		return tat, false
+	}
+	return newTat, true
+}
+
+// gcraRefund returns the TAT after putting n tokens back into a bucket with
+// the given TAT. A TAT at or before now is a full bucket.
+func gcraRefund(rule RateLimitRule, n int, now, tat float64) float64 {
+	interval, _ := gcraParams(rule)
+	return math.Max(tat-float64(n)*interval, now)
+}
+
+// gcraResult describes a bucket with the given TAT after a request for n
+// tokens was allowed or denied
+func gcraResult(rule RateLimitRule, n int, now, tat float64, allowed bool) LimitResult {
+	interval, tolerance := gcraParams(rule)
+	reset := math.Max(tat-now, 0)
+
+	result := LimitResult{
+		Allowed:   allowed,
+		Limit:     int(math.Round(tolerance / interval)),
+		Remaining: int(math.Max(math.Floor((tolerance-reset)/interval+1e-9), 0)),
+		Reset:     seconds(reset),
+	}
+	if !allowed {
+		result.RetryAfter = seconds(reset + float64(n)*interval - tolerance)
+	}
+	return result
+}
+
+// unixSeconds returns t as fractional Unix seconds
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// MemoryRateLimiter keeps buckets in process memory, so every replica
+// enforces the limits separately
+type MemoryRateLimiter struct {
+	buckets *cache.Cache
+	mu      sync.Mutex
+}
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: cache.New(time.Hour, 10*time.Minute),
+	}
+}
+
+func (rl *MemoryRateLimiter) Name() string {
+	return "memory"
+}
+
+func (rl *MemoryRateLimiter) Take(_ context.Context, key string, rule RateLimitRule, n int) (LimitResult, error) {
+	now := unixSeconds(time.Now())
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	tat := now
+	if stored, found := rl.buckets.Get(key); found {
+		tat = stored.(float64)
+	}
+
+	tat, allowed := gcraTake(rule, n, now, tat)
+	if allowed {
+		// A bucket past its TAT is full, the same as a missing one
+		rl.buckets.Set(key, tat, seconds(tat-now)+time.Second)
+	}
+	return gcraResult(rule, n, now, tat, allowed), nil
+}
+
+func (rl *MemoryRateLimiter) Refund(_ context.Context, key string, rule RateLimitRule, n int) error {
+	now := unixSeconds(time.Now())
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	stored, found := rl.buckets.Get(key)
+	if !found {
+		return nil
+	}
+	tat := gcraRefund(rule, n, now, stored.(float64))
+	if tat <= now {
+		rl.buckets.Delete(key)
+		return nil
+	}
+	rl.buckets.Set(key, tat, seconds(tat-now)+time.Second)
+	return nil
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"alertbot/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// rateLimitPruneInterval is how often full buckets are deleted
+const rateLimitPruneInterval = time.Minute
+
+// takeBucketSQL takes tokens from a bucket in one statement. The update
+// only happens when the request is allowed, so no row comes back for a
+// denied request.
+const takeBucketSQL = `
+INSERT INTO rate_limit_buckets (bucket_key, tat) VALUES (@key, @now + @cost)
+ON CONFLICT (bucket_key) DO UPDATE
+SET tat = GREATEST(rate_limit_buckets.tat, @now) + @cost
+WHERE GREATEST(rate_limit_buckets.tat, @now) + @cost - @now <= @tolerance
+RETURNING tat`
+
+// refundBucketSQL puts tokens back into a bucket; a TAT of now is a full
+// bucket
+const refundBucketSQL = `
+UPDATE rate_limit_buckets SET tat = GREATEST(tat - @cost, @now)
+WHERE bucket_key = @key`
+
+// PostgresRateLimiter keeps buckets in the rate_limit_buckets table, so
+// the limits hold across every replica using the database
+type PostgresRateLimiter struct {
+	db        *gorm.DB
+	lastPrune atomic.Int64
+}
+
+func NewPostgresRateLimiter(db *gorm.DB) *PostgresRateLimiter {
+	return &PostgresRateLimiter{db: db}
+}
+
+func (rl *PostgresRateLimiter) Name() string {
+	return "postgres"
+}
+
+func (rl *PostgresRateLimiter) Take(ctx context.Context, key string, rule RateLimitRule, n int) (LimitResult, error) {
+	now := unixSeconds(time.Now())
+	interval, tolerance := gcraParams(rule)
+	cost := float64(n) * interval
+	if cost > tolerance {
+		// More tokens than the bucket holds
+		return gcraResult(rule, n, now, now, false), nil
+	}
+
+	rl.prune(now)
+
+	db := rl.db.WithContext(ctx)
+	var tats []float64
+	err := db.Raw(takeBucketSQL,
+		sql.Named("key", key),
+		sql.Named("now", now),
+		sql.Named("cost", cost),
+		sql.Named("tolerance", tolerance),
+	).Scan(&tats).Error
+	if err != nil {
+		return LimitResult{}, err
+	}
+	if len(tats) > 0 {
+		return gcraResult(rule, n, now, tats[0], true), nil
+	}
+
+	var bucket models.RateLimitBucket
+	if err := db.Where("bucket_key = ?", key).Take(&bucket).Error; err != nil {
+		return LimitResult{}, err
+	}
+	return gcraResult(rule, n, now, bucket.TAT, false), nil
+}
+
+func (rl *PostgresRateLimiter) Refund(ctx context.Context, key string, rule RateLimitRule, n int) error {
+	interval, _ := gcraParams(rule)
+	return rl.db.WithContext(ctx).Exec(refundBucketSQL,
+		sql.Named("key", key),
+		sql.Named("now", unixSeconds(time.Now())),
+		sql.Named("cost", float64(n)*interval),
+	).Error
+}
+
+// prune deletes full buckets at most once per interval per replica
+func (rl *PostgresRateLimiter) prune(now float64) {
+	last := rl.lastPrune.Load()
+	if now-float64(last) < rateLimitPruneInterval.Seconds() || !rl.lastPrune.CompareAndSwap(last, int64(now)) {
+		return
+	}
+	go rl.db.Where("tat < ?", now).Delete(&models.RateLimitBucket{})
+}
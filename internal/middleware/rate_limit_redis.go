@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces the bucket keys
+const redisKeyPrefix = "alertbot:ratelimit:"
+
+// takeBucketScript takes tokens from a bucket atomically and returns
+// whether the request was allowed and the bucket's TAT. Numbers travel as
+// strings since Redis truncates Lua numbers to integers. The key expires
+// when the bucket is full again.
+var takeBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local cost = tonumber(ARGV[2])
+local tolerance = tonumber(ARGV[3])
+local tat = tonumber(redis.call("GET", KEYS[1])) or now
+if tat < now then
+  tat = now
+end
+local new_tat = tat + cost
+if new_tat - now > tolerance then
+  return {0, tostring(tat)}
+end
+redis.call("SET", KEYS[1], tostring(new_tat), "PX", math.ceil((new_tat - now) * 1000))
+return {1, tostring(new_tat)}
+`)
+
+// refundBucketScript puts tokens back into a bucket, deleting the key once
+// the bucket is full
+var refundBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local cost = tonumber(ARGV[2])
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat then
+  return 0
+end
+local new_tat = tat - cost
+if new_tat <= now then
+  redis.call("DEL", KEYS[1])
+  return 0
+end
+redis.call("SET", KEYS[1], tostring(new_tat), "PX", math.ceil((new_tat - now) * 1000))
+return 1
+`)
+
+// RedisRateLimiter keeps buckets in Redis or a compatible server, so the
+// limits hold across every replica using it
+type RedisRateLimiter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisRateLimiter connects to the server at url, e.g.
+// redis://:password@localhost:6379/0
+func NewRedisRateLimiter(url string) (*RedisRateLimiter, error) {
+	if url == "" {
+		return nil, fmt.Errorf("rate limit backend redis needs redis_url")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis_url: %w", err)
+	}
+	return NewRedisRateLimiterWithClient(redis.NewClient(opts)), nil
+}
+
+// NewRedisRateLimiterWithClient uses an existing client
+func NewRedisRateLimiterWithClient(client redis.UniversalClient) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client}
+}
+
+func (rl *RedisRateLimiter) Name() string {
+	return "redis"
+}
+
+func (rl *RedisRateLimiter) Take(ctx context.Context, key string, rule RateLimitRule, n int) (LimitResult, error) {
+	now := unixSeconds(time.Now())
+	interval, tolerance := gcraParams(rule)
+	cost := float64(n) * interval
+
+	reply, err := takeBucketScript.Run(ctx, rl.client, []string{redisKeyPrefix + key},
+		strconv.FormatFloat(now, 'f', -1, 64),
+		strconv.FormatFloat(cost, 'f', -1, 64),
+		strconv.FormatFloat(tolerance, 'f', -1, 64),
+	).Slice()
+	if err != nil {
+		return LimitResult{}, err
+	}
+	if len(reply) != 2 {
+		return LimitResult{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tatText, _ := reply[1].(string)
+	tat, err := strconv.ParseFloat(tatText, 64)
+	if err != nil {
+		return LimitResult{}, fmt.Errorf("invalid bucket time %q: %w", tatText, err)
+	}
+	return gcraResult(rule, n, now, tat, allowed == 1), nil
+}
+
+func (rl *RedisRateLimiter) Refund(ctx context.Context, key string, rule RateLimitRule, n int) error {
+	now := unixSeconds(time.Now())
+	interval, _ := gcraParams(rule)
+
+	return refundBucketScript.Run(ctx, rl.client, []string{redisKeyPrefix + key},
+		strconv.FormatFloat(now, 'f', -1, 64),
+		strconv.FormatFloat(float64(n)*interval, 'f', -1, 64),
+	).Err()
+}
@@ -0,0 +1,332 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/metrics"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forEachBackend runs fn against the memory backend and a Redis backend
+// backed by an in-process server
+func forEachBackend(t *testing.T, fn func(t *testing.T, backend LimiterBackend)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemoryRateLimiter())
+	})
+
+	t.Run("redis", func(t *testing.T) {
+		backend, _ := newRedisBackend(t)
+		fn(t, backend)
+	})
+}
+
+func newRedisBackend(t *testing.T) (*RedisRateLimiter, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisRateLimiterWithClient(client), server
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestBackendTakeSequence(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend LimiterBackend) {
+		ctx := context.Background()
+		rule := RateLimitRule{RPS: 1, Burst: 3}
+
+		// A full bucket allows burst requests, then denies
+		for want := 2; want >= 0; want-- {
+			result, err := backend.Take(ctx, "client", rule, 1)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+			assert.Equal(t, want, result.Remaining)
+			assert.Zero(t, result.RetryAfter)
+		}
+
+		result, err := backend.Take(ctx, "client", rule, 1)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.InDelta(t, time.Second, result.RetryAfter, float64(100*time.Millisecond))
+		assert.InDelta(t, 3*time.Second, result.Reset, float64(100*time.Millisecond))
+
+		// A denied request takes no tokens, and other keys are unaffected
+		result, err = backend.Take(ctx, "other", rule, 2)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1, result.Remaining)
+
+		// More tokens than the bucket holds are never allowed
+		result, err = backend.Take(ctx, "large", rule, 4)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+	})
+}
+
+func TestBackendRefill(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend LimiterBackend) {
+		ctx := context.Background()
+		rule := RateLimitRule{RPS: 20, Burst: 1}
+
+		result, err := backend.Take(ctx, "client", rule, 1)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+
+		result, err = backend.Take(ctx, "client", rule, 1)
+		require.NoError(t, err)
+		require.False(t, result.Allowed)
+
+		time.Sleep(result.RetryAfter + 10*time.Millisecond)
+
+		result, err = backend.Take(ctx, "client", rule, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+}
+
+func TestBackendRefund(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend LimiterBackend) {
+		ctx := context.Background()
+		rule := RateLimitRule{RPS: 1, Burst: 3}
+
+		// Refunding a bucket that was never used is a no-op
+		require.NoError(t, backend.Refund(ctx, "client", rule, 1))
+
+		result, err := backend.Take(ctx, "client", rule, 3)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+
+		require.NoError(t, backend.Refund(ctx, "client", rule, 1))
+		result, err = backend.Take(ctx, "client", rule, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+
+		// Refunds never fill a bucket past its size
+		require.NoError(t, backend.Refund(ctx, "client", rule, 10))
+		result, err = backend.Take(ctx, "client", rule, 3)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		result, err = backend.Take(ctx, "client", rule, 1)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+	})
+}
+
+func TestRedisBucketExpires(t *testing.T) {
+	backend, server := newRedisBackend(t)
+	rule := RateLimitRule{RPS: 1, Burst: 3}
+
+	_, err := backend.Take(context.Background(), "client", rule, 2)
+	require.NoError(t, err)
+
+	key := redisKeyPrefix + "client"
+	require.True(t, server.Exists(key))
+	assert.InDelta(t, 2*time.Second, server.TTL(key), float64(100*time.Millisecond))
+
+	server.FastForward(3 * time.Second)
+	assert.False(t, server.Exists(key))
+}
+
+// newLimitedRouter serves GET /ping behind the rate limit middleware,
+// using backend for the buckets
+func newLimitedRouter(t *testing.T, cfg *config.Config, backend LimiterBackend) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := quietLogger()
+
+	previous := globalRateLimiter
+	globalRateLimiter = NewRateLimiter(cfg.RateLimit, backend, logger)
+	t.Cleanup(func() { globalRateLimiter = previous })
+
+	router := gin.New()
+	router.Use(RateLimit(cfg, logger))
+	router.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return router
+}
+
+// newUserLimitedRouter serves GET /ping like newLimitedRouter, with the
+// user from the X-User header set by a stand-in for the auth middleware
+// and the per-user limit applied twice, as nested route groups do
+func newUserLimitedRouter(t *testing.T, cfg *config.Config, backend LimiterBackend) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := quietLogger()
+
+	previous := globalRateLimiter
+	globalRateLimiter = NewRateLimiter(cfg.RateLimit, backend, logger)
+	t.Cleanup(func() { globalRateLimiter = previous })
+
+	auth := func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("user_id", user)
+		}
+		c.Next()
+	}
+	userLimit := UserRateLimit(cfg, logger)
+
+	router := gin.New()
+	router.Use(RateLimit(cfg, logger))
+	group := router.Group("", auth, userLimit)
+	group.GET("/ping", userLimit, func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return router
+}
+
+func get(router http.Handler, path string, headers ...string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	request.RemoteAddr = "192.0.2.1:1234"
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend LimiterBackend) {
+		cfg := &config.Config{RateLimit: config.RateLimit{Enabled: true, RPS: 1, Burst: 3}}
+		router := newLimitedRouter(t, cfg, backend)
+
+		for _, remaining := range []string{"2", "1", "0"} {
+			response := get(router, "/ping")
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(t, "3", response.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, remaining, response.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, "3;w=3", response.Header().Get("RateLimit-Policy"))
+			assert.NotEmpty(t, response.Header().Get("RateLimit-Reset"))
+			assert.Empty(t, response.Header().Get("Retry-After"))
+		}
+
+		response := get(router, "/ping")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+		assert.Equal(t, "1", response.Header().Get("Retry-After"))
+		assert.Equal(t, "global", response.Header().Get("X-RateLimit-Type"))
+		assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "3", response.Header().Get("RateLimit-Reset"))
+		assert.Contains(t, response.Body.String(), "RATE_LIMITED")
+	})
+}
+
+func TestRateLimitEndpointRule(t *testing.T) {
+	cfg := &config.Config{RateLimit: config.RateLimit{
+		Enabled: true,
+		RPS:     100,
+		Burst:   100,
+		Endpoints: []config.RateLimitEndpoint{
+			{Path: "/ping", Method: "get", RPS: 1, Burst: 1},
+		},
+	}}
+	router := newLimitedRouter(t, cfg, NewMemoryRateLimiter())
+
+	response := get(router, "/ping")
+	require.Equal(t, http.StatusOK, response.Code)
+	// The endpoint bucket is the one closest to running out
+	assert.Equal(t, "1", response.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+
+	response = get(router, "/ping")
+	require.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "endpoint", response.Header().Get("X-RateLimit-Type"))
+}
+
+func TestRateLimitDeniedRequestKeepsQuota(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend LimiterBackend) {
+		cfg := &config.Config{RateLimit: config.RateLimit{
+			Enabled: true,
+			RPS:     1,
+			Burst:   3,
+			Endpoints: []config.RateLimitEndpoint{
+				{Path: "/ping", RPS: 1, Burst: 1},
+			},
+		}}
+		router := newLimitedRouter(t, cfg, backend)
+
+		require.Equal(t, http.StatusOK, get(router, "/ping").Code)
+		for i := 0; i < 3; i++ {
+			response := get(router, "/ping")
+			require.Equal(t, http.StatusTooManyRequests, response.Code)
+			assert.Equal(t, "endpoint", response.Header().Get("X-RateLimit-Type"))
+		}
+
+		// Only the allowed request took from the global bucket
+		result, err := backend.Take(context.Background(), "global:192.0.2.1", RateLimitRule{RPS: 1, Burst: 3}, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1, result.Remaining)
+	})
+}
+
+func TestUserRateLimit(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend LimiterBackend) {
+		cfg := &config.Config{RateLimit: config.RateLimit{
+			Enabled:   true,
+			RPS:       100,
+			Burst:     100,
+			UserRPS:   1,
+			UserBurst: 2,
+		}}
+		router := newUserLimitedRouter(t, cfg, backend)
+
+		// Each request counts once against its user, however many
+		// groups apply the limit
+		for _, remaining := range []string{"1", "0"} {
+			response := get(router, "/ping", "X-User", "alice")
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(t, "2", response.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, remaining, response.Header().Get("RateLimit-Remaining"))
+		}
+
+		response := get(router, "/ping", "X-User", "alice")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+		assert.Equal(t, "user", response.Header().Get("X-RateLimit-Type"))
+
+		// Other users and anonymous requests are not affected
+		assert.Equal(t, http.StatusOK, get(router, "/ping", "X-User", "bob").Code)
+		response = get(router, "/ping")
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "100", response.Header().Get("RateLimit-Limit"))
+	})
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	backend, server := newRedisBackend(t)
+	server.Close()
+
+	rule := RateLimitRule{RPS: 1, Burst: 1}
+	limiter := NewRateLimiter(config.RateLimit{}, backend, quietLogger())
+	errors := testutil.ToFloat64(metrics.RateLimiterErrors.WithLabelValues("redis"))
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow(context.Background(), "client", rule))
+	}
+	assert.Equal(t, errors+3, testutil.ToFloat64(metrics.RateLimiterErrors.WithLabelValues("redis")))
+
+	cfg := &config.Config{RateLimit: config.RateLimit{Enabled: true, RPS: 1, Burst: 1}}
+	router := newLimitedRouter(t, cfg, backend)
+	for i := 0; i < 3; i++ {
+		response := get(router, "/ping")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, response.Header().Get("RateLimit-Limit"))
+	}
+}
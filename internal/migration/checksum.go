@@ -0,0 +1,216 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sources holds the code of this package, so that checksums cover what a
+// migration actually runs
+//
+//go:embed *.go
+var sources embed.FS
+
+// checksumScheme prefixes checksums of migration contents. Older records
+// hashed the SQL a migration generated, which depended on the state of the
+// database, or only its declared identity; they are replaced on the next
+// verify.
+const checksumScheme = "v3:"
+
+var (
+	contentOnce   sync.Once
+	contentHashes map[string]string
+)
+
+// checksum fingerprints a migration by its ID, description, version and the
+// source of its Up and Down functions, so edits to applied migrations are
+// detected without depending on the database they run against
+func (m *Migrator) checksum(migration Migration) string {
+	contentOnce.Do(func() {
+		hashes, err := hashMigrationSources(sources)
+		if err != nil {
+			// The sources are embedded at build time, so this is a build defect
+			panic(err)
+		}
+		contentHashes = hashes
+	})
+
+	identity := strings.Join([]string{migration.ID, migration.Description, migration.Version, contentHashes[migration.ID]}, "\n")
+	sum := sha256.Sum256([]byte(identity))
+	return checksumScheme + hex.EncodeToString(sum[:])
+}
+
+// legacyChecksum reports whether a recorded checksum predates checksumScheme
+func legacyChecksum(checksum string) bool {
+	return checksum == "" || !strings.HasPrefix(checksum, checksumScheme)
+}
+
+// hashMigrationSources parses the Go files in fsys and hashes, for each
+// migration declared in migrations(), the source of its Up and Down
+// functions together with the package-level functions, methods, types,
+// constants and variables they reference, transitively. Code is hashed as
+// a token stream without comments, so comment and formatting changes do
+// not count as edits. Hashes are keyed by migration ID.
+func hashMigrationSources(fsys fs.FS) (map[string]string, error) {
+	fset := token.NewFileSet()
+	names, err := fs.Glob(fsys, "*.go")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	decls := make(map[string][]ast.Node)
+	var list *ast.FuncDecl
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = src
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Name.Name == "migrations" && decl.Recv != nil {
+					list = decl
+				}
+				decls[decl.Name.Name] = append(decls[decl.Name.Name], decl)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						decls[spec.Name.Name] = append(decls[spec.Name.Name], spec)
+					case *ast.ValueSpec:
+						for _, ident := range spec.Names {
+							decls[ident.Name] = append(decls[ident.Name], spec)
+						}
+					}
+				}
+			}
+		}
+	}
+	if list == nil {
+		return nil, fmt.Errorf("migrations() not found")
+	}
+
+	hashes := make(map[string]string)
+	var walkErr error
+	ast.Inspect(list.Body, func(node ast.Node) bool {
+		lit, ok := node.(*ast.CompositeLit)
+		if !ok || walkErr != nil {
+			return walkErr == nil
+		}
+
+		var id string
+		var roots []ast.Node
+		for _, elt := range lit.Elts {
+			field, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := field.Key.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			switch key.Name {
+			case "ID":
+				if value, ok := field.Value.(*ast.BasicLit); ok {
+					id, _ = strconv.Unquote(value.Value)
+				}
+			case "Up", "Down":
+				roots = append(roots, field.Value)
+			}
+		}
+		if id == "" {
+			return true
+		}
+
+		hash, err := hashReachable(fset, files, decls, roots)
+		if err != nil {
+			walkErr = fmt.Errorf("migration %s: %w", id, err)
+			return false
+		}
+		hashes[id] = hash
+		return false
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	return hashes, nil
+}
+
+// hashReachable hashes the tokens of the roots and of every package-level
+// declaration reachable from them by name. Method receivers are not
+// followed, so changes to the Migrator struct do not touch every migration.
+func hashReachable(fset *token.FileSet, files map[string][]byte, decls map[string][]ast.Node, roots []ast.Node) (string, error) {
+	hash := sha256.New()
+	seen := make(map[ast.Node]bool)
+	queue := append([]ast.Node(nil), roots...)
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+
+		if err := hashTokens(hash, fset, files, node); err != nil {
+			return "", err
+		}
+
+		var body ast.Node = node
+		if fn, ok := node.(*ast.FuncDecl); ok {
+			if fn.Body == nil {
+				continue
+			}
+			body = fn.Body
+		}
+		ast.Inspect(body, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				queue = append(queue, decls[ident.Name]...)
+			}
+			return true
+		})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hashTokens writes the tokens of node's source to w, one per line.
+// Semicolons are skipped since line breaks insert them.
+func hashTokens(w io.Writer, fset *token.FileSet, files map[string][]byte, node ast.Node) error {
+	file := fset.File(node.Pos())
+	src := files[file.Name()][file.Offset(node.Pos()):file.Offset(node.End())]
+
+	var s scanner.Scanner
+	var errs scanner.ErrorList
+	s.Init(token.NewFileSet().AddFile(file.Name(), -1, len(src)), src, errs.Add, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.SEMICOLON {
+			fmt.Fprintf(w, "%s %s\n", tok, lit)
+		}
+	}
+	return errs.Err()
+}
@@ -0,0 +1,110 @@
+package migration
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationContentHashes(t *testing.T) {
+	hashes, err := hashMigrationSources(sources)
+	require.NoError(t, err)
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	migrator := NewMigrator(nil, log)
+
+	seen := make(map[string]string)
+	for _, migration := range migrator.migrations() {
+		hash := hashes[migration.ID]
+		require.NotEmpty(t, hash, migration.ID)
+		assert.NotContains(t, seen, hash, "%s hashes like %s", migration.ID, seen[hash])
+		seen[hash] = migration.ID
+
+		checksum := migrator.checksum(migration)
+		assert.True(t, strings.HasPrefix(checksum, checksumScheme))
+		assert.False(t, legacyChecksum(checksum))
+
+		// The version still overrides the content
+		bumped := migration
+		bumped.Version += "-next"
+		assert.NotEqual(t, checksum, migrator.checksum(bumped))
+	}
+	assert.Len(t, hashes, len(migrator.migrations()))
+}
+
+// testSource is a package declaring two migrations that share a helper
+const testSource = `package migration
+
+const batchSize = 100
+
+func (m *Migrator) migrations() []Migration {
+	return []Migration{
+		{ID: "001_first", Up: m.first},
+		{ID: "002_second", Up: m.second, Down: func(tx *gorm.DB) error { return nil }},
+	}
+}
+
+func (m *Migrator) first(tx *gorm.DB) error {
+	return helper(tx, "first")
+}
+
+func (m *Migrator) second(tx *gorm.DB) error {
+	return tx.Exec("SELECT 2").Error
+}
+
+// helper runs a statement
+func helper(tx *gorm.DB, name string) error {
+	return tx.Limit(batchSize).Exec("SELECT " + name).Error
+}
+`
+
+func hashTestSource(t *testing.T, replacements ...string) map[string]string {
+	source := strings.NewReplacer(replacements...).Replace(testSource)
+	hashes, err := hashMigrationSources(fstest.MapFS{
+		"migration.go":      {Data: []byte(source)},
+		"migration_test.go": {Data: []byte("not go")},
+	})
+	require.NoError(t, err)
+	require.Len(t, hashes, 2)
+	return hashes
+}
+
+func TestHashMigrationSources(t *testing.T) {
+	base := hashTestSource(t)
+
+	tests := []struct {
+		name         string
+		replacements []string
+		changed      []string
+	}{
+		{"comment", []string{"// helper runs a statement", "// helper runs one statement"}, nil},
+		{"formatting", []string{"return helper(tx, \"first\")", "return helper(tx,\n\t\"first\")"}, nil},
+		{"unrelated function", []string{"SELECT 2", "SELECT 3"}, []string{"002_second"}},
+		{"called helper", []string{"\"SELECT \" + name", "\"SELECT DISTINCT \" + name"}, []string{"001_first"}},
+		{"referenced constant", []string{"batchSize = 100", "batchSize = 200"}, []string{"001_first"}},
+		{"inline down", []string{"return nil }", "return tx.Error }"}, []string{"002_second"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashes := hashTestSource(t, tt.replacements...)
+			for id, hash := range base {
+				changed := false
+				for _, want := range tt.changed {
+					changed = changed || want == id
+				}
+				if changed {
+					assert.NotEqual(t, hash, hashes[id], id)
+				} else {
+					assert.Equal(t, hash, hashes[id], id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"alertbot/internal/fingerprint"
+	"alertbot/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// rekeyPrefix marks fingerprints rewritten in the current pass, so that a new
+// fingerprint equal to another row's old one is not remapped twice
+const rekeyPrefix = "rekey:"
+
+// rekeyBatchSize bounds how many alerts are loaded per query
+const rekeyBatchSize = 1000
+
+// fingerprintSampleSize bounds how many alerts are checked against the
+// configured strategy when none has been recorded yet
+const fingerprintSampleSize = 100
+
+// rekeyRow is the part of an alert needed to recompute its fingerprint
+type rekeyRow struct {
+	ID          uint
+	Fingerprint string
+	Labels      models.JSONB `gorm:"type:jsonb"`
+	UpdatedAt   time.Time
+}
+
+// SetFingerprinter sets the strategy used to re-key alerts
+func (m *Migrator) SetFingerprinter(strategy *fingerprint.Strategy) {
+	m.fingerprinter = strategy
+}
+
+// RekeyFingerprints recomputes every alert fingerprint with the configured
+// strategy and updates history, inhibition and incident references. Alerts
+// that now share a fingerprint are merged into the most recently updated one,
+// which takes over their history.
+func (m *Migrator) RekeyFingerprints() error {
+	if err := m.rekeyFingerprints(m.db); err != nil {
+		return err
+	}
+	return m.recordFingerprintStrategy()
+}
+
+// CheckFingerprintStrategy verifies that stored alert fingerprints were
+// computed with the configured strategy. When no strategy has been recorded,
+// the most recently updated alerts are checked instead and the configured
+// strategy is recorded if they match. A mismatch means new alerts would not
+// deduplicate against stored ones until the alerts are re-keyed.
+func (m *Migrator) CheckFingerprintStrategy() error {
+	if !m.db.Migrator().HasTable(&models.FingerprintStrategy{}) {
+		return fmt.Errorf("fingerprint strategy table missing, run `migrate up`")
+	}
+
+	var recorded models.FingerprintStrategy
+	err := m.db.First(&recorded).Error
+	if err == nil {
+		if !fingerprint.New(recorded.IncludeLabels, recorded.ExcludeLabels).Equal(m.fingerprinter) {
+			return fmt.Errorf("alert fingerprints were computed with include_labels=%v exclude_labels=%v but include_labels=%v exclude_labels=%v is configured, run `migrate -rekey-fingerprints`",
+				recorded.IncludeLabels, recorded.ExcludeLabels, m.fingerprinter.Include(), m.fingerprinter.Exclude())
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load fingerprint strategy: %w", err)
+	}
+
+	var sample []rekeyRow
+	if err := m.db.Model(&models.Alert{}).
+		Select("id, fingerprint, labels, updated_at").
+		Order("updated_at DESC").
+		Limit(fingerprintSampleSize).
+		Find(&sample).Error; err != nil {
+		return fmt.Errorf("failed to load alerts: %w", err)
+	}
+	for _, alert := range sample {
+		if m.fingerprinter.FingerprintJSONB(alert.Labels) != alert.Fingerprint {
+			return fmt.Errorf("alert %d was fingerprinted with another strategy than the configured one, run `migrate -rekey-fingerprints`", alert.ID)
+		}
+	}
+	return m.recordFingerprintStrategy()
+}
+
+// recordFingerprintStrategy stores the configured strategy as the one the
+// alert fingerprints were computed with
+func (m *Migrator) recordFingerprintStrategy() error {
+	var strategy models.FingerprintStrategy
+	if err := m.db.FirstOrInit(&strategy, models.FingerprintStrategy{ID: 1}).Error; err != nil {
+		return fmt.Errorf("failed to load fingerprint strategy: %w", err)
+	}
+	strategy.IncludeLabels = m.fingerprinter.Include()
+	strategy.ExcludeLabels = m.fingerprinter.Exclude()
+	if err := m.db.Save(&strategy).Error; err != nil {
+		return fmt.Errorf("failed to record fingerprint strategy: %w", err)
+	}
+	return nil
+}
+
+// rekeyFingerprints re-keys alerts using db, which may be a transaction
+func (m *Migrator) rekeyFingerprints(db *gorm.DB) error {
+	var rows []rekeyRow
+	var batch []rekeyRow
+	err := db.Model(&models.Alert{}).
+		Select("id, fingerprint, labels, updated_at").
+		FindInBatches(&batch, rekeyBatchSize, func(tx *gorm.DB, _ int) error {
+			rows = append(rows, batch...)
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to load alerts: %w", err)
+	}
+
+	keep := make(map[string]rekeyRow, len(rows))
+	remap := make(map[string]string)
+	for _, row := range rows {
+		newFP := m.fingerprinter.FingerprintJSONB(row.Labels)
+		if newFP != row.Fingerprint {
+			remap[row.Fingerprint] = newFP
+		}
+		if current, ok := keep[newFP]; !ok || row.UpdatedAt.After(current.UpdatedAt) {
+			keep[newFP] = row
+		}
+	}
+
+	// Merged alerts are deleted; their history, inhibitions and incident links
+	// are re-pointed to the fingerprint of the surviving alert
+	var merged []uint
+	for _, row := range rows {
+		newFP, changed := remap[row.Fingerprint]
+		if !changed {
+			newFP = row.Fingerprint
+		}
+		if keep[newFP].ID != row.ID {
+			merged = append(merged, row.ID)
+		}
+	}
+
+	if len(remap) == 0 {
+		m.logger.Info("Alert fingerprints already match the configured strategy")
+		return nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(merged); start += rekeyBatchSize {
+			end := start + rekeyBatchSize
+			if end > len(merged) {
+				end = len(merged)
+			}
+			if err := tx.Where("id IN ?", merged[start:end]).Delete(&models.Alert{}).Error; err != nil {
+				return fmt.Errorf("failed to merge alerts: %w", err)
+			}
+		}
+
+		for newFP, row := range keep {
+			if row.Fingerprint == newFP {
+				continue
+			}
+			if err := tx.Model(&models.Alert{}).Where("id = ?", row.ID).
+				Update("fingerprint", rekeyPrefix+newFP).Error; err != nil {
+				return fmt.Errorf("failed to re-key alert %d: %w", row.ID, err)
+			}
+		}
+
+		for oldFP, newFP := range remap {
+			// An incident may already link another alert merged into the same
+			// survivor; keep one link per incident
+			existing := []string{rekeyPrefix + newFP}
+			if _, moved := remap[newFP]; !moved {
+				existing = append(existing, newFP)
+			}
+			if err := tx.Where("alert_fingerprint = ? AND incident_id IN (?)", oldFP,
+				tx.Model(&models.IncidentAlert{}).Select("incident_id").Where("alert_fingerprint IN ?", existing),
+			).Delete(&models.IncidentAlert{}).Error; err != nil {
+				return fmt.Errorf("failed to merge incident alerts: %w", err)
+			}
+
+			updates := []struct {
+				model  interface{}
+				column string
+			}{
+				{&models.AlertHistory{}, "alert_fingerprint"},
+				{&models.IncidentAlert{}, "alert_fingerprint"},
+				{&models.InhibitionStatus{}, "source_fingerprint"},
+				{&models.InhibitionStatus{}, "target_fingerprint"},
+			}
+			for _, u := range updates {
+				if err := tx.Model(u.model).Where(u.column+" = ?", oldFP).
+					Update(u.column, rekeyPrefix+newFP).Error; err != nil {
+					return fmt.Errorf("failed to re-key %s: %w", u.column, err)
+				}
+			}
+		}
+
+		strip := []string{
+			"UPDATE alerts SET fingerprint = SUBSTR(fingerprint, ?) WHERE fingerprint LIKE ?",
+			"UPDATE alert_history SET alert_fingerprint = SUBSTR(alert_fingerprint, ?) WHERE alert_fingerprint LIKE ?",
+			"UPDATE incident_alerts SET alert_fingerprint = SUBSTR(alert_fingerprint, ?) WHERE alert_fingerprint LIKE ?",
+			"UPDATE inhibition_statuses SET source_fingerprint = SUBSTR(source_fingerprint, ?) WHERE source_fingerprint LIKE ?",
+			"UPDATE inhibition_statuses SET target_fingerprint = SUBSTR(target_fingerprint, ?) WHERE target_fingerprint LIKE ?",
+		}
+		for _, sql := range strip {
+			if err := tx.Exec(sql, len(rekeyPrefix)+1, rekeyPrefix+"%").Error; err != nil {
+				return fmt.Errorf("failed to finalize fingerprints: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"alerts":  len(rows),
+		"rekeyed": len(remap),
+		"merged":  len(merged),
+	}).Info("Alert fingerprints re-keyed")
+	return nil
+}
@@ -2,8 +2,9 @@ package migration
 
 import (
 	"fmt"
-	"time"
+	"io"
 
+	"alertbot/internal/fingerprint"
 	"alertbot/internal/models"
 
 	"gorm.io/gorm"
@@ -14,6 +15,12 @@ import (
 type Migrator struct {
 	db     *gorm.DB
 	logger *logrus.Logger
+
+	fingerprinter *fingerprint.Strategy
+
+	// dryRun receives the SQL of migrations instead of running it
+	dryRun io.Writer
+	force  bool
 }
 
 // NewMigrator creates a new database migrator
@@ -21,12 +28,20 @@ func NewMigrator(db *gorm.DB, logger *logrus.Logger) *Migrator {
 	return &Migrator{
 		db:     db,
 		logger: logger,
+
+		fingerprinter: fingerprint.Default(),
 	}
 }
 
-// Migrate runs all database migrations
+// Migrate syncs the schema and applies all pending migrations
 func (m *Migrator) Migrate() error {
-	m.logger.Info("Starting database migrations")
+	return m.Up(0)
+}
+
+// syncSchema auto-migrates all models and creates the performance indexes.
+// Both are idempotent and run before versioned migrations are applied.
+func (m *Migrator) syncSchema() error {
+	m.logger.Info("Syncing database schema")
 
 	// Auto-migrate all models
 	err := m.db.AutoMigrate(
@@ -42,6 +57,22 @@ func (m *Migrator) Migrate() error {
 		&models.SystemConfig{},
 		&models.PrometheusConfig{},
 		&models.NotificationConfig{},
+		&models.DeduplicationConfig{},
+		&models.TopologyGraph{},
+		&models.RelabelConfigList{},
+		&models.FingerprintStrategy{},
+		&models.SavedView{},
+		&models.RevokedToken{},
+		&models.UsedTicket{},
+		&models.Incident{},
+		&models.IncidentAlert{},
+		&models.IncidentNote{},
+		&models.NotificationTemplate{},
+		&models.Heartbeat{},
+		&models.ClusterMember{},
+		&models.NotificationClaim{},
+		&models.EventPayload{},
+		&models.RateLimitBucket{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto-migrate models: %w", err)
@@ -51,13 +82,6 @@ func (m *Migrator) Migrate() error {
 	if err := m.createIndexes(); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
-
-	// Run custom migrations
-	if err := m.runCustomMigrations(); err != nil {
-		return fmt.Errorf("failed to run custom migrations: %w", err)
-	}
-
-	m.logger.Info("Database migrations completed successfully")
 	return nil
 }
 
@@ -85,20 +109,10 @@ func (m *Migrator) createIndexes() error {
 		"CREATE INDEX IF NOT EXISTS idx_alerts_fingerprint_unique ON alerts(fingerprint) WHERE status IN ('firing', 'acknowledged')", // Active alerts only
 		"CREATE INDEX IF NOT EXISTS idx_alerts_fingerprint_time ON alerts(fingerprint, starts_at DESC)", // Alert timeline
 		
-		// JSONB indexes for labels and annotations (with specific paths)
-		"CREATE INDEX IF NOT EXISTS idx_alerts_labels_gin ON alerts USING GIN(labels)",
-		"CREATE INDEX IF NOT EXISTS idx_alerts_annotations_gin ON alerts USING GIN(annotations)",
-		
-		// Specific label searches (commonly used in dashboards)
-		"CREATE INDEX IF NOT EXISTS idx_alerts_alertname ON alerts((labels->>'alertname')) WHERE labels ? 'alertname'",
-		"CREATE INDEX IF NOT EXISTS idx_alerts_instance ON alerts((labels->>'instance')) WHERE labels ? 'instance'",
-		"CREATE INDEX IF NOT EXISTS idx_alerts_job ON alerts((labels->>'job')) WHERE labels ? 'job'",
-		"CREATE INDEX IF NOT EXISTS idx_alerts_service ON alerts((labels->>'service')) WHERE labels ? 'service'",
-		"CREATE INDEX IF NOT EXISTS idx_alerts_cluster ON alerts((labels->>'cluster')) WHERE labels ? 'cluster'",
-		
-		// Composite label indexes for common queries
-		"CREATE INDEX IF NOT EXISTS idx_alerts_alertname_status ON alerts((labels->>'alertname'), status) WHERE labels ? 'alertname'",
-		"CREATE INDEX IF NOT EXISTS idx_alerts_instance_severity ON alerts((labels->>'instance'), severity) WHERE labels ? 'instance'",
+		// Keyset pagination (sort column + id tiebreaker)
+		"CREATE INDEX IF NOT EXISTS idx_alerts_created_id ON alerts(created_at DESC, id DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_starts_id ON alerts(starts_at DESC, id DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_updated_id ON alerts(updated_at DESC, id DESC)",
 		
 		// Dashboard query optimizations
 		"CREATE INDEX IF NOT EXISTS idx_alerts_dashboard_main ON alerts(status, severity, created_at DESC) WHERE status IN ('firing', 'acknowledged')", // Main dashboard
@@ -106,7 +120,6 @@ func (m *Migrator) createIndexes() error {
 		
 		// === ROUTING RULES INDEXES === //
 		"CREATE INDEX IF NOT EXISTS idx_routing_rules_enabled_priority ON routing_rules(enabled, priority DESC) WHERE enabled = true",
-		"CREATE INDEX IF NOT EXISTS idx_routing_rules_conditions_gin ON routing_rules USING GIN(conditions)",
 		"CREATE INDEX IF NOT EXISTS idx_routing_rules_updated ON routing_rules(updated_at DESC)", // Rule management
 		
 		// === NOTIFICATION CHANNELS INDEXES === //
@@ -115,37 +128,35 @@ func (m *Migrator) createIndexes() error {
 		"CREATE INDEX IF NOT EXISTS idx_notification_channels_name ON notification_channels(name)", // Search by name
 		
 		// === SILENCES INDEXES === //
-		"CREATE INDEX IF NOT EXISTS idx_silences_active ON silences(starts_at, ends_at) WHERE ends_at > NOW()",
-		"CREATE INDEX IF NOT EXISTS idx_silences_matchers_gin ON silences USING GIN(matchers)",
 		"CREATE INDEX IF NOT EXISTS idx_silences_creator ON silences(creator, created_at DESC)", // By creator
 		"CREATE INDEX IF NOT EXISTS idx_silences_time_range ON silences(starts_at, ends_at)", // Time range queries
 		
 		// === ALERT HISTORY INDEXES === //
 		"CREATE INDEX IF NOT EXISTS idx_alert_history_fingerprint_created ON alert_history(alert_fingerprint, created_at DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_alert_history_action_created ON alert_history(action, created_at DESC)",
-		"CREATE INDEX IF NOT EXISTS idx_alert_history_recent ON alert_history(created_at DESC) WHERE created_at > NOW() - INTERVAL '7 days'", // Recent history
 		
 		// === ALERT GROUP INDEXES === //
 		"CREATE INDEX IF NOT EXISTS idx_alert_groups_status_updated ON alert_groups(status, updated_at DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_alert_groups_severity ON alert_groups(severity, updated_at DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_alert_groups_key ON alert_groups(group_key)", // Unique group identification
-		"CREATE INDEX IF NOT EXISTS idx_alert_groups_common_labels_gin ON alert_groups USING GIN(common_labels)",
 		
 		// === ALERT GROUP RULES INDEXES === //
 		"CREATE INDEX IF NOT EXISTS idx_alert_group_rules_enabled_priority ON alert_group_rules(enabled, priority DESC) WHERE enabled = true",
-		"CREATE INDEX IF NOT EXISTS idx_alert_group_rules_matchers_gin ON alert_group_rules USING GIN(matchers)",
 		
 		// === INHIBITION RULES INDEXES === //
 		"CREATE INDEX IF NOT EXISTS idx_inhibition_rules_enabled ON inhibition_rules(enabled) WHERE enabled = true",
-		"CREATE INDEX IF NOT EXISTS idx_inhibition_rules_source_gin ON inhibition_rules USING GIN(source_matchers)",
-		"CREATE INDEX IF NOT EXISTS idx_inhibition_rules_target_gin ON inhibition_rules USING GIN(target_matchers)",
 		"CREATE INDEX IF NOT EXISTS idx_inhibition_rules_priority ON inhibition_rules(priority DESC, enabled) WHERE enabled = true",
 		
 		// === INHIBITION STATUS INDEXES === //
-		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_source ON inhibition_status(source_fingerprint, created_at DESC)",
-		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_target ON inhibition_status(target_fingerprint, created_at DESC)",
-		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_rule ON inhibition_status(rule_id, created_at DESC)",
-		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_expires ON inhibition_status(expires_at) WHERE expires_at IS NOT NULL",
+		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_source ON inhibition_statuses(source_fingerprint, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_target ON inhibition_statuses(target_fingerprint, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_rule ON inhibition_statuses(rule_id, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_inhibition_status_expires ON inhibition_statuses(expires_at) WHERE expires_at IS NOT NULL",
+		
+		// === INCIDENT INDEXES === //
+		"CREATE INDEX IF NOT EXISTS idx_incidents_status_created ON incidents(status, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_incidents_open_key ON incidents(correlation_key) WHERE status <> 'resolved'", // Attaching new alerts
+		"CREATE INDEX IF NOT EXISTS idx_incident_notes_incident_created ON incident_notes(incident_id, created_at)",
 		
 		// === SETTINGS TABLES INDEXES === //
 		"CREATE INDEX IF NOT EXISTS idx_system_config_updated ON system_configs(updated_at DESC)",
@@ -153,6 +164,38 @@ func (m *Migrator) createIndexes() error {
 		"CREATE INDEX IF NOT EXISTS idx_notification_config_updated ON notification_configs(updated_at DESC)",
 	}
 
+	// JSONB, full-text and time-relative indexes need Postgres
+	postgresIndexes := []string{
+		// JSONB indexes for labels and annotations (with specific paths)
+		"CREATE INDEX IF NOT EXISTS idx_alerts_labels_gin ON alerts USING GIN(labels)",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_annotations_gin ON alerts USING GIN(annotations)",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_annotations_fts ON alerts USING GIN(to_tsvector('simple', annotations))", // Full-text search
+		
+		// Specific label searches (commonly used in dashboards)
+		"CREATE INDEX IF NOT EXISTS idx_alerts_alertname ON alerts((labels->>'alertname')) WHERE labels ? 'alertname'",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_instance ON alerts((labels->>'instance')) WHERE labels ? 'instance'",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_job ON alerts((labels->>'job')) WHERE labels ? 'job'",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_service ON alerts((labels->>'service')) WHERE labels ? 'service'",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_cluster ON alerts((labels->>'cluster')) WHERE labels ? 'cluster'",
+		
+		// Composite label indexes for common queries
+		"CREATE INDEX IF NOT EXISTS idx_alerts_alertname_status ON alerts((labels->>'alertname'), status) WHERE labels ? 'alertname'",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_instance_severity ON alerts((labels->>'instance'), severity) WHERE labels ? 'instance'",
+		
+		// Matcher and time-relative indexes on the other tables
+		"CREATE INDEX IF NOT EXISTS idx_routing_rules_conditions_gin ON routing_rules USING GIN(conditions)",
+		"CREATE INDEX IF NOT EXISTS idx_silences_active ON silences(starts_at, ends_at) WHERE ends_at > NOW()",
+		"CREATE INDEX IF NOT EXISTS idx_silences_matchers_gin ON silences USING GIN(matchers)",
+		"CREATE INDEX IF NOT EXISTS idx_alert_history_recent ON alert_history(created_at DESC) WHERE created_at > NOW() - INTERVAL '7 days'", // Recent history
+		"CREATE INDEX IF NOT EXISTS idx_alert_groups_common_labels_gin ON alert_groups USING GIN(common_labels)",
+		"CREATE INDEX IF NOT EXISTS idx_alert_group_rules_matchers_gin ON alert_group_rules USING GIN(matchers)",
+		"CREATE INDEX IF NOT EXISTS idx_inhibition_rules_source_gin ON inhibition_rules USING GIN(source_matchers)",
+		"CREATE INDEX IF NOT EXISTS idx_inhibition_rules_target_gin ON inhibition_rules USING GIN(target_matchers)",
+	}
+	if isPostgres(m.db) {
+		indexes = append(indexes, postgresIndexes...)
+	}
+
 	for _, indexSQL := range indexes {
 		if err := m.db.Exec(indexSQL).Error; err != nil {
 			m.logger.WithError(err).WithField("sql", indexSQL).Error("Failed to create index")
@@ -164,120 +207,71 @@ func (m *Migrator) createIndexes() error {
 	return nil
 }
 
-// runCustomMigrations runs custom migration scripts
-func (m *Migrator) runCustomMigrations() error {
-	m.logger.Info("Running custom migrations")
-
-	// Create migration tracking table
-	if err := m.createMigrationTable(); err != nil {
-		return err
-	}
-
-	// List of custom migrations to run
-	migrations := []Migration{
+// migrations lists the versioned migrations in the order they are applied.
+// IDs must never change. Editing an applied migration flags it as modified,
+// so it needs a new migration that brings existing databases along.
+func (m *Migrator) migrations() []Migration {
+	return []Migration{
 		{
 			ID:          "001_add_default_notification_templates",
 			Description: "Add default notification templates",
+			Version:     "1",
 			Up:          m.migration001Up,
+			Down:        m.migration001Down,
 		},
 		{
 			ID:          "002_create_default_routing_rules",
 			Description: "Create default routing rules",
+			Version:     "1",
 			Up:          m.migration002Up,
+			Down:        m.migration002Down,
 		},
 		{
 			ID:          "003_optimize_alert_queries",
 			Description: "Add optimized views for alert queries",
+			Version:     "1",
 			Up:          m.migration003Up,
+			Down:        m.migration003Down,
+			// Views, functions and triggers use Postgres SQL
+			PostgresOnly: true,
 		},
 		{
 			ID:          "004_add_performance_optimizations",
 			Description: "Add advanced performance optimizations and partitioning",
+			Version:     "1",
 			Up:          m.migration004Up,
+			Down:        m.migration004Down,
+			// CREATE INDEX CONCURRENTLY cannot run inside a transaction
+			NoTransaction: true,
+			PostgresOnly:  true,
+		},
+		{
+			ID:          "005_rekey_alert_fingerprints",
+			Description: "Re-key alerts with Alertmanager-compatible fingerprints",
+			Version:     "1",
+			Up:          m.rekeyFingerprints,
+			// Irreversible: the previous fingerprints are not kept
 		},
 	}
-
-	// Run each migration
-	for _, migration := range migrations {
-		if err := m.runMigration(migration); err != nil {
-			return fmt.Errorf("failed to run migration %s: %w", migration.ID, err)
-		}
-	}
-
-	m.logger.Info("Custom migrations completed")
-	return nil
-}
-
-// Migration represents a database migration
-type Migration struct {
-	ID          string
-	Description string
-	Up          func() error
-}
-
-// MigrationRecord tracks applied migrations
-type MigrationRecord struct {
-	ID          string    `gorm:"primaryKey"`
-	Description string
-	AppliedAt   time.Time `gorm:"autoCreateTime"`
-}
-
-// createMigrationTable creates the migration tracking table
-func (m *Migrator) createMigrationTable() error {
-	return m.db.AutoMigrate(&MigrationRecord{})
-}
-
-// runMigration runs a single migration if it hasn't been applied yet
-func (m *Migrator) runMigration(migration Migration) error {
-	// Check if migration has already been applied
-	var record MigrationRecord
-	err := m.db.Where("id = ?", migration.ID).First(&record).Error
-	if err == nil {
-		m.logger.WithField("migration_id", migration.ID).Debug("Migration already applied, skipping")
-		return nil
-	}
-
-	if err != gorm.ErrRecordNotFound {
-		return fmt.Errorf("failed to check migration status: %w", err)
-	}
-
-	// Run the migration
-	m.logger.WithFields(logrus.Fields{
-		"migration_id":  migration.ID,
-		"description":   migration.Description,
-	}).Info("Running migration")
-
-	if err := migration.Up(); err != nil {
-		return err
-	}
-
-	// Record the migration as applied
-	record = MigrationRecord{
-		ID:          migration.ID,
-		Description: migration.Description,
-	}
-	
-	if err := m.db.Create(&record).Error; err != nil {
-		return fmt.Errorf("failed to record migration: %w", err)
-	}
-
-	m.logger.WithField("migration_id", migration.ID).Info("Migration completed successfully")
-	return nil
 }
 
 // Migration implementations
 
-func (m *Migrator) migration001Up() error {
+func (m *Migrator) migration001Up(tx *gorm.DB) error {
 	// This migration would add default notification templates
 	// For now, it's a placeholder
 	m.logger.Info("Adding default notification templates")
 	return nil
 }
 
-func (m *Migrator) migration002Up() error {
+func (m *Migrator) migration001Down(tx *gorm.DB) error {
+	return nil
+}
+
+func (m *Migrator) migration002Up(tx *gorm.DB) error {
 	// Create a default "catch-all" routing rule if none exist
 	var count int64
-	if err := m.db.Model(&models.RoutingRule{}).Count(&count).Error; err != nil {
+	if err := tx.Model(&models.RoutingRule{}).Count(&count).Error; err != nil {
 		return err
 	}
 
@@ -288,17 +282,15 @@ func (m *Migrator) migration002Up() error {
 			Conditions: models.JSONB{
 				"severity": []string{"critical", "warning", "info"},
 			},
-			Receivers: models.JSONB([]interface{}{
-				map[string]interface{}{
-					"channel_id": 1,
-					"template":   "default",
-				},
-			}),
+			Receivers: models.JSONB{
+				"channels": []interface{}{1},
+				"template": "default",
+			},
 			Priority: 1,
 			Enabled:  true,
 		}
 
-		if err := m.db.Create(defaultRule).Error; err != nil {
+		if err := tx.Create(defaultRule).Error; err != nil {
 			return fmt.Errorf("failed to create default routing rule: %w", err)
 		}
 
@@ -308,7 +300,12 @@ func (m *Migrator) migration002Up() error {
 	return nil
 }
 
-func (m *Migrator) migration003Up() error {
+func (m *Migrator) migration002Down(tx *gorm.DB) error {
+	return tx.Where("name = ? AND description = ?", "Default Rule", "Default catch-all routing rule").
+		Delete(&models.RoutingRule{}).Error
+}
+
+func (m *Migrator) migration003Up(tx *gorm.DB) error {
 	// Create optimized views and additional performance enhancements
 	views := []string{
 		// Active alerts view - most frequently accessed
@@ -470,7 +467,7 @@ func (m *Migrator) migration003Up() error {
 
 	// Execute all views
 	for _, viewSQL := range views {
-		if err := m.db.Exec(viewSQL).Error; err != nil {
+		if err := tx.Exec(viewSQL).Error; err != nil {
 			m.logger.WithError(err).WithField("sql", viewSQL).Error("Failed to create view")
 			return err
 		}
@@ -478,7 +475,7 @@ func (m *Migrator) migration003Up() error {
 
 	// Execute all functions
 	for _, funcSQL := range functions {
-		if err := m.db.Exec(funcSQL).Error; err != nil {
+		if err := execOptional(tx, funcSQL); err != nil {
 			m.logger.WithError(err).WithField("sql", funcSQL).Warn("Failed to create function")
 			// Don't fail migration if functions fail (PostgreSQL-specific)
 		}
@@ -486,7 +483,7 @@ func (m *Migrator) migration003Up() error {
 
 	// Execute all triggers
 	for _, triggerSQL := range triggers {
-		if err := m.db.Exec(triggerSQL).Error; err != nil {
+		if err := execOptional(tx, triggerSQL); err != nil {
 			m.logger.WithError(err).WithField("sql", triggerSQL).Warn("Failed to create trigger")
 			// Don't fail migration if triggers fail
 		}
@@ -495,7 +492,23 @@ func (m *Migrator) migration003Up() error {
 	return nil
 }
 
-func (m *Migrator) migration004Up() error {
+func (m *Migrator) migration003Down(tx *gorm.DB) error {
+	statements := []string{
+		"DROP TRIGGER IF EXISTS alert_groups_update_trigger ON alerts",
+		"DROP FUNCTION IF EXISTS update_alert_groups_trigger()",
+		"DROP FUNCTION IF EXISTS alert_matches_silence(JSONB, JSONB)",
+		"DROP FUNCTION IF EXISTS get_alert_fingerprint(JSONB)",
+		"DROP VIEW IF EXISTS channel_summary, active_silences, alert_stats, critical_alerts, alert_summary, active_alerts",
+	}
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) migration004Up(tx *gorm.DB) error {
 	// Advanced performance optimizations
 	m.logger.Info("Adding advanced performance optimizations")
 
@@ -632,7 +645,7 @@ func (m *Migrator) migration004Up() error {
 
 	// Execute optimizations (with error handling)
 	for _, sql := range optimizations {
-		if err := m.db.Exec(sql).Error; err != nil {
+		if err := tx.Exec(sql).Error; err != nil {
 			m.logger.WithError(err).WithField("sql", sql).Warn("Failed to apply optimization")
 			// Don't fail migration for optimization errors
 		}
@@ -640,7 +653,7 @@ func (m *Migrator) migration004Up() error {
 
 	// Execute specialized indexes
 	for _, indexSQL := range specializedIndexes {
-		if err := m.db.Exec(indexSQL).Error; err != nil {
+		if err := tx.Exec(indexSQL).Error; err != nil {
 			m.logger.WithError(err).WithField("sql", indexSQL).Warn("Failed to create specialized index")
 			// Don't fail migration for index creation errors
 		}
@@ -648,7 +661,7 @@ func (m *Migrator) migration004Up() error {
 
 	// Execute partitioning (optional, depends on database version and permissions)
 	for _, partSQL := range partitioningSQL {
-		if err := m.db.Exec(partSQL).Error; err != nil {
+		if err := tx.Exec(partSQL).Error; err != nil {
 			m.logger.WithError(err).WithField("sql", partSQL).Debug("Failed to create partition")
 			// Partitioning is optional and may not be supported in all environments
 		}
@@ -656,7 +669,7 @@ func (m *Migrator) migration004Up() error {
 
 	// Execute monitoring views
 	for _, viewSQL := range monitoringViews {
-		if err := m.db.Exec(viewSQL).Error; err != nil {
+		if err := tx.Exec(viewSQL).Error; err != nil {
 			m.logger.WithError(err).WithField("sql", viewSQL).Warn("Failed to create monitoring view")
 			// Don't fail migration for monitoring view errors
 		}
@@ -673,7 +686,7 @@ func (m *Migrator) migration004Up() error {
 	}
 
 	for _, sql := range statisticsSQL {
-		if err := m.db.Exec(sql).Error; err != nil {
+		if err := tx.Exec(sql).Error; err != nil {
 			m.logger.WithError(err).WithField("sql", sql).Warn("Failed to update statistics")
 		}
 	}
@@ -682,11 +695,46 @@ func (m *Migrator) migration004Up() error {
 	return nil
 }
 
+// migration004Down keeps the pg_trgm extension, which other objects may use
+func (m *Migrator) migration004Down(tx *gorm.DB) error {
+	statements := []string{
+		"DROP VIEW IF EXISTS table_stats, index_usage_stats, query_performance",
+		"DROP TABLE IF EXISTS alert_history_partitioned CASCADE",
+		"ALTER TABLE alerts RESET (autovacuum_analyze_scale_factor, parallel_workers)",
+		"ALTER TABLE alert_history RESET (autovacuum_analyze_scale_factor, parallel_workers)",
+	}
+	for _, index := range []string{
+		"idx_alerts_firing_recent", "idx_alerts_resolved_recent", "idx_alerts_duration", "idx_alerts_age",
+		"idx_alerts_alertname_trgm", "idx_alerts_description_trgm", "idx_alerts_dashboard_complex",
+		"idx_alerts_instance_status", "idx_alerts_timeseries", "idx_alerts_list_covering", "idx_alerts_api_covering",
+	} {
+		statements = append(statements, "DROP INDEX CONCURRENTLY IF EXISTS "+index)
+	}
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DropAll drops all tables (use with caution!)
 func (m *Migrator) DropAll() error {
 	m.logger.Warn("Dropping all database tables")
 	
 	tables := []interface{}{
+		&models.RateLimitBucket{},
+		&models.EventPayload{},
+		&models.NotificationClaim{},
+		&models.ClusterMember{},
+		&models.Heartbeat{},
+		&models.NotificationTemplate{},
+		&models.IncidentNote{},
+		&models.IncidentAlert{},
+		&models.Incident{},
+		&models.UsedTicket{},
+		&models.RevokedToken{},
+		&models.SavedView{},
 		&models.AlertHistory{},
 		&models.Silence{},
 		&models.NotificationChannel{},
@@ -695,6 +743,10 @@ func (m *Migrator) DropAll() error {
 		&models.SystemConfig{},
 		&models.PrometheusConfig{},
 		&models.NotificationConfig{},
+		&models.DeduplicationConfig{},
+		&models.TopologyGraph{},
+		&models.RelabelConfigList{},
+		&models.FingerprintStrategy{},
 		&MigrationRecord{},
 	}
 
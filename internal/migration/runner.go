@@ -0,0 +1,454 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// migrationLockID is the Postgres advisory lock key held while migrations
+// run, so that replicas starting at the same time apply them only once
+const migrationLockID int64 = 0x616c657274626f74 // "alertbot"
+
+// Migration states reported by Status
+const (
+	StatePending  = "pending"
+	StateApplied  = "applied"
+	StateModified = "modified" // Applied, but the migration changed since
+	StateMissing  = "missing"  // Applied, but no longer known to this build
+)
+
+// Migration represents a database migration
+type Migration struct {
+	ID          string
+	Description string
+	// Version is part of the recorded checksum along with the source of
+	// Up and Down. Bump it to flag changes the source cannot show, such as
+	// in code from other packages that a migration calls.
+	Version string
+	Up      func(tx *gorm.DB) error
+	// Down reverts Up; nil marks the migration as irreversible
+	Down func(tx *gorm.DB) error
+	// NoTransaction runs the migration outside a transaction, for
+	// statements such as CREATE INDEX CONCURRENTLY
+	NoTransaction bool
+	// PostgresOnly migrations are recorded without running on other
+	// databases
+	PostgresOnly bool
+}
+
+// MigrationRecord tracks applied migrations
+type MigrationRecord struct {
+	ID          string `gorm:"primaryKey"`
+	Description string
+	Checksum    string
+	AppliedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+// MigrationStatus describes a migration and whether it has been applied
+type MigrationStatus struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	Reversible  bool       `json:"reversible"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// SetDryRun makes Up, Down and To print the SQL they would run to w
+// instead of executing it. Statements that depend on query results are
+// shown as if the queries returned no rows.
+func (m *Migrator) SetDryRun(w io.Writer) {
+	m.dryRun = w
+}
+
+// SetForce lets Up, Down and To proceed when applied migrations were
+// modified, recording their new checksums
+func (m *Migrator) SetForce(force bool) {
+	m.force = force
+}
+
+// Status lists all known and applied migrations in order
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	known := make(map[string]bool)
+	for _, migration := range m.migrations() {
+		known[migration.ID] = true
+		status := MigrationStatus{
+			ID:          migration.ID,
+			Description: migration.Description,
+			State:       StatePending,
+			Reversible:  migration.Down != nil,
+		}
+		if record, ok := applied[migration.ID]; ok {
+			status.State = StateApplied
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+			if !legacyChecksum(record.Checksum) && record.Checksum != m.checksum(migration) {
+				status.State = StateModified
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	var missing []string
+	for id := range applied {
+		if !known[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	for _, id := range missing {
+		record := applied[id]
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			ID:          id,
+			Description: record.Description,
+			State:       StateMissing,
+			AppliedAt:   &appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Up syncs the schema and applies up to n pending migrations, or all of
+// them when n <= 0
+func (m *Migrator) Up(n int) error {
+	return m.withLock(func() error {
+		if m.dryRun == nil {
+			if err := m.syncSchema(); err != nil {
+				return err
+			}
+		}
+
+		applied, err := m.verify()
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for _, migration := range m.migrations() {
+			if n > 0 && count == n {
+				break
+			}
+			if _, ok := applied[migration.ID]; ok {
+				m.logger.WithField("migration_id", migration.ID).Debug("Migration already applied, skipping")
+				continue
+			}
+			if err := m.apply(migration); err != nil {
+				return fmt.Errorf("failed to run migration %s: %w", migration.ID, err)
+			}
+			count++
+		}
+
+		m.logger.WithField("applied", count).Info("Migrations completed")
+		return nil
+	})
+}
+
+// Down reverts the n most recently applied migrations
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("number of migrations to revert must be positive")
+	}
+	return m.withLock(func() error {
+		applied, err := m.verify()
+		if err != nil {
+			return err
+		}
+
+		migrations := m.migrations()
+		count := 0
+		for i := len(migrations) - 1; i >= 0 && count < n; i-- {
+			if _, ok := applied[migrations[i].ID]; !ok {
+				continue
+			}
+			if err := m.revert(migrations[i]); err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", migrations[i].ID, err)
+			}
+			count++
+		}
+
+		m.logger.WithField("reverted", count).Info("Migrations reverted")
+		return nil
+	})
+}
+
+// To applies or reverts migrations until id is the last applied one
+func (m *Migrator) To(id string) error {
+	migrations := m.migrations()
+	target := -1
+	for i, migration := range migrations {
+		if migration.ID == id {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		return fmt.Errorf("unknown migration %q", id)
+	}
+
+	return m.withLock(func() error {
+		if m.dryRun == nil {
+			if err := m.syncSchema(); err != nil {
+				return err
+			}
+		}
+
+		applied, err := m.verify()
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i > target; i-- {
+			if _, ok := applied[migrations[i].ID]; !ok {
+				continue
+			}
+			if err := m.revert(migrations[i]); err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", migrations[i].ID, err)
+			}
+		}
+		for _, migration := range migrations[:target+1] {
+			if _, ok := applied[migration.ID]; ok {
+				continue
+			}
+			if err := m.apply(migration); err != nil {
+				return fmt.Errorf("failed to run migration %s: %w", migration.ID, err)
+			}
+		}
+
+		m.logger.WithField("migration_id", id).Info("Migrated to target")
+		return nil
+	})
+}
+
+// withLock runs fn while holding the migration advisory lock on a
+// dedicated connection. Dry runs change nothing and skip the lock, as does
+// SQLite, which only serves a single instance.
+func (m *Migrator) withLock(fn func() error) error {
+	if m.dryRun != nil || !isPostgres(m.db) {
+		return fn()
+	}
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reserve connection for migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockID).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if !locked {
+		m.logger.Info("Another instance is running migrations, waiting for it to finish")
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			m.logger.WithError(err).Warn("Failed to release migration lock")
+		}
+	}()
+
+	return fn()
+}
+
+// appliedMigrations loads the migration records by ID
+func (m *Migrator) appliedMigrations() (map[string]MigrationRecord, error) {
+	applied := make(map[string]MigrationRecord)
+	if !m.db.Migrator().HasTable(&MigrationRecord{}) {
+		return applied, nil
+	}
+
+	var records []MigrationRecord
+	if err := m.db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to check migration status: %w", err)
+	}
+	for _, record := range records {
+		applied[record.ID] = record
+	}
+	return applied, nil
+}
+
+// verify loads the applied migrations and checks that none was modified
+// since. Records without a checksum or with a legacy one are backfilled.
+func (m *Migrator) verify() (map[string]MigrationRecord, error) {
+	if m.dryRun == nil {
+		// Also adds the checksum column to older tracking tables
+		if err := m.db.AutoMigrate(&MigrationRecord{}); err != nil {
+			return nil, fmt.Errorf("failed to create migration table: %w", err)
+		}
+	}
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range m.migrations() {
+		record, ok := applied[migration.ID]
+		if !ok {
+			continue
+		}
+		checksum := m.checksum(migration)
+		if record.Checksum == checksum {
+			continue
+		}
+		if !legacyChecksum(record.Checksum) && !m.force {
+			return nil, fmt.Errorf("migration %s was modified after it was applied; restore it or rerun with -force", migration.ID)
+		}
+		if m.dryRun != nil {
+			continue
+		}
+		if err := m.db.Model(&MigrationRecord{}).Where("id = ?", migration.ID).
+			Update("checksum", checksum).Error; err != nil {
+			return nil, fmt.Errorf("failed to record checksum of %s: %w", migration.ID, err)
+		}
+	}
+	return applied, nil
+}
+
+// apply runs a migration and records it, in one transaction unless the
+// migration opts out
+func (m *Migrator) apply(migration Migration) error {
+	m.logger.WithFields(logrus.Fields{
+		"migration_id": migration.ID,
+		"description":  migration.Description,
+	}).Info("Running migration")
+
+	record := MigrationRecord{
+		ID:          migration.ID,
+		Description: migration.Description,
+		Checksum:    m.checksum(migration),
+	}
+	err := m.run(migration, "up", func(tx *gorm.DB) error {
+		if err := m.step(migration, migration.Up)(tx); err != nil {
+			return err
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.WithField("migration_id", migration.ID).Info("Migration completed successfully")
+	return nil
+}
+
+// revert runs a migration's Down and removes its record
+func (m *Migrator) revert(migration Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("migration %s is irreversible", migration.ID)
+	}
+
+	m.logger.WithField("migration_id", migration.ID).Info("Reverting migration")
+	err := m.run(migration, "down", func(tx *gorm.DB) error {
+		if err := m.step(migration, migration.Down)(tx); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", migration.ID).Delete(&MigrationRecord{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.WithField("migration_id", migration.ID).Info("Migration reverted successfully")
+	return nil
+}
+
+// step returns fn, or a no-op for Postgres-only migrations on other
+// databases
+func (m *Migrator) step(migration Migration, fn func(tx *gorm.DB) error) func(tx *gorm.DB) error {
+	if !migration.PostgresOnly || isPostgres(m.db) {
+		return fn
+	}
+	return func(*gorm.DB) error {
+		m.logger.WithFields(logrus.Fields{
+			"migration_id": migration.ID,
+			"dialect":      m.db.Dialector.Name(),
+		}).Info("Skipping Postgres-only migration")
+		return nil
+	}
+}
+
+// run executes fn in a transaction, directly for NoTransaction
+// migrations, or against a dry-run session that prints its SQL
+func (m *Migrator) run(migration Migration, direction string, fn func(tx *gorm.DB) error) error {
+	if m.dryRun != nil {
+		fmt.Fprintf(m.dryRun, "-- %s (%s)\n", migration.ID, direction)
+		return fn(m.db.Session(&gorm.Session{
+			DryRun:                 true,
+			SkipDefaultTransaction: true,
+			Logger:                 &sqlRecorder{w: m.dryRun},
+		}))
+	}
+	if migration.NoTransaction {
+		return fn(m.db)
+	}
+	return m.db.Transaction(fn)
+}
+
+// isPostgres reports whether db is a Postgres database
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// execOptional runs a statement whose failure is tolerated. Inside a
+// transaction it is wrapped in a savepoint so the failure does not abort
+// the rest of the migration.
+func execOptional(tx *gorm.DB, sql string) error {
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return tx.Exec(sql).Error
+	}
+
+	const savepoint = "optional_statement"
+	if err := tx.SavePoint(savepoint).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(sql).Error; err != nil {
+		if rollbackErr := tx.RollbackTo(savepoint).Error; rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// sqlRecorder is a GORM logger that writes each statement to w
+type sqlRecorder struct {
+	w io.Writer
+}
+
+func (r *sqlRecorder) LogMode(gormlogger.LogLevel) gormlogger.Interface { return r }
+
+func (r *sqlRecorder) Info(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	statement, _ := fc()
+	fmt.Fprintf(r.w, "%s;\n", strings.TrimSpace(statement))
+}
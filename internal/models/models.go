@@ -2,18 +2,27 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"alertbot/internal/matcher"
 )
 
 type JSONB map[string]interface{}
 
+// Value encodes j as JSON text, which both Postgres JSONB and SQLite's
+// JSON functions accept
 func (j JSONB) Value() (driver.Value, error) {
 	if j == nil {
 		return nil, nil
 	}
-	return json.Marshal(j)
+	data, err := json.Marshal(j)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
 }
 
 func (j *JSONB) Scan(value interface{}) error {
@@ -22,12 +31,14 @@ func (j *JSONB) Scan(value interface{}) error {
 		return nil
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, j)
+	case string:
+		return json.Unmarshal([]byte(v), j)
+	default:
 		return fmt.Errorf("cannot scan %T into JSONB", value)
 	}
-
-	return json.Unmarshal(bytes, j)
 }
 
 type Alert struct {
@@ -39,6 +50,7 @@ type Alert struct {
 	Severity    string    `json:"severity" gorm:"size:20;default:warning;index"`
 	StartsAt    time.Time `json:"starts_at" gorm:"not null"`
 	EndsAt      *time.Time `json:"ends_at"`
+	SilencedStatus string `json:"silenced_status,omitempty" gorm:"size:20"` // Status to restore when a manual silence is released
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime;index"`
 }
@@ -67,6 +79,7 @@ type NotificationChannel struct {
 
 type Silence struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name,omitempty" gorm:"size:255;index"` // Set for silences managed by config apply
 	Matchers  JSONB     `json:"matchers" gorm:"type:jsonb;not null"`
 	StartsAt  time.Time `json:"starts_at" gorm:"not null"`
 	EndsAt    time.Time `json:"ends_at" gorm:"not null"`
@@ -97,6 +110,120 @@ type AlertFilters struct {
 	Size      int    `json:"size" form:"size"`
 	Sort      string `json:"sort" form:"sort"`
 	Order     string `json:"order" form:"order"`
+
+	// Label matcher expression, e.g. team="db",env=~"prod.*"
+	Query string `json:"query" form:"query"`
+	// Full-text search over annotation values
+	Search string `json:"search" form:"search"`
+
+	// Time range filters (RFC3339)
+	StartsAfter  time.Time `json:"starts_after" form:"starts_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	StartsBefore time.Time `json:"starts_before" form:"starts_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndsAfter    time.Time `json:"ends_after" form:"ends_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndsBefore   time.Time `json:"ends_before" form:"ends_before" time_format:"2006-01-02T15:04:05Z07:00"`
+
+	// Opaque keyset cursor returned by a previous page; replaces Page when set
+	Cursor string `json:"cursor" form:"cursor"`
+
+	// Saved view whose filters are merged into this request
+	ViewID uint `json:"view_id" form:"view_id"`
+}
+
+// AlertCursor identifies the last row of a page for keyset pagination
+type AlertCursor struct {
+	Value time.Time
+	ID    uint
+}
+
+// Encode returns the opaque string form of the cursor
+func (c AlertCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.Value.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeAlertCursor parses a cursor produced by AlertCursor.Encode
+func DecodeAlertCursor(s string) (*AlertCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var nanos int64
+	var id uint
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &nanos, &id); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &AlertCursor{Value: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+// CursorFor builds the cursor pointing at alert for the given sort column
+func CursorFor(alert Alert, sort string) AlertCursor {
+	value := alert.CreatedAt
+	switch sort {
+	case "starts_at":
+		value = alert.StartsAt
+	case "updated_at":
+		value = alert.UpdatedAt
+	}
+	return AlertCursor{Value: value, ID: alert.ID}
+}
+
+// SavedView is a named alert filter preset shared by the dashboard and the live stream
+type SavedView struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:255;not null"`
+	Owner     string    `json:"owner" gorm:"size:255;not null;index"`
+	Shared    bool      `json:"shared" gorm:"default:false;index"`
+	Query     string    `json:"query" gorm:"type:text"`                // Label matcher expression
+	Status    string    `json:"status" gorm:"size:20"`
+	Severity  string    `json:"severity" gorm:"size:20"`
+	Sort      string    `json:"sort" gorm:"size:50"`
+	Order     string    `json:"order" gorm:"size:10"`
+	Columns   JSONB     `json:"columns" gorm:"type:jsonb;default:'{}'"` // {"columns": ["alertname", ...]}
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// VisibleTo reports whether the given user may use this view
+func (v *SavedView) VisibleTo(username string) bool {
+	return v.Shared || v.Owner == username
+}
+
+// Apply merges the view into the filters. The view's label query is ANDed
+// with any query already present; status, severity and sort only fill in
+// values the request left empty.
+func (v *SavedView) Apply(filters *AlertFilters) {
+	filters.Query = matcher.Join(v.Query, filters.Query)
+	if filters.Status == "" {
+		filters.Status = v.Status
+	}
+	if filters.Severity == "" {
+		filters.Severity = v.Severity
+	}
+	if filters.Sort == "" {
+		filters.Sort = v.Sort
+	}
+	if filters.Order == "" {
+		filters.Order = v.Order
+	}
+}
+
+// RevokedToken records a session token invalidated before its expiry
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TokenID   string    `json:"token_id" gorm:"size:64;not null;uniqueIndex"`
+	Username  string    `json:"username" gorm:"size:255"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// UsedTicket records a redeemed WebSocket ticket so it is single use across replicas
+type UsedTicket struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TicketID  string    `json:"ticket_id" gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
 
 type AlertHistoryFilters struct {
@@ -116,6 +243,20 @@ type PrometheusAlert struct {
 	GeneratorURL string            `json:"generatorURL"`
 }
 
+// AlertIngestResult is the outcome of ingesting one received alert, in the
+// order the alerts were received
+type AlertIngestResult struct {
+	Index       int    `json:"index"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Action is created, updated, deduplicated, dropped or failed
+	Action string `json:"action"`
+	Status string `json:"status,omitempty"`
+	// DuplicateOf is the fingerprint of the alert a duplicate was merged into
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	Stage       string `json:"stage,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
 type AlertStatus string
 
 const (
@@ -242,15 +383,33 @@ type PrometheusConfig struct {
 	UpdatedAt          time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
-// NotificationConfig stores global notification configuration
+// NotificationConfig stores global notification configuration. Each channel
+// may send RateLimit notifications per RateLimitPer (a duration such as "1m"),
+// with up to RateLimitBurst sent back to back.
 type NotificationConfig struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	MaxRetries    int       `json:"max_retries" gorm:"default:3"`
-	RetryInterval int       `json:"retry_interval" gorm:"default:30"`
-	RateLimit     int       `json:"rate_limit" gorm:"default:100"`
-	BatchSize     int       `json:"batch_size" gorm:"default:10"`
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	MaxRetries     int       `json:"max_retries" gorm:"default:3"`
+	RetryInterval  int       `json:"retry_interval" gorm:"default:30"`
+	RateLimit      int       `json:"rate_limit" gorm:"default:100"`
+	RateLimitPer   string    `json:"rate_limit_per" gorm:"size:20;default:'1m'"`
+	RateLimitBurst int       `json:"rate_limit_burst" gorm:"default:10"`
+	BatchSize      int       `json:"batch_size" gorm:"default:10"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Defaults for rows and requests that predate the explicit rate limit settings
+const (
+	DefaultRateLimitPer   = "1m"
+	DefaultRateLimitBurst = 10
+)
+
+// RateLimitInterval parses RateLimitPer, falling back to DefaultRateLimitPer when unset
+func (c *NotificationConfig) RateLimitInterval() (time.Duration, error) {
+	if c.RateLimitPer == "" {
+		return time.ParseDuration(DefaultRateLimitPer)
+	}
+	return time.ParseDuration(c.RateLimitPer)
 }
 
 // AlertRelations contains information about alert relationships and deduplication
@@ -264,16 +423,19 @@ type AlertRelations struct {
 	DeduplicationAction string   `json:"deduplication_action"`
 }
 
-// DeduplicationConfig contains configuration for alert deduplication
+// DeduplicationConfig contains configuration for alert deduplication.
+// It is stored as a single settings row so all replicas share it.
 type DeduplicationConfig struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	
 	// Time window for deduplication (alerts within this window are considered duplicates)
 	DeduplicationWindow time.Duration `json:"deduplication_window"`
 	
 	// Labels to ignore when generating fingerprints
-	IgnoreLabels []string `json:"ignore_labels"`
+	IgnoreLabels []string `json:"ignore_labels" gorm:"type:jsonb;serializer:json"`
 	
 	// Labels that must match for correlation
-	CorrelationLabels []string `json:"correlation_labels"`
+	CorrelationLabels []string `json:"correlation_labels" gorm:"type:jsonb;serializer:json"`
 	
 	// Time window for correlation (alerts within this window can be correlated)
 	CorrelationWindow time.Duration `json:"correlation_window"`
@@ -289,4 +451,369 @@ type DeduplicationConfig struct {
 	
 	// Enable alert correlation
 	EnableCorrelation bool `json:"enable_correlation"`
-}
\ No newline at end of file
+	
+	// Scoped overrides, evaluated in order; the first matching one applies
+	Overrides []DeduplicationOverride `json:"overrides" gorm:"type:jsonb;serializer:json"`
+	
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// DeduplicationOverride replaces parts of the deduplication config for alerts
+// with a given alertname and/or matching a label matcher expression.
+// Zero windows and null label lists inherit the global value.
+type DeduplicationOverride struct {
+	Name                string        `json:"name"`
+	AlertName           string        `json:"alertname,omitempty"`
+	Matchers            string        `json:"matchers,omitempty"` // e.g. {team="db",env=~"prod.*"}
+	DeduplicationWindow time.Duration `json:"deduplication_window,omitempty"`
+	CorrelationWindow   time.Duration `json:"correlation_window,omitempty"`
+	IgnoreLabels        []string      `json:"ignore_labels"`
+	CorrelationLabels   []string      `json:"correlation_labels"`
+}
+
+// DefaultDeduplicationConfig returns the built-in deduplication settings
+func DefaultDeduplicationConfig() *DeduplicationConfig {
+	return &DeduplicationConfig{
+		DeduplicationWindow:     5 * time.Minute,
+		CorrelationWindow:       30 * time.Minute,
+		MaxRelatedAlerts:        10,
+		EnableTimeBasedDedup:    true,
+		EnableContentBasedDedup: true,
+		EnableCorrelation:       true,
+		IgnoreLabels: []string{
+			"__name__",
+			"__tmp_",
+			"timestamp",
+			"receive_timestamp",
+		},
+		CorrelationLabels: []string{
+			"instance",
+			"job",
+			"service",
+			"cluster",
+			"node",
+		},
+	}
+}
+
+// DeduplicationDryRunRequest replays recent alerts against a proposed config
+type DeduplicationDryRunRequest struct {
+	Hours  int                 `json:"hours"`
+	Config DeduplicationConfig `json:"config"`
+}
+
+// DeduplicationReplay summarizes what a config deduplicates over a set of alerts
+type DeduplicationReplay struct {
+	Duplicates int                        `json:"duplicates"`
+	Groups     []DeduplicationReplayGroup `json:"groups"`
+}
+
+// DeduplicationReplayGroup is an alert and the alerts merged into it
+type DeduplicationReplayGroup struct {
+	Fingerprint      string   `json:"fingerprint"`
+	AlertName        string   `json:"alertname"`
+	DeduplicationKey string   `json:"deduplication_key"`
+	Override         string   `json:"override,omitempty"`
+	Duplicates       []string `json:"duplicates"`
+}
+
+// DeduplicationDryRunResult compares the current and proposed configs
+type DeduplicationDryRunResult struct {
+	Hours                int                 `json:"hours"`
+	SampleSize           int                 `json:"sample_size"`
+	Current              DeduplicationReplay `json:"current"`
+	Proposed             DeduplicationReplay `json:"proposed"`
+	NewlyDeduplicated    []string            `json:"newly_deduplicated"`
+	NoLongerDeduplicated []string            `json:"no_longer_deduplicated"`
+}
+type IncidentStatus string
+
+const (
+	IncidentStatusOpen         IncidentStatus = "open"
+	IncidentStatusAcknowledged IncidentStatus = "acknowledged"
+	IncidentStatusResolved     IncidentStatus = "resolved"
+)
+
+// Incident groups correlated alerts so they are handled and notified as one
+type Incident struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Title          string     `json:"title" gorm:"size:255;not null"`
+	CorrelationKey string     `json:"correlation_key" gorm:"size:64;index"`
+	Status         string     `json:"status" gorm:"size:20;default:open;index"`
+	Severity       string     `json:"severity" gorm:"size:20;default:warning"`
+	Owner          string     `json:"owner" gorm:"size:255;index"`
+	AlertCount     int        `json:"alert_count" gorm:"default:0"`
+	StartedAt      time.Time  `json:"started_at" gorm:"not null"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	NotifiedAt     *time.Time `json:"notified_at"` // Set once the incident's single notification was routed
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Alerts []IncidentAlert `json:"alerts,omitempty" gorm:"foreignKey:IncidentID;constraint:OnDelete:CASCADE"`
+	Notes  []IncidentNote  `json:"notes,omitempty" gorm:"foreignKey:IncidentID;constraint:OnDelete:CASCADE"`
+}
+
+// IsOpen reports whether alerts may still attach to the incident
+func (i *Incident) IsOpen() bool {
+	return i.Status != string(IncidentStatusResolved)
+}
+
+// IncidentAlert attaches an alert to an incident
+type IncidentAlert struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	IncidentID       uint      `json:"incident_id" gorm:"not null;uniqueIndex:idx_incident_alert"`
+	AlertFingerprint string    `json:"alert_fingerprint" gorm:"size:64;not null;uniqueIndex:idx_incident_alert;index"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// IncidentNote is a comment or a recorded change on an incident
+type IncidentNote struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	IncidentID uint      `json:"incident_id" gorm:"not null;index"`
+	Kind       string    `json:"kind" gorm:"size:20;default:note"` // note, status or owner
+	Author     string    `json:"author" gorm:"size:255"`
+	Content    string    `json:"content" gorm:"type:text;not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// IncidentUpdate holds the fields changed by an incident update; nil fields are kept
+type IncidentUpdate struct {
+	Title    *string `json:"title"`
+	Status   *string `json:"status"`
+	Severity *string `json:"severity"`
+	Owner    *string `json:"owner"`
+}
+
+type IncidentFilters struct {
+	Status string `json:"status" form:"status"`
+	Owner  string `json:"owner" form:"owner"`
+	Page   int    `json:"page" form:"page"`
+	Size   int    `json:"size" form:"size"`
+}
+
+// IncidentTimelineEntry is one event on an incident timeline
+type IncidentTimelineEntry struct {
+	Time             time.Time `json:"time"`
+	Source           string    `json:"source"` // alert, note or incident
+	Action           string    `json:"action"`
+	AlertFingerprint string    `json:"alert_fingerprint,omitempty"`
+	Author           string    `json:"author,omitempty"`
+	Details          JSONB     `json:"details,omitempty"`
+}
+
+// TopologyGraph is the uploaded service/host dependency graph. It is stored
+// as a single settings row so all replicas share it.
+type TopologyGraph struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	Nodes            []TopologyNode `json:"nodes" yaml:"nodes" gorm:"type:jsonb;serializer:json"`
+	SuppressSymptoms bool           `json:"suppress_symptoms" yaml:"suppress_symptoms" gorm:"default:true"`
+	CreatedAt        time.Time      `json:"created_at" yaml:"-" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time      `json:"updated_at" yaml:"-" gorm:"autoUpdateTime"`
+}
+
+// TopologyNode is a service, database or host in the dependency graph.
+// Matchers select the alerts raised for the node; when empty they default
+// to {service="<name>"}.
+type TopologyNode struct {
+	Name      string   `json:"name" yaml:"name"`
+	Type      string   `json:"type" yaml:"type"` // service, database, host, ...
+	Matchers  string   `json:"matchers" yaml:"matchers"`
+	DependsOn []string `json:"depends_on" yaml:"depends_on"`
+}
+
+// DefaultTopologyGraph returns an empty graph with symptom suppression on
+func DefaultTopologyGraph() *TopologyGraph {
+	return &TopologyGraph{
+		Nodes:            []TopologyNode{},
+		SuppressSymptoms: true,
+	}
+}
+
+// RelabelConfigList is the ordered list of relabel configs applied to
+// received alerts before they are fingerprinted. It is stored as a single
+// settings row so all replicas share it.
+type RelabelConfigList struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	Configs   []RelabelConfig `json:"configs" yaml:"configs" gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time       `json:"created_at" yaml:"-" gorm:"autoCreateTime"`
+	UpdatedAt time.Time       `json:"updated_at" yaml:"-" gorm:"autoUpdateTime"`
+}
+
+// RelabelConfig rewrites alert labels the way Prometheus relabel_configs do.
+// Empty fields take the Prometheus defaults: separator ";", regex "(.*)",
+// replacement "$1" and action replace.
+type RelabelConfig struct {
+	SourceLabels []string `json:"source_labels,omitempty" yaml:"source_labels,omitempty"`
+	Separator    string   `json:"separator,omitempty" yaml:"separator,omitempty"`
+	Regex        string   `json:"regex,omitempty" yaml:"regex,omitempty"`
+	TargetLabel  string   `json:"target_label,omitempty" yaml:"target_label,omitempty"`
+	Replacement  string   `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// Action is replace, labelmap, labeldrop, labelkeep, drop or keep
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+}
+
+// DefaultRelabelConfigList returns an empty list, which leaves labels as received
+func DefaultRelabelConfigList() *RelabelConfigList {
+	return &RelabelConfigList{Configs: []RelabelConfig{}}
+}
+
+// FingerprintStrategy records the label patterns the stored alert
+// fingerprints were computed with, so a server configured differently can
+// refuse to start. It is stored as a single settings row so all replicas
+// share it.
+type FingerprintStrategy struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	IncludeLabels []string  `json:"include_labels" gorm:"type:jsonb;serializer:json"`
+	ExcludeLabels []string  `json:"exclude_labels" gorm:"type:jsonb;serializer:json"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RelabelTestRequest runs label sets through relabel configs without storing
+// them. Without configs the stored ones are used.
+type RelabelTestRequest struct {
+	Configs []RelabelConfig     `json:"configs"`
+	Alerts  []map[string]string `json:"alerts" binding:"required,min=1"`
+}
+
+// RelabelTestResult shows one label set before and after relabeling
+type RelabelTestResult struct {
+	Before            map[string]string `json:"before"`
+	After             map[string]string `json:"after,omitempty"`
+	Dropped           bool              `json:"dropped"`
+	FingerprintBefore string            `json:"fingerprint_before"`
+	FingerprintAfter  string            `json:"fingerprint_after,omitempty"`
+	Severity          string            `json:"severity,omitempty"`
+}
+
+// RootCauseCandidate is a firing alert on a node the analysed alert depends on
+type RootCauseCandidate struct {
+	Alert    *Alert `json:"alert"`
+	Node     string `json:"node"`
+	Distance int    `json:"distance"` // Dependency hops from the analysed alert's node
+}
+
+// RootCauseAnalysis explains an alert in terms of the dependency graph
+type RootCauseAnalysis struct {
+	Alert      *Alert               `json:"alert"`
+	Nodes      []string             `json:"nodes"`
+	IsSymptom  bool                 `json:"is_symptom"`
+	RootCause  *RootCauseCandidate  `json:"root_cause,omitempty"`
+	Path       []string             `json:"path,omitempty"` // Nodes from the alert's node to the root cause
+	Candidates []RootCauseCandidate `json:"candidates"`
+}
+
+// ConfigChangeAction is what an import does to one object
+type ConfigChangeAction string
+
+const (
+	ConfigChangeCreate    ConfigChangeAction = "create"
+	ConfigChangeUpdate    ConfigChangeAction = "update"
+	ConfigChangeUnchanged ConfigChangeAction = "unchanged"
+	ConfigChangeDelete    ConfigChangeAction = "delete"
+)
+
+// ConfigChange describes one object created, updated or deleted by an import
+// or a config apply
+type ConfigChange struct {
+	Kind   string             `json:"kind"` // notification_channel, routing_rule, inhibition_rule, alert_group_rule, ...
+	Name   string             `json:"name"`
+	Action ConfigChangeAction `json:"action"`
+	Fields []string           `json:"fields,omitempty"` // Changed fields of an update
+}
+
+// ConfigIssue is a construct an import or export could not translate exactly
+type ConfigIssue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ImportReport is the outcome, or with DryRun the plan, of a config import
+type ImportReport struct {
+	DryRun      bool           `json:"dry_run"`
+	Changes     []ConfigChange `json:"changes"`
+	Unsupported []ConfigIssue  `json:"unsupported"`
+}
+
+// ApplyReport is the outcome, or with DryRun the plan, of a config apply
+type ApplyReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Prune   bool           `json:"prune"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// NotificationTemplate renders the title and content of notifications for
+// routing rules that name it in their receivers
+type NotificationTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:255;not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:text"`
+	Title       string    `json:"title" gorm:"type:text"`            // Go text/template; default title when empty
+	Content     string    `json:"content" gorm:"type:text;not null"` // Go text/template
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type HeartbeatStatus string
+
+const (
+	HeartbeatStatusPending HeartbeatStatus = "pending" // No ping received yet
+	HeartbeatStatusUp      HeartbeatStatus = "up"
+	HeartbeatStatusMissed  HeartbeatStatus = "missed"
+)
+
+// Heartbeat is a dead man's switch: it expects a ping at least every
+// Interval seconds and raises an alert when none arrived within Interval
+// plus Grace. Pings come from the ping endpoint or from firing alerts that
+// match Matchers, such as Prometheus' always-firing Watchdog alert.
+type Heartbeat struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name" gorm:"size:255;not null;uniqueIndex"`
+	Description string     `json:"description" gorm:"type:text"`
+	Interval    int        `json:"interval" gorm:"not null"`   // Expected seconds between pings
+	Grace       int        `json:"grace" gorm:"default:60"`    // Extra seconds before a ping counts as missed
+	Matchers    JSONB      `json:"matchers" gorm:"type:jsonb"` // Firing alerts matching these count as pings
+	Labels      JSONB      `json:"labels" gorm:"type:jsonb"`   // Extra labels for the missed heartbeat alert
+	Enabled     bool       `json:"enabled" gorm:"default:true"`
+	Status      string     `json:"status" gorm:"size:20;default:pending"`
+	LastPingAt  *time.Time `json:"last_ping_at"`
+	MissedAt    *time.Time `json:"missed_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// ClusterMember is an alertbot replica sharing the database. Each replica
+// refreshes its row on every cluster heartbeat.
+type ClusterMember struct {
+	NodeID     string    `json:"node_id" gorm:"primaryKey;size:128"`
+	Hostname   string    `json:"hostname" gorm:"size:255"`
+	Address    string    `json:"address" gorm:"size:255"`
+	Leader     bool      `json:"leader"`
+	StartedAt  time.Time `json:"started_at"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"index"`
+}
+
+// NotificationClaim records the replica that sends a notification, so
+// replicas receiving the same alert do not all send it
+type NotificationClaim struct {
+	ClaimKey  string    `json:"claim_key" gorm:"primaryKey;size:255"` // fingerprint:rule:channel:status
+	NodeID    string    `json:"node_id" gorm:"size:128"`
+	ClaimedAt time.Time `json:"claimed_at" gorm:"index"`
+}
+
+// EventPayload holds a streaming event too large for a Postgres
+// notification; the notification carries its ID instead
+type EventPayload struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:36"`
+	Type      string    `json:"type" gorm:"size:100"`
+	Payload   string    `json:"payload" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// RateLimitBucket is a rate limit token bucket shared by all replicas,
+// stored as the Unix time in seconds at which it is full again
+type RateLimitBucket struct {
+	BucketKey string  `json:"bucket_key" gorm:"primaryKey;size:255"`
+	TAT       float64 `json:"tat" gorm:"column:tat;index"`
+}
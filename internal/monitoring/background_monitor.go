@@ -22,6 +22,9 @@ type BackgroundMonitor struct {
 	
 	// Configuration
 	config BackgroundMonitorConfig
+
+	// isLeader gates cleanup to one replica; nil means always run
+	isLeader func() bool
 	
 	// Control
 	stopChan chan struct{}
@@ -106,6 +109,14 @@ func NewBackgroundMonitor(repos *repository.Repositories, db *gorm.DB, logger *l
 	}
 }
 
+// SetLeaderCheck makes cleanup run only while isLeader reports true.
+// Metrics collection stays on every replica.
+func (bm *BackgroundMonitor) SetLeaderCheck(isLeader func() bool) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.isLeader = isLeader
+}
+
 // Start begins background monitoring
 func (bm *BackgroundMonitor) Start(ctx context.Context) error {
 	bm.mu.Lock()
@@ -438,6 +449,11 @@ func (bm *BackgroundMonitor) checkPerformanceThresholds() {
 
 // performCleanup performs cleanup tasks
 func (bm *BackgroundMonitor) performCleanup() {
+	if bm.isLeader != nil && !bm.isLeader() {
+		bm.logger.Debug("Skipping cleanup tasks, not the cluster leader")
+		return
+	}
+
 	start := time.Now()
 	defer func() {
 		metrics.RecordBackgroundJob("cleanup", "success", time.Since(start).Seconds())
@@ -455,6 +471,13 @@ func (bm *BackgroundMonitor) performCleanup() {
 		bm.logger.WithField("cutoff", cutoff).Info("Alert history cleanup completed")
 	}
 
+	// Clean up expired inhibitions
+	if bm.repositories != nil && bm.repositories.Inhibition != nil {
+		if err := bm.repositories.Inhibition.CleanupExpiredInhibitions(context.Background()); err != nil {
+			bm.logger.WithError(err).Error("Failed to clean up expired inhibitions")
+		}
+	}
+
 	// Force garbage collection
 	runtime.GC()
 	
@@ -70,14 +70,14 @@ func (d *DatabaseHealthChecker) CheckHealth(ctx context.Context) HealthStatus {
 	status.Details["wait_duration"] = stats.WaitDuration.String()
 
 	// Check if we can perform a simple query
-	var count int64
-	if err := d.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM information_schema.tables").Scan(&count).Error; err != nil {
+	tables, err := d.db.WithContext(ctx).Migrator().GetTables()
+	if err != nil {
 		status.Status = "degraded"
 		status.Message = "Database query failed: " + err.Error()
 		return status
 	}
 
-	status.Details["tables_count"] = count
+	status.Details["tables_count"] = len(tables)
 
 	// Check connection usage
 	maxConn := stats.MaxOpenConnections
@@ -27,6 +27,9 @@ type MonitoringService struct {
 	
 	// Monitoring configuration
 	config MonitoringConfig
+
+	// isLeader gates periodic health checks to one replica; nil means always run
+	isLeader func() bool
 	
 	// Background monitoring
 	stopChan chan struct{}
@@ -186,6 +189,14 @@ func (ms *MonitoringService) RegisterHealthChecker(checker HealthChecker) {
 	ms.healthCheckers[checker.Name()] = checker
 }
 
+// SetLeaderCheck makes periodic health checks run only while isLeader
+// reports true. System metrics collection stays on every replica.
+func (ms *MonitoringService) SetLeaderCheck(isLeader func() bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.isLeader = isLeader
+}
+
 // Start starts the monitoring service
 func (ms *MonitoringService) Start(ctx context.Context) error {
 	ms.logger.Info("Starting monitoring service")
@@ -323,6 +334,11 @@ func (ms *MonitoringService) updateSystemMetrics() {
 func (ms *MonitoringService) performHealthChecks(ctx context.Context) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
+
+	if ms.isLeader != nil && !ms.isLeader() {
+		ms.logger.Debug("Skipping health checks, not the cluster leader")
+		return
+	}
 	
 	for name, checker := range ms.healthCheckers {
 		start := time.Now()
@@ -472,7 +472,7 @@ func (e *EmailChannel) authenticateAndSend(client *smtp.Client, config *EmailCon
 
 // testConnection tests SMTP connection without sending email
 func (e *EmailChannel) testConnection(ctx context.Context, config *EmailConfig) error {
-	addr := fmt.Sprintf("%s:%d", config.SMTPHost, config.SMTPPort)
+	addr := net.JoinHostPort(config.SMTPHost, strconv.Itoa(config.SMTPPort))
 	
 	// Test connection based on TLS configuration
 	if config.UseTLS {
@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// maxRateLimitWait bounds how long a send may queue behind the rate limits
+// before it fails instead of piling up goroutines
+const maxRateLimitWait = time.Minute
+
+// ErrRateLimited is returned when a notification would wait longer than maxRateLimitWait
+var ErrRateLimited = fmt.Errorf("notification rate limit exceeded")
+
+// sendLimiter throttles outbound notifications globally and per channel
+type sendLimiter struct {
+	mu         sync.Mutex
+	global     *rate.Limiter
+	perChannel map[string]*rate.Limiter
+
+	// Per-channel allowance
+	channelLimit rate.Limit
+	channelBurst int
+}
+
+func newSendLimiter(globalRPS, globalBurst int, channelLimit int, channelPer time.Duration, channelBurst int) *sendLimiter {
+	l := &sendLimiter{perChannel: make(map[string]*rate.Limiter)}
+	l.setGlobal(globalRPS, globalBurst)
+	l.setChannel(channelLimit, channelPer, channelBurst)
+	return l
+}
+
+// setGlobal changes the limit shared by all channels
+func (l *sendLimiter) setGlobal(rps, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := rate.Limit(rps)
+	if rps <= 0 {
+		limit = rate.Inf
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	if l.global == nil {
+		l.global = rate.NewLimiter(limit, burst)
+		return
+	}
+	l.global.SetLimit(limit)
+	l.global.SetBurst(burst)
+}
+
+// setChannel changes the per-channel limit to limit notifications every per
+func (l *sendLimiter) setChannel(limit int, per time.Duration, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if per <= 0 {
+		per = time.Minute
+	}
+	l.channelLimit = rate.Limit(float64(limit) / per.Seconds())
+	if limit <= 0 {
+		l.channelLimit = rate.Inf
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	l.channelBurst = burst
+
+	// Existing limiters keep their tokens and pick up the new rate
+	for _, limiter := range l.perChannel {
+		limiter.SetLimit(l.channelLimit)
+		limiter.SetBurst(l.channelBurst)
+	}
+}
+
+func (l *sendLimiter) channel(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.perChannel[key]
+	if !ok {
+		limiter = rate.NewLimiter(l.channelLimit, l.channelBurst)
+		l.perChannel[key] = limiter
+	}
+	return limiter
+}
+
+// wait blocks until both the channel and the global limiter admit one send.
+// Reservations are cancelled when the combined delay exceeds maxRateLimitWait.
+func (l *sendLimiter) wait(ctx context.Context, key string) error {
+	now := time.Now()
+
+	channelRes := l.channel(key).ReserveN(now, 1)
+	if !channelRes.OK() {
+		return ErrRateLimited
+	}
+	globalRes := l.global.ReserveN(now, 1)
+	if !globalRes.OK() {
+		channelRes.Cancel()
+		return ErrRateLimited
+	}
+
+	delay := channelRes.DelayFrom(now)
+	if d := globalRes.DelayFrom(now); d > delay {
+		delay = d
+	}
+	if delay > maxRateLimitWait {
+		channelRes.Cancel()
+		globalRes.Cancel()
+		return ErrRateLimited
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		channelRes.Cancel()
+		globalRes.Cancel()
+		return ctx.Err()
+	}
+}
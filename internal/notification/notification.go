@@ -3,6 +3,7 @@ package notification
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"alertbot/internal/errors"
@@ -19,6 +20,8 @@ type NotificationManager struct {
 	logger         *logrus.Logger
 	circuitBreaker *recovery.CircuitBreaker
 	retryConfig    recovery.RetryConfig
+	limiter        *sendLimiter
+	mu             sync.RWMutex
 }
 
 // NotificationChannel interface for all notification channels
@@ -36,6 +39,7 @@ type NotificationMessage struct {
 	Alert       *models.Alert          `json:"alert,omitempty"`
 	ChannelConfig map[string]interface{} `json:"channel_config"`
 	Template    string                 `json:"template,omitempty"`
+	ChannelID   uint                   `json:"channel_id,omitempty"` // Rate limit key; the channel type is used when zero
 }
 
 func NewNotificationManager(logger *logrus.Logger) *NotificationManager {
@@ -59,6 +63,8 @@ func NewNotificationManager(logger *logrus.Logger) *NotificationManager {
 			},
 			Logger: logger,
 		},
+		// Defaults match models.NotificationConfig until ApplyConfig is called
+		limiter: newSendLimiter(30, 100, 100, time.Minute, models.DefaultRateLimitBurst),
 	}
 
 	// Register notification channels
@@ -79,11 +85,27 @@ func (nm *NotificationManager) SendNotification(ctx context.Context, channelType
 		return errors.NewNotFoundError("notification channel", string(channelType))
 	}
 
+	limitKey := fmt.Sprintf("type:%s", channelType)
+	if message.ChannelID != 0 {
+		limitKey = fmt.Sprintf("channel:%d", message.ChannelID)
+	}
+
+	nm.mu.RLock()
+	retryConfig := nm.retryConfig
+	nm.mu.RUnlock()
+
 	start := time.Now()
 	
-	// Use retry with circuit breaker
-	err := recovery.RetryWithCircuitBreaker(ctx, nm.retryConfig, nm.circuitBreaker, func(ctx context.Context) error {
-		return channel.Send(ctx, message)
+	// Use retry with circuit breaker. Every attempt counts against the rate
+	// limits, which are checked outside the breaker so throttling never opens it.
+	err := recovery.Retry(ctx, retryConfig, func(ctx context.Context) error {
+		if err := nm.limiter.wait(ctx, limitKey); err != nil {
+			metrics.RecordNotificationError(string(channelType), "rate_limited")
+			return err
+		}
+		return nm.circuitBreaker.Execute(ctx, func(ctx context.Context) error {
+			return channel.Send(ctx, message)
+		})
 	})
 	
 	duration := time.Since(start)
@@ -194,9 +216,61 @@ func (nm *NotificationManager) TestChannelWithConfig(ctx context.Context, channe
 }
 
 // SendAlertNotification sends an alert notification with proper formatting
-func (nm *NotificationManager) SendAlertNotification(ctx context.Context, alert *models.Alert, channelConfig models.JSONB, channelType models.NotificationChannelType) error {
-	message := nm.formatAlertMessage(alert, channelConfig)
-	return nm.SendNotification(ctx, channelType, message)
+func (nm *NotificationManager) SendAlertNotification(ctx context.Context, alert *models.Alert, channel *models.NotificationChannel) error {
+	return nm.SendTemplatedAlertNotification(ctx, alert, channel, nil)
+}
+
+// SendTemplatedAlertNotification sends an alert notification rendered with a
+// stored template, falling back to the default format if rendering fails
+func (nm *NotificationManager) SendTemplatedAlertNotification(ctx context.Context, alert *models.Alert, channel *models.NotificationChannel, tmpl *models.NotificationTemplate) error {
+	message := nm.formatAlertMessage(alert, channel.Config)
+	message.ChannelID = channel.ID
+	if tmpl != nil {
+		if err := applyTemplate(message, alert, tmpl); err != nil {
+			nm.logger.WithError(err).WithField("template", tmpl.Name).Warn("Failed to render notification template, using default format")
+		}
+	}
+	return nm.SendNotification(ctx, models.NotificationChannelType(channel.Type), message)
+}
+
+// ApplyConfig updates retry and per-channel rate limit settings without a restart.
+// Each channel may send RateLimit notifications per RateLimitPer, bursting to RateLimitBurst.
+func (nm *NotificationManager) ApplyConfig(cfg *models.NotificationConfig) {
+	interval := time.Duration(cfg.RetryInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	nm.mu.Lock()
+	nm.retryConfig.MaxAttempts = cfg.MaxRetries + 1
+	nm.retryConfig.InitialDelay = interval
+	nm.retryConfig.MaxDelay = interval
+	nm.retryConfig.BackoffFactor = 1.0
+	nm.mu.Unlock()
+
+	per, err := cfg.RateLimitInterval()
+	if err != nil {
+		nm.logger.WithError(err).WithField("rate_limit_per", cfg.RateLimitPer).Warn("Invalid notification rate limit interval, using 1m")
+		per = time.Minute
+	}
+	burst := cfg.RateLimitBurst
+	if burst <= 0 {
+		burst = models.DefaultRateLimitBurst
+	}
+	nm.limiter.setChannel(cfg.RateLimit, per, burst)
+
+	nm.logger.WithFields(logrus.Fields{
+		"max_retries":      cfg.MaxRetries,
+		"retry_interval":   interval,
+		"rate_limit":       cfg.RateLimit,
+		"rate_limit_per":   per,
+		"rate_limit_burst": burst,
+	}).Info("Applied notification settings")
+}
+
+// SetGlobalRateLimit caps outbound notifications across all channels
+func (nm *NotificationManager) SetGlobalRateLimit(rps, burst int) {
+	nm.limiter.setGlobal(rps, burst)
 }
 
 // formatAlertMessage formats an alert into a notification message
@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"alertbot/internal/models"
+)
+
+// TemplateData is what notification templates are executed against
+type TemplateData struct {
+	Alert       *models.Alert
+	Labels      map[string]string
+	Annotations map[string]string
+	Status      string
+	Severity    string
+	StartsAt    time.Time
+	EndsAt      *time.Time
+}
+
+// ParseTemplate compiles a stored template, reporting syntax errors
+func ParseTemplate(tmpl *models.NotificationTemplate) (title, content *template.Template, err error) {
+	if tmpl.Title != "" {
+		title, err = template.New(tmpl.Name + ".title").Option("missingkey=zero").Parse(tmpl.Title)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid title template: %w", err)
+		}
+	}
+	content, err = template.New(tmpl.Name).Option("missingkey=zero").Parse(tmpl.Content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid content template: %w", err)
+	}
+	return title, content, nil
+}
+
+// applyTemplate replaces the message title and content with the rendered
+// template; the title is kept when the template does not define one
+func applyTemplate(message *NotificationMessage, alert *models.Alert, tmpl *models.NotificationTemplate) error {
+	titleTmpl, contentTmpl, err := ParseTemplate(tmpl)
+	if err != nil {
+		return err
+	}
+
+	data := TemplateData{
+		Alert:       alert,
+		Labels:      stringMap(alert.Labels),
+		Annotations: stringMap(alert.Annotations),
+		Status:      alert.Status,
+		Severity:    alert.Severity,
+		StartsAt:    alert.StartsAt,
+		EndsAt:      alert.EndsAt,
+	}
+
+	var content bytes.Buffer
+	if err := contentTmpl.Execute(&content, data); err != nil {
+		return fmt.Errorf("failed to render content: %w", err)
+	}
+	if titleTmpl != nil {
+		var title bytes.Buffer
+		if err := titleTmpl.Execute(&title, data); err != nil {
+			return fmt.Errorf("failed to render title: %w", err)
+		}
+		message.Title = title.String()
+	}
+	message.Content = content.String()
+	message.Template = tmpl.Name
+	return nil
+}
+
+func stringMap(m models.JSONB) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			out[k] = s
+		} else {
+			out[k] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
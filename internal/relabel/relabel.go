@@ -0,0 +1,207 @@
+// Package relabel rewrites alert label sets with Prometheus-style relabel
+// configs, so alerts from sources that disagree on label names (env and
+// environment, sev and severity) are made consistent before they are
+// fingerprinted.
+package relabel
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"alertbot/internal/models"
+)
+
+// Actions supported by a relabel config
+const (
+	ActionReplace   = "replace"
+	ActionLabelMap  = "labelmap"
+	ActionLabelDrop = "labeldrop"
+	ActionLabelKeep = "labelkeep"
+	ActionDrop      = "drop"
+	ActionKeep      = "keep"
+)
+
+// Prometheus defaults for empty fields
+const (
+	DefaultSeparator   = ";"
+	DefaultRegex       = "(.*)"
+	DefaultReplacement = "$1"
+)
+
+// labelNamePattern is the Prometheus label name syntax
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Rule is a validated relabel config with its regex compiled
+type Rule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// Rules are applied in order
+type Rules []*Rule
+
+// Compile validates the configs and compiles their regexes. Like
+// Prometheus, the regex must match the whole value.
+func Compile(configs []models.RelabelConfig) (Rules, error) {
+	rules := make(Rules, 0, len(configs))
+	for i, cfg := range configs {
+		rule, err := compileRule(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("config %d: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func compileRule(cfg models.RelabelConfig) (*Rule, error) {
+	r := &Rule{
+		sourceLabels: cfg.SourceLabels,
+		separator:    cfg.Separator,
+		targetLabel:  cfg.TargetLabel,
+		replacement:  cfg.Replacement,
+		action:       strings.ToLower(cfg.Action),
+	}
+	if r.separator == "" {
+		r.separator = DefaultSeparator
+	}
+	if r.replacement == "" {
+		r.replacement = DefaultReplacement
+	}
+	if r.action == "" {
+		r.action = ActionReplace
+	}
+
+	expr := cfg.Regex
+	if expr == "" {
+		expr = DefaultRegex
+	}
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", expr, err)
+	}
+	r.regex = regex
+
+	for _, name := range r.sourceLabels {
+		if !labelNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid source label %q", name)
+		}
+	}
+
+	switch r.action {
+	case ActionReplace:
+		if r.targetLabel == "" {
+			return nil, errors.New("replace needs a target_label")
+		}
+		// Targets with $ references are checked once expanded
+		if !strings.Contains(r.targetLabel, "$") && !labelNamePattern.MatchString(r.targetLabel) {
+			return nil, fmt.Errorf("invalid target label %q", r.targetLabel)
+		}
+	case ActionLabelMap:
+		if len(r.sourceLabels) > 0 || r.targetLabel != "" {
+			return nil, errors.New("labelmap works on label names and takes no source_labels or target_label")
+		}
+	case ActionLabelDrop, ActionLabelKeep:
+		if len(r.sourceLabels) > 0 || r.targetLabel != "" || cfg.Replacement != "" {
+			return nil, fmt.Errorf("%s works on label names and takes no source_labels, target_label or replacement", r.action)
+		}
+	case ActionDrop, ActionKeep:
+		if len(r.sourceLabels) == 0 {
+			return nil, fmt.Errorf("%s needs source_labels", r.action)
+		}
+		if r.targetLabel != "" {
+			return nil, fmt.Errorf("%s takes no target_label", r.action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q, use replace, labelmap, labeldrop, labelkeep, drop or keep", cfg.Action)
+	}
+	return r, nil
+}
+
+// Process applies the rules to a copy of labels. It returns false when a
+// drop or keep rule discarded the alert, or when no labels are left.
+func (rules Rules) Process(labels map[string]string) (map[string]string, bool) {
+	result := make(map[string]string, len(labels))
+	for name, value := range labels {
+		result[name] = value
+	}
+
+	for _, r := range rules {
+		if !r.apply(result) {
+			return nil, false
+		}
+	}
+	if len(result) == 0 {
+		return nil, false
+	}
+	return result, true
+}
+
+// apply rewrites labels in place; it returns false if the alert is dropped
+func (r *Rule) apply(labels map[string]string) bool {
+	switch r.action {
+	case ActionDrop:
+		return !r.regex.MatchString(r.sourceValue(labels))
+	case ActionKeep:
+		return r.regex.MatchString(r.sourceValue(labels))
+
+	case ActionReplace:
+		value := r.sourceValue(labels)
+		match := r.regex.FindStringSubmatchIndex(value)
+		if match == nil {
+			return true
+		}
+		target := string(r.regex.ExpandString(nil, r.targetLabel, value, match))
+		if !labelNamePattern.MatchString(target) {
+			return true
+		}
+		if replaced := string(r.regex.ExpandString(nil, r.replacement, value, match)); replaced != "" {
+			labels[target] = replaced
+		} else {
+			delete(labels, target)
+		}
+
+	case ActionLabelMap:
+		// Map from the labels as they were before this rule
+		mapped := make(map[string]string)
+		for name, value := range labels {
+			if r.regex.MatchString(name) {
+				mapped[r.regex.ReplaceAllString(name, r.replacement)] = value
+			}
+		}
+		for name, value := range mapped {
+			if labelNamePattern.MatchString(name) {
+				labels[name] = value
+			}
+		}
+
+	case ActionLabelDrop:
+		for name := range labels {
+			if r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case ActionLabelKeep:
+		for name := range labels {
+			if !r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+// sourceValue joins the source label values; missing labels are empty
+func (r *Rule) sourceValue(labels map[string]string) string {
+	values := make([]string, len(r.sourceLabels))
+	for i, name := range r.sourceLabels {
+		values[i] = labels[name]
+	}
+	return strings.Join(values, r.separator)
+}
@@ -0,0 +1,274 @@
+package relabel
+
+import (
+	"testing"
+
+	"alertbot/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The cases follow the Prometheus relabel tests, so Process keeps the
+// semantics of relabel.Process from prometheus/prometheus
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   map[string]string
+		configs []models.RelabelConfig
+		want    map[string]string
+		drop    bool
+	}{
+		{
+			name:  "replace with capture groups",
+			input: map[string]string{"a": "foo", "b": "bar", "c": "baz"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "f(.*)", TargetLabel: "d", Replacement: "ch${1}-ch${1}", Action: ActionReplace},
+			},
+			want: map[string]string{"a": "foo", "b": "bar", "c": "baz", "d": "choo-choo"},
+		},
+		{
+			name:  "replace joins sources with the separator",
+			input: map[string]string{"a": "foo", "b": "bar", "c": "baz"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a", "b"}, Regex: "f(.*);(.*)r", TargetLabel: "a", Replacement: "b${1}${2}m"},
+				{SourceLabels: []string{"c", "a"}, Regex: "(b).*b(.*)ba(.*)", TargetLabel: "d", Replacement: "$1$2$2$3"},
+			},
+			want: map[string]string{"a": "boobam", "b": "bar", "c": "baz", "d": "boooom"},
+		},
+		{
+			name:  "custom separator",
+			input: map[string]string{"a": "foo", "b": "bar"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a", "b"}, Separator: "-", TargetLabel: "ab"},
+			},
+			want: map[string]string{"a": "foo", "b": "bar", "ab": "foo-bar"},
+		},
+		{
+			name:  "defaults copy the source value",
+			input: map[string]string{"sev": "critical"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"sev"}, TargetLabel: "severity"},
+			},
+			want: map[string]string{"sev": "critical", "severity": "critical"},
+		},
+		{
+			name:  "regex is anchored at the start",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "o+", TargetLabel: "b", Replacement: "matched"},
+			},
+			want: map[string]string{"a": "foo"},
+		},
+		{
+			name:  "regex is anchored at the end",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "f", TargetLabel: "b", Replacement: "matched"},
+			},
+			want: map[string]string{"a": "foo"},
+		},
+		{
+			name:  "alternation is anchored as a whole",
+			input: map[string]string{"env": "production"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"env"}, Regex: "prod|staging", TargetLabel: "tier", Replacement: "live"},
+			},
+			want: map[string]string{"env": "production"},
+		},
+		{
+			name:  "missing source labels are empty",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a", "missing"}, Regex: "(.*);", TargetLabel: "b"},
+			},
+			want: map[string]string{"a": "foo", "b": "foo"},
+		},
+		{
+			name:  "empty replacement result deletes the target",
+			input: map[string]string{"a": "foo", "b": "bar"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"missing"}, TargetLabel: "b"},
+			},
+			want: map[string]string{"a": "foo"},
+		},
+		{
+			name:  "$1 followed by a name character refers to that name",
+			input: map[string]string{"a": "foo", "b": "bar"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "(.*)", TargetLabel: "b", Replacement: "$1x"},
+			},
+			want: map[string]string{"a": "foo"},
+		},
+		{
+			name:  "target label from capture groups",
+			input: map[string]string{"a": "some-name-value"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "some-([^-]+)-([^,]+)", TargetLabel: "${1}", Replacement: "${2}"},
+			},
+			want: map[string]string{"a": "some-name-value", "name": "value"},
+		},
+		{
+			name:  "invalid expanded target is skipped",
+			input: map[string]string{"a": "some-0name-value"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "some-([^-]+)-([^,]+)", TargetLabel: "${1}", Replacement: "${2}"},
+			},
+			want: map[string]string{"a": "some-0name-value"},
+		},
+		{
+			name:  "drop on match",
+			input: map[string]string{"a": "foo", "c": "baz"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"c"}, Regex: ".*(b).*", Action: ActionDrop},
+			},
+			drop: true,
+		},
+		{
+			name:  "drop without match",
+			input: map[string]string{"a": "foo", "c": "baz"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "b", Action: ActionDrop},
+			},
+			want: map[string]string{"a": "foo", "c": "baz"},
+		},
+		{
+			name:  "keep on match",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "f.*", Action: ActionKeep},
+			},
+			want: map[string]string{"a": "foo"},
+		},
+		{
+			name:  "keep without match",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Regex: "f", Action: ActionKeep},
+			},
+			drop: true,
+		},
+		{
+			name:  "labelmap copies matching labels",
+			input: map[string]string{"foo": "bar", "__meta_my_bar": "aaa", "__meta_my_baz": "bbb", "__meta_other": "ccc"},
+			configs: []models.RelabelConfig{
+				{Regex: "__meta_(my.*)", Replacement: "${1}", Action: ActionLabelMap},
+			},
+			want: map[string]string{"foo": "bar", "__meta_my_bar": "aaa", "__meta_my_baz": "bbb", "__meta_other": "ccc", "my_bar": "aaa", "my_baz": "bbb"},
+		},
+		{
+			name:  "labelmap replaces inside the name",
+			input: map[string]string{"__meta_kubernetes_pod_label_app": "web", "env": "prod"},
+			configs: []models.RelabelConfig{
+				{Regex: "__meta_kubernetes_pod_label_(.+)", Action: ActionLabelMap},
+			},
+			want: map[string]string{"__meta_kubernetes_pod_label_app": "web", "env": "prod", "app": "web"},
+		},
+		{
+			name:  "labelmap skips invalid names",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{Regex: "(a)", Replacement: "1${1}", Action: ActionLabelMap},
+			},
+			want: map[string]string{"a": "foo"},
+		},
+		{
+			name:  "labeldrop",
+			input: map[string]string{"a": "foo", "b1": "bar", "b2": "baz"},
+			configs: []models.RelabelConfig{
+				{Regex: "b.*", Action: ActionLabelDrop},
+			},
+			want: map[string]string{"a": "foo"},
+		},
+		{
+			name:  "labeldrop is anchored",
+			input: map[string]string{"a": "foo", "ab": "bar"},
+			configs: []models.RelabelConfig{
+				{Regex: "b", Action: ActionLabelDrop},
+			},
+			want: map[string]string{"a": "foo", "ab": "bar"},
+		},
+		{
+			name:  "labelkeep",
+			input: map[string]string{"a": "foo", "b1": "bar", "b2": "baz"},
+			configs: []models.RelabelConfig{
+				{Regex: "b.*", Action: ActionLabelKeep},
+			},
+			want: map[string]string{"b1": "bar", "b2": "baz"},
+		},
+		{
+			name:  "no labels left",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{Regex: "b", Action: ActionLabelKeep},
+			},
+			drop: true,
+		},
+		{
+			name:  "rules apply in order",
+			input: map[string]string{"environment": "prod", "sev": "page"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"environment"}, TargetLabel: "env"},
+				{SourceLabels: []string{"sev"}, Regex: "page", TargetLabel: "severity", Replacement: "critical"},
+				{Regex: "environment|sev", Action: ActionLabelDrop},
+				{SourceLabels: []string{"env", "severity"}, Regex: "prod;critical", Action: ActionKeep},
+			},
+			want: map[string]string{"env": "prod", "severity": "critical"},
+		},
+		{
+			name:  "upper case action",
+			input: map[string]string{"a": "foo"},
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"a"}, Action: "DROP"},
+			},
+			drop: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := Compile(tt.configs)
+			require.NoError(t, err)
+
+			input := make(map[string]string, len(tt.input))
+			for name, value := range tt.input {
+				input[name] = value
+			}
+			got, kept := rules.Process(input)
+			assert.Equal(t, tt.input, input, "input must not be modified")
+			if tt.drop {
+				assert.False(t, kept)
+				assert.Nil(t, got)
+				return
+			}
+			assert.True(t, kept)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config models.RelabelConfig
+	}{
+		{"unknown action", models.RelabelConfig{SourceLabels: []string{"a"}, Action: "hashmod"}},
+		{"invalid regex", models.RelabelConfig{SourceLabels: []string{"a"}, Regex: "(", TargetLabel: "b"}},
+		{"invalid source label", models.RelabelConfig{SourceLabels: []string{"a-b"}, TargetLabel: "b"}},
+		{"replace without target", models.RelabelConfig{SourceLabels: []string{"a"}}},
+		{"replace with invalid target", models.RelabelConfig{SourceLabels: []string{"a"}, TargetLabel: "0b"}},
+		{"labelmap with source labels", models.RelabelConfig{SourceLabels: []string{"a"}, Action: ActionLabelMap}},
+		{"labelmap with target", models.RelabelConfig{TargetLabel: "b", Action: ActionLabelMap}},
+		{"labeldrop with replacement", models.RelabelConfig{Regex: "a", Replacement: "b", Action: ActionLabelDrop}},
+		{"labelkeep with source labels", models.RelabelConfig{SourceLabels: []string{"a"}, Action: ActionLabelKeep}},
+		{"drop without source labels", models.RelabelConfig{Regex: "a", Action: ActionDrop}},
+		{"keep with target", models.RelabelConfig{SourceLabels: []string{"a"}, TargetLabel: "b", Action: ActionKeep}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]models.RelabelConfig{tt.config})
+			assert.Error(t, err)
+		})
+	}
+}
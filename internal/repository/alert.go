@@ -1,12 +1,22 @@
 package repository
 
 import (
-	"alertbot/internal/models"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
+
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// bulkInsertBatchSize keeps bulk inserts below the bind parameter limits of
+// Postgres and SQLite
+const bulkInsertBatchSize = 500
+
 type alertRepository struct {
 	db *gorm.DB
 }
@@ -28,23 +38,63 @@ func (r *alertRepository) GetByFingerprint(fingerprint string) (*models.Alert, e
 	return &alert, nil
 }
 
-func (r *alertRepository) List(filters models.AlertFilters) ([]models.Alert, int64, error) {
+// ListByStatus returns every alert in the given status, oldest update first
+func (r *alertRepository) ListByStatus(status string) ([]models.Alert, error) {
 	var alerts []models.Alert
-	var total int64
+	err := r.db.Where("status = ?", status).Order("updated_at ASC").Find(&alerts).Error
+	return alerts, err
+}
 
-	query := r.db.Model(&models.Alert{})
+// ListStartedSince returns up to limit alerts that started after since, oldest first
+func (r *alertRepository) ListStartedSince(since time.Time, limit int) ([]models.Alert, error) {
+	var alerts []models.Alert
+	err := r.db.Where("starts_at >= ?", since).Order("starts_at ASC").Limit(limit).Find(&alerts).Error
+	return alerts, err
+}
 
-	if filters.Status != "" {
-		query = query.Where("status = ?", filters.Status)
-	}
-	if filters.Severity != "" {
-		query = query.Where("severity = ?", filters.Severity)
+// ListByFingerprints returns the alerts with the given fingerprints
+func (r *alertRepository) ListByFingerprints(fingerprints []string) ([]models.Alert, error) {
+	var alerts []models.Alert
+	if len(fingerprints) == 0 {
+		return alerts, nil
 	}
-	if filters.AlertName != "" {
-		query = query.Where("labels->>'alertname' ILIKE ?", "%"+filters.AlertName+"%")
+	err := r.db.Where("fingerprint IN ?", fingerprints).Order("starts_at ASC").Find(&alerts).Error
+	return alerts, err
+}
+
+// sortableAlertColumns whitelists columns accepted by the sort parameter
+var sortableAlertColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"starts_at":  true,
+	"ends_at":    true,
+	"severity":   true,
+	"status":     true,
+}
+
+// cursorAlertColumns are the sort columns that support keyset pagination
+var cursorAlertColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"starts_at":  true,
+}
+
+// SupportsCursor reports whether keyset pagination works with the sort
+// column; unknown columns fall back to created_at
+func SupportsCursor(sort string) bool {
+	if sort == "" || !sortableAlertColumns[sort] {
+		return true
 	}
-	if filters.Instance != "" {
-		query = query.Where("labels->>'instance' ILIKE ?", "%"+filters.Instance+"%")
+	return cursorAlertColumns[sort]
+}
+
+func (r *alertRepository) List(filters models.AlertFilters) ([]models.Alert, int64, error) {
+	var alerts []models.Alert
+	var total int64
+
+	query, err := applyAlertFilters(r.db.Model(&models.Alert{}), filters)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	if err := query.Count(&total).Error; err != nil {
@@ -61,18 +111,37 @@ func (r *alertRepository) List(filters models.AlertFilters) ([]models.Alert, int
 		filters.Size = 100
 	}
 
-	offset := (filters.Page - 1) * filters.Size
-
-	if filters.Sort == "" {
+	if filters.Sort == "" || !sortableAlertColumns[filters.Sort] {
 		filters.Sort = "created_at"
 	}
-	if filters.Order == "" {
+	if strings.ToLower(filters.Order) != "asc" {
 		filters.Order = "desc"
 	}
 
-	orderBy := fmt.Sprintf("%s %s", filters.Sort, filters.Order)
-	
-	err := query.Order(orderBy).Offset(offset).Limit(filters.Size).Find(&alerts).Error
+	if filters.Cursor != "" {
+		// Keyset pagination: seek past the last row of the previous page
+		// instead of scanning and discarding OFFSET rows.
+		if !cursorAlertColumns[filters.Sort] {
+			return nil, 0, fmt.Errorf("cursor pagination is not supported when sorting by %s", filters.Sort)
+		}
+
+		cursor, err := models.DecodeAlertCursor(filters.Cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		op := "<"
+		if filters.Order == "asc" {
+			op = ">"
+		}
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", filters.Sort, op), cursor.Value, cursor.ID)
+	} else {
+		query = query.Offset((filters.Page - 1) * filters.Size)
+	}
+
+	orderBy := fmt.Sprintf("%s %s, id %s", filters.Sort, filters.Order, filters.Order)
+
+	err = query.Order(orderBy).Limit(filters.Size).Find(&alerts).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -80,6 +149,95 @@ func (r *alertRepository) List(filters models.AlertFilters) ([]models.Alert, int
 	return alerts, total, nil
 }
 
+// applyAlertFilters translates AlertFilters into WHERE clauses
+func applyAlertFilters(query *gorm.DB, filters models.AlertFilters) (*gorm.DB, error) {
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+	if filters.Severity != "" {
+		query = query.Where("severity = ?", filters.Severity)
+	}
+	if filters.AlertName != "" {
+		value, arg := labelValue(query, "labels", "alertname")
+		query = query.Where(value+" "+containsOperator(query)+" ?", arg, "%"+filters.AlertName+"%")
+	}
+	if filters.Instance != "" {
+		value, arg := labelValue(query, "labels", "instance")
+		query = query.Where(value+" "+containsOperator(query)+" ?", arg, "%"+filters.Instance+"%")
+	}
+
+	if filters.Query != "" {
+		matchers, err := matcher.Parse(filters.Query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label query: %w", err)
+		}
+		query = applyLabelMatchers(query, matchers)
+	}
+
+	if filters.Search != "" {
+		if isSQLite(query) {
+			query = query.Where("annotations LIKE ?", "%"+filters.Search+"%")
+		} else {
+			// Backed by the idx_alerts_annotations_fts GIN index
+			query = query.Where("to_tsvector('simple', annotations) @@ plainto_tsquery('simple', ?)", filters.Search)
+		}
+	}
+
+	if !filters.StartsAfter.IsZero() {
+		query = query.Where("starts_at >= ?", filters.StartsAfter)
+	}
+	if !filters.StartsBefore.IsZero() {
+		query = query.Where("starts_at < ?", filters.StartsBefore)
+	}
+	if !filters.EndsAfter.IsZero() {
+		query = query.Where("ends_at >= ?", filters.EndsAfter)
+	}
+	if !filters.EndsBefore.IsZero() {
+		query = query.Where("ends_at < ?", filters.EndsBefore)
+	}
+
+	return query, nil
+}
+
+// applyLabelMatchers adds one condition per matcher on the labels column.
+// Missing labels compare as the empty string, as in Prometheus.
+func applyLabelMatchers(query *gorm.DB, matchers matcher.Matchers) *gorm.DB {
+	for _, m := range matchers {
+		value, arg := labelValue(query, "labels", m.Name)
+		switch m.Type {
+		case matcher.MatchEqual:
+			if m.Value == "" || isSQLite(query) {
+				query = query.Where(value+" = ?", arg, m.Value)
+				continue
+			}
+			// Containment lets Postgres use the labels GIN index
+			containment, _ := json.Marshal(map[string]string{m.Name: m.Value})
+			query = query.Where("labels @> ?::jsonb", string(containment))
+		case matcher.MatchNotEqual:
+			query = query.Where(value+" <> ?", arg, m.Value)
+		case matcher.MatchRegexp:
+			query = query.Where(value+" "+regexpOperator(query, false)+" ?", arg, matcher.Anchor(m.Value))
+		case matcher.MatchNotRegexp:
+			query = query.Where(value+" "+regexpOperator(query, true)+" ?", arg, matcher.Anchor(m.Value))
+		}
+	}
+	return query
+}
+
+// Upsert inserts alerts in bulk. An alert whose fingerprint is already
+// stored updates that row's labels, status, severity, annotations, end and
+// saved silence status instead.
+// Alerts must not carry an ID; each gets the ID of its row.
+func (r *alertRepository) Upsert(alerts []*models.Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"labels", "status", "severity", "annotations", "ends_at", "silenced_status", "updated_at"}),
+	}).CreateInBatches(alerts, bulkInsertBatchSize).Error
+}
+
 func (r *alertRepository) Update(alert *models.Alert) error {
 	return r.db.Save(alert).Error
 }
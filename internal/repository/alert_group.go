@@ -14,7 +14,9 @@ type AlertGroupRepository interface {
 	ListAlertGroups(ctx context.Context, filters *models.AlertFilters) ([]*models.AlertGroup, error)
 	GetAlertGroup(ctx context.Context, id uint) (*models.AlertGroup, error)
 	GetAlertGroupByKey(ctx context.Context, groupKey string) (*models.AlertGroup, error)
+	ListAlertGroupsByKeys(ctx context.Context, groupKeys []string) ([]*models.AlertGroup, error)
 	CreateAlertGroup(ctx context.Context, group *models.AlertGroup) error
+	CreateAlertGroups(ctx context.Context, groups []*models.AlertGroup) error
 	UpdateAlertGroup(ctx context.Context, group *models.AlertGroup) error
 	DeleteAlertGroup(ctx context.Context, id uint) error
 
@@ -93,10 +95,26 @@ func (r *alertGroupRepository) GetAlertGroupByKey(ctx context.Context, groupKey
 	return &group, nil
 }
 
+func (r *alertGroupRepository) ListAlertGroupsByKeys(ctx context.Context, groupKeys []string) ([]*models.AlertGroup, error) {
+	var groups []*models.AlertGroup
+	if len(groupKeys) == 0 {
+		return groups, nil
+	}
+	err := r.db.WithContext(ctx).Where("group_key IN ?", groupKeys).Find(&groups).Error
+	return groups, err
+}
+
 func (r *alertGroupRepository) CreateAlertGroup(ctx context.Context, group *models.AlertGroup) error {
 	return r.db.WithContext(ctx).Create(group).Error
 }
 
+func (r *alertGroupRepository) CreateAlertGroups(ctx context.Context, groups []*models.AlertGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(groups, bulkInsertBatchSize).Error
+}
+
 func (r *alertGroupRepository) UpdateAlertGroup(ctx context.Context, group *models.AlertGroup) error {
 	return r.db.WithContext(ctx).Save(group).Error
 }
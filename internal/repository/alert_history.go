@@ -2,6 +2,7 @@ package repository
 
 import (
 	"alertbot/internal/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -18,6 +19,14 @@ func (r *alertHistoryRepository) Create(history *models.AlertHistory) error {
 	return r.db.Create(history).Error
 }
 
+// CreateBatch inserts histories in bulk
+func (r *alertHistoryRepository) CreateBatch(histories []*models.AlertHistory) error {
+	if len(histories) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(histories, bulkInsertBatchSize).Error
+}
+
 func (r *alertHistoryRepository) GetByAlertFingerprint(fingerprint string) ([]models.AlertHistory, error) {
 	var histories []models.AlertHistory
 	err := r.db.Where("alert_fingerprint = ?", fingerprint).Order("created_at DESC").Find(&histories).Error
@@ -66,4 +75,29 @@ func (r *alertHistoryRepository) List(filters models.AlertHistoryFilters) ([]mod
 	
 	err := query.Find(&histories).Error
 	return histories, total, err
-}
\ No newline at end of file
+}
+
+// ListByActionsSince returns up to limit history entries with one of the
+// given actions recorded after since, oldest first
+func (r *alertHistoryRepository) ListByActionsSince(actions []string, since time.Time, limit int) ([]models.AlertHistory, error) {
+	var histories []models.AlertHistory
+	err := r.db.Where("action IN ? AND created_at >= ?", actions, since).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&histories).Error
+	return histories, err
+}
+
+// ListByFingerprintsSince returns up to limit history entries for the given
+// alerts recorded after since, oldest first
+func (r *alertHistoryRepository) ListByFingerprintsSince(fingerprints []string, since time.Time, limit int) ([]models.AlertHistory, error) {
+	var histories []models.AlertHistory
+	if len(fingerprints) == 0 {
+		return histories, nil
+	}
+	err := r.db.Where("alert_fingerprint IN ? AND created_at >= ?", fingerprints, since).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&histories).Error
+	return histories, err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"alertbot/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type clusterRepository struct {
+	db *gorm.DB
+}
+
+func NewClusterRepository(db *gorm.DB) ClusterRepository {
+	return &clusterRepository{db: db}
+}
+
+func (r *clusterRepository) UpsertMember(member *models.ClusterMember) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "node_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hostname", "address", "leader", "last_seen_at"}),
+	}).Create(member).Error
+}
+
+func (r *clusterRepository) DeleteMember(nodeID string) error {
+	return r.db.Where("node_id = ?", nodeID).Delete(&models.ClusterMember{}).Error
+}
+
+func (r *clusterRepository) ListMembers(since time.Time) ([]models.ClusterMember, error) {
+	var members []models.ClusterMember
+	err := r.db.Where("last_seen_at >= ?", since).Order("started_at ASC").Find(&members).Error
+	return members, err
+}
+
+func (r *clusterRepository) DeleteMembersNotSeenSince(before time.Time) error {
+	return r.db.Where("last_seen_at < ?", before).Delete(&models.ClusterMember{}).Error
+}
+
+func (r *clusterRepository) ClaimNotification(claim *models.NotificationClaim, staleBefore time.Time) (bool, error) {
+	// A conflicting claim is only taken over once it is stale, so the
+	// insert or update affects no rows while another replica holds it
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "claim_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"node_id", "claimed_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "notification_claims.claimed_at < ?", Vars: []interface{}{staleBefore}},
+		}},
+	}).Create(claim)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *clusterRepository) ReleaseClaim(key, nodeID string) error {
+	return r.db.Where("claim_key = ? AND node_id = ?", key, nodeID).Delete(&models.NotificationClaim{}).Error
+}
+
+func (r *clusterRepository) DeleteClaimsBefore(before time.Time) error {
+	return r.db.Where("claimed_at < ?", before).Delete(&models.NotificationClaim{}).Error
+}
+
+func (r *clusterRepository) CreateEventPayload(payload *models.EventPayload) error {
+	return r.db.Create(payload).Error
+}
+
+func (r *clusterRepository) GetEventPayload(id string) (*models.EventPayload, error) {
+	var payload models.EventPayload
+	err := r.db.Where("id = ?", id).First(&payload).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+func (r *clusterRepository) DeleteEventPayloadsBefore(before time.Time) error {
+	return r.db.Where("created_at < ?", before).Delete(&models.EventPayload{}).Error
+}
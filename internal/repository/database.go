@@ -9,6 +9,7 @@ import (
 	"alertbot/internal/errors"
 	"alertbot/internal/recovery"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -30,11 +31,13 @@ func NewDatabase(cfg config.Database) (*gorm.DB, error) {
 	var db *gorm.DB
 	var connectionErr error
 
-	// Retry database connection
-	err := recovery.Retry(context.Background(), retryConfig, func(ctx context.Context) error {
-		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
-			cfg.Host, cfg.User, cfg.Password, cfg.DBName, cfg.Port, cfg.SSLMode, cfg.TimeZone)
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
 
+	// Retry database connection
+	err = recovery.Retry(context.Background(), retryConfig, func(ctx context.Context) error {
 		gormConfig := &gorm.Config{
 			Logger: logger.Default.LogMode(logger.Silent), // Use Silent in production
 			PrepareStmt: true, // Enable prepared statements for better performance
@@ -42,7 +45,7 @@ func NewDatabase(cfg config.Database) (*gorm.DB, error) {
 		}
 
 		var dbErr error
-		db, dbErr = gorm.Open(postgres.Open(dsn), gormConfig)
+		db, dbErr = gorm.Open(dialector, gormConfig)
 		if dbErr != nil {
 			connectionErr = dbErr
 			return errors.Wrap(dbErr, "DATABASE_CONNECTION_FAILED", "Failed to connect to database", 500)
@@ -59,6 +62,11 @@ func NewDatabase(cfg config.Database) (*gorm.DB, error) {
 		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 		sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
 		sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Second)
+		if cfg.Driver == DialectSQLite && cfg.Path == sqliteMemory {
+			// A shared in-memory database reports SQLITE_LOCKED rather than
+			// waiting for concurrent writers, so keep to one connection
+			sqlDB.SetMaxOpenConns(1)
+		}
 
 		// Test connection
 		testCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -76,4 +84,22 @@ func NewDatabase(cfg config.Database) (*gorm.DB, error) {
 	}
 
 	return db, nil
-}
\ No newline at end of file
+}
+
+// PostgresDSN returns the connection string for a Postgres database
+func PostgresDSN(cfg config.Database) string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+		cfg.Host, cfg.User, cfg.Password, cfg.DBName, cfg.Port, cfg.SSLMode, cfg.TimeZone)
+}
+
+// openDialector returns the GORM dialector for the configured driver
+func openDialector(cfg config.Database) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", DialectPostgres:
+		return postgres.Open(PostgresDSN(cfg)), nil
+	case DialectSQLite:
+		return sqlite.Open(sqliteDSN(cfg.Path)), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"gorm.io/gorm"
+)
+
+// Database drivers supported by NewDatabase, named like GORM's dialectors
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// sqliteMemory is the path of a SQLite database that lives in memory
+const sqliteMemory = ":memory:"
+
+func init() {
+	// SQLite parses REGEXP but leaves the function to the application
+	gosqlite.MustRegisterDeterministicScalarFunction("regexp", 2, sqliteRegexp)
+}
+
+// sqliteRegexps caches compiled REGEXP patterns, which repeat per row
+var sqliteRegexps sync.Map
+
+// sqliteRegexp implements "value REGEXP pattern", called as
+// regexp(pattern, value)
+func sqliteRegexp(_ *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	pattern, value := fmt.Sprint(args[0]), fmt.Sprint(args[1])
+	if b, ok := args[1].([]byte); ok {
+		value = string(b)
+	}
+
+	re, ok := sqliteRegexps.Load(pattern)
+	if !ok {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		re, _ = sqliteRegexps.LoadOrStore(pattern, compiled)
+	}
+	return re.(*regexp.Regexp).MatchString(value), nil
+}
+
+// sqliteDSN opens path in WAL mode with a busy timeout, so concurrent
+// writers wait for each other instead of failing. An in-memory database
+// is shared between connections.
+func sqliteDSN(path string) string {
+	params := url.Values{"_pragma": {"busy_timeout(5000)"}}
+	if path == sqliteMemory {
+		return "file::memory:?cache=shared&" + params.Encode()
+	}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	return "file:" + path + "?" + params.Encode()
+}
+
+// isSQLite reports whether db is a SQLite database
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == DialectSQLite
+}
+
+// labelValue returns an expression for the value of label name in a JSON
+// column, the empty string when the label is missing, and the argument
+// it takes
+func labelValue(db *gorm.DB, column, name string) (string, interface{}) {
+	if isSQLite(db) {
+		return fmt.Sprintf("COALESCE(json_extract(%s, ?), '')", column), fmt.Sprintf("$.%q", name)
+	}
+	return fmt.Sprintf("COALESCE(%s->>?, '')", column), name
+}
+
+// containsOperator is the case-insensitive LIKE operator; SQLite's LIKE
+// ignores ASCII case already
+func containsOperator(db *gorm.DB) string {
+	if isSQLite(db) {
+		return "LIKE"
+	}
+	return "ILIKE"
+}
+
+// regexpOperator is the regular expression match operator, optionally
+// negated
+func regexpOperator(db *gorm.DB, negate bool) string {
+	switch {
+	case isSQLite(db) && negate:
+		return "NOT REGEXP"
+	case isSQLite(db):
+		return "REGEXP"
+	case negate:
+		return "!~"
+	default:
+		return "~"
+	}
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"alertbot/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type heartbeatRepository struct {
+	db *gorm.DB
+}
+
+func NewHeartbeatRepository(db *gorm.DB) HeartbeatRepository {
+	return &heartbeatRepository{db: db}
+}
+
+func (r *heartbeatRepository) Create(heartbeat *models.Heartbeat) error {
+	return r.db.Create(heartbeat).Error
+}
+
+func (r *heartbeatRepository) GetByID(id uint) (*models.Heartbeat, error) {
+	var heartbeat models.Heartbeat
+	err := r.db.First(&heartbeat, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &heartbeat, nil
+}
+
+func (r *heartbeatRepository) List() ([]models.Heartbeat, error) {
+	var heartbeats []models.Heartbeat
+	err := r.db.Order("name ASC").Find(&heartbeats).Error
+	return heartbeats, err
+}
+
+func (r *heartbeatRepository) ListEnabled() ([]models.Heartbeat, error) {
+	var heartbeats []models.Heartbeat
+	err := r.db.Where("enabled = ?", true).Find(&heartbeats).Error
+	return heartbeats, err
+}
+
+func (r *heartbeatRepository) Update(heartbeat *models.Heartbeat) error {
+	return r.db.Save(heartbeat).Error
+}
+
+func (r *heartbeatRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Heartbeat{}, id).Error
+}
+
+// RecordPing only touches last_ping_at so it never overwrites a concurrent
+// status change
+func (r *heartbeatRepository) RecordPing(id uint, at time.Time) error {
+	return r.db.Model(&models.Heartbeat{}).Where("id = ?", id).UpdateColumn("last_ping_at", at).Error
+}
+
+// UpdateStatus only touches the status columns so it never overwrites a
+// concurrent ping
+func (r *heartbeatRepository) UpdateStatus(id uint, status string, missedAt *time.Time) error {
+	return r.db.Model(&models.Heartbeat{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"status":    status,
+		"missed_at": missedAt,
+	}).Error
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"alertbot/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type incidentRepository struct {
+	db *gorm.DB
+}
+
+func NewIncidentRepository(db *gorm.DB) IncidentRepository {
+	return &incidentRepository{db: db}
+}
+
+func (r *incidentRepository) Create(incident *models.Incident) error {
+	return r.db.Create(incident).Error
+}
+
+// CreateBatch inserts the incidents in batches
+func (r *incidentRepository) CreateBatch(incidents []*models.Incident) error {
+	if len(incidents) == 0 {
+		return nil
+	}
+	return r.db.Omit(clause.Associations).CreateInBatches(incidents, bulkInsertBatchSize).Error
+}
+
+// GetByID returns the incident with its alerts and notes
+func (r *incidentRepository) GetByID(id uint) (*models.Incident, error) {
+	var incident models.Incident
+	err := r.db.
+		Preload("Alerts", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Notes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(&incident, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+func (r *incidentRepository) List(filters models.IncidentFilters) ([]models.Incident, int64, error) {
+	var incidents []models.Incident
+	var total int64
+
+	query := r.db.Model(&models.Incident{})
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+	if filters.Owner != "" {
+		query = query.Where("owner = ?", filters.Owner)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filters.Page == 0 {
+		filters.Page = 1
+	}
+	if filters.Size == 0 {
+		filters.Size = 20
+	}
+	if filters.Size > 100 {
+		filters.Size = 100
+	}
+
+	err := query.Order("created_at DESC, id DESC").
+		Offset((filters.Page - 1) * filters.Size).
+		Limit(filters.Size).
+		Find(&incidents).Error
+	return incidents, total, err
+}
+
+func (r *incidentRepository) Update(incident *models.Incident) error {
+	// alert_count is only changed by AttachAlert so concurrent attaches are not lost
+	return r.db.Omit(clause.Associations, "alert_count").Save(incident).Error
+}
+
+func (r *incidentRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("incident_id = ?", id).Delete(&models.IncidentAlert{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("incident_id = ?", id).Delete(&models.IncidentNote{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Incident{}, id).Error
+	})
+}
+
+// GetOpenByCorrelationKey returns the newest unresolved incident for the key
+func (r *incidentRepository) GetOpenByCorrelationKey(key string) (*models.Incident, error) {
+	var incident models.Incident
+	err := r.db.Where("correlation_key = ? AND status <> ?", key, models.IncidentStatusResolved).
+		Order("created_at DESC").
+		First(&incident).Error
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// ListOpenByCorrelationKeys returns the newest unresolved incident of each
+// correlation key
+func (r *incidentRepository) ListOpenByCorrelationKeys(keys []string) (map[string]*models.Incident, error) {
+	byKey := make(map[string]*models.Incident)
+	if len(keys) == 0 {
+		return byKey, nil
+	}
+
+	var incidents []models.Incident
+	err := r.db.Where("correlation_key IN ? AND status <> ?", keys, models.IncidentStatusResolved).
+		Order("created_at DESC").
+		Find(&incidents).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range incidents {
+		if _, ok := byKey[incidents[i].CorrelationKey]; !ok {
+			byKey[incidents[i].CorrelationKey] = &incidents[i]
+		}
+	}
+	return byKey, nil
+}
+
+// GetOpenByAlert returns the unresolved incident the alert is attached to
+func (r *incidentRepository) GetOpenByAlert(fingerprint string) (*models.Incident, error) {
+	var incident models.Incident
+	err := r.db.Joins("JOIN incident_alerts ON incident_alerts.incident_id = incidents.id").
+		Where("incident_alerts.alert_fingerprint = ? AND incidents.status <> ?", fingerprint, models.IncidentStatusResolved).
+		Order("incidents.created_at DESC").
+		First(&incident).Error
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// ListOpenByAlerts returns the unresolved incident of each given alert that
+// has one, keyed by fingerprint. Alerts sharing an incident share its value.
+func (r *incidentRepository) ListOpenByAlerts(fingerprints []string) (map[string]*models.Incident, error) {
+	byAlert := make(map[string]*models.Incident)
+	if len(fingerprints) == 0 {
+		return byAlert, nil
+	}
+
+	var rows []struct {
+		models.Incident  `gorm:"embedded"`
+		AlertFingerprint string
+	}
+	err := r.db.Model(&models.Incident{}).
+		Select("incidents.*, incident_alerts.alert_fingerprint").
+		Joins("JOIN incident_alerts ON incident_alerts.incident_id = incidents.id").
+		Where("incident_alerts.alert_fingerprint IN ? AND incidents.status <> ?", fingerprints, models.IncidentStatusResolved).
+		Order("incidents.created_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	incidents := make(map[uint]*models.Incident)
+	for i := range rows {
+		if _, ok := byAlert[rows[i].AlertFingerprint]; ok {
+			continue
+		}
+		incident, ok := incidents[rows[i].ID]
+		if !ok {
+			incident = &rows[i].Incident
+			incidents[incident.ID] = incident
+		}
+		byAlert[rows[i].AlertFingerprint] = incident
+	}
+	return byAlert, nil
+}
+
+// AttachAlert links an alert to an incident and keeps AlertCount in step.
+// Attaching an alert twice is a no-op.
+func (r *incidentRepository) AttachAlert(incidentID uint, fingerprint string) (bool, error) {
+	attached := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.IncidentAlert{
+			IncidentID:       incidentID,
+			AlertFingerprint: fingerprint,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		attached = true
+		return tx.Model(&models.Incident{}).Where("id = ?", incidentID).
+			UpdateColumn("alert_count", gorm.Expr("alert_count + 1")).Error
+	})
+	return attached, err
+}
+
+// AttachAlerts links alerts to incidents, skipping existing links, and
+// recounts the alerts of the incidents involved
+func (r *incidentRepository) AttachAlerts(links []*models.IncidentAlert) error {
+	if len(links) == 0 {
+		return nil
+	}
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(links, bulkInsertBatchSize).Error
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[uint]bool, len(links))
+	var ids []uint
+	for _, link := range links {
+		if !seen[link.IncidentID] {
+			seen[link.IncidentID] = true
+			ids = append(ids, link.IncidentID)
+		}
+	}
+	// Counting the links keeps alert_count right however many were skipped
+	return r.db.Model(&models.Incident{}).Where("id IN ?", ids).
+		UpdateColumn("alert_count", gorm.Expr("(SELECT COUNT(*) FROM incident_alerts WHERE incident_alerts.incident_id = incidents.id)")).Error
+}
+
+func (r *incidentRepository) ListAlertFingerprints(incidentID uint) ([]string, error) {
+	var fingerprints []string
+	err := r.db.Model(&models.IncidentAlert{}).
+		Where("incident_id = ?", incidentID).
+		Pluck("alert_fingerprint", &fingerprints).Error
+	return fingerprints, err
+}
+
+func (r *incidentRepository) CreateNote(note *models.IncidentNote) error {
+	return r.db.Create(note).Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"alertbot/internal/models"
 	"gorm.io/gorm"
@@ -115,7 +116,7 @@ func (r *inhibitionRepository) GetInhibitionsByTarget(ctx context.Context, targe
 	query := r.db.WithContext(ctx).Where("target_fingerprint = ?", targetFingerprint)
 	
 	// Only get active inhibitions (not expired)
-	query = query.Where("expires_at IS NULL OR expires_at > NOW()")
+	query = query.Where("expires_at IS NULL OR expires_at > ?", time.Now())
 	
 	err := query.Find(&inhibitions).Error
 	return inhibitions, err
@@ -126,7 +127,7 @@ func (r *inhibitionRepository) GetInhibitionsBySource(ctx context.Context, sourc
 	query := r.db.WithContext(ctx).Where("source_fingerprint = ?", sourceFingerprint)
 	
 	// Only get active inhibitions (not expired)
-	query = query.Where("expires_at IS NULL OR expires_at > NOW()")
+	query = query.Where("expires_at IS NULL OR expires_at > ?", time.Now())
 	
 	err := query.Find(&inhibitions).Error
 	return inhibitions, err
@@ -134,12 +135,12 @@ func (r *inhibitionRepository) GetInhibitionsBySource(ctx context.Context, sourc
 
 func (r *inhibitionRepository) CleanupExpiredInhibitions(ctx context.Context) error {
 	// Delete expired inhibitions
-	return r.db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at <= NOW()").Delete(&models.InhibitionStatus{}).Error
+	return r.db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).Delete(&models.InhibitionStatus{}).Error
 }
 
 func (r *inhibitionRepository) GetActiveInhibitions(ctx context.Context) ([]*models.InhibitionStatus, error) {
 	var inhibitions []*models.InhibitionStatus
-	query := r.db.WithContext(ctx).Where("expires_at IS NULL OR expires_at > NOW()")
+	query := r.db.WithContext(ctx).Where("expires_at IS NULL OR expires_at > ?", time.Now())
 	err := query.Find(&inhibitions).Error
 	return inhibitions, err
 }
\ No newline at end of file
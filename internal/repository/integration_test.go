@@ -0,0 +1,401 @@
+//go:build integration
+
+// Integration tests run the repositories against real databases:
+//
+//	go test -tags integration ./internal/repository/
+//
+// SQLite always runs. Set TEST_POSTGRES_DSN to also run against Postgres;
+// each run creates and drops its own schema there.
+package repository_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/fingerprint"
+	"alertbot/internal/migration"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// forEachDatabase runs fn against a freshly migrated SQLite database and,
+// when TEST_POSTGRES_DSN is set, a freshly migrated Postgres schema
+func forEachDatabase(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := repository.NewDatabase(config.Database{
+			Driver: repository.DialectSQLite,
+			Path:   filepath.Join(t.TempDir(), "alertbot.db"),
+		})
+		require.NoError(t, err)
+		migrate(t, db)
+		fn(t, db)
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_POSTGRES_DSN is not set")
+		}
+		db := openPostgresSchema(t, dsn)
+		migrate(t, db)
+		fn(t, db)
+	})
+}
+
+// openPostgresSchema connects to a new schema that is dropped when the test ends
+func openPostgresSchema(t *testing.T, dsn string) *gorm.DB {
+	gormConfig := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	admin, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	require.NoError(t, err)
+	schema := fmt.Sprintf("alertbot_test_%d", time.Now().UnixNano())
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+separator+"search_path="+schema), gormConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func migrate(t *testing.T, db *gorm.DB) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	require.NoError(t, migration.NewMigrator(db, log).Migrate())
+}
+
+func newAlert(fingerprint string, labels, annotations models.JSONB, startsAt time.Time) *models.Alert {
+	return &models.Alert{
+		Fingerprint: fingerprint,
+		Labels:      labels,
+		Annotations: annotations,
+		Status:      string(models.AlertStatusFiring),
+		Severity:    "warning",
+		StartsAt:    startsAt,
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		migrator := migration.NewMigrator(db, log)
+
+		statuses, err := migrator.Status()
+		require.NoError(t, err)
+		require.NotEmpty(t, statuses)
+		for _, status := range statuses {
+			assert.Equal(t, migration.StateApplied, status.State, status.ID)
+		}
+
+		// Applying again is a no-op
+		require.NoError(t, migrator.Migrate())
+
+		// The latest migration is irreversible, so rolling back fails
+		// without touching the recorded state
+		last := statuses[len(statuses)-1]
+		require.False(t, last.Reversible)
+		assert.Error(t, migrator.Down(1))
+		statuses, err = migrator.Status()
+		require.NoError(t, err)
+		assert.Equal(t, migration.StateApplied, statuses[len(statuses)-1].State)
+	})
+}
+
+func TestFingerprintStrategyCheck(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		newMigrator := func(strategy *fingerprint.Strategy) *migration.Migrator {
+			migrator := migration.NewMigrator(db, log)
+			migrator.SetFingerprinter(strategy)
+			return migrator
+		}
+
+		labels := models.JSONB{"alertname": "HighCPU", "instance": "web1:9100", "pod": "web-7d9f"}
+		byPod := fingerprint.New(nil, []string{"pod"})
+		repo := repository.NewAlertRepository(db)
+		require.NoError(t, repo.Create(newAlert(fingerprint.Default().FingerprintJSONB(labels), labels, nil, time.Now())))
+
+		// With nothing recorded, stored alerts are sampled
+		assert.Error(t, newMigrator(byPod).CheckFingerprintStrategy())
+		require.NoError(t, newMigrator(fingerprint.Default()).CheckFingerprintStrategy())
+
+		// Once recorded, a changed config is refused until alerts are re-keyed
+		assert.Error(t, newMigrator(byPod).CheckFingerprintStrategy())
+		require.NoError(t, newMigrator(byPod).RekeyFingerprints())
+		assert.NoError(t, newMigrator(fingerprint.New(nil, []string{"pod", "pod"})).CheckFingerprintStrategy())
+		assert.Error(t, newMigrator(fingerprint.Default()).CheckFingerprintStrategy())
+
+		alert, err := repo.GetByFingerprint(byPod.FingerprintJSONB(labels))
+		require.NoError(t, err)
+		assert.Equal(t, "HighCPU", alert.Labels["alertname"])
+	})
+}
+
+func TestAlertCRUD(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewAlertRepository(db)
+		startsAt := time.Now().UTC().Truncate(time.Second)
+
+		alert := newAlert("crud", models.JSONB{"alertname": "DiskFull"}, models.JSONB{"summary": "disk"}, startsAt)
+		require.NoError(t, repo.Create(alert))
+		require.NotZero(t, alert.ID)
+
+		stored, err := repo.GetByFingerprint("crud")
+		require.NoError(t, err)
+		assert.Equal(t, alert.ID, stored.ID)
+		assert.Equal(t, "DiskFull", stored.Labels["alertname"])
+		assert.True(t, startsAt.Equal(stored.StartsAt))
+
+		endsAt := startsAt.Add(time.Minute)
+		stored.Status = string(models.AlertStatusResolved)
+		stored.EndsAt = &endsAt
+		require.NoError(t, repo.Update(stored))
+
+		resolved, err := repo.ListByStatus(string(models.AlertStatusResolved))
+		require.NoError(t, err)
+		require.Len(t, resolved, 1)
+		require.NotNil(t, resolved[0].EndsAt)
+		assert.True(t, endsAt.Equal(*resolved[0].EndsAt))
+
+		byFingerprint, err := repo.ListByFingerprints([]string{"crud", "unknown"})
+		require.NoError(t, err)
+		assert.Len(t, byFingerprint, 1)
+
+		require.NoError(t, repo.Delete("crud"))
+		_, err = repo.GetByFingerprint("crud")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestAlertUpsert(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewAlertRepository(db)
+		startsAt := time.Now().UTC().Truncate(time.Second)
+
+		first := []*models.Alert{
+			newAlert("a", models.JSONB{"alertname": "A"}, models.JSONB{"summary": "first"}, startsAt),
+			newAlert("b", models.JSONB{"alertname": "B"}, models.JSONB{}, startsAt),
+		}
+		require.NoError(t, repo.Upsert(first))
+		require.NotZero(t, first[0].ID)
+		require.NotZero(t, first[1].ID)
+
+		endsAt := startsAt.Add(time.Hour)
+		update := newAlert("a", models.JSONB{"alertname": "A", "pod": "web-2"}, models.JSONB{"summary": "second"}, startsAt.Add(time.Minute))
+		update.Status = string(models.AlertStatusResolved)
+		update.Severity = "critical"
+		update.EndsAt = &endsAt
+		created := newAlert("c", models.JSONB{"alertname": "C"}, models.JSONB{}, startsAt)
+		require.NoError(t, repo.Upsert([]*models.Alert{update, created}))
+
+		stored, err := repo.GetByFingerprint("a")
+		require.NoError(t, err)
+		assert.Equal(t, first[0].ID, stored.ID)
+		assert.Equal(t, models.JSONB{"alertname": "A", "pod": "web-2"}, stored.Labels)
+		assert.Equal(t, string(models.AlertStatusResolved), stored.Status)
+		assert.Equal(t, "critical", stored.Severity)
+		assert.Equal(t, "second", stored.Annotations["summary"])
+		require.NotNil(t, stored.EndsAt)
+		assert.True(t, endsAt.Equal(*stored.EndsAt))
+		// The start of an updated alert is kept
+		assert.True(t, startsAt.Equal(stored.StartsAt))
+
+		var count int64
+		require.NoError(t, db.Model(&models.Alert{}).Count(&count).Error)
+		assert.EqualValues(t, 3, count)
+
+		require.NoError(t, repo.Upsert(nil))
+	})
+}
+
+func TestIncidentBulkLinks(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewIncidentRepository(db)
+		startsAt := time.Now().UTC().Truncate(time.Second)
+
+		incidents := []*models.Incident{
+			{Title: "disk", CorrelationKey: "disk", Status: string(models.IncidentStatusOpen), Severity: "warning", StartedAt: startsAt},
+			{Title: "network", CorrelationKey: "network", Status: string(models.IncidentStatusOpen), Severity: "critical", StartedAt: startsAt},
+		}
+		require.NoError(t, repo.CreateBatch(incidents))
+		require.NotZero(t, incidents[0].ID)
+		require.NotZero(t, incidents[1].ID)
+
+		byKey, err := repo.ListOpenByCorrelationKeys([]string{"disk", "network", "missing"})
+		require.NoError(t, err)
+		require.Len(t, byKey, 2)
+		assert.Equal(t, incidents[0].ID, byKey["disk"].ID)
+		assert.Equal(t, incidents[1].ID, byKey["network"].ID)
+
+		links := []*models.IncidentAlert{
+			{IncidentID: incidents[0].ID, AlertFingerprint: "a"},
+			{IncidentID: incidents[0].ID, AlertFingerprint: "b"},
+			{IncidentID: incidents[1].ID, AlertFingerprint: "c"},
+		}
+		require.NoError(t, repo.AttachAlerts(links))
+		// Existing links are skipped and do not inflate the count
+		require.NoError(t, repo.AttachAlerts([]*models.IncidentAlert{
+			{IncidentID: incidents[0].ID, AlertFingerprint: "a"},
+		}))
+
+		byAlert, err := repo.ListOpenByAlerts([]string{"a", "b", "c"})
+		require.NoError(t, err)
+		assert.Equal(t, incidents[0].ID, byAlert["a"].ID)
+		assert.Equal(t, incidents[0].ID, byAlert["b"].ID)
+		assert.Equal(t, incidents[1].ID, byAlert["c"].ID)
+
+		var counts []int
+		require.NoError(t, db.Model(&models.Incident{}).Order("id").Pluck("alert_count", &counts).Error)
+		assert.Equal(t, []int{2, 1}, counts)
+
+		require.NoError(t, repo.CreateBatch(nil))
+		require.NoError(t, repo.AttachAlerts(nil))
+	})
+}
+
+func TestAlertKeysetPagination(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewAlertRepository(db)
+		base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		// Pairs of alerts share a creation time, so pages must break ties by ID
+		const total = 25
+		for i := 0; i < total; i++ {
+			alert := newAlert(fmt.Sprintf("fp-%02d", i), models.JSONB{"alertname": "Paged"}, models.JSONB{}, base)
+			alert.CreatedAt = base.Add(time.Duration(i/2) * time.Minute)
+			require.NoError(t, repo.Create(alert))
+		}
+
+		for _, order := range []string{"desc", "asc"} {
+			t.Run(order, func(t *testing.T) {
+				filters := models.AlertFilters{Size: 10, Sort: "created_at", Order: order}
+				seen := make(map[uint]bool)
+				var previous *models.Alert
+				for page := 0; ; page++ {
+					require.Less(t, page, 5, "pagination does not terminate")
+
+					alerts, count, err := repo.List(filters)
+					require.NoError(t, err)
+					assert.EqualValues(t, total, count)
+					if len(alerts) == 0 {
+						break
+					}
+
+					for i := range alerts {
+						alert := alerts[i]
+						assert.False(t, seen[alert.ID], "alert %d returned twice", alert.ID)
+						seen[alert.ID] = true
+						if previous != nil {
+							assertOrdered(t, order, *previous, alert)
+						}
+						previous = &alert
+					}
+					filters.Cursor = models.CursorFor(alerts[len(alerts)-1], filters.Sort).Encode()
+				}
+				assert.Len(t, seen, total)
+			})
+		}
+	})
+}
+
+func assertOrdered(t *testing.T, order string, a, b models.Alert) {
+	t.Helper()
+	before := a.CreatedAt.Before(b.CreatedAt) || (a.CreatedAt.Equal(b.CreatedAt) && a.ID < b.ID)
+	if order == "desc" {
+		before = b.CreatedAt.Before(a.CreatedAt) || (a.CreatedAt.Equal(b.CreatedAt) && b.ID < a.ID)
+	}
+	assert.True(t, before, "alert %d and %d out of %s order", a.ID, b.ID, order)
+}
+
+func TestAlertSearch(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewAlertRepository(db)
+		startsAt := time.Now().UTC().Truncate(time.Second)
+
+		alerts := []*models.Alert{
+			newAlert("db-prod", models.JSONB{"alertname": "DiskFull", "team": "db", "env": "prod", "instance": "db-1:9100"},
+				models.JSONB{"summary": "Disk almost full", "description": "The data volume on db-1 is at 95%"}, startsAt),
+			newAlert("db-staging", models.JSONB{"alertname": "DiskFull", "team": "db", "env": "staging", "instance": "db-2:9100"},
+				models.JSONB{"summary": "Disk almost full"}, startsAt),
+			newAlert("web-prod", models.JSONB{"alertname": "HighLatency", "team": "web", "env": "production", "instance": "web-1:9100"},
+				models.JSONB{"summary": "Requests are slow", "runbook": "check the load balancer"}, startsAt),
+			newAlert("no-team", models.JSONB{"alertname": "Watchdog"}, models.JSONB{}, startsAt),
+		}
+		alerts[1].Severity = "critical"
+		for _, alert := range alerts {
+			require.NoError(t, repo.Create(alert))
+		}
+
+		tests := []struct {
+			name    string
+			filters models.AlertFilters
+			want    []string
+		}{
+			{"equal", models.AlertFilters{Query: `team="db"`}, []string{"db-prod", "db-staging"}},
+			{"equal and not equal", models.AlertFilters{Query: `team="db",env!="prod"`}, []string{"db-staging"}},
+			{"regexp is anchored", models.AlertFilters{Query: `env=~"prod"`}, []string{"db-prod"}},
+			{"regexp", models.AlertFilters{Query: `env=~"prod.*"`}, []string{"db-prod", "web-prod"}},
+			{"alternation is anchored", models.AlertFilters{Query: `env=~"prod|staging"`}, []string{"db-prod", "db-staging"}},
+			{"negative regexp is anchored", models.AlertFilters{Query: `env!~"prod"`}, []string{"db-staging", "no-team", "web-prod"}},
+			{"regexp classes", models.AlertFilters{Query: `instance=~"db-[0-9]:\\d+"`}, []string{"db-prod", "db-staging"}},
+			{"negative regexp", models.AlertFilters{Query: `alertname!~"Disk.*"`}, []string{"no-team", "web-prod"}},
+			{"missing label equals empty", models.AlertFilters{Query: `team=""`}, []string{"no-team"}},
+			{"alertname contains", models.AlertFilters{AlertName: "Disk"}, []string{"db-prod", "db-staging"}},
+			{"instance contains", models.AlertFilters{Instance: "web"}, []string{"web-prod"}},
+			{"severity", models.AlertFilters{Severity: "critical"}, []string{"db-staging"}},
+			{"annotation search", models.AlertFilters{Search: "volume"}, []string{"db-prod"}},
+			{"annotation search in any annotation", models.AlertFilters{Search: "balancer"}, []string{"web-prod"}},
+			{"annotation search with labels", models.AlertFilters{Search: "full", Query: `env="staging"`}, []string{"db-staging"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				found, count, err := repo.List(tt.filters)
+				require.NoError(t, err)
+				assert.EqualValues(t, len(tt.want), count)
+				assert.ElementsMatch(t, tt.want, fingerprints(found))
+			})
+		}
+
+		_, _, err := repo.List(models.AlertFilters{Query: `team~"db"`})
+		assert.Error(t, err)
+	})
+}
+
+func fingerprints(alerts []models.Alert) []string {
+	out := make([]string, len(alerts))
+	for i, alert := range alerts {
+		out[i] = alert.Fingerprint
+	}
+	return out
+}
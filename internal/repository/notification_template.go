@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"alertbot/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type notificationTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationTemplateRepository(db *gorm.DB) NotificationTemplateRepository {
+	return &notificationTemplateRepository{db: db}
+}
+
+func (r *notificationTemplateRepository) Create(tmpl *models.NotificationTemplate) error {
+	return r.db.Create(tmpl).Error
+}
+
+func (r *notificationTemplateRepository) GetByName(name string) (*models.NotificationTemplate, error) {
+	var tmpl models.NotificationTemplate
+	err := r.db.Where("name = ?", name).First(&tmpl).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+func (r *notificationTemplateRepository) List() ([]models.NotificationTemplate, error) {
+	var templates []models.NotificationTemplate
+	err := r.db.Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+func (r *notificationTemplateRepository) Update(tmpl *models.NotificationTemplate) error {
+	return r.db.Save(tmpl).Error
+}
+
+func (r *notificationTemplateRepository) Delete(id uint) error {
+	return r.db.Delete(&models.NotificationTemplate{}, id).Error
+}
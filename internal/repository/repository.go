@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"alertbot/internal/models"
 
@@ -9,20 +10,32 @@ import (
 )
 
 type Repositories struct {
-	Alert               AlertRepository
-	RoutingRule         RoutingRuleRepository
-	NotificationChannel NotificationChannelRepository
-	Silence             SilenceRepository
-	AlertHistory        AlertHistoryRepository
-	AlertGroup          AlertGroupRepository
-	Inhibition          InhibitionRepository
-	Settings            SettingsRepository
+	Alert                AlertRepository
+	RoutingRule          RoutingRuleRepository
+	NotificationChannel  NotificationChannelRepository
+	Silence              SilenceRepository
+	AlertHistory         AlertHistoryRepository
+	AlertGroup           AlertGroupRepository
+	Inhibition           InhibitionRepository
+	Settings             SettingsRepository
+	SavedView            SavedViewRepository
+	RevokedToken         RevokedTokenRepository
+	Incident             IncidentRepository
+	NotificationTemplate NotificationTemplateRepository
+	Heartbeat            HeartbeatRepository
+	Cluster              ClusterRepository
+
+	db *gorm.DB
 }
 
 type AlertRepository interface {
 	Create(alert *models.Alert) error
 	GetByFingerprint(fingerprint string) (*models.Alert, error)
 	List(filters models.AlertFilters) ([]models.Alert, int64, error)
+	ListByStatus(status string) ([]models.Alert, error)
+	ListStartedSince(since time.Time, limit int) ([]models.Alert, error)
+	ListByFingerprints(fingerprints []string) ([]models.Alert, error)
+	Upsert(alerts []*models.Alert) error
 	Update(alert *models.Alert) error
 	Delete(fingerprint string) error
 }
@@ -49,15 +62,20 @@ type SilenceRepository interface {
 	Create(silence *models.Silence) error
 	GetByID(id uint) (*models.Silence, error)
 	List() ([]models.Silence, error)
+	ListNamed() ([]models.Silence, error)
+	Update(silence *models.Silence) error
 	Delete(id uint) error
 	GetActiveSilences() ([]models.Silence, error)
 }
 
 type AlertHistoryRepository interface {
 	Create(history *models.AlertHistory) error
+	CreateBatch(histories []*models.AlertHistory) error
 	GetByAlertFingerprint(fingerprint string) ([]models.AlertHistory, error)
 	GetByFingerprint(fingerprint string) ([]models.AlertHistory, error)
 	List(filters models.AlertHistoryFilters) ([]models.AlertHistory, int64, error)
+	ListByActionsSince(actions []string, since time.Time, limit int) ([]models.AlertHistory, error)
+	ListByFingerprintsSince(fingerprints []string, since time.Time, limit int) ([]models.AlertHistory, error)
 }
 
 type InhibitionRepository interface {
@@ -84,6 +102,80 @@ type InhibitionRepository interface {
 	GetActiveInhibitions(ctx context.Context) ([]*models.InhibitionStatus, error)
 }
 
+type SavedViewRepository interface {
+	Create(view *models.SavedView) error
+	GetByID(id uint) (*models.SavedView, error)
+	List(owner string) ([]models.SavedView, error)
+	Update(view *models.SavedView) error
+	Delete(id uint) error
+}
+
+type IncidentRepository interface {
+	Create(incident *models.Incident) error
+	GetByID(id uint) (*models.Incident, error)
+	List(filters models.IncidentFilters) ([]models.Incident, int64, error)
+	Update(incident *models.Incident) error
+	Delete(id uint) error
+	// CreateBatch inserts incidents in bulk; each gets the ID of its row
+	CreateBatch(incidents []*models.Incident) error
+	GetOpenByCorrelationKey(key string) (*models.Incident, error)
+	// ListOpenByCorrelationKeys returns the newest unresolved incident of
+	// each given correlation key that has one
+	ListOpenByCorrelationKeys(keys []string) (map[string]*models.Incident, error)
+	GetOpenByAlert(fingerprint string) (*models.Incident, error)
+	ListOpenByAlerts(fingerprints []string) (map[string]*models.Incident, error)
+	AttachAlert(incidentID uint, fingerprint string) (bool, error)
+	// AttachAlerts links alerts to incidents in bulk, skipping existing
+	// links, and recounts the alerts of the incidents involved
+	AttachAlerts(links []*models.IncidentAlert) error
+	ListAlertFingerprints(incidentID uint) ([]string, error)
+	CreateNote(note *models.IncidentNote) error
+}
+
+type NotificationTemplateRepository interface {
+	Create(tmpl *models.NotificationTemplate) error
+	GetByName(name string) (*models.NotificationTemplate, error)
+	List() ([]models.NotificationTemplate, error)
+	Update(tmpl *models.NotificationTemplate) error
+	Delete(id uint) error
+}
+
+type HeartbeatRepository interface {
+	Create(heartbeat *models.Heartbeat) error
+	GetByID(id uint) (*models.Heartbeat, error)
+	List() ([]models.Heartbeat, error)
+	ListEnabled() ([]models.Heartbeat, error)
+	Update(heartbeat *models.Heartbeat) error
+	Delete(id uint) error
+	RecordPing(id uint, at time.Time) error
+	UpdateStatus(id uint, status string, missedAt *time.Time) error
+}
+
+type ClusterRepository interface {
+	UpsertMember(member *models.ClusterMember) error
+	DeleteMember(nodeID string) error
+	// ListMembers returns members seen since the given time, oldest first
+	ListMembers(since time.Time) ([]models.ClusterMember, error)
+	DeleteMembersNotSeenSince(before time.Time) error
+	// ClaimNotification stores the claim unless another one for the same
+	// key was made after staleBefore, and reports whether it was stored
+	ClaimNotification(claim *models.NotificationClaim, staleBefore time.Time) (bool, error)
+	// ReleaseClaim deletes the claim for key if nodeID still holds it
+	ReleaseClaim(key, nodeID string) error
+	DeleteClaimsBefore(before time.Time) error
+	// Event payloads too large to send inline between replicas
+	CreateEventPayload(payload *models.EventPayload) error
+	GetEventPayload(id string) (*models.EventPayload, error)
+	DeleteEventPayloadsBefore(before time.Time) error
+}
+
+type RevokedTokenRepository interface {
+	Create(token *models.RevokedToken) error
+	ListActive() ([]models.RevokedToken, error)
+	MarkTicketUsed(ticketID string, expiresAt time.Time) (bool, error)
+	DeleteExpired() error
+}
+
 type SettingsRepository interface {
 	// System settings
 	GetSystemConfig() (*models.SystemConfig, error)
@@ -96,17 +188,44 @@ type SettingsRepository interface {
 	// Notification settings
 	GetNotificationConfig() (*models.NotificationConfig, error)
 	UpdateNotificationConfig(config *models.NotificationConfig) error
+	
+	// Deduplication settings
+	GetDeduplicationConfig() (*models.DeduplicationConfig, error)
+	UpdateDeduplicationConfig(config *models.DeduplicationConfig) error
+	
+	// Dependency graph for topology correlation
+	GetTopologyGraph() (*models.TopologyGraph, error)
+	UpdateTopologyGraph(graph *models.TopologyGraph) error
+	
+	// Relabel configs applied to received alerts
+	GetRelabelConfigs() (*models.RelabelConfigList, error)
+	UpdateRelabelConfigs(list *models.RelabelConfigList) error
 }
 
 func NewRepositories(db *gorm.DB) *Repositories {
 	return &Repositories{
-		Alert:               NewAlertRepository(db),
-		RoutingRule:         NewRoutingRuleRepository(db),
-		NotificationChannel: NewNotificationChannelRepository(db),
-		Silence:             NewSilenceRepository(db),
-		AlertHistory:        NewAlertHistoryRepository(db),
-		AlertGroup:          NewAlertGroupRepository(db),
-		Inhibition:          NewInhibitionRepository(db),
-		Settings:            NewSettingsRepository(db),
+		Alert:                NewAlertRepository(db),
+		RoutingRule:          NewRoutingRuleRepository(db),
+		NotificationChannel:  NewNotificationChannelRepository(db),
+		Silence:              NewSilenceRepository(db),
+		AlertHistory:         NewAlertHistoryRepository(db),
+		AlertGroup:           NewAlertGroupRepository(db),
+		Inhibition:           NewInhibitionRepository(db),
+		Settings:             NewSettingsRepository(db),
+		SavedView:            NewSavedViewRepository(db),
+		RevokedToken:         NewRevokedTokenRepository(db),
+		Incident:             NewIncidentRepository(db),
+		NotificationTemplate: NewNotificationTemplateRepository(db),
+		Heartbeat:            NewHeartbeatRepository(db),
+		Cluster:              NewClusterRepository(db),
+		db:                   db,
 	}
+}
+
+// Transaction runs fn with repositories bound to one database transaction,
+// committing when fn returns nil and rolling back otherwise
+func (r *Repositories) Transaction(fn func(tx *Repositories) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepositories(tx))
+	})
 }
\ No newline at end of file
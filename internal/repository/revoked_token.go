@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"alertbot/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
+
+// Create stores a revoked token; revoking the same token twice is a no-op
+func (r *revokedTokenRepository) Create(token *models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// ListActive returns revocations for tokens that have not expired yet
+func (r *revokedTokenRepository) ListActive() ([]models.RevokedToken, error) {
+	var tokens []models.RevokedToken
+	err := r.db.Where("expires_at > ?", time.Now()).Find(&tokens).Error
+	return tokens, err
+}
+
+// MarkTicketUsed records the redemption of a ticket. It reports false when the
+// ticket was already redeemed, on this or any other replica.
+func (r *revokedTokenRepository) MarkTicketUsed(ticketID string, expiresAt time.Time) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UsedTicket{
+		TicketID:  ticketID,
+		ExpiresAt: expiresAt,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteExpired removes revocations and used tickets for tokens that would be rejected anyway
+func (r *revokedTokenRepository) DeleteExpired() error {
+	now := time.Now()
+	if err := r.db.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("expires_at <= ?", now).Delete(&models.UsedTicket{}).Error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"alertbot/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type savedViewRepository struct {
+	db *gorm.DB
+}
+
+func NewSavedViewRepository(db *gorm.DB) SavedViewRepository {
+	return &savedViewRepository{db: db}
+}
+
+func (r *savedViewRepository) Create(view *models.SavedView) error {
+	return r.db.Create(view).Error
+}
+
+func (r *savedViewRepository) GetByID(id uint) (*models.SavedView, error) {
+	var view models.SavedView
+	err := r.db.First(&view, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// List returns the views owned by the user plus all shared views
+func (r *savedViewRepository) List(owner string) ([]models.SavedView, error) {
+	var views []models.SavedView
+	err := r.db.Where("owner = ? OR shared = ?", owner, true).
+		Order("name ASC").
+		Find(&views).Error
+	return views, err
+}
+
+func (r *savedViewRepository) Update(view *models.SavedView) error {
+	return r.db.Save(view).Error
+}
+
+func (r *savedViewRepository) Delete(id uint) error {
+	return r.db.Delete(&models.SavedView{}, id).Error
+}
@@ -98,10 +98,12 @@ func (r *settingsRepository) GetNotificationConfig() (*models.NotificationConfig
 		if err == gorm.ErrRecordNotFound {
 			// Return default settings if not found
 			return &models.NotificationConfig{
-				MaxRetries:    3,
-				RetryInterval: 30,
-				RateLimit:     100,
-				BatchSize:     10,
+				MaxRetries:     3,
+				RetryInterval:  30,
+				RateLimit:      100,
+				RateLimitPer:   models.DefaultRateLimitPer,
+				RateLimitBurst: models.DefaultRateLimitBurst,
+				BatchSize:      10,
 			}, nil
 		}
 		return nil, err
@@ -124,4 +126,97 @@ func (r *settingsRepository) UpdateNotificationConfig(config *models.Notificatio
 	// Update existing record
 	config.ID = existingConfig.ID
 	return r.db.Save(config).Error
-}
\ No newline at end of file
+}
+// Deduplication settings methods
+func (r *settingsRepository) GetDeduplicationConfig() (*models.DeduplicationConfig, error) {
+	var config models.DeduplicationConfig
+	err := r.db.First(&config).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Return default settings if not found
+			return models.DefaultDeduplicationConfig(), nil
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *settingsRepository) UpdateDeduplicationConfig(config *models.DeduplicationConfig) error {
+	var existingConfig models.DeduplicationConfig
+	err := r.db.First(&existingConfig).Error
+	
+	if err == gorm.ErrRecordNotFound {
+		// Create new record
+		config.ID = 1 // Ensure single record with ID 1
+		return r.db.Create(config).Error
+	} else if err != nil {
+		return err
+	}
+	
+	// Update existing record
+	config.ID = existingConfig.ID
+	config.CreatedAt = existingConfig.CreatedAt
+	return r.db.Save(config).Error
+}
+
+func (r *settingsRepository) GetTopologyGraph() (*models.TopologyGraph, error) {
+	var graph models.TopologyGraph
+	err := r.db.First(&graph).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Return an empty graph if none was uploaded
+			return models.DefaultTopologyGraph(), nil
+		}
+		return nil, err
+	}
+	return &graph, nil
+}
+
+func (r *settingsRepository) UpdateTopologyGraph(graph *models.TopologyGraph) error {
+	var existing models.TopologyGraph
+	err := r.db.First(&existing).Error
+	
+	if err == gorm.ErrRecordNotFound {
+		// Create new record
+		graph.ID = 1 // Ensure single record with ID 1
+		return r.db.Create(graph).Error
+	} else if err != nil {
+		return err
+	}
+	
+	// Update existing record
+	graph.ID = existing.ID
+	graph.CreatedAt = existing.CreatedAt
+	return r.db.Save(graph).Error
+}
+
+func (r *settingsRepository) GetRelabelConfigs() (*models.RelabelConfigList, error) {
+	var list models.RelabelConfigList
+	err := r.db.First(&list).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Labels are kept as received until configs are stored
+			return models.DefaultRelabelConfigList(), nil
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *settingsRepository) UpdateRelabelConfigs(list *models.RelabelConfigList) error {
+	var existing models.RelabelConfigList
+	err := r.db.First(&existing).Error
+	
+	if err == gorm.ErrRecordNotFound {
+		// Create new record
+		list.ID = 1 // Ensure single record with ID 1
+		return r.db.Create(list).Error
+	} else if err != nil {
+		return err
+	}
+	
+	// Update existing record
+	list.ID = existing.ID
+	list.CreatedAt = existing.CreatedAt
+	return r.db.Save(list).Error
+}
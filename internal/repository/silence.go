@@ -34,6 +34,17 @@ func (r *silenceRepository) List() ([]models.Silence, error) {
 	return silences, err
 }
 
+func (r *silenceRepository) Update(silence *models.Silence) error {
+	return r.db.Save(silence).Error
+}
+
+// ListNamed returns the silences managed by config apply
+func (r *silenceRepository) ListNamed() ([]models.Silence, error) {
+	var silences []models.Silence
+	err := r.db.Where("name <> ''").Order("name ASC").Find(&silences).Error
+	return silences, err
+}
+
 func (r *silenceRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Silence{}, id).Error
 }
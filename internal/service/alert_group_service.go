@@ -27,7 +27,10 @@ type AlertGroupService interface {
 	DeleteAlertGroupRule(ctx context.Context, id uint) error
 	
 	// Alert grouping logic
-	ProcessAlertForGrouping(ctx context.Context, alert *models.Alert) (*models.AlertGroup, error)
+	// GroupAlerts adds alerts to the groups of their first matching rule,
+	// creating missing groups, and returns each alert's group keyed by
+	// fingerprint
+	GroupAlerts(ctx context.Context, alerts []*models.Alert) (map[string]*models.AlertGroup, error)
 	UpdateGroupFromAlert(ctx context.Context, group *models.AlertGroup, alert *models.Alert) error
 }
 
@@ -103,59 +106,91 @@ func (s *alertGroupService) DeleteAlertGroupRule(ctx context.Context, id uint) e
 	return s.groupRepo.DeleteAlertGroupRule(ctx, id)
 }
 
-func (s *alertGroupService) ProcessAlertForGrouping(ctx context.Context, alert *models.Alert) (*models.AlertGroup, error) {
+func (s *alertGroupService) GroupAlerts(ctx context.Context, alerts []*models.Alert) (map[string]*models.AlertGroup, error) {
+	byAlert := make(map[string]*models.AlertGroup, len(alerts))
+	if len(alerts) == 0 {
+		return byAlert, nil
+	}
+
 	// Get active group rules
 	rules, err := s.groupRepo.GetActiveAlertGroupRules(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get group rules: %w", err)
 	}
-	
-	// Find the first matching rule (rules are ordered by priority)
-	var matchingRule *models.AlertGroupRule
-	for _, rule := range rules {
-		if s.alertMatchesRule(alert, rule) {
-			matchingRule = rule
-			break
+
+	matchingRules := make([]*models.AlertGroupRule, len(alerts))
+	groupKeys := make([]string, len(alerts))
+	for i, alert := range alerts {
+		// Find the first matching rule (rules are ordered by priority)
+		for _, rule := range rules {
+			if s.alertMatchesRule(alert, rule) {
+				matchingRules[i] = rule
+				break
+			}
+		}
+
+		// If no rule matches, create a default group by alertname
+		if matchingRules[i] == nil {
+			matchingRules[i] = s.getDefaultGroupRule()
 		}
+		groupKeys[i] = s.generateGroupKey(alert, matchingRules[i])
 	}
-	
-	// If no rule matches, create a default group by alertname
-	if matchingRule == nil {
-		matchingRule = s.getDefaultGroupRule()
+
+	existing, err := s.groupRepo.ListAlertGroupsByKeys(ctx, groupKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up alert groups: %w", err)
 	}
-	
-	// Generate group key based on the rule
-	groupKey := s.generateGroupKey(alert, matchingRule)
-	
-	// Try to find existing group
-	existingGroup, err := s.groupRepo.GetAlertGroupByKey(ctx, groupKey)
-	if err == nil {
-		// Update existing group
-		err = s.UpdateGroupFromAlert(ctx, existingGroup, alert)
-		return existingGroup, err
+	groups := make(map[string]*models.AlertGroup, len(existing))
+	for _, group := range existing {
+		groups[group.GroupKey] = group
 	}
-	
-	// Create new group
-	group := &models.AlertGroup{
-		GroupKey:     groupKey,
-		GroupBy:      matchingRule.GroupBy,
-		CommonLabels: s.extractCommonLabels(alert, matchingRule),
-		AlertCount:   1,
-		Status:       alert.Status,
-		Severity:     alert.Severity,
-		FirstAlertAt: alert.StartsAt,
-		LastAlertAt:  alert.StartsAt,
+
+	var created, updated []*models.AlertGroup
+	dirty := make(map[uint]bool)
+	for i, alert := range alerts {
+		group, ok := groups[groupKeys[i]]
+		if !ok {
+			group = &models.AlertGroup{
+				GroupKey:     groupKeys[i],
+				GroupBy:      matchingRules[i].GroupBy,
+				CommonLabels: s.extractCommonLabels(alert, matchingRules[i]),
+				AlertCount:   1,
+				Status:       alert.Status,
+				Severity:     alert.Severity,
+				FirstAlertAt: alert.StartsAt,
+				LastAlertAt:  alert.StartsAt,
+			}
+			groups[group.GroupKey] = group
+			created = append(created, group)
+		} else {
+			// Stored groups are saved once, after all their alerts are added
+			if group.ID != 0 && !dirty[group.ID] {
+				dirty[group.ID] = true
+				updated = append(updated, group)
+			}
+			s.addAlertToGroup(group, alert)
+		}
+		byAlert[alert.Fingerprint] = group
 	}
-	
-	err = s.groupRepo.CreateAlertGroup(ctx, group)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create alert group: %w", err)
+
+	if err := s.groupRepo.CreateAlertGroups(ctx, created); err != nil {
+		return nil, fmt.Errorf("failed to create alert groups: %w", err)
 	}
-	
-	return group, nil
+	for _, group := range updated {
+		if err := s.groupRepo.UpdateAlertGroup(ctx, group); err != nil {
+			return nil, fmt.Errorf("failed to update alert group: %w", err)
+		}
+	}
+	return byAlert, nil
 }
 
 func (s *alertGroupService) UpdateGroupFromAlert(ctx context.Context, group *models.AlertGroup, alert *models.Alert) error {
+	s.addAlertToGroup(group, alert)
+	return s.groupRepo.UpdateAlertGroup(ctx, group)
+}
+
+// addAlertToGroup updates the group's statistics for a new alert
+func (s *alertGroupService) addAlertToGroup(group *models.AlertGroup, alert *models.Alert) {
 	// Update group statistics
 	group.AlertCount++
 	group.LastAlertAt = alert.StartsAt
@@ -169,8 +204,6 @@ func (s *alertGroupService) UpdateGroupFromAlert(ctx context.Context, group *mod
 	if alert.Status == "firing" {
 		group.Status = "firing"
 	}
-	
-	return s.groupRepo.UpdateAlertGroup(ctx, group)
 }
 
 // Helper methods
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// incidentSeverityOrder ranks severities so an incident reports its worst alert
+var incidentSeverityOrder = map[string]int{
+	"info":     1,
+	"warning":  2,
+	"critical": 3,
+}
+
+// linkIncidents attaches the new correlated alerts of a batch to the open
+// incident for their correlation key, or to the open incident of a related
+// alert, opening incidents where neither exists. Updated alerts get the
+// incident they are attached to. Within an incident only the first routed
+// alert notifies; later ones are recorded as suppressed. Everything is read
+// and written in bulk through repos, and the history entries to record are
+// returned.
+func (s *alertService) linkIncidents(repos *repository.Repositories, pending []*IngestAlert) ([]*models.AlertHistory, error) {
+	repo := repos.Incident
+	if repo == nil {
+		return nil, nil
+	}
+
+	var keys, fingerprints []string
+	for _, a := range pending {
+		switch {
+		case a.Action == "updated":
+			fingerprints = append(fingerprints, a.Alert.Fingerprint)
+		case opensIncident(a):
+			keys = append(keys, a.Dedup.CorrelationKey)
+			for _, related := range a.Dedup.RelatedAlerts {
+				fingerprints = append(fingerprints, related.Fingerprint)
+			}
+		}
+	}
+	if len(keys) == 0 && len(fingerprints) == 0 {
+		return nil, nil
+	}
+
+	byKey, err := repo.ListOpenByCorrelationKeys(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up incidents: %w", err)
+	}
+	byAlert, err := repo.ListOpenByAlerts(fingerprints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up incidents: %w", err)
+	}
+
+	var opened, changed []*models.Incident
+	isChanged := make(map[uint]bool)
+	markChanged := func(incident *models.Incident) {
+		// Incidents opened by this batch are stored with their final state
+		if incident.ID != 0 && !isChanged[incident.ID] {
+			isChanged[incident.ID] = true
+			changed = append(changed, incident)
+		}
+	}
+	attached := make(map[*IngestAlert]bool)
+	now := time.Now()
+
+	for _, a := range pending {
+		var incident *models.Incident
+		switch {
+		case a.Action == "updated":
+			incident = byAlert[a.Alert.Fingerprint]
+		case opensIncident(a):
+			incident = byKey[a.Dedup.CorrelationKey]
+			related := a.Dedup.RelatedAlerts
+			for i := 0; incident == nil && i < len(related); i++ {
+				incident = byAlert[related[i].Fingerprint]
+			}
+			if incident == nil {
+				incident = &models.Incident{
+					Title:          incidentTitle(a.Alert),
+					CorrelationKey: a.Dedup.CorrelationKey,
+					Status:         string(models.IncidentStatusOpen),
+					Severity:       a.Alert.Severity,
+					StartedAt:      a.Alert.StartsAt,
+				}
+				opened = append(opened, incident)
+				byKey[incident.CorrelationKey] = incident
+			}
+			// Later alerts of the batch may be related to this one
+			byAlert[a.Alert.Fingerprint] = incident
+
+			attached[a] = true
+			incident.AlertCount++
+			if incidentSeverityOrder[a.Alert.Severity] > incidentSeverityOrder[incident.Severity] {
+				incident.Severity = a.Alert.Severity
+				markChanged(incident)
+			}
+		}
+		if incident == nil {
+			continue
+		}
+
+		a.Incident = incident
+		if incident.NotifiedAt == nil {
+			incident.NotifiedAt = &now
+			markChanged(incident)
+		} else {
+			a.Notify = false
+		}
+	}
+
+	if err := repo.CreateBatch(opened); err != nil {
+		return nil, fmt.Errorf("failed to open incidents: %w", err)
+	}
+	for _, incident := range changed {
+		if err := repo.Update(incident); err != nil {
+			return nil, fmt.Errorf("failed to update incident %d: %w", incident.ID, err)
+		}
+	}
+
+	var links []*models.IncidentAlert
+	var history []*models.AlertHistory
+	for _, a := range pending {
+		if a.Incident == nil {
+			continue
+		}
+		if attached[a] {
+			links = append(links, &models.IncidentAlert{IncidentID: a.Incident.ID, AlertFingerprint: a.Alert.Fingerprint})
+			history = append(history, &models.AlertHistory{
+				AlertFingerprint: a.Alert.Fingerprint,
+				Action:           "incident_attached",
+				Details: models.JSONB{
+					"incident_id":     a.Incident.ID,
+					"correlation_key": a.Dedup.CorrelationKey,
+				},
+			})
+		}
+		if !a.Notify {
+			history = append(history, &models.AlertHistory{
+				AlertFingerprint: a.Alert.Fingerprint,
+				Action:           "notification_suppressed",
+				Details: models.JSONB{
+					"incident_id": a.Incident.ID,
+					"reason":      "incident already notified",
+				},
+			})
+		}
+	}
+	if err := repo.AttachAlerts(links); err != nil {
+		return nil, fmt.Errorf("failed to attach alerts to incidents: %w", err)
+	}
+	return history, nil
+}
+
+// opensIncident reports whether a new alert belongs in an incident
+func opensIncident(a *IngestAlert) bool {
+	return a.Action == "created" && a.Dedup != nil && a.Dedup.Correlated && a.Alert.Status == string(models.AlertStatusFiring)
+}
+
+// openIncident returns the open incident an alert belongs to, if any
+func (s *alertService) openIncident(fingerprint string) *models.Incident {
+	if s.deps.Repositories.Incident == nil {
+		return nil
+	}
+	incident, err := s.deps.Repositories.Incident.GetOpenByAlert(fingerprint)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.deps.Logger.WithError(err).WithField("alert_fingerprint", fingerprint).Warn("Failed to look up incident")
+		}
+		return nil
+	}
+	return incident
+}
+
+// resolveIncidentIfDone resolves the alert's incident once all of its alerts are resolved
+func (s *alertService) resolveIncidentIfDone(fingerprint string) {
+	s.resolveIncidentIfAllResolved(s.openIncident(fingerprint))
+}
+
+// resolveIncidentIfAllResolved resolves the incident once all of its alerts are resolved
+func (s *alertService) resolveIncidentIfAllResolved(incident *models.Incident) {
+	if incident == nil || incident.Status == string(models.IncidentStatusResolved) {
+		return
+	}
+
+	fingerprints, err := s.deps.Repositories.Incident.ListAlertFingerprints(incident.ID)
+	if err != nil {
+		s.deps.Logger.WithError(err).WithField("incident_id", incident.ID).Warn("Failed to list incident alerts")
+		return
+	}
+	alerts, err := s.deps.Repositories.Alert.ListByFingerprints(fingerprints)
+	if err != nil {
+		s.deps.Logger.WithError(err).WithField("incident_id", incident.ID).Warn("Failed to load incident alerts")
+		return
+	}
+	for _, alert := range alerts {
+		if alert.Status != string(models.AlertStatusResolved) {
+			return
+		}
+	}
+
+	now := time.Now()
+	incident.Status = string(models.IncidentStatusResolved)
+	incident.ResolvedAt = &now
+	if err := s.deps.Repositories.Incident.Update(incident); err != nil {
+		s.deps.Logger.WithError(err).WithField("incident_id", incident.ID).Error("Failed to resolve incident")
+		return
+	}
+	s.deps.Repositories.Incident.CreateNote(&models.IncidentNote{
+		IncidentID: incident.ID,
+		Kind:       incidentNoteStatus,
+		Author:     "system",
+		Content:    "All alerts resolved",
+	})
+}
+
+// incidentTitle names an incident after the alert that opened it
+func incidentTitle(alert *models.Alert) string {
+	if summary, ok := alert.Annotations["summary"].(string); ok && summary != "" {
+		return summary
+	}
+	name, _ := alert.Labels["alertname"].(string)
+	if name == "" {
+		name = alert.Fingerprint
+	}
+	if instance, ok := alert.Labels["instance"].(string); ok && instance != "" {
+		return fmt.Sprintf("%s on %s", name, instance)
+	}
+	return name
+}
@@ -2,158 +2,287 @@ package service
 
 import (
 	"context"
-	"crypto/md5"
+	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"alertbot/internal/engine"
+	"alertbot/internal/matcher"
 	"alertbot/internal/metrics"
 	"alertbot/internal/models"
+	"alertbot/internal/repository"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type alertService struct {
-	deps ServiceDependencies
+	deps     ServiceDependencies
+	pipeline *Pipeline
+	// grouping is set when the pipeline has the group stage
+	grouping bool
 }
 
 func NewAlertService(deps ServiceDependencies) AlertService {
-	return &alertService{deps: deps}
+	s := &alertService{deps: deps}
+
+	var stages []string
+	if deps.Config != nil {
+		stages = deps.Config.Pipeline.Stages
+	}
+	s.pipeline = NewPipeline(stages, s.ingestionStages(), deps.Logger)
+	for _, name := range s.pipeline.Stages() {
+		if name == StageGroup && deps.Repositories.AlertGroup != nil {
+			s.grouping = true
+		}
+	}
+	deps.Logger.WithField("stages", s.pipeline.Stages()).Info("Alert ingestion pipeline configured")
+	return s
 }
 
+// ReceiveAlerts ingests the alerts and returns an error when any of them
+// failed
 func (s *alertService) ReceiveAlerts(ctx context.Context, prometheusAlerts []models.PrometheusAlert) error {
+	results := s.IngestAlerts(ctx, prometheusAlerts)
+
+	failed := 0
+	var firstErr string
+	for _, result := range results {
+		if result.Action == "failed" {
+			if failed == 0 {
+				firstErr = result.Error
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d alerts failed: %s", failed, len(results), firstErr)
+	}
+	return nil
+}
+
+// IngestAlerts runs the alerts through the ingestion pipeline as one batch
+// and reports what happened to each
+func (s *alertService) IngestAlerts(ctx context.Context, prometheusAlerts []models.PrometheusAlert) []models.AlertIngestResult {
 	start := time.Now()
 	defer func() {
 		metrics.RecordAlertProcessingDuration("receive_alerts", time.Since(start).Seconds())
 	}()
 
-	for _, promAlert := range prometheusAlerts {
-		// Record alert received metric
-		status := "firing"
-		if !promAlert.EndsAt.IsZero() && promAlert.EndsAt.Before(time.Now()) {
-			status = "resolved"
-		}
-		severity := promAlert.Labels["severity"]
-		if severity == "" {
-			severity = "warning"
-		}
-		metrics.RecordAlertReceived(status, severity)
-		alert := s.convertPrometheusAlert(promAlert)
-		
-		// Process alert through deduplication engine
-		var dedupResult *engine.DeduplicationResult
-		if s.deps.DeduplicationEngine != nil {
-			var err error
-			dedupResult, err = s.deps.DeduplicationEngine.ProcessAlert(ctx, alert)
-			if err != nil {
-				s.deps.Logger.WithError(err).WithField("alert_fingerprint", alert.Fingerprint).Error("Failed to process alert deduplication")
-				// Continue without deduplication if there's an error
-			}
+	ingested := s.pipeline.Process(ctx, prometheusAlerts)
+
+	results := make([]models.AlertIngestResult, len(ingested))
+	for i, a := range ingested {
+		result := models.AlertIngestResult{Index: i, Fingerprint: a.Fingerprint, Action: a.Action}
+		if a.Alert != nil {
+			result.Status = a.Alert.Status
 		}
-		
-		// Handle deduplication result
-		if dedupResult != nil && dedupResult.IsDuplicate {
-			s.handleDuplicateAlert(ctx, alert, dedupResult)
-			continue
+		if a.Duplicate && a.Dedup != nil && a.Dedup.ExistingAlert != nil {
+			result.DuplicateOf = a.Dedup.ExistingAlert.Fingerprint
 		}
-		
-		// 检查是否已存在
-		existingAlert, err := s.deps.Repositories.Alert.GetByFingerprint(alert.Fingerprint)
-		if err == nil {
-			// 更新现有告警
-			existingAlert.Status = alert.Status
-			existingAlert.Annotations = alert.Annotations
-			existingAlert.EndsAt = alert.EndsAt
-			existingAlert.UpdatedAt = time.Now()
-			
-			if err := s.deps.Repositories.Alert.Update(existingAlert); err != nil {
-				s.deps.Logger.WithError(err).Error("Failed to update alert")
-				continue
-			}
-			
-			// 记录历史
-			history := &models.AlertHistory{
-				AlertFingerprint: alert.Fingerprint,
-				Action:          "updated",
-				Details:         models.JSONB{"status": alert.Status},
-			}
-			s.deps.Repositories.AlertHistory.Create(history)
-			
-			// Record processing metric
-			metrics.RecordAlertProcessed("updated", existingAlert.Status)
-			
-			// Apply routing rules for updated alerts
-			s.processAlertRouting(ctx, existingAlert)
-			
-			// Broadcast alert update via WebSocket
-			if s.deps.WebSocketHub != nil {
-				s.deps.WebSocketHub.BroadcastAlertUpdate(existingAlert, "updated")
-			}
-		} else {
-			// 创建新告警
-			if err := s.deps.Repositories.Alert.Create(alert); err != nil {
-				s.deps.Logger.WithError(err).Error("Failed to create alert")
-				continue
-			}
-			
-			// 记录历史
-			history := &models.AlertHistory{
-				AlertFingerprint: alert.Fingerprint,
-				Action:          "created",
-				Details:         models.JSONB{"status": alert.Status, "severity": alert.Severity},
-			}
-			s.deps.Repositories.AlertHistory.Create(history)
-			
-			// Record processing metric
-			metrics.RecordAlertProcessed("created", alert.Status)
-			
-			// Store deduplication metadata if available
-			if dedupResult != nil {
-				s.storeDeduplicationMetadata(alert, dedupResult)
-			}
-			
-			// Apply routing rules for new alerts
-			s.processAlertRouting(ctx, alert)
-			
-			// Broadcast new alert via WebSocket
-			if s.deps.WebSocketHub != nil {
-				s.deps.WebSocketHub.BroadcastAlertUpdate(alert, "created")
-			}
+		if a.Err != nil {
+			result.Action = "failed"
+			result.Stage = a.FailedStage
+			result.Error = a.Err.Error()
 		}
+		results[i] = result
 	}
-	
-	return nil
+	return results
 }
 
 func (s *alertService) GetAlert(ctx context.Context, fingerprint string) (*models.Alert, error) {
 	return s.deps.Repositories.Alert.GetByFingerprint(fingerprint)
 }
 
+// ListAlerts checks the label query and cursor before querying, so a
+// malformed request is reported as a validation error
 func (s *alertService) ListAlerts(ctx context.Context, filters models.AlertFilters) ([]models.Alert, int64, error) {
+	if err := validateAlertFilters(filters); err != nil {
+		return nil, 0, err
+	}
 	return s.deps.Repositories.Alert.List(filters)
 }
 
-func (s *alertService) SilenceAlert(ctx context.Context, fingerprint string, duration string, comment string) error {
+func validateAlertFilters(filters models.AlertFilters) error {
+	if filters.Query != "" {
+		if _, err := matcher.Parse(filters.Query); err != nil {
+			return &ValidationError{Field: "query", Message: fmt.Sprintf("Invalid label query: %v", err)}
+		}
+	}
+	if filters.Cursor != "" {
+		if !repository.SupportsCursor(filters.Sort) {
+			return &ValidationError{Field: "cursor", Message: fmt.Sprintf("Cursor pagination is not supported when sorting by %s", filters.Sort)}
+		}
+		if _, err := models.DecodeAlertCursor(filters.Cursor); err != nil {
+			return &ValidationError{Field: "cursor", Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// SilenceAlert creates a silence matching the alert's full label set for the
+// given duration. The alert is restored by ReleaseExpiredSilences once no
+// active silence covers it any more.
+func (s *alertService) SilenceAlert(ctx context.Context, fingerprint string, duration string, comment string, creator string) (*models.Silence, error) {
+	length, err := parseSilenceDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+
 	alert, err := s.deps.Repositories.Alert.GetByFingerprint(fingerprint)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	labels := matcher.FromLabels(matcher.LabelSet(alert.Labels))
+	if len(labels) == 0 {
+		return nil, &ValidationError{Field: "fingerprint", Message: "alert has no labels to silence on"}
+	}
+	matchers, err := matcher.Encode(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if creator == "" {
+		creator = "system"
+	}
+
+	now := time.Now()
+	silence := &models.Silence{
+		Matchers: models.JSONB(matchers),
+		StartsAt: now,
+		EndsAt:   now.Add(length),
+		Creator:  creator,
+		Comment:  comment,
+	}
+	// Remember the status to restore, unless an earlier silence already did
+	if alert.Status != string(models.AlertStatusSilenced) {
+		alert.SilencedStatus = alert.Status
 	}
-	
 	alert.Status = string(models.AlertStatusSilenced)
-	alert.UpdatedAt = time.Now()
-	
-	if err := s.deps.Repositories.Alert.Update(alert); err != nil {
-		return err
+	alert.UpdatedAt = now
+
+	err = s.deps.Repositories.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Silence.Create(silence); err != nil {
+			return fmt.Errorf("failed to create silence: %w", err)
+		}
+		if err := tx.Alert.Update(alert); err != nil {
+			return err
+		}
+		return tx.AlertHistory.Create(&models.AlertHistory{
+			AlertFingerprint: fingerprint,
+			Action:           "silenced",
+			Details: models.JSONB{
+				"duration":   duration,
+				"comment":    comment,
+				"creator":    creator,
+				"silence_id": silence.ID,
+				"ends_at":    silence.EndsAt,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.deps.WebSocketHub != nil {
+		s.deps.WebSocketHub.BroadcastAlertUpdate(alert, "silenced")
 	}
 	
-	// 记录历史
-	history := &models.AlertHistory{
-		AlertFingerprint: fingerprint,
-		Action:          "silenced",
-		Details:         models.JSONB{"duration": duration, "comment": comment},
+	return silence, nil
+}
+
+// ReleaseExpiredSilences restores silenced alerts that are no longer covered
+// by an active silence to the status they had before, firing for alerts
+// silenced on ingestion, and routes the ones still firing again. It returns
+// the number of alerts released.
+func (s *alertService) ReleaseExpiredSilences(ctx context.Context) (int, error) {
+	alerts, err := s.deps.Repositories.Alert.ListByStatus(string(models.AlertStatusSilenced))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list silenced alerts: %w", err)
 	}
-	return s.deps.Repositories.AlertHistory.Create(history)
+	if len(alerts) == 0 {
+		return 0, nil
+	}
+
+	silences, err := s.activeSilences()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load active silences: %w", err)
+	}
+
+	released := 0
+	now := time.Now()
+	for i := range alerts {
+		alert := &alerts[i]
+		if silenced, _ := matchSilence(silences, alert); silenced {
+			continue
+		}
+
+		status := alert.SilencedStatus
+		if status == "" {
+			status = string(models.AlertStatusFiring)
+		}
+		if alert.EndsAt != nil && !alert.EndsAt.After(now) {
+			status = string(models.AlertStatusResolved)
+		}
+		alert.Status = status
+		alert.SilencedStatus = ""
+		alert.UpdatedAt = now
+
+		err := s.deps.Repositories.Transaction(func(tx *repository.Repositories) error {
+			if err := tx.Alert.Update(alert); err != nil {
+				return err
+			}
+			return tx.AlertHistory.Create(&models.AlertHistory{
+				AlertFingerprint: alert.Fingerprint,
+				Action:           "unsilenced",
+				Details:          models.JSONB{"status": status},
+			})
+		})
+		if err != nil {
+			s.deps.Logger.WithError(err).WithField("alert_fingerprint", alert.Fingerprint).Error("Failed to release silenced alert")
+			continue
+		}
+		released++
+
+		if s.deps.WebSocketHub != nil {
+			s.deps.WebSocketHub.BroadcastAlertUpdate(alert, "unsilenced")
+		}
+
+		// Re-evaluate routing so a firing alert notifies again
+		if status == string(models.AlertStatusFiring) {
+			s.processAlertRouting(ctx, alert)
+		}
+	}
+
+	return released, nil
+}
+
+// parseSilenceDuration accepts Go durations plus a day suffix such as "2d"
+func parseSilenceDuration(duration string) (time.Duration, error) {
+	duration = strings.TrimSpace(duration)
+
+	var length time.Duration
+	if days, ok := strings.CutSuffix(duration, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, &ValidationError{Field: "duration", Message: fmt.Sprintf("invalid duration %q", duration)}
+		}
+		length = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return 0, &ValidationError{Field: "duration", Message: fmt.Sprintf("invalid duration %q", duration)}
+		}
+		length = d
+	}
+
+	if length <= 0 {
+		return 0, &ValidationError{Field: "duration", Message: "duration must be positive"}
+	}
+	return length, nil
 }
 
 func (s *alertService) AcknowledgeAlert(ctx context.Context, fingerprint string, comment string) error {
@@ -205,7 +334,12 @@ func (s *alertService) ResolveAlert(ctx context.Context, fingerprint string, com
 		s.deps.WebSocketHub.BroadcastAlertUpdate(alert, "resolved")
 	}
 	
-	return s.deps.Repositories.AlertHistory.Create(history)
+	if err := s.deps.Repositories.AlertHistory.Create(history); err != nil {
+		return err
+	}
+	
+	s.resolveIncidentIfDone(fingerprint)
+	return nil
 }
 
 func (s *alertService) convertPrometheusAlert(promAlert models.PrometheusAlert) *models.Alert {
@@ -231,18 +365,12 @@ func (s *alertService) convertPrometheusAlert(promAlert models.PrometheusAlert)
 		endsAt = &promAlert.EndsAt
 	}
 	
-	// 确定严重程度
-	severity := string(models.AlertSeverityWarning)
-	if sev, ok := promAlert.Labels["severity"]; ok {
-		severity = sev
-	}
-	
 	return &models.Alert{
 		Fingerprint: fingerprint,
 		Labels:      labels,
 		Annotations: annotations,
 		Status:      status,
-		Severity:    severity,
+		Severity:    alertSeverity(promAlert.Labels),
 		StartsAt:    promAlert.StartsAt,
 		EndsAt:      endsAt,
 		CreatedAt:   time.Now(),
@@ -250,25 +378,16 @@ func (s *alertService) convertPrometheusAlert(promAlert models.PrometheusAlert)
 	}
 }
 
-func (s *alertService) generateFingerprint(labels map[string]string) string {
-	// 排除特定的标签
-	excludeLabels := map[string]bool{
-		"__name__": true,
-		"job":      true,
+// alertSeverity reads the severity label, defaulting to warning
+func alertSeverity(labels map[string]string) string {
+	if severity, ok := labels["severity"]; ok {
+		return severity
 	}
-	
-	var pairs []string
-	for k, v := range labels {
-		if !excludeLabels[k] {
-			pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
-		}
-	}
-	
-	sort.Strings(pairs)
-	combined := strings.Join(pairs, ",")
-	
-	hash := md5.Sum([]byte(combined))
-	return fmt.Sprintf("%x", hash)[:16]
+	return string(models.AlertSeverityWarning)
+}
+
+func (s *alertService) generateFingerprint(labels map[string]string) string {
+	return s.deps.Fingerprinter.Fingerprint(labels)
 }
 
 // processAlertRouting applies routing rules to an alert
@@ -298,6 +417,16 @@ func (s *alertService) processAlertRouting(ctx context.Context, alert *models.Al
 		return
 	}
 	
+	// Check if alert is a symptom of an upstream alert in the dependency graph
+	if root := s.topologyRootCause(ctx, alert); root != nil {
+		s.deps.Logger.WithFields(logrus.Fields{
+			"alert_fingerprint":      alert.Fingerprint,
+			"root_cause_fingerprint": root.Alert.Fingerprint,
+			"root_cause_node":        root.Node,
+		}).Info("Alert is a topology symptom, skipping notification")
+		return
+	}
+	
 	// Find matching rules
 	matchedRules, err := s.deps.RuleEngine.MatchAlert(ctx, alert)
 	if err != nil {
@@ -364,6 +493,8 @@ func (s *alertService) sendRuleNotifications(ctx context.Context, alert *models.
 		return
 	}
 
+	tmpl := s.ruleTemplate(rule)
+
 	// Send notifications to each channel
 	for _, channelID := range channelIDs {
 		// Get channel configuration
@@ -378,12 +509,22 @@ func (s *alertService) sendRuleNotifications(ctx context.Context, alert *models.
 			continue
 		}
 
-		// Convert channel type to NotificationChannelType
-		channelType := models.NotificationChannelType(channel.Type)
+		// Another replica may have received the same alert and sent it already
+		claimKey := ""
+		if s.deps.Cluster != nil {
+			claimKey = fmt.Sprintf("%s:%d:%d:%s", alert.Fingerprint, rule.ID, channelID, alert.Status)
+			if !s.deps.Cluster.ClaimNotification(claimKey) {
+				s.deps.Logger.WithFields(logrus.Fields{
+					"fingerprint": alert.Fingerprint,
+					"channel_id":  channelID,
+				}).Debug("Notification claimed by another replica, skipping")
+				continue
+			}
+		}
 
 		// Send notification through the notification manager
 		start := time.Now()
-		err = s.deps.NotificationManager.SendAlertNotification(ctx, alert, channel.Config, channelType)
+		err = s.deps.NotificationManager.SendTemplatedAlertNotification(ctx, alert, channel, tmpl)
 		duration := time.Since(start).Seconds()
 		
 		if err != nil {
@@ -392,6 +533,11 @@ func (s *alertService) sendRuleNotifications(ctx context.Context, alert *models.
 				"channel_id":        channelID,
 				"channel_type":      channel.Type,
 			}).Error("Failed to send notification")
+
+			// Let a retry or another replica send it instead
+			if claimKey != "" {
+				s.deps.Cluster.ReleaseNotification(claimKey)
+			}
 			
 			// Record notification failure metric
 			metrics.RecordNotificationSent(channel.Type, "failed", duration)
@@ -410,34 +556,56 @@ func (s *alertService) sendRuleNotifications(ctx context.Context, alert *models.
 	// Notification implementation enabled
 }
 
-// BatchSilenceAlerts silences multiple alerts at once
-func (s *alertService) BatchSilenceAlerts(ctx context.Context, fingerprints []string, duration string, comment string) error {
+// ruleTemplate returns the notification template named in the rule's
+// receivers, or nil to use the default format
+func (s *alertService) ruleTemplate(rule models.RoutingRule) *models.NotificationTemplate {
+	name, _ := rule.Receivers["template"].(string)
+	if name == "" || s.deps.Repositories.NotificationTemplate == nil {
+		return nil
+	}
+	tmpl, err := s.deps.Repositories.NotificationTemplate.GetByName(name)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.deps.Logger.WithError(err).WithField("template", name).Warn("Failed to load notification template")
+		}
+		return nil
+	}
+	return tmpl
+}
+
+// BatchSilenceAlerts silences multiple alerts at once, creating one silence per alert
+func (s *alertService) BatchSilenceAlerts(ctx context.Context, fingerprints []string, duration string, comment string, creator string) ([]models.Silence, error) {
 	if len(fingerprints) == 0 {
-		return fmt.Errorf("no fingerprints provided")
+		return nil, fmt.Errorf("no fingerprints provided")
 	}
 
-	successCount := 0
+	// Reject a bad duration once instead of failing every alert
+	if _, err := parseSilenceDuration(duration); err != nil {
+		return nil, err
+	}
+
+	var silences []models.Silence
 	var lastError error
 
 	for _, fingerprint := range fingerprints {
-		err := s.SilenceAlert(ctx, fingerprint, duration, comment)
+		silence, err := s.SilenceAlert(ctx, fingerprint, duration, comment, creator)
 		if err != nil {
 			s.deps.Logger.WithError(err).WithField("fingerprint", fingerprint).Error("Failed to silence alert in batch operation")
 			lastError = err
 		} else {
-			successCount++
+			silences = append(silences, *silence)
 		}
 	}
 
-	if successCount == 0 {
-		return fmt.Errorf("failed to silence any alerts: %v", lastError)
+	if len(silences) == 0 {
+		return nil, fmt.Errorf("failed to silence any alerts: %v", lastError)
 	}
 
 	if lastError != nil {
-		s.deps.Logger.Warnf("Batch silence completed with errors: %d/%d succeeded", successCount, len(fingerprints))
+		s.deps.Logger.Warnf("Batch silence completed with errors: %d/%d succeeded", len(silences), len(fingerprints))
 	}
 
-	return nil
+	return silences, nil
 }
 
 // BatchAcknowledgeAlerts acknowledges multiple alerts at once
@@ -514,152 +682,106 @@ func (s *alertService) ListAlertHistory(ctx context.Context, filters models.Aler
 	return s.deps.Repositories.AlertHistory.List(filters)
 }
 
-// handleDuplicateAlert processes duplicate alerts based on deduplication result
-func (s *alertService) handleDuplicateAlert(ctx context.Context, alert *models.Alert, dedupResult *engine.DeduplicationResult) {
+// mergeDuplicate applies a duplicate alert to the alert it duplicates based
+// on the deduplication result. It reports whether the existing alert
+// changed; the store stage writes the change and the queued history.
+func (s *alertService) mergeDuplicate(a *IngestAlert, dedupResult *engine.DeduplicationResult) bool {
 	if dedupResult.ExistingAlert == nil {
-		return
+		return false
 	}
 
+	alert := a.Alert
 	existingAlert := dedupResult.ExistingAlert
 	action := dedupResult.Action
 
 	s.deps.Logger.WithFields(logrus.Fields{
 		"alert_fingerprint":    alert.Fingerprint,
 		"existing_fingerprint": existingAlert.Fingerprint,
-		"action":              action,
+		"action":               action,
 	}).Info("Processing duplicate alert")
 
+	// Keep the merged alert so deduplication dry runs can replay it
+	incoming := duplicateSample(alert)
+
 	switch action {
 	case "update_severity":
-		if s.shouldUpdateSeverity(alert, existingAlert) {
-			existingAlert.Severity = alert.Severity
-			existingAlert.UpdatedAt = time.Now()
-			s.deps.Repositories.Alert.Update(existingAlert)
-			
-			// Record severity update
-			s.recordAlertHistory(existingAlert.Fingerprint, "severity_updated", 
-				models.JSONB{"old_severity": existingAlert.Severity, "new_severity": alert.Severity})
+		if !s.shouldUpdateSeverity(alert, existingAlert) {
+			return false
 		}
+		a.addHistory(existingAlert.Fingerprint, "severity_updated",
+			models.JSONB{"old_severity": existingAlert.Severity, "new_severity": alert.Severity, "incoming": incoming})
+		existingAlert.Severity = alert.Severity
+		existingAlert.UpdatedAt = time.Now()
+		return true
 
 	case "update_status":
-		if existingAlert.Status != alert.Status {
-			existingAlert.Status = alert.Status
-			existingAlert.UpdatedAt = time.Now()
-			s.deps.Repositories.Alert.Update(existingAlert)
-			
-			// Record status update
-			s.recordAlertHistory(existingAlert.Fingerprint, "status_updated",
-				models.JSONB{"old_status": existingAlert.Status, "new_status": alert.Status})
+		if existingAlert.Status == alert.Status {
+			return false
 		}
+		a.addHistory(existingAlert.Fingerprint, "status_updated",
+			models.JSONB{"old_status": existingAlert.Status, "new_status": alert.Status, "incoming": incoming})
+		existingAlert.Status = alert.Status
+		existingAlert.UpdatedAt = time.Now()
+		return true
 
 	case "refresh":
 		existingAlert.UpdatedAt = time.Now()
 		existingAlert.Annotations = alert.Annotations // Update annotations
-		s.deps.Repositories.Alert.Update(existingAlert)
-		
-		// Record refresh
-		s.recordAlertHistory(existingAlert.Fingerprint, "refreshed", models.JSONB{})
+		a.addHistory(existingAlert.Fingerprint, "refreshed", models.JSONB{"incoming": incoming})
+		return true
 
-	case "ignore":
 	default:
 		// Just record that we saw a duplicate
-		s.recordAlertHistory(existingAlert.Fingerprint, "duplicate_ignored", 
-			models.JSONB{"deduplication_key": dedupResult.DeduplicationKey})
-	}
-
-	// Update metrics
-	metrics.RecordAlertProcessed("deduplicated", existingAlert.Status)
-	
-	// Broadcast update if action was taken
-	if action != "ignore" && s.deps.WebSocketHub != nil {
-		s.deps.WebSocketHub.BroadcastAlertUpdate(existingAlert, "deduplicated")
+		a.addHistory(existingAlert.Fingerprint, "duplicate_ignored",
+			models.JSONB{"deduplication_key": dedupResult.DeduplicationKey, "incoming": incoming})
+		return false
 	}
 }
 
 // isAlertSilenced checks if an alert matches any active silence rules
 func (s *alertService) isAlertSilenced(ctx context.Context, alert *models.Alert) (bool, uint) {
-	// Get all active silences
-	silences, err := s.deps.Repositories.Silence.List()
+	silences, err := s.activeSilences()
 	if err != nil {
 		s.deps.Logger.WithError(err).Error("Failed to get silences")
 		return false, 0
 	}
-	
-	now := time.Now()
-	
+	return matchSilence(silences, alert)
+}
+
+// compiledSilence is an active silence with its matchers parsed
+type compiledSilence struct {
+	id       uint
+	matchers matcher.Matchers
+}
+
+// activeSilences loads and compiles the silences in effect now.
+// Silences with unparseable matchers are skipped so they never match everything.
+func (s *alertService) activeSilences() ([]compiledSilence, error) {
+	silences, err := s.deps.Repositories.Silence.GetActiveSilences()
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := make([]compiledSilence, 0, len(silences))
 	for _, silence := range silences {
-		// Check if silence is currently active
-		if now.Before(silence.StartsAt) || now.After(silence.EndsAt) {
+		ms, err := matcher.Decode(silence.Matchers)
+		if err != nil || len(ms) == 0 {
+			s.deps.Logger.WithError(err).WithField("silence_id", silence.ID).Warn("Skipping silence with invalid matchers")
 			continue
 		}
-		
-		// Check if alert matches silence matchers
-		if s.alertMatchesSilence(alert, silence) {
-			return true, silence.ID
-		}
+		compiled = append(compiled, compiledSilence{id: silence.ID, matchers: ms})
 	}
-	
-	return false, 0
+	return compiled, nil
 }
 
-// alertMatchesSilence checks if an alert matches a silence rule
-func (s *alertService) alertMatchesSilence(alert *models.Alert, silence models.Silence) bool {
-	// Parse matchers from silence
-	matchersData, ok := silence.Matchers["matchers"]
-	if !ok {
-		return false
-	}
-	
-	matchers, ok := matchersData.([]interface{})
-	if !ok {
-		return false
-	}
-	
-	// All matchers must match for the silence to apply
-	for _, matcherInterface := range matchers {
-		matcher, ok := matcherInterface.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		
-		name, nameOk := matcher["name"].(string)
-		value, valueOk := matcher["value"].(string)
-		isRegex, _ := matcher["is_regex"].(bool)
-		
-		if !nameOk || !valueOk {
-			continue
-		}
-		
-		// Get the label value from the alert
-		alertLabelInterface, exists := alert.Labels[name]
-		if !exists {
-			return false // If the label doesn't exist, the matcher doesn't match
-		}
-		
-		// Convert interface{} to string
-		alertLabelValue, ok := alertLabelInterface.(string)
-		if !ok {
-			// Try to convert to string using fmt.Sprint
-			alertLabelValue = fmt.Sprint(alertLabelInterface)
-		}
-		
-		// Check if the value matches
-		if isRegex {
-			// TODO: Implement regex matching
-			// For now, we'll do a simple contains check
-			if !strings.Contains(alertLabelValue, value) {
-				return false
-			}
-		} else {
-			// Exact match
-			if alertLabelValue != value {
-				return false
-			}
+// matchSilence returns the first silence whose matchers all match the alert
+func matchSilence(silences []compiledSilence, alert *models.Alert) (bool, uint) {
+	for _, silence := range silences {
+		if silence.matchers.MatchesJSONB(alert.Labels) {
+			return true, silence.id
 		}
 	}
-	
-	// All matchers matched
-	return true
+	return false, 0
 }
 
 // isAlertInhibited checks if an alert matches any active inhibition rules
@@ -670,7 +792,12 @@ func (s *alertService) isAlertInhibited(ctx context.Context, alert *models.Alert
 		s.deps.Logger.WithError(err).Error("Failed to get inhibitions")
 		return false, 0
 	}
-	
+	return s.matchInhibition(ctx, inhibitions, alert)
+}
+
+// matchInhibition returns the first enabled inhibition rule that targets
+// the alert and has an active source alert
+func (s *alertService) matchInhibition(ctx context.Context, inhibitions []models.InhibitionRule, alert *models.Alert) (bool, uint) {
 	for _, inhibition := range inhibitions {
 		if !inhibition.Enabled {
 			continue
@@ -697,6 +824,24 @@ func (s *alertService) isAlertInhibited(ctx context.Context, alert *models.Alert
 	return false, 0
 }
 
+// topologyRootCause returns the root cause of the alert when symptom
+// suppression is enabled and an upstream alert is active
+func (s *alertService) topologyRootCause(ctx context.Context, alert *models.Alert) *models.RootCauseCandidate {
+	if s.deps.TopologyEngine == nil {
+		return nil
+	}
+	if topology := s.deps.TopologyEngine.Current(); topology.Empty() || !topology.SuppressSymptoms {
+		return nil
+	}
+
+	analysis, err := s.deps.TopologyEngine.Analyze(ctx, alert)
+	if err != nil {
+		s.deps.Logger.WithError(err).WithField("alert_fingerprint", alert.Fingerprint).Warn("Failed to analyze alert topology")
+		return nil
+	}
+	return analysis.RootCause
+}
+
 // alertMatchesInhibitionTarget checks if an alert matches inhibition target matchers
 func (s *alertService) alertMatchesInhibitionTarget(alert *models.Alert, inhibition models.InhibitionRule) bool {
 	// Parse target matchers
@@ -866,8 +1011,9 @@ func (s *alertService) findInhibitionSourceAlerts(ctx context.Context, inhibitio
 	return sourceAlerts, nil
 }
 
-// storeDeduplicationMetadata stores deduplication information in alert history
-func (s *alertService) storeDeduplicationMetadata(alert *models.Alert, dedupResult *engine.DeduplicationResult) {
+// deduplicationMetadata returns the deduplication information recorded in
+// the history of a new alert
+func deduplicationMetadata(dedupResult *engine.DeduplicationResult) models.JSONB {
 	details := models.JSONB{
 		"deduplication_key": dedupResult.DeduplicationKey,
 		"correlation_key":   dedupResult.CorrelationKey,
@@ -882,7 +1028,7 @@ func (s *alertService) storeDeduplicationMetadata(alert *models.Alert, dedupResu
 		details["related_fingerprints"] = relatedFingerprints
 	}
 
-	s.recordAlertHistory(alert.Fingerprint, "deduplication_processed", details)
+	return details
 }
 
 // shouldUpdateSeverity determines if severity should be updated
@@ -899,19 +1045,6 @@ func (s *alertService) shouldUpdateSeverity(newAlert, existingAlert *models.Aler
 	return newLevel > existingLevel
 }
 
-// recordAlertHistory creates an alert history entry
-func (s *alertService) recordAlertHistory(fingerprint, action string, details models.JSONB) {
-	history := &models.AlertHistory{
-		AlertFingerprint: fingerprint,
-		Action:          action,
-		Details:         details,
-	}
-	
-	if err := s.deps.Repositories.AlertHistory.Create(history); err != nil {
-		s.deps.Logger.WithError(err).WithField("fingerprint", fingerprint).Error("Failed to record alert history")
-	}
-}
-
 // GetAlertRelations returns alert relationships and deduplication information
 func (s *alertService) GetAlertRelations(ctx context.Context, fingerprint string) (*models.AlertRelations, error) {
 	alert, err := s.deps.Repositories.Alert.GetByFingerprint(fingerprint)
@@ -982,22 +1115,20 @@ func (s *alertService) findDuplicatesOf(ctx context.Context, fingerprint string)
 	return duplicates, nil
 }
 
-// UpdateDeduplicationConfig updates the deduplication engine configuration
+// UpdateDeduplicationConfig validates and stores the deduplication configuration
+// and applies it to the local engine; other replicas pick it up on their next refresh
 func (s *alertService) UpdateDeduplicationConfig(ctx context.Context, config models.DeduplicationConfig) error {
 	if s.deps.DeduplicationEngine == nil {
 		return fmt.Errorf("deduplication engine not available")
 	}
 
-	// Convert models.DeduplicationConfig to engine.DeduplicationConfig
-	engineConfig := engine.DeduplicationConfig{
-		DeduplicationWindow:     config.DeduplicationWindow,
-		IgnoreLabels:           config.IgnoreLabels,
-		CorrelationLabels:      config.CorrelationLabels,
-		CorrelationWindow:      config.CorrelationWindow,
-		MaxRelatedAlerts:       config.MaxRelatedAlerts,
-		EnableTimeBasedDedup:   config.EnableTimeBasedDedup,
-		EnableContentBasedDedup: config.EnableContentBasedDedup,
-		EnableCorrelation:      config.EnableCorrelation,
+	engineConfig, err := validateDeduplicationConfig(&config)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deps.Repositories.Settings.UpdateDeduplicationConfig(&config); err != nil {
+		return fmt.Errorf("failed to store deduplication configuration: %w", err)
 	}
 
 	s.deps.DeduplicationEngine.UpdateDeduplicationConfig(engineConfig)
@@ -1006,7 +1137,176 @@ func (s *alertService) UpdateDeduplicationConfig(ctx context.Context, config mod
 		"deduplication_window": config.DeduplicationWindow,
 		"correlation_window":   config.CorrelationWindow,
 		"max_related_alerts":   config.MaxRelatedAlerts,
+		"overrides":            len(config.Overrides),
 	}).Info("Deduplication configuration updated")
 
 	return nil
-}
\ No newline at end of file
+}
+
+// GetDeduplicationConfig returns the stored deduplication configuration
+func (s *alertService) GetDeduplicationConfig(ctx context.Context) (*models.DeduplicationConfig, error) {
+	return s.deps.Repositories.Settings.GetDeduplicationConfig()
+}
+
+// Limits for deduplication dry runs
+const (
+	defaultDryRunHours = 24
+	maxDryRunHours     = 168
+	maxDryRunSamples   = 5000
+)
+
+// duplicateHistoryActions are the history actions recorded for merged duplicates
+var duplicateHistoryActions = []string{"duplicate_ignored", "refreshed", "severity_updated", "status_updated"}
+
+// DryRunDeduplication replays the alerts of the last N hours, including
+// duplicates that were merged on ingestion, against the current and the
+// proposed configuration and reports the difference
+func (s *alertService) DryRunDeduplication(ctx context.Context, req models.DeduplicationDryRunRequest) (*models.DeduplicationDryRunResult, error) {
+	if s.deps.DeduplicationEngine == nil {
+		return nil, fmt.Errorf("deduplication engine not available")
+	}
+
+	hours := req.Hours
+	if hours == 0 {
+		hours = defaultDryRunHours
+	}
+	if hours < 1 || hours > maxDryRunHours {
+		return nil, &ValidationError{Field: "hours", Message: fmt.Sprintf("Hours must be between 1 and %d", maxDryRunHours)}
+	}
+
+	proposed, err := validateDeduplicationConfig(&req.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	samples, err := s.deduplicationSamples(since)
+	if err != nil {
+		return nil, err
+	}
+
+	dedup := s.deps.DeduplicationEngine
+	result := &models.DeduplicationDryRunResult{
+		Hours:      hours,
+		SampleSize: len(samples),
+		Current:    dedup.Replay(samples, dedup.Config()),
+		Proposed:   dedup.Replay(samples, proposed),
+	}
+
+	current := replayDuplicates(result.Current)
+	next := replayDuplicates(result.Proposed)
+	result.NewlyDeduplicated = setDifference(next, current)
+	result.NoLongerDeduplicated = setDifference(current, next)
+
+	return result, nil
+}
+
+// deduplicationSamples loads stored alerts and merged duplicates seen since the given time
+func (s *alertService) deduplicationSamples(since time.Time) ([]*models.Alert, error) {
+	alerts, err := s.deps.Repositories.Alert.ListStartedSince(since, maxDryRunSamples)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alerts: %w", err)
+	}
+
+	samples := make([]*models.Alert, 0, len(alerts))
+	for i := range alerts {
+		samples = append(samples, &alerts[i])
+	}
+
+	histories, err := s.deps.Repositories.AlertHistory.ListByActionsSince(duplicateHistoryActions, since, maxDryRunSamples)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load duplicate history: %w", err)
+	}
+	for _, history := range histories {
+		if alert := alertFromDuplicateSample(history.Details["incoming"]); alert != nil {
+			samples = append(samples, alert)
+		}
+	}
+
+	return samples, nil
+}
+
+// validateDeduplicationConfig checks the settings and compiles them for the engine
+func validateDeduplicationConfig(config *models.DeduplicationConfig) (engine.DeduplicationConfig, error) {
+	if config.DeduplicationWindow <= 0 {
+		return engine.DeduplicationConfig{}, &ValidationError{Field: "deduplication_window", Message: "Deduplication window must be greater than 0"}
+	}
+	if config.CorrelationWindow <= 0 {
+		return engine.DeduplicationConfig{}, &ValidationError{Field: "correlation_window", Message: "Correlation window must be greater than 0"}
+	}
+	if config.MaxRelatedAlerts < 1 {
+		return engine.DeduplicationConfig{}, &ValidationError{Field: "max_related_alerts", Message: "Max related alerts must be at least 1"}
+	}
+
+	compiled, err := engine.NewDeduplicationConfig(config)
+	if err != nil {
+		return engine.DeduplicationConfig{}, &ValidationError{Field: "overrides", Message: err.Error()}
+	}
+	return compiled, nil
+}
+
+// duplicateSample captures the fields of a merged alert needed to replay it
+func duplicateSample(alert *models.Alert) models.JSONB {
+	return models.JSONB{
+		"fingerprint": alert.Fingerprint,
+		"labels":      alert.Labels,
+		"annotations": alert.Annotations,
+		"severity":    alert.Severity,
+		"status":      alert.Status,
+		"starts_at":   alert.StartsAt.Format(time.RFC3339Nano),
+	}
+}
+
+// alertFromDuplicateSample rebuilds an alert stored by duplicateSample
+func alertFromDuplicateSample(value interface{}) *models.Alert {
+	sample, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	fingerprint, _ := sample["fingerprint"].(string)
+	labels, _ := sample["labels"].(map[string]interface{})
+	if fingerprint == "" || labels == nil {
+		return nil
+	}
+	annotations, _ := sample["annotations"].(map[string]interface{})
+	severity, _ := sample["severity"].(string)
+	status, _ := sample["status"].(string)
+	startsAtStr, _ := sample["starts_at"].(string)
+	startsAt, err := time.Parse(time.RFC3339Nano, startsAtStr)
+	if err != nil {
+		return nil
+	}
+
+	return &models.Alert{
+		Fingerprint: fingerprint,
+		Labels:      models.JSONB(labels),
+		Annotations: models.JSONB(annotations),
+		Severity:    severity,
+		Status:      status,
+		StartsAt:    startsAt,
+	}
+}
+
+// replayDuplicates returns the fingerprints merged into other alerts during a replay
+func replayDuplicates(replay models.DeduplicationReplay) map[string]bool {
+	set := make(map[string]bool, replay.Duplicates)
+	for _, group := range replay.Groups {
+		for _, fingerprint := range group.Duplicates {
+			set[fingerprint] = true
+		}
+	}
+	return set
+}
+
+// setDifference returns the sorted keys of a that are not in b
+func setDifference(a, b map[string]bool) []string {
+	diff := []string{}
+	for key := range a {
+		if !b[key] {
+			diff = append(diff, key)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
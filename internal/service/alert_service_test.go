@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/engine"
+	"alertbot/internal/fingerprint"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestAlertService runs the default pipeline against a freshly migrated
+// SQLite database, without a rule engine so nothing is notified
+func newTestAlertService(t *testing.T) (AlertService, *repository.Repositories, *gorm.DB) {
+	db, log := newTestDatabase(t)
+	repos := repository.NewRepositories(db)
+	fingerprinter := fingerprint.Default()
+	dedup := engine.NewDeduplicationEngine(repos, log)
+	dedup.SetFingerprinter(fingerprinter)
+
+	alerts := NewAlertService(ServiceDependencies{
+		Repositories:        repos,
+		Logger:              log,
+		Config:              &config.Config{},
+		DeduplicationEngine: dedup,
+		Fingerprinter:       fingerprinter,
+	})
+	return alerts, repos, db
+}
+
+// createTestAlert stores an alert for instance, returning its fingerprint
+func createTestAlert(t *testing.T, repos *repository.Repositories, instance, status string) string {
+	labels := map[string]string{"alertname": "DiskFull", "instance": instance}
+	fp := fingerprint.Default().Fingerprint(labels)
+	require.NoError(t, repos.Alert.Create(&models.Alert{
+		Fingerprint: fp,
+		Labels:      models.JSONB{"alertname": "DiskFull", "instance": instance},
+		Status:      status,
+		Severity:    "warning",
+		StartsAt:    time.Now().Add(-time.Hour),
+	}))
+	return fp
+}
+
+// expireSilences ends every silence so the next release lifts them
+func expireSilences(t *testing.T, repos *repository.Repositories) {
+	silences, err := repos.Silence.List()
+	require.NoError(t, err)
+	for i := range silences {
+		silences[i].EndsAt = time.Now().Add(-time.Second)
+		require.NoError(t, repos.Silence.Update(&silences[i]))
+	}
+}
+
+func TestReleaseRestoresStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		silences int
+		ended    bool
+		want     string
+	}{
+		{"firing", string(models.AlertStatusFiring), 1, false, string(models.AlertStatusFiring)},
+		{"acknowledged", string(models.AlertStatusAcknowledged), 1, false, string(models.AlertStatusAcknowledged)},
+		{"silenced twice", string(models.AlertStatusAcknowledged), 2, false, string(models.AlertStatusAcknowledged)},
+		{"ended while silenced", string(models.AlertStatusAcknowledged), 1, true, string(models.AlertStatusResolved)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts, repos, _ := newTestAlertService(t)
+			ctx := context.Background()
+			fp := createTestAlert(t, repos, "a1", tt.status)
+
+			for i := 0; i < tt.silences; i++ {
+				_, err := alerts.SilenceAlert(ctx, fp, "1h", "maintenance", "admin")
+				require.NoError(t, err)
+			}
+			alert, err := repos.Alert.GetByFingerprint(fp)
+			require.NoError(t, err)
+			assert.Equal(t, string(models.AlertStatusSilenced), alert.Status)
+			assert.Equal(t, tt.status, alert.SilencedStatus)
+
+			// Nothing is released while a silence is active
+			released, err := alerts.ReleaseExpiredSilences(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, released)
+
+			if tt.ended {
+				endsAt := time.Now().Add(-time.Minute)
+				alert.EndsAt = &endsAt
+				require.NoError(t, repos.Alert.Update(alert))
+			}
+			expireSilences(t, repos)
+			released, err = alerts.ReleaseExpiredSilences(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 1, released)
+
+			alert, err = repos.Alert.GetByFingerprint(fp)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, alert.Status)
+			assert.Empty(t, alert.SilencedStatus)
+		})
+	}
+}
+
+func TestReleaseIngestedSilence(t *testing.T) {
+	alerts, repos, _ := newTestAlertService(t)
+	ctx := context.Background()
+
+	// An acknowledged alert silenced by hand and then resolved by Prometheus
+	// no longer has a status to restore
+	fp := createTestAlert(t, repos, "a1", string(models.AlertStatusAcknowledged))
+	_, err := alerts.SilenceAlert(ctx, fp, "1h", "", "admin")
+	require.NoError(t, err)
+	expireSilences(t, repos)
+
+	labels := map[string]string{"alertname": "DiskFull", "instance": "a1"}
+	startsAt := time.Now().Add(-time.Hour)
+	results := alerts.IngestAlerts(ctx, []models.PrometheusAlert{{Labels: labels, StartsAt: startsAt, EndsAt: time.Now()}})
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Error)
+	stored, err := repos.Alert.GetByFingerprint(fp)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.AlertStatusResolved), stored.Status)
+	assert.Empty(t, stored.SilencedStatus)
+
+	// Silenced on ingestion, the alert is released to firing
+	require.NoError(t, repos.Silence.Create(&models.Silence{
+		Matchers: models.JSONB{"matchers": []interface{}{map[string]interface{}{"name": "instance", "value": "a1", "is_regex": false}}},
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   time.Now().Add(time.Hour),
+		Creator:  "admin",
+	}))
+	results = alerts.IngestAlerts(ctx, []models.PrometheusAlert{{Labels: labels, StartsAt: startsAt}})
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Error)
+	stored, err = repos.Alert.GetByFingerprint(fp)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.AlertStatusSilenced), stored.Status)
+
+	expireSilences(t, repos)
+	released, err := alerts.ReleaseExpiredSilences(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, released)
+	stored, err = repos.Alert.GetByFingerprint(fp)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.AlertStatusFiring), stored.Status)
+}
+
+func TestSilenceAlertIsAtomic(t *testing.T) {
+	alerts, repos, db := newTestAlertService(t)
+	fp := createTestAlert(t, repos, "a1", string(models.AlertStatusFiring))
+
+	// Fail the alert update after the silence is created
+	require.NoError(t, db.Exec(`CREATE TRIGGER alerts_read_only BEFORE UPDATE ON alerts
+		BEGIN SELECT RAISE(ABORT, 'alerts are read only'); END`).Error)
+
+	_, err := alerts.SilenceAlert(context.Background(), fp, "1h", "", "admin")
+	require.Error(t, err)
+
+	silences, err := repos.Silence.List()
+	require.NoError(t, err)
+	assert.Empty(t, silences)
+	history, err := repos.AlertHistory.GetByFingerprint(fp)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	alert, err := repos.Alert.GetByFingerprint(fp)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.AlertStatusFiring), alert.Status)
+}
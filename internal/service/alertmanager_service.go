@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"alertbot/internal/alertmanager"
+	"alertbot/internal/engine"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Object kinds reported in import changes
+const (
+	configKindChannel     = "notification_channel"
+	configKindRoutingRule = "routing_rule"
+	configKindInhibition  = "inhibition_rule"
+	configKindGroupRule   = "alert_group_rule"
+)
+
+type AlertmanagerService interface {
+	// Import translates an alertmanager.yml into alertbot objects. Objects
+	// are matched to existing ones by name; with dryRun nothing is written.
+	Import(ctx context.Context, data []byte, dryRun bool) (*models.ImportReport, error)
+	// Export renders the current configuration as an alertmanager.yml
+	Export(ctx context.Context) ([]byte, []models.ConfigIssue, error)
+}
+
+type alertmanagerService struct {
+	repos      *repository.Repositories
+	ruleEngine *engine.RuleEngine
+	logger     *logrus.Logger
+}
+
+func NewAlertmanagerService(repos *repository.Repositories, ruleEngine *engine.RuleEngine, logger *logrus.Logger) AlertmanagerService {
+	return &alertmanagerService{
+		repos:      repos,
+		ruleEngine: ruleEngine,
+		logger:     logger,
+	}
+}
+
+func (s *alertmanagerService) Import(ctx context.Context, data []byte, dryRun bool) (*models.ImportReport, error) {
+	cfg, err := alertmanager.Parse(data)
+	if err != nil {
+		return nil, &ValidationError{Field: "config", Message: err.Error()}
+	}
+	result := alertmanager.Import(cfg)
+
+	report := &models.ImportReport{
+		DryRun:      dryRun,
+		Changes:     []models.ConfigChange{},
+		Unsupported: result.Unsupported,
+	}
+	if report.Unsupported == nil {
+		report.Unsupported = []models.ConfigIssue{}
+	}
+
+	if dryRun {
+		if err := applyImport(s.repos, result, report, false); err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+
+	err = s.repos.Transaction(func(tx *repository.Repositories) error {
+		return applyImport(tx, result, report, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ruleEngine != nil {
+		if err := s.ruleEngine.RefreshRules(); err != nil {
+			s.logger.WithError(err).Warn("Failed to reload routing rules after import")
+		}
+	}
+	s.logger.WithField("changes", len(report.Changes)).Info("Imported Alertmanager configuration")
+	return report, nil
+}
+
+// applyImport diffs the translated objects against repos by name and, when
+// write is set, stores them. Channels go first so rules can refer to their IDs.
+func applyImport(repos *repository.Repositories, result *alertmanager.Result, report *models.ImportReport, write bool) error {
+	channels, err := repos.NotificationChannel.List()
+	if err != nil {
+		return fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	channelIDs := make(map[string]uint, len(channels))
+	existingChannels := make(map[string]*models.NotificationChannel, len(channels))
+	for i := range channels {
+		if _, ok := existingChannels[channels[i].Name]; !ok {
+			existingChannels[channels[i].Name] = &channels[i]
+			channelIDs[channels[i].Name] = channels[i].ID
+		}
+	}
+
+	for i := range result.Channels {
+		channel := &result.Channels[i]
+		existing := existingChannels[channel.Name]
+		change := diffObject(configKindChannel, channel.Name, existing, channel, func() {
+			channel.ID, channel.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.NotificationChannel.Create(channel) }, func() error { return repos.NotificationChannel.Update(channel) }); err != nil {
+				return err
+			}
+		}
+		channelIDs[channel.Name] = channel.ID
+	}
+
+	rules, err := repos.RoutingRule.List()
+	if err != nil {
+		return fmt.Errorf("failed to list routing rules: %w", err)
+	}
+	existingRules := make(map[string]*models.RoutingRule, len(rules))
+	for i := range rules {
+		if _, ok := existingRules[rules[i].Name]; !ok {
+			existingRules[rules[i].Name] = &rules[i]
+		}
+	}
+	for i := range result.RoutingRules {
+		imported := &result.RoutingRules[i]
+		rule := &imported.Rule
+		ids := make([]interface{}, 0, len(imported.Channels))
+		for _, name := range imported.Channels {
+			ids = append(ids, channelIDs[name]) // 0 for channels a dry run would create
+		}
+		rule.Receivers = models.JSONB{"channels": ids, "receiver": imported.Receiver}
+
+		existing := existingRules[rule.Name]
+		change := diffObject(configKindRoutingRule, rule.Name, existing, rule, func() {
+			rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.RoutingRule.Create(rule) }, func() error { return repos.RoutingRule.Update(rule) }); err != nil {
+				return err
+			}
+		}
+	}
+
+	inhibitions, err := repos.Inhibition.List()
+	if err != nil {
+		return fmt.Errorf("failed to list inhibition rules: %w", err)
+	}
+	existingInhibitions := make(map[string]*models.InhibitionRule, len(inhibitions))
+	for i := range inhibitions {
+		if _, ok := existingInhibitions[inhibitions[i].Name]; !ok {
+			existingInhibitions[inhibitions[i].Name] = &inhibitions[i]
+		}
+	}
+	for i := range result.InhibitionRules {
+		rule := &result.InhibitionRules[i]
+		existing := existingInhibitions[rule.Name]
+		change := diffObject(configKindInhibition, rule.Name, existing, rule, func() {
+			rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.Inhibition.Create(rule) }, func() error { return repos.Inhibition.Update(rule) }); err != nil {
+				return err
+			}
+		}
+	}
+
+	ctx := context.Background()
+	groupRules, err := repos.AlertGroup.ListAlertGroupRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list alert group rules: %w", err)
+	}
+	existingGroupRules := make(map[string]*models.AlertGroupRule, len(groupRules))
+	for _, rule := range groupRules {
+		if _, ok := existingGroupRules[rule.Name]; !ok {
+			existingGroupRules[rule.Name] = rule
+		}
+	}
+	for i := range result.GroupRules {
+		rule := &result.GroupRules[i]
+		existing := existingGroupRules[rule.Name]
+		change := diffObject(configKindGroupRule, rule.Name, existing, rule, func() {
+			rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.AlertGroup.CreateAlertGroupRule(ctx, rule) }, func() error { return repos.AlertGroup.UpdateAlertGroupRule(ctx, rule) }); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffObject compares desired with existing (a nil pointer when there is
+// none). For updates, adopt copies the existing identity onto desired first.
+func diffObject(kind, name string, existing, desired interface{}, adopt func()) models.ConfigChange {
+	change := models.ConfigChange{Kind: kind, Name: name, Action: models.ConfigChangeCreate}
+	if reflect.ValueOf(existing).IsNil() {
+		return change
+	}
+	adopt()
+
+	change.Fields = changedFields(existing, desired)
+	change.Action = models.ConfigChangeUpdate
+	if len(change.Fields) == 0 {
+		change.Action = models.ConfigChangeUnchanged
+	}
+	return change
+}
+
+// changedFields lists the JSON fields that differ between two objects,
+// ignoring identity and timestamps
+func changedFields(a, b interface{}) []string {
+	am, bm := jsonFields(a), jsonFields(b)
+	var fields []string
+	for key, value := range bm {
+		switch key {
+		case "id", "created_at", "updated_at":
+			continue
+		}
+		if !reflect.DeepEqual(am[key], value) {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func jsonFields(v interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+func saveChange(change models.ConfigChange, create, update func() error) error {
+	var err error
+	switch change.Action {
+	case models.ConfigChangeCreate:
+		err = create()
+	case models.ConfigChangeUpdate:
+		err = update()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s %s %q: %w", change.Action, change.Kind, change.Name, err)
+	}
+	return nil
+}
+
+func (s *alertmanagerService) Export(ctx context.Context) ([]byte, []models.ConfigIssue, error) {
+	channels, err := s.repos.NotificationChannel.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	rules, err := s.repos.RoutingRule.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list routing rules: %w", err)
+	}
+	inhibitions, err := s.repos.Inhibition.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list inhibition rules: %w", err)
+	}
+	groupRules, err := s.repos.AlertGroup.ListAlertGroupRules(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list alert group rules: %w", err)
+	}
+	groups := make([]models.AlertGroupRule, len(groupRules))
+	for i, rule := range groupRules {
+		groups[i] = *rule
+	}
+
+	cfg, issues := alertmanager.Export(channels, rules, inhibitions, groups)
+	data, err := alertmanager.Marshal(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode alertmanager config: %w", err)
+	}
+	if issues == nil {
+		issues = []models.ConfigIssue{}
+	}
+	return data, issues, nil
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+	"alertbot/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the username or password is wrong
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrTokenRevoked is returned for tokens that were logged out
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrTicketUsed is returned when a WebSocket ticket is redeemed twice
+	ErrTicketUsed = errors.New("ticket has already been used")
+)
+
+// revocationRefreshInterval bounds how stale the local revocation list may be,
+// so revocations made on other replicas take effect within this interval
+const revocationRefreshInterval = 15 * time.Second
+
+type AuthService interface {
+	Login(ctx context.Context, username, password string) (string, *utils.Claims, error)
+	ParseToken(ctx context.Context, token string) (*utils.Claims, error)
+	RefreshToken(ctx context.Context, claims *utils.Claims) (string, *utils.Claims, error)
+	RevokeToken(ctx context.Context, claims *utils.Claims) error
+	IsRevoked(tokenID string) bool
+
+	// WebSocket tickets let browsers authenticate without setting headers
+	IssueTicket(ctx context.Context, claims *utils.Claims) (string, time.Time, error)
+	RedeemTicket(ctx context.Context, ticket string) (*utils.Claims, error)
+}
+
+type authService struct {
+	cfg       *config.Config
+	tokenRepo repository.RevokedTokenRepository
+	logger    *logrus.Logger
+
+	revoked         map[string]time.Time
+	revokedLoadedAt time.Time
+	mutex           sync.Mutex
+}
+
+func NewAuthService(cfg *config.Config, tokenRepo repository.RevokedTokenRepository, logger *logrus.Logger) AuthService {
+	return &authService{
+		cfg:       cfg,
+		tokenRepo: tokenRepo,
+		logger:    logger,
+		revoked:   make(map[string]time.Time),
+	}
+}
+
+func (s *authService) Login(ctx context.Context, username, password string) (string, *utils.Claims, error) {
+	user, err := s.authenticate(username, password)
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := utils.NewClaims(user.ID, user.Username, user.Role, s.tokenTTL())
+	claims.Teams = user.Teams
+	claims.Scope = user.Scope
+
+	token, err := utils.SignClaims(claims, s.cfg.JWT.Secret)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, claims, nil
+}
+
+func (s *authService) ParseToken(ctx context.Context, token string) (*utils.Claims, error) {
+	claims, err := utils.ParseJWT(token, s.cfg.JWT.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.IsTicket() {
+		return nil, utils.ErrTicketNotAllowed
+	}
+	if s.IsRevoked(claims.ID) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+func (s *authService) RefreshToken(ctx context.Context, claims *utils.Claims) (string, *utils.Claims, error) {
+	fresh := utils.NewClaims(claims.UserID, claims.Username, claims.Role, s.tokenTTL())
+	fresh.Teams = claims.Teams
+	fresh.Scope = claims.Scope
+
+	token, err := utils.SignClaims(fresh, s.cfg.JWT.Secret)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	// The old token stops working so open WebSocket sessions bound to it are closed
+	if err := s.RevokeToken(ctx, claims); err != nil {
+		return "", nil, err
+	}
+	return token, fresh, nil
+}
+
+func (s *authService) RevokeToken(ctx context.Context, claims *utils.Claims) error {
+	if claims.ID == "" {
+		return fmt.Errorf("token has no id and cannot be revoked")
+	}
+
+	expiresAt := time.Now().Add(s.tokenTTL())
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	if err := s.tokenRepo.Create(&models.RevokedToken{
+		TokenID:   claims.ID,
+		Username:  claims.Username,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.revoked[claims.ID] = expiresAt
+	s.mutex.Unlock()
+	return nil
+}
+
+// IsRevoked checks the local revocation list, reloading it from the database
+// when it is older than revocationRefreshInterval
+func (s *authService) IsRevoked(tokenID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if time.Since(s.revokedLoadedAt) > revocationRefreshInterval {
+		s.reloadRevoked()
+	}
+
+	_, revoked := s.revoked[tokenID]
+	return revoked
+}
+
+func (s *authService) IssueTicket(ctx context.Context, claims *utils.Claims) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ticketTTL())
+	ticket := &utils.Claims{
+		UserID:           claims.UserID,
+		Username:         claims.Username,
+		Role:             claims.Role,
+		Teams:            claims.Teams,
+		Scope:            claims.Scope,
+		SessionID:        claims.ID,
+		SessionExpiresAt: claims.ExpiresAt,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Audience:  jwt.ClaimStrings{utils.TicketAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "alertbot",
+		},
+	}
+
+	signed, err := utils.SignClaims(ticket, s.cfg.JWT.Secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign ticket: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// RedeemTicket validates a ticket and returns the claims of the session it was issued for
+func (s *authService) RedeemTicket(ctx context.Context, ticket string) (*utils.Claims, error) {
+	claims, err := utils.ParseJWT(ticket, s.cfg.JWT.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.IsTicket() {
+		return nil, fmt.Errorf("not a websocket ticket")
+	}
+
+	// Tickets are single use; recording them in the database covers every replica
+	expiresAt := time.Now().Add(s.ticketTTL())
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	first, err := s.tokenRepo.MarkTicketUsed(claims.ID, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record ticket use: %w", err)
+	}
+	if !first {
+		return nil, ErrTicketUsed
+	}
+
+	if s.IsRevoked(claims.SessionID) {
+		return nil, ErrTokenRevoked
+	}
+
+	session := &utils.Claims{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+		Teams:    claims.Teams,
+		Scope:    claims.Scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        claims.SessionID,
+			ExpiresAt: claims.SessionExpiresAt,
+			Issuer:    claims.Issuer,
+		},
+	}
+	return session, nil
+}
+
+// authenticate checks the credentials against configured users. Without
+// configured users the demo admin account stays available.
+func (s *authService) authenticate(username, password string) (*config.User, error) {
+	users := s.cfg.Security.Users
+	if len(users) == 0 {
+		if username == "admin" && password == "admin123" {
+			return &config.User{ID: 1, Username: "admin", Role: "admin"}, nil
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	for _, user := range users {
+		if user.Username != username {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+			return nil, ErrInvalidCredentials
+		}
+		if user.Role == "" {
+			user.Role = "user"
+		}
+		return &user, nil
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+// reloadRevoked refreshes the revocation list; callers must hold the mutex
+func (s *authService) reloadRevoked() {
+	// Record the attempt even on failure so an unavailable database is not queried on every check
+	s.revokedLoadedAt = time.Now()
+
+	tokens, err := s.tokenRepo.ListActive()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load revoked tokens")
+		return
+	}
+
+	revoked := make(map[string]time.Time, len(tokens))
+	for _, token := range tokens {
+		revoked[token.TokenID] = token.ExpiresAt
+	}
+	s.revoked = revoked
+
+	if err := s.tokenRepo.DeleteExpired(); err != nil {
+		s.logger.WithError(err).Warn("Failed to delete expired token revocations")
+	}
+}
+
+func (s *authService) tokenTTL() time.Duration {
+	hours := s.cfg.JWT.Expiration
+	if hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func (s *authService) ticketTTL() time.Duration {
+	ttl := time.Duration(s.cfg.Security.TicketTTL) * time.Second
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return ttl
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/manifest"
+	"alertbot/internal/models"
+	"alertbot/internal/notification"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Object kinds reported in apply changes, besides the import ones
+const (
+	configKindTemplate = "notification_template"
+	configKindSilence  = "silence"
+)
+
+type ConfigService interface {
+	// Apply makes the stored configuration match a manifest bundle in one
+	// transaction. Objects are matched by kind and name; with prune, stored
+	// objects missing from the bundle are deleted. Ad-hoc silences, which
+	// have no name, are never pruned.
+	Apply(ctx context.Context, data []byte, dryRun, prune bool) (*models.ApplyReport, error)
+	// Export renders the stored configuration as a manifest bundle and lists
+	// the environment variables its secret references need
+	Export(ctx context.Context) ([]byte, []string, error)
+}
+
+type configService struct {
+	repos      *repository.Repositories
+	ruleEngine *engine.RuleEngine
+	logger     *logrus.Logger
+}
+
+func NewConfigService(repos *repository.Repositories, ruleEngine *engine.RuleEngine, logger *logrus.Logger) ConfigService {
+	return &configService{
+		repos:      repos,
+		ruleEngine: ruleEngine,
+		logger:     logger,
+	}
+}
+
+func (s *configService) Apply(ctx context.Context, data []byte, dryRun, prune bool) (*models.ApplyReport, error) {
+	docs, err := manifest.Parse(data)
+	if err != nil {
+		return nil, &ValidationError{Field: "manifests", Message: err.Error()}
+	}
+	objects, err := manifest.Build(docs, os.LookupEnv)
+	if err != nil {
+		return nil, &ValidationError{Field: "manifests", Message: err.Error()}
+	}
+	for _, tmpl := range objects.Templates {
+		if _, _, err := notification.ParseTemplate(&tmpl); err != nil {
+			return nil, &ValidationError{Field: "manifests", Message: fmt.Sprintf("%s/%s: %v", manifest.KindNotificationTemplate, tmpl.Name, err)}
+		}
+	}
+
+	report := &models.ApplyReport{
+		DryRun:  dryRun,
+		Prune:   prune,
+		Changes: []models.ConfigChange{},
+	}
+	if dryRun {
+		if err := applyManifests(ctx, s.repos, objects, report, false); err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+
+	err = s.repos.Transaction(func(tx *repository.Repositories) error {
+		return applyManifests(ctx, tx, objects, report, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ruleEngine != nil {
+		if err := s.ruleEngine.RefreshRules(); err != nil {
+			s.logger.WithError(err).Warn("Failed to reload routing rules after config apply")
+		}
+	}
+	s.logger.WithFields(logrus.Fields{
+		"changes": len(report.Changes),
+		"prune":   prune,
+	}).Info("Applied configuration manifests")
+	return report, nil
+}
+
+// applyManifests diffs the bundle against repos and, when write is set,
+// stores it. Templates and channels go first so rules can refer to them;
+// pruning deletes rules before the channels and templates they use.
+func applyManifests(ctx context.Context, repos *repository.Repositories, objects *manifest.Objects, report *models.ApplyReport, write bool) error {
+	var prunes []func() error
+	prune := func(kind, name string, del func() error) {
+		if !report.Prune {
+			return
+		}
+		report.Changes = append(report.Changes, models.ConfigChange{Kind: kind, Name: name, Action: models.ConfigChangeDelete})
+		if write {
+			prunes = append(prunes, func() error {
+				if err := del(); err != nil {
+					return fmt.Errorf("failed to delete %s %q: %w", kind, name, err)
+				}
+				return nil
+			})
+		}
+	}
+
+	// Notification templates
+	templates, err := repos.NotificationTemplate.List()
+	if err != nil {
+		return fmt.Errorf("failed to list notification templates: %w", err)
+	}
+	existingTemplates := make(map[string]*models.NotificationTemplate, len(templates))
+	for i := range templates {
+		existingTemplates[templates[i].Name] = &templates[i]
+	}
+	knownTemplates := make(map[string]bool)
+	for i := range objects.Templates {
+		tmpl := &objects.Templates[i]
+		existing := existingTemplates[tmpl.Name]
+		change := diffObject(configKindTemplate, tmpl.Name, existing, tmpl, func() {
+			tmpl.ID, tmpl.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.NotificationTemplate.Create(tmpl) }, func() error { return repos.NotificationTemplate.Update(tmpl) }); err != nil {
+				return err
+			}
+		}
+		knownTemplates[tmpl.Name] = true
+	}
+
+	// Notification channels
+	channels, err := repos.NotificationChannel.List()
+	if err != nil {
+		return fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+	existingChannels := make(map[string]*models.NotificationChannel, len(channels))
+	for i := range channels {
+		if _, ok := existingChannels[channels[i].Name]; !ok {
+			existingChannels[channels[i].Name] = &channels[i]
+		}
+	}
+	channelIDs := make(map[string]uint)
+	for i := range objects.Channels {
+		channel := &objects.Channels[i]
+		existing := existingChannels[channel.Name]
+		change := diffObject(configKindChannel, channel.Name, existing, channel, func() {
+			channel.ID, channel.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.NotificationChannel.Create(channel) }, func() error { return repos.NotificationChannel.Update(channel) }); err != nil {
+				return err
+			}
+		}
+		channelIDs[channel.Name] = channel.ID
+	}
+
+	// Without pruning, rules may also refer to stored objects
+	if !report.Prune {
+		for name := range existingTemplates {
+			knownTemplates[name] = true
+		}
+		for name, channel := range existingChannels {
+			if _, ok := channelIDs[name]; !ok {
+				channelIDs[name] = channel.ID
+			}
+		}
+	}
+
+	// Routing rules
+	rules, err := repos.RoutingRule.List()
+	if err != nil {
+		return fmt.Errorf("failed to list routing rules: %w", err)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	existingRules := make(map[string]*models.RoutingRule, len(rules))
+	for i := range rules {
+		if _, ok := existingRules[rules[i].Name]; !ok {
+			existingRules[rules[i].Name] = &rules[i]
+		}
+	}
+	var refErrs manifest.Errors
+	applied := make(map[string]bool, len(objects.RoutingRules))
+	for i := range objects.RoutingRules {
+		built := &objects.RoutingRules[i]
+		rule := &built.Rule
+		applied[rule.Name] = true
+
+		ids := make([]interface{}, 0, len(built.Channels))
+		for _, name := range built.Channels {
+			id, ok := channelIDs[name]
+			if !ok {
+				refErrs = append(refErrs, fmt.Sprintf("%s/%s: unknown channel %q", manifest.KindRoutingRule, rule.Name, name))
+				continue
+			}
+			ids = append(ids, id) // 0 for channels a dry run would create
+		}
+		rule.Receivers = models.JSONB{"channels": ids}
+		if built.Template != "" {
+			if !knownTemplates[built.Template] {
+				refErrs = append(refErrs, fmt.Sprintf("%s/%s: unknown template %q", manifest.KindRoutingRule, rule.Name, built.Template))
+			}
+			rule.Receivers["template"] = built.Template
+		}
+
+		existing := existingRules[rule.Name]
+		change := diffObject(configKindRoutingRule, rule.Name, existing, rule, func() {
+			rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write && len(refErrs) == 0 {
+			if err := saveChange(change, func() error { return repos.RoutingRule.Create(rule) }, func() error { return repos.RoutingRule.Update(rule) }); err != nil {
+				return err
+			}
+		}
+	}
+	if len(refErrs) > 0 {
+		return &ValidationError{Field: "manifests", Message: refErrs.Error()}
+	}
+	for _, rule := range rules {
+		if !applied[rule.Name] {
+			id := rule.ID
+			prune(configKindRoutingRule, rule.Name, func() error { return repos.RoutingRule.Delete(id) })
+		}
+	}
+
+	// Inhibition rules
+	inhibitions, err := repos.Inhibition.List()
+	if err != nil {
+		return fmt.Errorf("failed to list inhibition rules: %w", err)
+	}
+	sort.Slice(inhibitions, func(i, j int) bool { return inhibitions[i].Name < inhibitions[j].Name })
+	existingInhibitions := make(map[string]*models.InhibitionRule, len(inhibitions))
+	for i := range inhibitions {
+		if _, ok := existingInhibitions[inhibitions[i].Name]; !ok {
+			existingInhibitions[inhibitions[i].Name] = &inhibitions[i]
+		}
+	}
+	applied = make(map[string]bool, len(objects.InhibitionRules))
+	for i := range objects.InhibitionRules {
+		rule := &objects.InhibitionRules[i]
+		applied[rule.Name] = true
+		existing := existingInhibitions[rule.Name]
+		change := diffObject(configKindInhibition, rule.Name, existing, rule, func() {
+			rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.Inhibition.Create(rule) }, func() error { return repos.Inhibition.Update(rule) }); err != nil {
+				return err
+			}
+		}
+	}
+	for _, rule := range inhibitions {
+		if !applied[rule.Name] {
+			id := rule.ID
+			prune(configKindInhibition, rule.Name, func() error { return repos.Inhibition.Delete(id) })
+		}
+	}
+
+	// Alert group rules
+	groupRulePtrs, err := repos.AlertGroup.ListAlertGroupRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list alert group rules: %w", err)
+	}
+	sort.Slice(groupRulePtrs, func(i, j int) bool { return groupRulePtrs[i].Name < groupRulePtrs[j].Name })
+	groupRules := make([]models.AlertGroupRule, len(groupRulePtrs))
+	existingGroupRules := make(map[string]*models.AlertGroupRule, len(groupRulePtrs))
+	for i, rule := range groupRulePtrs {
+		groupRules[i] = *rule
+		if _, ok := existingGroupRules[rule.Name]; !ok {
+			existingGroupRules[rule.Name] = rule
+		}
+	}
+	applied = make(map[string]bool, len(objects.GroupRules))
+	for i := range objects.GroupRules {
+		rule := &objects.GroupRules[i]
+		applied[rule.Name] = true
+		existing := existingGroupRules[rule.Name]
+		change := diffObject(configKindGroupRule, rule.Name, existing, rule, func() {
+			rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.AlertGroup.CreateAlertGroupRule(ctx, rule) }, func() error { return repos.AlertGroup.UpdateAlertGroupRule(ctx, rule) }); err != nil {
+				return err
+			}
+		}
+	}
+	for _, rule := range groupRules {
+		if !applied[rule.Name] {
+			id := rule.ID
+			prune(configKindGroupRule, rule.Name, func() error { return repos.AlertGroup.DeleteAlertGroupRule(ctx, id) })
+		}
+	}
+
+	// Named silences
+	silences, err := repos.Silence.ListNamed()
+	if err != nil {
+		return fmt.Errorf("failed to list silences: %w", err)
+	}
+	existingSilences := make(map[string]*models.Silence, len(silences))
+	for i := range silences {
+		if _, ok := existingSilences[silences[i].Name]; !ok {
+			existingSilences[silences[i].Name] = &silences[i]
+		}
+	}
+	applied = make(map[string]bool, len(objects.Silences))
+	for i := range objects.Silences {
+		silence := &objects.Silences[i]
+		applied[silence.Name] = true
+		existing := existingSilences[silence.Name]
+		change := diffObject(configKindSilence, silence.Name, existing, silence, func() {
+			silence.ID, silence.CreatedAt = existing.ID, existing.CreatedAt
+		})
+		report.Changes = append(report.Changes, change)
+		if write {
+			if err := saveChange(change, func() error { return repos.Silence.Create(silence) }, func() error { return repos.Silence.Update(silence) }); err != nil {
+				return err
+			}
+		}
+	}
+	for _, silence := range silences {
+		if !applied[silence.Name] {
+			id := silence.ID
+			prune(configKindSilence, silence.Name, func() error { return repos.Silence.Delete(id) })
+		}
+	}
+
+	// Channels and templates are pruned last, once no rule refers to them
+	applied = make(map[string]bool, len(objects.Channels))
+	for _, channel := range objects.Channels {
+		applied[channel.Name] = true
+	}
+	for _, channel := range channels {
+		if !applied[channel.Name] {
+			id := channel.ID
+			prune(configKindChannel, channel.Name, func() error { return repos.NotificationChannel.Delete(id) })
+		}
+	}
+	applied = make(map[string]bool, len(objects.Templates))
+	for _, tmpl := range objects.Templates {
+		applied[tmpl.Name] = true
+	}
+	for _, tmpl := range templates {
+		if !applied[tmpl.Name] {
+			id := tmpl.ID
+			prune(configKindTemplate, tmpl.Name, func() error { return repos.NotificationTemplate.Delete(id) })
+		}
+	}
+
+	for _, del := range prunes {
+		if err := del(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *configService) Export(ctx context.Context) ([]byte, []string, error) {
+	state := &manifest.State{}
+	var err error
+
+	if state.Templates, err = s.repos.NotificationTemplate.List(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list notification templates: %w", err)
+	}
+	if state.Channels, err = s.repos.NotificationChannel.List(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	if state.RoutingRules, err = s.repos.RoutingRule.List(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list routing rules: %w", err)
+	}
+	if state.InhibitionRules, err = s.repos.Inhibition.List(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list inhibition rules: %w", err)
+	}
+	groupRules, err := s.repos.AlertGroup.ListAlertGroupRules(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list alert group rules: %w", err)
+	}
+	for _, rule := range groupRules {
+		state.GroupRules = append(state.GroupRules, *rule)
+	}
+	if state.Silences, err = s.repos.Silence.ListNamed(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list silences: %w", err)
+	}
+
+	sort.Slice(state.Channels, func(i, j int) bool { return state.Channels[i].Name < state.Channels[j].Name })
+	sort.Slice(state.RoutingRules, func(i, j int) bool { return state.RoutingRules[i].Name < state.RoutingRules[j].Name })
+	sort.Slice(state.InhibitionRules, func(i, j int) bool { return state.InhibitionRules[i].Name < state.InhibitionRules[j].Name })
+	sort.Slice(state.GroupRules, func(i, j int) bool { return state.GroupRules[i].Name < state.GroupRules[j].Name })
+
+	data, envVars, err := manifest.Export(state)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to export manifests: %w", err)
+	}
+	sort.Strings(envVars)
+	return data, envVars, nil
+}
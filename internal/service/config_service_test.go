@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/migration"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestRepositories returns repositories over a freshly migrated SQLite
+// database
+func newTestRepositories(t *testing.T) (*repository.Repositories, *logrus.Logger) {
+	db, log := newTestDatabase(t)
+	return repository.NewRepositories(db), log
+}
+
+func newTestDatabase(t *testing.T) (*gorm.DB, *logrus.Logger) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	db, err := repository.NewDatabase(config.Database{
+		Driver: repository.DialectSQLite,
+		Path:   filepath.Join(t.TempDir(), "alertbot.db"),
+	})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	require.NoError(t, migration.NewMigrator(db, log).Migrate())
+	return db, log
+}
+
+const baseBundle = `
+kind: NotificationTemplate
+name: short
+spec:
+  content: '{{ .Alert.Labels.alertname }}'
+---
+kind: NotificationChannel
+name: dba-slack
+spec:
+  type: slack
+  config:
+    webhook_url: ${ALERTBOT_MANIFEST_DBA_SLACK_WEBHOOK_URL}
+---
+kind: NotificationChannel
+name: ops-email
+spec:
+  type: email
+  config:
+    to: [ops@example.com]
+---
+kind: RoutingRule
+name: database
+spec:
+  matchers: ['team="db"']
+  channels: [dba-slack]
+  template: short
+---
+kind: RoutingRule
+name: everything
+spec:
+  matchers: ['alertname=~".+"']
+  channels: [ops-email]
+---
+kind: InhibitionRule
+name: critical-mutes-warning
+spec:
+  source_matchers: ['severity="critical"']
+  target_matchers: ['severity="warning"']
+---
+kind: AlertGroupRule
+name: by-cluster
+spec:
+  group_by: [cluster]
+---
+kind: Silence
+name: maintenance
+spec:
+  matchers: ['cluster="eu-1"']
+  starts_at: 2026-01-10T22:00:00Z
+  ends_at: 2026-01-11T02:00:00Z
+`
+
+// removeDocument drops the documents of a bundle that contain line
+func removeDocument(bundle, line string) string {
+	var kept []string
+	for _, doc := range strings.Split(bundle, "---\n") {
+		if !strings.Contains(doc, line) {
+			kept = append(kept, doc)
+		}
+	}
+	return strings.Join(kept, "---\n")
+}
+
+// actions indexes a report by kind/name
+func actions(changes []models.ConfigChange) map[string]models.ConfigChangeAction {
+	out := make(map[string]models.ConfigChangeAction, len(changes))
+	for _, change := range changes {
+		out[change.Kind+"/"+change.Name] = change.Action
+	}
+	return out
+}
+
+func newTestConfigService(t *testing.T) (ConfigService, *repository.Repositories) {
+	t.Setenv("ALERTBOT_MANIFEST_DBA_SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/X")
+	repos, log := newTestRepositories(t)
+
+	// Start from an empty configuration, without the seeded default rule
+	rules, err := repos.RoutingRule.List()
+	require.NoError(t, err)
+	for _, rule := range rules {
+		require.NoError(t, repos.RoutingRule.Delete(rule.ID))
+	}
+	return NewConfigService(repos, nil, log), repos
+}
+
+func TestConfigApply(t *testing.T) {
+	svc, repos := newTestConfigService(t)
+	ctx := context.Background()
+
+	report, err := svc.Apply(ctx, []byte(baseBundle), false, false)
+	require.NoError(t, err)
+	assert.Len(t, report.Changes, 8)
+	for name, action := range actions(report.Changes) {
+		assert.Equal(t, models.ConfigChangeCreate, action, name)
+	}
+
+	channels, err := repos.NotificationChannel.List()
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+	ids := make(map[string]uint)
+	for _, channel := range channels {
+		ids[channel.Name] = channel.ID
+		if channel.Name == "dba-slack" {
+			assert.Equal(t, "https://hooks.slack.com/services/T/B/X", channel.Config["webhook_url"])
+		}
+	}
+
+	rules, err := repos.RoutingRule.List()
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	for _, rule := range rules {
+		if rule.Name == "database" {
+			assert.Equal(t, []interface{}{float64(ids["dba-slack"])}, rule.Receivers["channels"])
+			assert.Equal(t, "short", rule.Receivers["template"])
+		}
+	}
+
+	// Applying the same bundle again changes nothing
+	report, err = svc.Apply(ctx, []byte(baseBundle), false, false)
+	require.NoError(t, err)
+	for name, action := range actions(report.Changes) {
+		assert.Equal(t, models.ConfigChangeUnchanged, action, name)
+	}
+
+	// Export gives a bundle that is also unchanged when applied
+	exported, envVars, err := svc.Export(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ALERTBOT_MANIFEST_DBA_SLACK_WEBHOOK_URL"}, envVars)
+	report, err = svc.Apply(ctx, exported, false, true)
+	require.NoError(t, err)
+	assert.Len(t, report.Changes, 8)
+	for name, action := range actions(report.Changes) {
+		assert.Equal(t, models.ConfigChangeUnchanged, action, name)
+	}
+}
+
+func TestConfigApplyUpdate(t *testing.T) {
+	svc, repos := newTestConfigService(t)
+	ctx := context.Background()
+	_, err := svc.Apply(ctx, []byte(baseBundle), false, false)
+	require.NoError(t, err)
+	before, err := repos.RoutingRule.List()
+	require.NoError(t, err)
+
+	updated := removeDocument(baseBundle, "name: database\n") + `---
+kind: RoutingRule
+name: database
+spec:
+  priority: 5
+  matchers: ['team="db"']
+  channels: [dba-slack, ops-email]
+  template: short
+`
+	report, err := svc.Apply(ctx, []byte(updated), false, false)
+	require.NoError(t, err)
+	for _, change := range report.Changes {
+		if change.Name == "database" {
+			assert.Equal(t, models.ConfigChangeUpdate, change.Action)
+			assert.Equal(t, []string{"priority", "receivers"}, change.Fields)
+		} else {
+			assert.Equal(t, models.ConfigChangeUnchanged, change.Action, change.Name)
+		}
+	}
+
+	// Updates keep the stored identity
+	after, err := repos.RoutingRule.List()
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	stored := make(map[string]models.RoutingRule, len(before))
+	for _, rule := range before {
+		stored[rule.Name] = rule
+	}
+	for _, rule := range after {
+		assert.Equal(t, stored[rule.Name].ID, rule.ID)
+		assert.Equal(t, stored[rule.Name].CreatedAt.Unix(), rule.CreatedAt.Unix())
+	}
+}
+
+func TestConfigApplyPrune(t *testing.T) {
+	svc, repos := newTestConfigService(t)
+	ctx := context.Background()
+	_, err := svc.Apply(ctx, []byte(baseBundle), false, false)
+	require.NoError(t, err)
+
+	adHoc := &models.Silence{
+		Matchers: models.JSONB{"matchers": []interface{}{map[string]interface{}{"name": "team", "value": "db", "is_regex": false}}},
+		StartsAt: time.Now(),
+		EndsAt:   time.Now().Add(time.Hour),
+		Creator:  "admin",
+	}
+	require.NoError(t, repos.Silence.Create(adHoc))
+
+	// A smaller bundle keeps the objects it omits unless pruning, and its
+	// rules may refer to stored channels
+	smaller := `
+kind: RoutingRule
+name: database
+spec:
+  matchers: ['team="db"']
+  channels: [dba-slack]
+  template: short
+`
+	report, err := svc.Apply(ctx, []byte(smaller), false, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]models.ConfigChangeAction{
+		"routing_rule/database": models.ConfigChangeUnchanged,
+	}, actions(report.Changes))
+
+	// With pruning the stored channels and template are gone, so the rule's
+	// references are errors
+	_, err = svc.Apply(ctx, []byte(smaller), true, true)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, validationErr.Message, `unknown channel "dba-slack"`)
+	assert.Contains(t, validationErr.Message, `unknown template "short"`)
+
+	pruned := removeDocument(removeDocument(baseBundle, "name: everything\n"), "name: ops-email\n")
+	pruned = removeDocument(removeDocument(pruned, "name: critical-mutes-warning\n"), "name: maintenance\n")
+	want := map[string]models.ConfigChangeAction{
+		"notification_template/short":            models.ConfigChangeUnchanged,
+		"notification_channel/dba-slack":         models.ConfigChangeUnchanged,
+		"notification_channel/ops-email":         models.ConfigChangeDelete,
+		"routing_rule/database":                  models.ConfigChangeUnchanged,
+		"routing_rule/everything":                models.ConfigChangeDelete,
+		"inhibition_rule/critical-mutes-warning": models.ConfigChangeDelete,
+		"alert_group_rule/by-cluster":            models.ConfigChangeUnchanged,
+		"silence/maintenance":                    models.ConfigChangeDelete,
+	}
+
+	// A dry run reports the deletes without making them
+	report, err = svc.Apply(ctx, []byte(pruned), true, true)
+	require.NoError(t, err)
+	assert.Equal(t, want, actions(report.Changes))
+	channels, err := repos.NotificationChannel.List()
+	require.NoError(t, err)
+	assert.Len(t, channels, 2)
+
+	report, err = svc.Apply(ctx, []byte(pruned), false, true)
+	require.NoError(t, err)
+	assert.Equal(t, want, actions(report.Changes))
+
+	channels, err = repos.NotificationChannel.List()
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	assert.Equal(t, "dba-slack", channels[0].Name)
+	rules, err := repos.RoutingRule.List()
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	inhibitions, err := repos.Inhibition.List()
+	require.NoError(t, err)
+	assert.Empty(t, inhibitions)
+
+	// Ad-hoc silences are not managed by the bundle
+	silences, err := repos.Silence.List()
+	require.NoError(t, err)
+	require.Len(t, silences, 1)
+	assert.Equal(t, adHoc.ID, silences[0].ID)
+}
+
+func TestConfigApplyFailureWritesNothing(t *testing.T) {
+	svc, repos := newTestConfigService(t)
+	ctx := context.Background()
+
+	bundle := baseBundle + `---
+kind: RoutingRule
+name: broken
+spec:
+  matchers: ['team="web"']
+  channels: [web-slack]
+`
+	_, err := svc.Apply(ctx, []byte(bundle), false, false)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	channels, err := repos.NotificationChannel.List()
+	require.NoError(t, err)
+	assert.Empty(t, channels)
+	templates, err := repos.NotificationTemplate.List()
+	require.NoError(t, err)
+	assert.Empty(t, templates)
+}
+
+func TestAlertmanagerImportReapply(t *testing.T) {
+	repos, log := newTestRepositories(t)
+	svc := NewAlertmanagerService(repos, nil, log)
+	ctx := context.Background()
+
+	cfg := []byte(`
+route:
+  receiver: ops
+  routes:
+    - matchers: ['team="db"']
+      receiver: dba
+receivers:
+  - name: ops
+    email_configs:
+      - to: ops@example.com
+  - name: dba
+    slack_configs:
+      - api_url: https://hooks.slack.com/services/T/B/X
+inhibit_rules:
+  - source_matchers: ['severity="critical"']
+    target_matchers: ['severity="warning"']
+`)
+
+	report, err := svc.Import(ctx, cfg, true)
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Changes)
+	channels, err := repos.NotificationChannel.List()
+	require.NoError(t, err)
+	assert.Empty(t, channels)
+
+	report, err = svc.Import(ctx, cfg, false)
+	require.NoError(t, err)
+	created := len(report.Changes)
+	for name, action := range actions(report.Changes) {
+		assert.Equal(t, models.ConfigChangeCreate, action, name)
+	}
+
+	report, err = svc.Import(ctx, cfg, false)
+	require.NoError(t, err)
+	assert.Len(t, report.Changes, created)
+	for name, action := range actions(report.Changes) {
+		assert.Equal(t, models.ConfigChangeUnchanged, action, name)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultHeartbeatCheckInterval is how often heartbeat deadlines are checked
+const DefaultHeartbeatCheckInterval = 15 * time.Second
+
+// HeartbeatScheduler periodically raises and resolves missed heartbeat alerts
+type HeartbeatScheduler struct {
+	*leaderTicker
+
+	heartbeats HeartbeatService
+	logger     *logrus.Logger
+}
+
+func NewHeartbeatScheduler(heartbeats HeartbeatService, logger *logrus.Logger, interval time.Duration) *HeartbeatScheduler {
+	if interval <= 0 {
+		interval = DefaultHeartbeatCheckInterval
+	}
+	s := &HeartbeatScheduler{
+		heartbeats: heartbeats,
+		logger:     logger,
+	}
+	s.leaderTicker = newLeaderTicker("heartbeat scheduler", logger, interval, s.check)
+	return s
+}
+
+func (s *HeartbeatScheduler) check(ctx context.Context) {
+	changed, err := s.heartbeats.CheckHeartbeats(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to check heartbeats")
+		return
+	}
+	if changed > 0 {
+		s.logger.WithField("changed", changed).Debug("Heartbeat statuses changed")
+	}
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HeartbeatAlertName is the alertname of the alert raised for a missed heartbeat
+const HeartbeatAlertName = "HeartbeatMissed"
+
+// heartbeatReservedLabels are set on the missed heartbeat alert and cannot
+// be overridden by a heartbeat's labels
+var heartbeatReservedLabels = []string{"alertname", "heartbeat", "severity"}
+
+type HeartbeatService interface {
+	ListHeartbeats(ctx context.Context) ([]models.Heartbeat, error)
+	GetHeartbeat(ctx context.Context, id uint) (*models.Heartbeat, error)
+	CreateHeartbeat(ctx context.Context, heartbeat *models.Heartbeat) error
+	UpdateHeartbeat(ctx context.Context, id uint, heartbeat *models.Heartbeat) (*models.Heartbeat, error)
+	DeleteHeartbeat(ctx context.Context, id uint) error
+	// Ping records a ping and clears a missed heartbeat right away
+	Ping(ctx context.Context, id uint) (*models.Heartbeat, error)
+	// CheckHeartbeats raises alerts for heartbeats whose deadline passed and
+	// resolves them once pings resume. It returns the number of heartbeats
+	// that changed status.
+	CheckHeartbeats(ctx context.Context) (int, error)
+}
+
+type heartbeatService struct {
+	repos  *repository.Repositories
+	alerts AlertService
+	logger *logrus.Logger
+
+	// startedAt bounds deadlines so time the server was down does not count
+	// as missed pings
+	startedAt time.Time
+	// mu serialises status changes between the scheduler and pings
+	mu sync.Mutex
+}
+
+func NewHeartbeatService(repos *repository.Repositories, alerts AlertService, logger *logrus.Logger) HeartbeatService {
+	return &heartbeatService{
+		repos:     repos,
+		alerts:    alerts,
+		logger:    logger,
+		startedAt: time.Now(),
+	}
+}
+
+func (s *heartbeatService) ListHeartbeats(ctx context.Context) ([]models.Heartbeat, error) {
+	return s.repos.Heartbeat.List()
+}
+
+func (s *heartbeatService) GetHeartbeat(ctx context.Context, id uint) (*models.Heartbeat, error) {
+	return s.repos.Heartbeat.GetByID(id)
+}
+
+func (s *heartbeatService) CreateHeartbeat(ctx context.Context, heartbeat *models.Heartbeat) error {
+	if err := validateHeartbeat(heartbeat); err != nil {
+		return err
+	}
+	heartbeat.ID = 0
+	heartbeat.Status = string(models.HeartbeatStatusPending)
+	heartbeat.LastPingAt = nil
+	heartbeat.MissedAt = nil
+	return s.repos.Heartbeat.Create(heartbeat)
+}
+
+// UpdateHeartbeat replaces a heartbeat's settings. A missed heartbeat's
+// alert is resolved first, since its labels may change; the heartbeat then
+// starts over as pending.
+func (s *heartbeatService) UpdateHeartbeat(ctx context.Context, id uint, data *models.Heartbeat) (*models.Heartbeat, error) {
+	if err := validateHeartbeat(data); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heartbeat, err := s.repos.Heartbeat.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if heartbeat.Status == string(models.HeartbeatStatusMissed) {
+		if err := s.resolveAlert(ctx, heartbeat); err != nil {
+			return nil, err
+		}
+		heartbeat.Status = string(models.HeartbeatStatusPending)
+		heartbeat.MissedAt = nil
+	}
+
+	heartbeat.Name = data.Name
+	heartbeat.Description = data.Description
+	heartbeat.Interval = data.Interval
+	heartbeat.Grace = data.Grace
+	heartbeat.Matchers = data.Matchers
+	heartbeat.Labels = data.Labels
+	heartbeat.Enabled = data.Enabled
+	if err := s.repos.Heartbeat.Update(heartbeat); err != nil {
+		return nil, err
+	}
+	return heartbeat, nil
+}
+
+// DeleteHeartbeat deletes a heartbeat, resolving its alert if it was missed
+func (s *heartbeatService) DeleteHeartbeat(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heartbeat, err := s.repos.Heartbeat.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if heartbeat.Status == string(models.HeartbeatStatusMissed) {
+		if err := s.resolveAlert(ctx, heartbeat); err != nil {
+			return err
+		}
+	}
+	return s.repos.Heartbeat.Delete(id)
+}
+
+func (s *heartbeatService) Ping(ctx context.Context, id uint) (*models.Heartbeat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if err := s.repos.Heartbeat.RecordPing(id, now); err != nil {
+		return nil, err
+	}
+	heartbeat, err := s.repos.Heartbeat.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.evaluate(ctx, heartbeat, now); err != nil {
+		return nil, err
+	}
+	return heartbeat, nil
+}
+
+func (s *heartbeatService) CheckHeartbeats(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heartbeats, err := s.repos.Heartbeat.ListEnabled()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list heartbeats: %w", err)
+	}
+
+	changed := 0
+	now := time.Now()
+	for i := range heartbeats {
+		ok, err := s.evaluate(ctx, &heartbeats[i], now)
+		if err != nil {
+			s.logger.WithError(err).WithField("heartbeat", heartbeats[i].Name).Error("Failed to check heartbeat")
+			continue
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// evaluate moves a heartbeat between pending, up and missed, raising or
+// resolving its alert. It updates heartbeat in place and reports whether
+// the status changed.
+func (s *heartbeatService) evaluate(ctx context.Context, heartbeat *models.Heartbeat, now time.Time) (bool, error) {
+	if !heartbeat.Enabled {
+		return false, nil
+	}
+
+	since := heartbeat.CreatedAt
+	if heartbeat.LastPingAt != nil {
+		since = *heartbeat.LastPingAt
+	}
+	if since.Before(s.startedAt) {
+		since = s.startedAt
+	}
+	deadline := since.Add(time.Duration(heartbeat.Interval+heartbeat.Grace) * time.Second)
+
+	status := models.HeartbeatStatus(heartbeat.Status)
+	var missedAt *time.Time
+	switch {
+	case now.After(deadline):
+		if status == models.HeartbeatStatusMissed {
+			return false, nil
+		}
+		if err := s.alerts.ReceiveAlerts(ctx, []models.PrometheusAlert{heartbeatAlert(heartbeat, deadline, time.Time{})}); err != nil {
+			return false, fmt.Errorf("failed to raise missed heartbeat alert: %w", err)
+		}
+		status, missedAt = models.HeartbeatStatusMissed, &now
+		s.logger.WithFields(logrus.Fields{
+			"heartbeat":    heartbeat.Name,
+			"last_ping_at": heartbeat.LastPingAt,
+		}).Warn("Heartbeat missed")
+	case status == models.HeartbeatStatusMissed:
+		if err := s.resolveAlert(ctx, heartbeat); err != nil {
+			return false, err
+		}
+		status = models.HeartbeatStatusUp
+		s.logger.WithField("heartbeat", heartbeat.Name).Info("Heartbeat recovered")
+	case status == models.HeartbeatStatusPending && heartbeat.LastPingAt != nil:
+		status = models.HeartbeatStatusUp
+	default:
+		return false, nil
+	}
+
+	if err := s.repos.Heartbeat.UpdateStatus(heartbeat.ID, string(status), missedAt); err != nil {
+		return false, fmt.Errorf("failed to update heartbeat status: %w", err)
+	}
+	heartbeat.Status, heartbeat.MissedAt = string(status), missedAt
+	return true, nil
+}
+
+func (s *heartbeatService) resolveAlert(ctx context.Context, heartbeat *models.Heartbeat) error {
+	startsAt := time.Now()
+	if heartbeat.MissedAt != nil {
+		startsAt = *heartbeat.MissedAt
+	}
+	if err := s.alerts.ReceiveAlerts(ctx, []models.PrometheusAlert{heartbeatAlert(heartbeat, startsAt, time.Now())}); err != nil {
+		return fmt.Errorf("failed to resolve missed heartbeat alert: %w", err)
+	}
+	return nil
+}
+
+// heartbeatAlert builds the alert for a missed heartbeat; a non-zero endsAt
+// resolves it
+func heartbeatAlert(heartbeat *models.Heartbeat, startsAt, endsAt time.Time) models.PrometheusAlert {
+	labels := make(map[string]string, len(heartbeat.Labels)+3)
+	for name, value := range heartbeat.Labels {
+		labels[name] = fmt.Sprint(value)
+	}
+	labels["alertname"] = HeartbeatAlertName
+	labels["heartbeat"] = heartbeat.Name
+	labels["severity"] = string(models.AlertSeverityCritical)
+
+	last := "never"
+	if heartbeat.LastPingAt != nil {
+		last = heartbeat.LastPingAt.UTC().Format(time.RFC3339)
+	}
+	return models.PrometheusAlert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("Heartbeat %s missed", heartbeat.Name),
+			"description": fmt.Sprintf("No ping received within %ds plus %ds grace; last ping: %s", heartbeat.Interval, heartbeat.Grace, last),
+		},
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	}
+}
+
+func validateHeartbeat(heartbeat *models.Heartbeat) error {
+	if heartbeat.Name == "" {
+		return &ValidationError{Field: "name", Message: "Heartbeat name is required"}
+	}
+	if heartbeat.Interval <= 0 {
+		return &ValidationError{Field: "interval", Message: "Interval must be a positive number of seconds"}
+	}
+	if heartbeat.Grace < 0 {
+		return &ValidationError{Field: "grace", Message: "Grace must not be negative"}
+	}
+	if len(heartbeat.Matchers) > 0 {
+		if _, err := matcher.Decode(heartbeat.Matchers); err != nil {
+			return &ValidationError{Field: "matchers", Message: err.Error()}
+		}
+	}
+	for _, name := range heartbeatReservedLabels {
+		if _, ok := heartbeat.Labels[name]; ok {
+			return &ValidationError{Field: "labels", Message: fmt.Sprintf("Label %q is set by alertbot", name)}
+		}
+	}
+	return nil
+}
+
+// recordHeartbeatPings counts firing alerts that match a heartbeat's
+// matchers as pings of that heartbeat. The scheduler clears missed
+// heartbeats on its next check.
+func (s *alertService) recordHeartbeatPings(alerts []*IngestAlert) error {
+	heartbeats, err := s.deps.Repositories.Heartbeat.ListEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to list heartbeats: %w", err)
+	}
+
+	now := time.Now()
+	for _, heartbeat := range heartbeats {
+		if len(heartbeat.Matchers) == 0 {
+			continue
+		}
+		ms, err := matcher.Decode(heartbeat.Matchers)
+		if err != nil || len(ms) == 0 {
+			continue
+		}
+		for _, a := range alerts {
+			labels := a.Raw.Labels
+			firing := a.Raw.EndsAt.IsZero() || a.Raw.EndsAt.After(now)
+			if !firing || labels["alertname"] == HeartbeatAlertName || !ms.Matches(labels) {
+				continue
+			}
+			if err := s.deps.Repositories.Heartbeat.RecordPing(heartbeat.ID, now); err != nil {
+				s.deps.Logger.WithError(err).WithField("heartbeat", heartbeat.Name).Warn("Failed to record heartbeat ping")
+			}
+			break
+		}
+	}
+	return nil
+}
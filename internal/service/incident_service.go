@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Incident note kinds; status and owner notes are written on changes
+const (
+	incidentNoteComment = "note"
+	incidentNoteStatus  = "status"
+	incidentNoteOwner   = "owner"
+)
+
+// incidentTimelineLimit caps the alert history entries on a timeline
+const incidentTimelineLimit = 1000
+
+type IncidentService interface {
+	ListIncidents(ctx context.Context, filters models.IncidentFilters) ([]models.Incident, int64, error)
+	GetIncident(ctx context.Context, id uint) (*models.Incident, error)
+	CreateIncident(ctx context.Context, incident *models.Incident, fingerprints []string, author string) error
+	UpdateIncident(ctx context.Context, id uint, update models.IncidentUpdate, author string) (*models.Incident, error)
+	DeleteIncident(ctx context.Context, id uint) error
+	AttachAlert(ctx context.Context, id uint, fingerprint string) (*models.Incident, error)
+	AddNote(ctx context.Context, id uint, author string, content string) (*models.IncidentNote, error)
+	GetTimeline(ctx context.Context, id uint) ([]models.IncidentTimelineEntry, error)
+}
+
+type incidentService struct {
+	repos  *repository.Repositories
+	logger *logrus.Logger
+}
+
+func NewIncidentService(repos *repository.Repositories, logger *logrus.Logger) IncidentService {
+	return &incidentService{
+		repos:  repos,
+		logger: logger,
+	}
+}
+
+func (s *incidentService) ListIncidents(ctx context.Context, filters models.IncidentFilters) ([]models.Incident, int64, error) {
+	if filters.Status != "" && !validIncidentStatus(filters.Status) {
+		return nil, 0, &ValidationError{Field: "status", Message: "Status must be open, acknowledged or resolved"}
+	}
+	return s.repos.Incident.List(filters)
+}
+
+func (s *incidentService) GetIncident(ctx context.Context, id uint) (*models.Incident, error) {
+	return s.repos.Incident.GetByID(id)
+}
+
+// CreateIncident opens an incident by hand and attaches the given alerts
+func (s *incidentService) CreateIncident(ctx context.Context, incident *models.Incident, fingerprints []string, author string) error {
+	if incident.Title == "" {
+		return &ValidationError{Field: "title", Message: "Incident title is required"}
+	}
+	if incident.Severity == "" {
+		incident.Severity = string(models.AlertSeverityWarning)
+	}
+	if _, ok := incidentSeverityOrder[incident.Severity]; !ok {
+		return &ValidationError{Field: "severity", Message: "Severity must be info, warning or critical"}
+	}
+
+	alerts, err := s.repos.Alert.ListByFingerprints(fingerprints)
+	if err != nil {
+		return err
+	}
+	if len(alerts) != len(uniqueStrings(fingerprints)) {
+		return &ValidationError{Field: "alerts", Message: "One or more alerts do not exist"}
+	}
+
+	incident.ID = 0
+	incident.Status = string(models.IncidentStatusOpen)
+	incident.AlertCount = 0
+	incident.StartedAt = time.Now()
+	for _, alert := range alerts {
+		if alert.StartsAt.Before(incident.StartedAt) {
+			incident.StartedAt = alert.StartsAt
+		}
+	}
+
+	if err := s.repos.Incident.Create(incident); err != nil {
+		return fmt.Errorf("failed to create incident: %w", err)
+	}
+	for _, alert := range alerts {
+		if _, err := s.repos.Incident.AttachAlert(incident.ID, alert.Fingerprint); err != nil {
+			return fmt.Errorf("failed to attach alert %s: %w", alert.Fingerprint, err)
+		}
+		incident.AlertCount++
+	}
+
+	s.addNote(incident.ID, incidentNoteStatus, author, "Incident opened")
+	return nil
+}
+
+// UpdateIncident changes title, status, severity or owner and records status
+// and owner changes on the timeline
+func (s *incidentService) UpdateIncident(ctx context.Context, id uint, update models.IncidentUpdate, author string) (*models.Incident, error) {
+	incident, err := s.repos.Incident.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.IncidentNote
+	if update.Title != nil {
+		if *update.Title == "" {
+			return nil, &ValidationError{Field: "title", Message: "Incident title cannot be empty"}
+		}
+		incident.Title = *update.Title
+	}
+	if update.Severity != nil {
+		if _, ok := incidentSeverityOrder[*update.Severity]; !ok {
+			return nil, &ValidationError{Field: "severity", Message: "Severity must be info, warning or critical"}
+		}
+		incident.Severity = *update.Severity
+	}
+	if update.Status != nil && *update.Status != incident.Status {
+		if !validIncidentStatus(*update.Status) {
+			return nil, &ValidationError{Field: "status", Message: "Status must be open, acknowledged or resolved"}
+		}
+		notes = append(notes, models.IncidentNote{
+			Kind:    incidentNoteStatus,
+			Content: fmt.Sprintf("Status changed from %s to %s", incident.Status, *update.Status),
+		})
+		incident.Status = *update.Status
+		if *update.Status == string(models.IncidentStatusResolved) {
+			now := time.Now()
+			incident.ResolvedAt = &now
+		} else {
+			incident.ResolvedAt = nil
+		}
+	}
+	if update.Owner != nil && *update.Owner != incident.Owner {
+		content := fmt.Sprintf("Owner changed to %s", *update.Owner)
+		if *update.Owner == "" {
+			content = "Owner cleared"
+		}
+		notes = append(notes, models.IncidentNote{Kind: incidentNoteOwner, Content: content})
+		incident.Owner = *update.Owner
+	}
+
+	if err := s.repos.Incident.Update(incident); err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		if created := s.addNote(incident.ID, note.Kind, author, note.Content); created != nil {
+			incident.Notes = append(incident.Notes, *created)
+		}
+	}
+	return incident, nil
+}
+
+func (s *incidentService) DeleteIncident(ctx context.Context, id uint) error {
+	if _, err := s.repos.Incident.GetByID(id); err != nil {
+		return err
+	}
+	return s.repos.Incident.Delete(id)
+}
+
+// AttachAlert adds an existing alert to an incident by hand
+func (s *incidentService) AttachAlert(ctx context.Context, id uint, fingerprint string) (*models.Incident, error) {
+	incident, err := s.repos.Incident.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !incident.IsOpen() {
+		return nil, &ValidationError{Field: "status", Message: "Alerts cannot be attached to a resolved incident"}
+	}
+	alert, err := s.repos.Alert.GetByFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	attached, err := s.repos.Incident.AttachAlert(incident.ID, alert.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if attached {
+		s.recordHistory(alert.Fingerprint, "incident_attached", models.JSONB{"incident_id": incident.ID})
+	}
+	return s.repos.Incident.GetByID(id)
+}
+
+func (s *incidentService) AddNote(ctx context.Context, id uint, author string, content string) (*models.IncidentNote, error) {
+	if content == "" {
+		return nil, &ValidationError{Field: "content", Message: "Note content is required"}
+	}
+	if _, err := s.repos.Incident.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	note := &models.IncidentNote{
+		IncidentID: id,
+		Kind:       incidentNoteComment,
+		Author:     author,
+		Content:    content,
+	}
+	if err := s.repos.Incident.CreateNote(note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// GetTimeline merges the history of the incident's alerts with its notes and
+// attachments, oldest first
+func (s *incidentService) GetTimeline(ctx context.Context, id uint) ([]models.IncidentTimelineEntry, error) {
+	incident, err := s.repos.Incident.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	since := incident.StartedAt
+	if incident.CreatedAt.Before(since) {
+		since = incident.CreatedAt
+	}
+
+	fingerprints := make([]string, 0, len(incident.Alerts))
+	for _, a := range incident.Alerts {
+		fingerprints = append(fingerprints, a.AlertFingerprint)
+	}
+	history, err := s.repos.AlertHistory.ListByFingerprintsSince(fingerprints, since, incidentTimelineLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]models.IncidentTimelineEntry, 0, len(history)+len(incident.Notes)+len(incident.Alerts))
+	for _, h := range history {
+		timeline = append(timeline, models.IncidentTimelineEntry{
+			Time:             h.CreatedAt,
+			Source:           "alert",
+			Action:           h.Action,
+			AlertFingerprint: h.AlertFingerprint,
+			Details:          h.Details,
+		})
+	}
+	for _, a := range incident.Alerts {
+		timeline = append(timeline, models.IncidentTimelineEntry{
+			Time:             a.CreatedAt,
+			Source:           "incident",
+			Action:           "alert_attached",
+			AlertFingerprint: a.AlertFingerprint,
+		})
+	}
+	for _, n := range incident.Notes {
+		timeline = append(timeline, models.IncidentTimelineEntry{
+			Time:    n.CreatedAt,
+			Source:  "note",
+			Action:  n.Kind,
+			Author:  n.Author,
+			Details: models.JSONB{"content": n.Content},
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline, nil
+}
+
+func (s *incidentService) addNote(incidentID uint, kind, author, content string) *models.IncidentNote {
+	if author == "" {
+		author = "system"
+	}
+	note := &models.IncidentNote{
+		IncidentID: incidentID,
+		Kind:       kind,
+		Author:     author,
+		Content:    content,
+	}
+	if err := s.repos.Incident.CreateNote(note); err != nil {
+		s.logger.WithError(err).WithField("incident_id", incidentID).Warn("Failed to record incident note")
+		return nil
+	}
+	return note
+}
+
+func (s *incidentService) recordHistory(fingerprint, action string, details models.JSONB) {
+	history := &models.AlertHistory{
+		AlertFingerprint: fingerprint,
+		Action:           action,
+		Details:          details,
+	}
+	if err := s.repos.AlertHistory.Create(history); err != nil {
+		s.logger.WithError(err).WithField("fingerprint", fingerprint).Error("Failed to record alert history")
+	}
+}
+
+func validIncidentStatus(status string) bool {
+	switch models.IncidentStatus(status) {
+	case models.IncidentStatusOpen, models.IncidentStatusAcknowledged, models.IncidentStatusResolved:
+		return true
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/metrics"
+	"alertbot/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultIngestionWorkers is how many batches are ingested at once
+	DefaultIngestionWorkers = 4
+	// DefaultIngestionMaxDepth is how many alerts may wait in the queue
+	DefaultIngestionMaxDepth = 20000
+	// DefaultIngestionRetryAfter is the Retry-After sent while the queue is full
+	DefaultIngestionRetryAfter = 10 * time.Second
+)
+
+var (
+	// ErrIngestionQueueFull is returned when a batch would exceed the
+	// queue's maximum depth
+	ErrIngestionQueueFull = errors.New("ingestion queue is full")
+	// ErrIngestionQueueClosed is returned once the queue stopped accepting
+	// alerts
+	ErrIngestionQueueClosed = errors.New("ingestion queue is not accepting alerts")
+	// ErrIngestionBatchTooLarge is returned for a batch with more alerts
+	// than the queue can ever hold, so retrying it cannot succeed
+	ErrIngestionBatchTooLarge = errors.New("batch is larger than the ingestion queue")
+)
+
+// labelNamePattern is the Prometheus label name syntax
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidatePrometheusAlerts checks received alerts before they are queued,
+// since errors found during asynchronous ingestion cannot reach the sender
+func ValidatePrometheusAlerts(alerts []models.PrometheusAlert) error {
+	for i, alert := range alerts {
+		field := fmt.Sprintf("alerts[%d]", i)
+		if len(alert.Labels) == 0 {
+			return &ValidationError{Field: field + ".labels", Message: "alert has no labels"}
+		}
+		for name := range alert.Labels {
+			if !labelNamePattern.MatchString(name) {
+				return &ValidationError{Field: field + ".labels", Message: fmt.Sprintf("invalid label name %q", name)}
+			}
+		}
+		if !alert.StartsAt.IsZero() && !alert.EndsAt.IsZero() && alert.EndsAt.Before(alert.StartsAt) {
+			return &ValidationError{Field: field + ".endsAt", Message: "endsAt is before startsAt"}
+		}
+	}
+	return nil
+}
+
+// ingestionBatch is a queued request
+type ingestionBatch struct {
+	alerts     []models.PrometheusAlert
+	enqueuedAt time.Time
+}
+
+// IngestionQueue decouples receiving alerts from ingesting them. Batches
+// wait in a bounded queue and a fixed number of workers run them through
+// the alert service. Queued alerts are lost if the process dies.
+type IngestionQueue struct {
+	alerts     AlertService
+	logger     *logrus.Logger
+	workers    int
+	maxDepth   int
+	retryAfter time.Duration
+
+	batches chan ingestionBatch
+	depth   int
+	closed  bool
+	mu      sync.Mutex
+
+	wg      sync.WaitGroup
+	running bool
+	runMu   sync.Mutex
+}
+
+func NewIngestionQueue(alerts AlertService, cfg config.Ingestion, logger *logrus.Logger) *IngestionQueue {
+	q := &IngestionQueue{
+		alerts:     alerts,
+		logger:     logger,
+		workers:    cfg.Workers,
+		maxDepth:   cfg.MaxDepth,
+		retryAfter: time.Duration(cfg.RetryAfter) * time.Second,
+	}
+	if q.workers <= 0 {
+		q.workers = DefaultIngestionWorkers
+	}
+	if q.maxDepth <= 0 {
+		q.maxDepth = DefaultIngestionMaxDepth
+	}
+	if q.retryAfter <= 0 {
+		q.retryAfter = DefaultIngestionRetryAfter
+	}
+
+	// Every batch holds at least one alert, so the channel never blocks
+	q.batches = make(chan ingestionBatch, q.maxDepth)
+	return q
+}
+
+// RetryAfter is how long senders are asked to wait when a batch is refused
+func (q *IngestionQueue) RetryAfter() time.Duration {
+	return q.retryAfter
+}
+
+// Depth returns the number of queued alerts
+func (q *IngestionQueue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.depth
+}
+
+// Enqueue queues a batch for ingestion. It fails without queueing any of
+// the alerts when they do not all fit, and a batch larger than the queue is
+// refused outright.
+func (q *IngestionQueue) Enqueue(alerts []models.PrometheusAlert) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		metrics.RecordIngestionQueueDrop("closed", len(alerts))
+		return ErrIngestionQueueClosed
+	}
+	if len(alerts) > q.maxDepth {
+		metrics.RecordIngestionQueueDrop("too_large", len(alerts))
+		return fmt.Errorf("%w: %d alerts, at most %d per request", ErrIngestionBatchTooLarge, len(alerts), q.maxDepth)
+	}
+	if q.depth+len(alerts) > q.maxDepth {
+		metrics.RecordIngestionQueueDrop("full", len(alerts))
+		return ErrIngestionQueueFull
+	}
+
+	q.depth += len(alerts)
+	metrics.UpdateIngestionQueueDepth(float64(q.depth))
+	q.batches <- ingestionBatch{alerts: alerts, enqueuedAt: time.Now()}
+	return nil
+}
+
+// Start runs the workers until Stop is called
+func (q *IngestionQueue) Start(ctx context.Context) {
+	q.runMu.Lock()
+	defer q.runMu.Unlock()
+
+	if q.running {
+		return
+	}
+
+	q.logger.WithFields(logrus.Fields{
+		"workers":   q.workers,
+		"max_depth": q.maxDepth,
+	}).Info("Starting ingestion queue")
+	q.running = true
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+}
+
+// Stop refuses new alerts and waits for the queued ones to be ingested
+func (q *IngestionQueue) Stop() {
+	q.runMu.Lock()
+	defer q.runMu.Unlock()
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.batches)
+	q.mu.Unlock()
+
+	if !q.running {
+		return
+	}
+
+	q.logger.WithField("depth", q.Depth()).Info("Stopping ingestion queue, draining queued alerts")
+	q.wg.Wait()
+	q.running = false
+}
+
+func (q *IngestionQueue) work(ctx context.Context) {
+	defer q.wg.Done()
+
+	for batch := range q.batches {
+		metrics.RecordIngestionQueueLag(time.Since(batch.enqueuedAt).Seconds())
+
+		// Requests are answered before ingestion, so their contexts are
+		// already done; only the queue's own context cancels ingestion
+		failed := 0
+		for _, result := range q.alerts.IngestAlerts(ctx, batch.alerts) {
+			if result.Action == "failed" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			q.logger.WithFields(logrus.Fields{
+				"failed":   failed,
+				"received": len(batch.alerts),
+			}).Error("Failed to ingest queued alerts")
+		}
+
+		q.mu.Lock()
+		q.depth -= len(batch.alerts)
+		metrics.UpdateIngestionQueueDepth(float64(q.depth))
+		q.mu.Unlock()
+	}
+}
@@ -7,18 +7,22 @@ import (
 
 type AlertService interface {
 	ReceiveAlerts(ctx context.Context, alerts []models.PrometheusAlert) error
+	IngestAlerts(ctx context.Context, alerts []models.PrometheusAlert) []models.AlertIngestResult
 	GetAlert(ctx context.Context, fingerprint string) (*models.Alert, error)
 	ListAlerts(ctx context.Context, filters models.AlertFilters) ([]models.Alert, int64, error)
-	SilenceAlert(ctx context.Context, fingerprint string, duration string, comment string) error
+	SilenceAlert(ctx context.Context, fingerprint string, duration string, comment string, creator string) (*models.Silence, error)
 	AcknowledgeAlert(ctx context.Context, fingerprint string, comment string) error
 	ResolveAlert(ctx context.Context, fingerprint string, comment string) error
-	BatchSilenceAlerts(ctx context.Context, fingerprints []string, duration string, comment string) error
+	BatchSilenceAlerts(ctx context.Context, fingerprints []string, duration string, comment string, creator string) ([]models.Silence, error)
+	ReleaseExpiredSilences(ctx context.Context) (int, error)
 	BatchAcknowledgeAlerts(ctx context.Context, fingerprints []string, comment string) error
 	BatchResolveAlerts(ctx context.Context, fingerprints []string, comment string) error
 	GetAlertHistory(ctx context.Context, fingerprint string) ([]models.AlertHistory, error)
 	ListAlertHistory(ctx context.Context, filters models.AlertHistoryFilters) ([]models.AlertHistory, int64, error)
 	GetAlertRelations(ctx context.Context, fingerprint string) (*models.AlertRelations, error)
 	UpdateDeduplicationConfig(ctx context.Context, config models.DeduplicationConfig) error
+	GetDeduplicationConfig(ctx context.Context) (*models.DeduplicationConfig, error)
+	DryRunDeduplication(ctx context.Context, req models.DeduplicationDryRunRequest) (*models.DeduplicationDryRunResult, error)
 }
 
 type RoutingRuleService interface {
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/metrics"
+	"alertbot/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Ingestion stage names, usable in the pipeline.stages setting
+const (
+	StageNormalize = "normalize"
+	StageHeartbeat = "heartbeat"
+	StageEnrich    = "enrich"
+	StageDedup     = "dedup"
+	StageSilence   = "silence"
+	StageInhibit   = "inhibit"
+	StageStore     = "store"
+	StageGroup     = "group"
+	StageRoute     = "route"
+	StageNotify    = "notify"
+	StageBroadcast = "broadcast"
+)
+
+// DefaultPipelineStages is the stage order used when none is configured
+var DefaultPipelineStages = []string{
+	StageNormalize,
+	StageHeartbeat,
+	StageEnrich,
+	StageDedup,
+	StageSilence,
+	StageInhibit,
+	StageStore,
+	StageGroup,
+	StageRoute,
+	StageNotify,
+	StageBroadcast,
+}
+
+// stageDependencies lists the stages that must run before a stage. Every
+// stage reads the alert built by normalize, and the stages after store
+// need the stored row.
+var stageDependencies = map[string][]string{
+	StageHeartbeat: {StageNormalize},
+	StageEnrich:    {StageNormalize},
+	StageDedup:     {StageNormalize},
+	StageSilence:   {StageNormalize},
+	StageInhibit:   {StageNormalize},
+	StageStore:     {StageNormalize},
+	StageGroup:     {StageNormalize, StageStore},
+	StageRoute:     {StageNormalize, StageStore},
+	StageNotify:    {StageNormalize, StageStore},
+	StageBroadcast: {StageNormalize, StageStore},
+}
+
+// Stage is one step of alert ingestion. Stages process a whole batch, so
+// they can share lookups and write in bulk, and read and update each
+// IngestAlert passed along the pipeline.
+type Stage interface {
+	Name() string
+	// Required stages stop an alert's processing when they fail it; the
+	// pipeline continues past other failed stages
+	Required() bool
+	// Process fails single alerts with IngestAlert.Fail; an error fails
+	// every pending alert of the batch
+	Process(ctx context.Context, batch *IngestBatch) error
+}
+
+// IngestBatch carries the alerts received together through the pipeline
+type IngestBatch struct {
+	Alerts []*IngestAlert
+}
+
+// Pending returns the alerts no required stage has failed or dropped
+func (b *IngestBatch) Pending() []*IngestAlert {
+	pending := make([]*IngestAlert, 0, len(b.Alerts))
+	for _, alert := range b.Alerts {
+		if alert.Err == nil && !alert.Dropped {
+			pending = append(pending, alert)
+		}
+	}
+	return pending
+}
+
+// IngestAlert carries one received alert through the pipeline
+type IngestAlert struct {
+	Raw models.PrometheusAlert
+	// Fingerprint identifies the received alert, even once Alert is
+	// replaced by the alert it duplicates
+	Fingerprint string
+
+	// Alert is the normalized alert, replaced by the stored row once the
+	// store stage ran
+	Alert *models.Alert
+
+	// Action is what ingestion did with the alert: created, updated,
+	// deduplicated or dropped
+	Action string
+	// Dropped is set when a relabel config discarded the alert
+	Dropped bool
+
+	Dedup     *engine.DeduplicationResult
+	Duplicate bool
+	// Merged is set when a duplicate changed the alert it duplicates
+	Merged bool
+
+	// Reasons not to notify, set by the silence and inhibit stages
+	SilenceID    uint
+	InhibitionID uint
+	RootCause    *models.RootCauseCandidate
+
+	Incident *models.Incident
+	// Notify is false when the alert's incident was already notified
+	Notify bool
+
+	Group *models.AlertGroup
+	Rules []models.RoutingRule
+
+	// History is written by the store stage together with the alert
+	History []*models.AlertHistory
+
+	// Err is the error of the required stage that stopped processing
+	Err         error
+	FailedStage string
+
+	// stageErr is the error of the running stage
+	stageErr error
+}
+
+// Fail records that the running stage failed the alert
+func (a *IngestAlert) Fail(err error) {
+	a.stageErr = err
+}
+
+// addHistory queues a history entry for the store stage
+func (a *IngestAlert) addHistory(fingerprint, action string, details models.JSONB) {
+	a.History = append(a.History, &models.AlertHistory{
+		AlertFingerprint: fingerprint,
+		Action:           action,
+		Details:          details,
+	})
+}
+
+// Suppressed reports whether a silence, inhibition or upstream root cause
+// keeps the alert from being routed
+func (a *IngestAlert) Suppressed() bool {
+	return a.SilenceID != 0 || a.InhibitionID != 0 || a.RootCause != nil
+}
+
+// Pipeline runs received alerts through an ordered list of stages
+type Pipeline struct {
+	stages []Stage
+	logger *logrus.Logger
+}
+
+// NewPipeline builds a pipeline from stage names, looked up in available.
+// Unknown and repeated names are skipped; without every required stage, or
+// with a stage before one it depends on, the default order is used instead.
+func NewPipeline(names []string, available map[string]Stage, logger *logrus.Logger) *Pipeline {
+	if len(names) == 0 {
+		names = DefaultPipelineStages
+	}
+
+	stages, err := selectStages(names, available, logger)
+	if err != nil {
+		logger.WithError(err).WithField("stages", names).Error("Invalid ingestion pipeline, using the default stages")
+		stages, _ = selectStages(DefaultPipelineStages, available, logger)
+	}
+
+	return &Pipeline{stages: stages, logger: logger}
+}
+
+func selectStages(names []string, available map[string]Stage, logger *logrus.Logger) ([]Stage, error) {
+	stages := make([]Stage, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		stage, ok := available[name]
+		if !ok {
+			logger.WithField("stage", name).Warn("Skipping unknown ingestion stage")
+			continue
+		}
+		if seen[name] {
+			logger.WithField("stage", name).Warn("Skipping repeated ingestion stage")
+			continue
+		}
+		seen[name] = true
+		stages = append(stages, stage)
+	}
+
+	for name, stage := range available {
+		if stage.Required() && !seen[name] {
+			return nil, fmt.Errorf("required stage %q is missing", name)
+		}
+	}
+
+	position := make(map[string]int, len(stages))
+	for i, stage := range stages {
+		position[stage.Name()] = i
+	}
+	for i, stage := range stages {
+		for _, dependency := range stageDependencies[stage.Name()] {
+			if at, ok := position[dependency]; ok && at > i {
+				return nil, fmt.Errorf("stage %q must run after %q", stage.Name(), dependency)
+			}
+		}
+	}
+	return stages, nil
+}
+
+// Stages returns the names of the stages in the order they run
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.Name()
+	}
+	return names
+}
+
+// Process runs the alerts through every stage in order as one batch
+func (p *Pipeline) Process(ctx context.Context, alerts []models.PrometheusAlert) []*IngestAlert {
+	batch := &IngestBatch{Alerts: make([]*IngestAlert, len(alerts))}
+	for i, raw := range alerts {
+		batch.Alerts[i] = &IngestAlert{Raw: raw, Notify: true}
+	}
+
+	for _, stage := range p.stages {
+		pending := batch.Pending()
+		if len(pending) == 0 {
+			break
+		}
+
+		start := time.Now()
+		if err := stage.Process(ctx, batch); err != nil {
+			for _, alert := range pending {
+				if alert.stageErr == nil {
+					alert.Fail(err)
+				}
+			}
+		}
+
+		failed := 0
+		for _, alert := range pending {
+			if alert.stageErr != nil {
+				failed++
+				p.stageFailed(stage, alert)
+			}
+		}
+		metrics.RecordPipelineStage(stage.Name(), time.Since(start).Seconds(), failed)
+	}
+
+	return batch.Alerts
+}
+
+// stageFailed logs a stage failure and, for required stages, stops the
+// alert's processing
+func (p *Pipeline) stageFailed(stage Stage, alert *IngestAlert) {
+	err := alert.stageErr
+	alert.stageErr = nil
+
+	entry := p.logger.WithError(err).WithField("stage", stage.Name())
+	if alert.Alert != nil {
+		entry = entry.WithField("alert_fingerprint", alert.Alert.Fingerprint)
+	}
+	if !stage.Required() {
+		entry.Warn("Ingestion stage failed, continuing")
+		return
+	}
+
+	entry.Error("Ingestion stage failed, dropping alert")
+	alert.Err = err
+	alert.FailedStage = stage.Name()
+}
+
+// stageFunc adapts functions to the Stage interface. Stages without a
+// batch function run process for each pending alert.
+type stageFunc struct {
+	name     string
+	required bool
+	process  func(ctx context.Context, alert *IngestAlert) error
+	batch    func(ctx context.Context, batch *IngestBatch) error
+}
+
+func (s *stageFunc) Name() string   { return s.name }
+func (s *stageFunc) Required() bool { return s.required }
+
+func (s *stageFunc) Process(ctx context.Context, batch *IngestBatch) error {
+	if s.batch != nil {
+		return s.batch(ctx, batch)
+	}
+	for _, alert := range batch.Pending() {
+		if err := s.process(ctx, alert); err != nil {
+			alert.Fail(err)
+		}
+	}
+	return nil
+}
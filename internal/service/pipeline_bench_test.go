@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/engine"
+	"alertbot/internal/fingerprint"
+	"alertbot/internal/migration"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newBenchmarkAlertService runs the default pipeline against a freshly
+// migrated SQLite database. It wires only the engines the stages need, so
+// no rule engine or hub goroutine outlives the benchmark
+func newBenchmarkAlertService(b *testing.B) AlertService {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	db, err := repository.NewDatabase(config.Database{
+		Driver: repository.DialectSQLite,
+		Path:   filepath.Join(b.TempDir(), "alertbot.db"),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { sqlDB.Close() })
+
+	if err := migration.NewMigrator(db, log).Migrate(); err != nil {
+		b.Fatal(err)
+	}
+
+	repos := repository.NewRepositories(db)
+	fingerprinter := fingerprint.Default()
+	dedup := engine.NewDeduplicationEngine(repos, log)
+	dedup.SetFingerprinter(fingerprinter)
+
+	return NewAlertService(ServiceDependencies{
+		Repositories:        repos,
+		Logger:              log,
+		Config:              &config.Config{},
+		DeduplicationEngine: dedup,
+		Fingerprinter:       fingerprinter,
+	})
+}
+
+// benchmarkBatch returns size firing alerts with distinct label sets
+func benchmarkBatch(prefix string, size int, summary string) []models.PrometheusAlert {
+	startsAt := time.Now().Add(-time.Minute)
+	alerts := make([]models.PrometheusAlert, size)
+	for i := range alerts {
+		alerts[i] = models.PrometheusAlert{
+			Labels: map[string]string{
+				"alertname": "BenchmarkAlert",
+				"instance":  fmt.Sprintf("%s-%d:9100", prefix, i),
+				"severity":  "warning",
+			},
+			Annotations: map[string]string{"summary": summary},
+			StartsAt:    startsAt,
+		}
+	}
+	return alerts
+}
+
+func ingestBenchmarkBatch(b *testing.B, alerts AlertService, batch []models.PrometheusAlert, action string) {
+	for _, result := range alerts.IngestAlerts(context.Background(), batch) {
+		if result.Action != action {
+			b.Fatalf("alert %d: expected %s, got %s %s", result.Index, action, result.Action, result.Error)
+		}
+	}
+}
+
+// BenchmarkIngestAlerts measures one batch going through the pipeline when
+// its alerts are all new and when they all update stored alerts
+func BenchmarkIngestAlerts(b *testing.B) {
+	for _, size := range []int{1000, 5000} {
+		b.Run(fmt.Sprintf("new/%d", size), func(b *testing.B) {
+			alerts := newBenchmarkAlertService(b)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				batch := benchmarkBatch(fmt.Sprintf("new-%d", i), size, "created")
+				b.StartTimer()
+
+				ingestBenchmarkBatch(b, alerts, batch, "created")
+			}
+		})
+
+		b.Run(fmt.Sprintf("updated/%d", size), func(b *testing.B) {
+			alerts := newBenchmarkAlertService(b)
+			ingestBenchmarkBatch(b, alerts, benchmarkBatch("updated", size, "created"), "created")
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				batch := benchmarkBatch("updated", size, fmt.Sprintf("update %d", i))
+				b.StartTimer()
+
+				ingestBenchmarkBatch(b, alerts, batch, "updated")
+			}
+		})
+	}
+}
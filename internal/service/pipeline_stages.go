@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"alertbot/internal/metrics"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// enrichConcurrency bounds the alerts of a batch enriched at once
+const enrichConcurrency = 16
+
+// ingestionStages returns the stages the alert service provides, by name
+func (s *alertService) ingestionStages() map[string]Stage {
+	stages := []*stageFunc{
+		{name: StageNormalize, required: true, process: s.normalizeStage},
+		{name: StageHeartbeat, batch: s.heartbeatStage},
+		{name: StageEnrich, batch: s.enrichStage},
+		{name: StageDedup, batch: s.dedupStage},
+		{name: StageSilence, batch: s.silenceStage},
+		{name: StageInhibit, batch: s.inhibitStage},
+		{name: StageStore, required: true, batch: s.storeStage},
+		{name: StageGroup, batch: s.groupStage},
+		{name: StageRoute, process: s.routeStage},
+		{name: StageNotify, process: s.notifyStage},
+		{name: StageBroadcast, process: s.broadcastStage},
+	}
+
+	available := make(map[string]Stage, len(stages))
+	for _, stage := range stages {
+		available[stage.name] = stage
+	}
+	return available
+}
+
+// normalizeStage relabels the received labels and converts the Prometheus
+// payload into an alert. Alerts discarded by a drop or keep rule go no
+// further.
+func (s *alertService) normalizeStage(ctx context.Context, a *IngestAlert) error {
+	if s.deps.RelabelEngine != nil {
+		labels, keep := s.deps.RelabelEngine.Current().Process(a.Raw.Labels)
+		if !keep {
+			a.Dropped = true
+			a.Action = "dropped"
+			metrics.RecordRelabelDropped()
+			return nil
+		}
+		a.Raw.Labels = labels
+	}
+
+	a.Alert = s.convertPrometheusAlert(a.Raw)
+	a.Fingerprint = a.Alert.Fingerprint
+	metrics.RecordAlertReceived(a.Alert.Status, a.Alert.Severity)
+	return nil
+}
+
+// heartbeatStage records pings of heartbeats matched by firing alerts. It
+// runs after normalize, so matchers see the relabeled labels.
+func (s *alertService) heartbeatStage(ctx context.Context, batch *IngestBatch) error {
+	return s.recordHeartbeatPings(batch.Pending())
+}
+
+// enrichStage adds labels and annotations from the enrichment rules before
+// alerts are deduplicated and routed. Alerts are enriched concurrently, as
+// lookups may wait on a remote service.
+func (s *alertService) enrichStage(ctx context.Context, batch *IngestBatch) error {
+	if !s.deps.Enricher.Enabled() {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, enrichConcurrency)
+	for _, a := range batch.Pending() {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(a *IngestAlert) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := s.deps.Enricher.Enrich(ctx, a.Alert); err != nil {
+				a.Fail(err)
+			}
+		}(a)
+	}
+	wg.Wait()
+	return nil
+}
+
+// dedupStage merges alerts into existing ones when the deduplication
+// engine finds a duplicate; later stages skip duplicates. The batch is
+// checked against one index of recent alerts, which also holds the batch's
+// earlier alerts.
+func (s *alertService) dedupStage(ctx context.Context, batch *IngestBatch) error {
+	if s.deps.DeduplicationEngine == nil {
+		return nil
+	}
+
+	idx, err := s.deps.DeduplicationEngine.NewIndex(ctx)
+	if err != nil {
+		return fmt.Errorf("deduplication failed: %w", err)
+	}
+
+	for _, a := range batch.Pending() {
+		result, err := s.deps.DeduplicationEngine.ProcessAlertWithIndex(ctx, a.Alert, idx)
+		if err != nil {
+			a.Fail(fmt.Errorf("deduplication failed: %w", err))
+			continue
+		}
+		a.Dedup = result
+
+		if !result.IsDuplicate {
+			idx.Add(a.Alert)
+			continue
+		}
+
+		a.Duplicate = true
+		a.Action = "deduplicated"
+		a.Merged = s.mergeDuplicate(a, result)
+		if result.ExistingAlert != nil {
+			a.Alert = result.ExistingAlert
+		}
+	}
+	return nil
+}
+
+// silenceStage marks alerts covered by an active silence. Firing ones stay
+// in the silenced state, so re-sent alerts do not lift the silence early.
+func (s *alertService) silenceStage(ctx context.Context, batch *IngestBatch) error {
+	silences, err := s.activeSilences()
+	if err != nil {
+		return fmt.Errorf("failed to get silences: %w", err)
+	}
+
+	for _, a := range batch.Pending() {
+		if a.Duplicate {
+			continue
+		}
+		if silenced, silenceID := matchSilence(silences, a.Alert); silenced {
+			a.SilenceID = silenceID
+			if a.Alert.Status == string(models.AlertStatusFiring) {
+				a.Alert.Status = string(models.AlertStatusSilenced)
+			}
+		}
+	}
+	return nil
+}
+
+// inhibitStage marks alerts inhibited by another active alert or caused by
+// an upstream alert in the topology
+func (s *alertService) inhibitStage(ctx context.Context, batch *IngestBatch) error {
+	inhibitions, err := s.deps.Repositories.Inhibition.List()
+	if err != nil {
+		return fmt.Errorf("failed to get inhibitions: %w", err)
+	}
+
+	for _, a := range batch.Pending() {
+		if a.Duplicate {
+			continue
+		}
+		if inhibited, inhibitionID := s.matchInhibition(ctx, inhibitions, a.Alert); inhibited {
+			a.InhibitionID = inhibitionID
+		}
+		a.RootCause = s.topologyRootCause(ctx, a.Alert)
+	}
+	return nil
+}
+
+// storeStage writes the batch in one transaction: new alerts are created,
+// ones with a stored fingerprint updated, changes from duplicates applied,
+// alerts attached to their incidents and groups, and the history of all of
+// them inserted.
+func (s *alertService) storeStage(ctx context.Context, batch *IngestBatch) error {
+	pending := batch.Pending()
+	now := time.Now()
+
+	fingerprints := make([]string, 0, len(pending))
+	for _, a := range pending {
+		if !a.Duplicate {
+			fingerprints = append(fingerprints, a.Alert.Fingerprint)
+		}
+	}
+	stored, err := s.deps.Repositories.Alert.ListByFingerprints(fingerprints)
+	if err != nil {
+		return fmt.Errorf("failed to look up alerts: %w", err)
+	}
+	existing := make(map[string]*models.Alert, len(stored))
+	for i := range stored {
+		existing[stored[i].Fingerprint] = &stored[i]
+	}
+
+	// One row per fingerprint; alerts repeated in the batch update the row
+	// of their first occurrence
+	rows := make(map[string]*models.Alert)
+	var order []*models.Alert
+	addRow := func(row *models.Alert) {
+		rows[row.Fingerprint] = row
+		order = append(order, row)
+	}
+
+	for _, a := range pending {
+		alert := a.Alert
+		row, seen := rows[alert.Fingerprint]
+
+		if a.Duplicate {
+			switch {
+			case !a.Merged:
+			case seen && row != alert:
+				row.Status = alert.Status
+				row.Severity = alert.Severity
+				row.Annotations = alert.Annotations
+				a.Alert = row
+			case !seen && alert.ID != 0:
+				addRow(alert)
+			}
+			continue
+		}
+
+		if !seen {
+			row = existing[alert.Fingerprint]
+		}
+		if row == nil {
+			a.Action = "created"
+			a.addHistory(alert.Fingerprint, "created", models.JSONB{"status": alert.Status, "severity": alert.Severity})
+			if a.Dedup != nil {
+				a.addHistory(alert.Fingerprint, "deduplication_processed", deduplicationMetadata(a.Dedup))
+			}
+			addRow(alert)
+			continue
+		}
+
+		// Labels outside the fingerprint, and the severity read from
+		// them, may have changed since the row was stored
+		row.Labels = alert.Labels
+		row.Severity = alert.Severity
+		row.Status = alert.Status
+		row.Annotations = alert.Annotations
+		row.EndsAt = alert.EndsAt
+		row.UpdatedAt = now
+		if !seen {
+			addRow(row)
+		}
+
+		a.Alert = row
+		a.Action = "updated"
+		a.addHistory(row.Fingerprint, "updated", models.JSONB{"status": row.Status})
+	}
+
+	// Stored rows are upserted by fingerprint from copies without their ID.
+	// A row leaving the silenced state drops the status a manual silence
+	// saved, so a later silence does not restore it.
+	upserts := make([]*models.Alert, len(order))
+	for i, row := range order {
+		if row.Status != string(models.AlertStatusSilenced) {
+			row.SilencedStatus = ""
+		}
+		if row.ID == 0 {
+			upserts[i] = row
+			continue
+		}
+		upsert := *row
+		upsert.ID = 0
+		upserts[i] = &upsert
+	}
+
+	var histories []*models.AlertHistory
+	for _, a := range pending {
+		histories = append(histories, a.History...)
+	}
+
+	err = s.deps.Repositories.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Alert.Upsert(upserts); err != nil {
+			return fmt.Errorf("failed to store alerts: %w", err)
+		}
+		histories = append(histories, s.storeIncidents(tx, pending)...)
+		if s.grouping {
+			s.storeGroups(ctx, tx, pending)
+		}
+		if err := tx.AlertHistory.CreateBatch(histories); err != nil {
+			return fmt.Errorf("failed to record alert history: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	resolved := make(map[uint]bool)
+	for _, a := range pending {
+		metrics.RecordAlertProcessed(a.Action, a.Alert.Status)
+
+		if a.Action == "updated" && a.Alert.Status == string(models.AlertStatusResolved) &&
+			a.Incident != nil && !resolved[a.Incident.ID] {
+			resolved[a.Incident.ID] = true
+			s.resolveIncidentIfAllResolved(a.Incident)
+		}
+	}
+	return nil
+}
+
+// storeIncidents links the batch's alerts to incidents within the store
+// transaction and returns the history entries to record. Failures only
+// roll back the incident changes and are logged, so the alerts are still
+// stored.
+func (s *alertService) storeIncidents(tx *repository.Repositories, pending []*IngestAlert) []*models.AlertHistory {
+	var history []*models.AlertHistory
+	err := tx.Transaction(func(savepoint *repository.Repositories) error {
+		var err error
+		history, err = s.linkIncidents(savepoint, pending)
+		return err
+	})
+	if err != nil {
+		s.deps.Logger.WithError(err).WithField("alerts", len(pending)).Error("Failed to link alerts to incidents")
+		for _, a := range pending {
+			a.Incident = nil
+			a.Notify = true
+		}
+		return nil
+	}
+	return history
+}
+
+// storeGroups adds the batch's new alerts to their alert groups within the
+// store transaction. Failures only roll back the group changes and are
+// logged, so the alerts are still stored.
+func (s *alertService) storeGroups(ctx context.Context, tx *repository.Repositories, pending []*IngestAlert) {
+	var created []*models.Alert
+	for _, a := range pending {
+		if a.Action == "created" {
+			created = append(created, a.Alert)
+		}
+	}
+	if len(created) == 0 {
+		return
+	}
+
+	var groups map[string]*models.AlertGroup
+	err := tx.Transaction(func(savepoint *repository.Repositories) error {
+		var err error
+		groups, err = NewAlertGroupService(savepoint.AlertGroup, savepoint.Alert, s.deps.Logger).GroupAlerts(ctx, created)
+		return err
+	})
+	if err != nil {
+		s.deps.Logger.WithError(err).WithField("alerts", len(created)).Error("Failed to group alerts")
+		return
+	}
+	for _, a := range pending {
+		if a.Action == "created" {
+			a.Group = groups[a.Alert.Fingerprint]
+		}
+	}
+}
+
+// groupStage enables alert grouping. New alerts are added to their groups
+// by the store stage, inside its transaction, when this stage is listed.
+func (s *alertService) groupStage(ctx context.Context, batch *IngestBatch) error {
+	return nil
+}
+
+// routeStage finds the routing rules for alerts that should notify
+func (s *alertService) routeStage(ctx context.Context, a *IngestAlert) error {
+	if a.Duplicate || !a.Notify || s.deps.RuleEngine == nil {
+		return nil
+	}
+
+	if a.Suppressed() {
+		s.deps.Logger.WithFields(logrus.Fields{
+			"alert_fingerprint": a.Alert.Fingerprint,
+			"silence_id":        a.SilenceID,
+			"inhibition_id":     a.InhibitionID,
+			"root_cause":        a.RootCause != nil,
+		}).Info("Alert is suppressed, skipping notification")
+		return nil
+	}
+
+	rules, err := s.deps.RuleEngine.MatchAlert(ctx, a.Alert)
+	if err != nil {
+		return fmt.Errorf("failed to match routing rules: %w", err)
+	}
+	if len(rules) == 0 {
+		s.deps.Logger.WithField("alert_fingerprint", a.Alert.Fingerprint).Debug("No routing rules matched alert")
+	}
+	a.Rules = rules
+	return nil
+}
+
+// notifyStage sends the alert to the channels of its routing rules
+func (s *alertService) notifyStage(ctx context.Context, a *IngestAlert) error {
+	for _, rule := range a.Rules {
+		s.deps.Logger.WithFields(logrus.Fields{
+			"alert_fingerprint": a.Alert.Fingerprint,
+			"rule_id":           rule.ID,
+			"rule_name":         rule.Name,
+		}).Info("Alert matched routing rule")
+
+		go s.sendRuleNotifications(context.Background(), a.Alert, rule)
+	}
+	return nil
+}
+
+// broadcastStage pushes the alert to streaming clients
+func (s *alertService) broadcastStage(ctx context.Context, a *IngestAlert) error {
+	if s.deps.WebSocketHub == nil || a.Action == "" {
+		return nil
+	}
+	if a.Duplicate && (a.Dedup == nil || a.Dedup.Action == "ignore" || a.Dedup.ExistingAlert == nil) {
+		return nil
+	}
+
+	s.deps.WebSocketHub.BroadcastAlertUpdate(a.Alert, a.Action)
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"alertbot/internal/config"
+	"alertbot/internal/enrichment"
+	"alertbot/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStages provides every default stage as a no-op
+func testStages() map[string]Stage {
+	available := make(map[string]Stage, len(DefaultPipelineStages))
+	for _, name := range DefaultPipelineStages {
+		available[name] = &stageFunc{
+			name:     name,
+			required: name == StageNormalize || name == StageStore,
+			batch:    func(context.Context, *IngestBatch) error { return nil },
+		}
+	}
+	return available
+}
+
+func TestNewPipelineStageOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	tests := []struct {
+		name   string
+		stages []string
+		want   []string
+	}{
+		{"default", nil, DefaultPipelineStages},
+		{"minimal", []string{StageNormalize, StageStore}, []string{StageNormalize, StageStore}},
+		{
+			"reordered",
+			[]string{StageNormalize, StageInhibit, StageDedup, StageStore, StageBroadcast, StageRoute},
+			[]string{StageNormalize, StageInhibit, StageDedup, StageStore, StageBroadcast, StageRoute},
+		},
+		{
+			"unknown and repeated stages skipped",
+			[]string{StageNormalize, "bogus", StageStore, StageStore},
+			[]string{StageNormalize, StageStore},
+		},
+		{"required stage missing", []string{StageNormalize, StageRoute}, DefaultPipelineStages},
+		{"store before normalize", []string{StageStore, StageNormalize, StageRoute}, DefaultPipelineStages},
+		{"enrich before normalize", []string{StageEnrich, StageNormalize, StageStore}, DefaultPipelineStages},
+		{"group before store", []string{StageNormalize, StageGroup, StageStore}, DefaultPipelineStages},
+		{"notify before store", []string{StageNormalize, StageNotify, StageStore}, DefaultPipelineStages},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := NewPipeline(tt.stages, testStages(), logger)
+			assert.Equal(t, tt.want, pipeline.Stages())
+		})
+	}
+}
+
+func TestEnrichLookupFailureKeepsAlert(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	release := make(chan struct{})
+	cmdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(cmdb.Close)
+	t.Cleanup(func() { close(release) })
+
+	runbooks := filepath.Join(t.TempDir(), "runbooks.csv")
+	require.NoError(t, os.WriteFile(runbooks, []byte("alertname,runbook\nDiskFull,https://runbooks/disk\n"), 0o600))
+
+	s := &alertService{deps: ServiceDependencies{
+		Logger: logger,
+		Enricher: enrichment.New([]config.EnrichmentRule{
+			{Name: "cmdb", URL: cmdb.URL + "/services/{service}", Timeout: 20, Labels: map[string]string{"team": "team"}},
+			{Name: "runbooks", Table: runbooks, KeyLabels: []string{"alertname"}, Annotations: map[string]string{"runbook_url": "runbook"}},
+		}, logger),
+	}}
+
+	var stored []*models.Alert
+	stages := s.ingestionStages()
+	stages[StageStore] = &stageFunc{name: StageStore, required: true, batch: func(ctx context.Context, batch *IngestBatch) error {
+		for _, a := range batch.Pending() {
+			stored = append(stored, a.Alert)
+		}
+		return nil
+	}}
+	pipeline := NewPipeline([]string{StageNormalize, StageEnrich, StageStore}, stages, logger)
+
+	results := pipeline.Process(context.Background(), []models.PrometheusAlert{{
+		Labels:   map[string]string{"alertname": "DiskFull", "service": "checkout"},
+		StartsAt: time.Now(),
+	}})
+	require.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.Empty(t, results[0].FailedStage)
+
+	// The alert is stored with what the other rules added
+	require.Len(t, stored, 1)
+	assert.Equal(t, "https://runbooks/disk", stored[0].Annotations["runbook_url"])
+	assert.NotContains(t, stored[0].Labels, "team")
+}
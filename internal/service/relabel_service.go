@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/fingerprint"
+	"alertbot/internal/models"
+	"alertbot/internal/relabel"
+	"alertbot/internal/repository"
+)
+
+// maxRelabelTestAlerts bounds the label sets one test request may run
+const maxRelabelTestAlerts = 1000
+
+type RelabelService interface {
+	GetConfigs(ctx context.Context) (*models.RelabelConfigList, error)
+	UpdateConfigs(ctx context.Context, list *models.RelabelConfigList) error
+	// Test shows what the configs do to the label sets, without storing them
+	Test(ctx context.Context, req models.RelabelTestRequest) ([]models.RelabelTestResult, error)
+}
+
+type relabelService struct {
+	repos         *repository.Repositories
+	relabel       *engine.RelabelEngine
+	fingerprinter *fingerprint.Strategy
+}
+
+func NewRelabelService(repos *repository.Repositories, relabel *engine.RelabelEngine, fingerprinter *fingerprint.Strategy) RelabelService {
+	return &relabelService{
+		repos:         repos,
+		relabel:       relabel,
+		fingerprinter: fingerprinter,
+	}
+}
+
+func (s *relabelService) GetConfigs(ctx context.Context) (*models.RelabelConfigList, error) {
+	return s.repos.Settings.GetRelabelConfigs()
+}
+
+// UpdateConfigs validates and stores the configs, then applies them on this
+// replica immediately; other replicas pick them up on their next refresh
+func (s *relabelService) UpdateConfigs(ctx context.Context, list *models.RelabelConfigList) error {
+	if list.Configs == nil {
+		list.Configs = []models.RelabelConfig{}
+	}
+	rules, err := relabel.Compile(list.Configs)
+	if err != nil {
+		return &ValidationError{Field: "configs", Message: err.Error()}
+	}
+
+	if err := s.repos.Settings.UpdateRelabelConfigs(list); err != nil {
+		return fmt.Errorf("failed to save relabel configs: %w", err)
+	}
+	s.relabel.Set(rules)
+	return nil
+}
+
+func (s *relabelService) Test(ctx context.Context, req models.RelabelTestRequest) ([]models.RelabelTestResult, error) {
+	if len(req.Alerts) > maxRelabelTestAlerts {
+		return nil, &ValidationError{Field: "alerts", Message: fmt.Sprintf("At most %d label sets can be tested at once", maxRelabelTestAlerts)}
+	}
+
+	rules := s.relabel.Current()
+	if req.Configs != nil {
+		var err error
+		if rules, err = relabel.Compile(req.Configs); err != nil {
+			return nil, &ValidationError{Field: "configs", Message: err.Error()}
+		}
+	}
+
+	results := make([]models.RelabelTestResult, len(req.Alerts))
+	for i, labels := range req.Alerts {
+		result := models.RelabelTestResult{
+			Before:            labels,
+			FingerprintBefore: s.fingerprinter.Fingerprint(labels),
+		}
+		after, keep := rules.Process(labels)
+		if keep {
+			result.After = after
+			result.FingerprintAfter = s.fingerprinter.Fingerprint(after)
+			result.Severity = alertSeverity(after)
+		} else {
+			result.Dropped = true
+		}
+		results[i] = result
+	}
+	return results, nil
+}
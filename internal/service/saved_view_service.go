@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrViewForbidden is returned when a user accesses a private view owned by someone else
+var ErrViewForbidden = errors.New("saved view is not accessible")
+
+type SavedViewService interface {
+	ListViews(ctx context.Context, owner string) ([]models.SavedView, error)
+	GetView(ctx context.Context, id uint, user string) (*models.SavedView, error)
+	CreateView(ctx context.Context, view *models.SavedView) error
+	UpdateView(ctx context.Context, id uint, user string, data *models.SavedView) (*models.SavedView, error)
+	DeleteView(ctx context.Context, id uint, user string) error
+
+	// ApplyView merges the view referenced by filters.ViewID into filters
+	ApplyView(ctx context.Context, filters *models.AlertFilters, user string) error
+}
+
+type savedViewService struct {
+	viewRepo repository.SavedViewRepository
+	logger   *logrus.Logger
+}
+
+func NewSavedViewService(viewRepo repository.SavedViewRepository, logger *logrus.Logger) SavedViewService {
+	return &savedViewService{
+		viewRepo: viewRepo,
+		logger:   logger,
+	}
+}
+
+func (s *savedViewService) ListViews(ctx context.Context, owner string) ([]models.SavedView, error) {
+	return s.viewRepo.List(owner)
+}
+
+func (s *savedViewService) GetView(ctx context.Context, id uint, user string) (*models.SavedView, error) {
+	view, err := s.viewRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !view.VisibleTo(user) {
+		return nil, ErrViewForbidden
+	}
+	return view, nil
+}
+
+func (s *savedViewService) CreateView(ctx context.Context, view *models.SavedView) error {
+	if err := s.validateView(view); err != nil {
+		return err
+	}
+	return s.viewRepo.Create(view)
+}
+
+func (s *savedViewService) UpdateView(ctx context.Context, id uint, user string, data *models.SavedView) (*models.SavedView, error) {
+	existing, err := s.viewRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	// Shared views are readable by everyone but only the owner may change them
+	if existing.Owner != user {
+		return nil, ErrViewForbidden
+	}
+
+	existing.Name = data.Name
+	existing.Shared = data.Shared
+	existing.Query = data.Query
+	existing.Status = data.Status
+	existing.Severity = data.Severity
+	existing.Sort = data.Sort
+	existing.Order = data.Order
+	existing.Columns = data.Columns
+
+	if err := s.validateView(existing); err != nil {
+		return nil, err
+	}
+
+	if err := s.viewRepo.Update(existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (s *savedViewService) DeleteView(ctx context.Context, id uint, user string) error {
+	existing, err := s.viewRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if existing.Owner != user {
+		return ErrViewForbidden
+	}
+	return s.viewRepo.Delete(id)
+}
+
+func (s *savedViewService) ApplyView(ctx context.Context, filters *models.AlertFilters, user string) error {
+	if filters.ViewID == 0 {
+		return nil
+	}
+
+	view, err := s.GetView(ctx, filters.ViewID, user)
+	if err != nil {
+		return err
+	}
+
+	view.Apply(filters)
+	return nil
+}
+
+func (s *savedViewService) validateView(view *models.SavedView) error {
+	if view.Name == "" {
+		return &ValidationError{Field: "name", Message: "View name is required"}
+	}
+	if view.Owner == "" {
+		return &ValidationError{Field: "owner", Message: "View owner is required"}
+	}
+	if view.Query != "" {
+		if _, err := matcher.Parse(view.Query); err != nil {
+			return &ValidationError{Field: "query", Message: fmt.Sprintf("Invalid label query: %v", err)}
+		}
+	}
+	if view.Order != "" && view.Order != "asc" && view.Order != "desc" {
+		return &ValidationError{Field: "order", Message: "Order must be asc or desc"}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LeaderCheck reports whether this replica should run singleton jobs
+type LeaderCheck func() bool
+
+// leaderTicker runs a job on a fixed interval until stopped. With a leader
+// check set, ticks are skipped while this replica is not the leader, so
+// only one replica runs the job.
+type leaderTicker struct {
+	name     string
+	logger   *logrus.Logger
+	interval time.Duration
+	job      func(ctx context.Context)
+	// runOnStart runs the job once before the first tick
+	runOnStart bool
+
+	leaderMu sync.Mutex
+	isLeader LeaderCheck
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+func newLeaderTicker(name string, logger *logrus.Logger, interval time.Duration, job func(ctx context.Context)) *leaderTicker {
+	return &leaderTicker{
+		name:     name,
+		logger:   logger,
+		interval: interval,
+		job:      job,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// SetLeaderCheck makes the job skip ticks while isLeader reports false, so
+// only one replica runs it
+func (t *leaderTicker) SetLeaderCheck(isLeader LeaderCheck) {
+	t.leaderMu.Lock()
+	defer t.leaderMu.Unlock()
+	t.isLeader = isLeader
+}
+
+// Start runs the job until Stop is called or the context is cancelled
+func (t *leaderTicker) Start(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running {
+		return
+	}
+
+	t.logger.WithField("interval", t.interval).Infof("Starting %s", t.name)
+	t.running = true
+
+	t.wg.Add(1)
+	go t.run(ctx)
+}
+
+// Stop stops the ticker and waits for a running job to finish
+func (t *leaderTicker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.running {
+		return
+	}
+
+	t.logger.Infof("Stopping %s", t.name)
+	close(t.stopChan)
+	t.wg.Wait()
+	t.running = false
+}
+
+func (t *leaderTicker) run(ctx context.Context) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	if t.runOnStart {
+		t.tick(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+			t.tick(ctx)
+		}
+	}
+}
+
+func (t *leaderTicker) tick(ctx context.Context) {
+	t.leaderMu.Lock()
+	isLeader := t.isLeader
+	t.leaderMu.Unlock()
+
+	if isLeader != nil && !isLeader() {
+		return
+	}
+	t.job(ctx)
+}
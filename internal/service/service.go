@@ -1,8 +1,11 @@
 package service
 
 import (
+	"alertbot/internal/cluster"
 	"alertbot/internal/config"
 	"alertbot/internal/engine"
+	"alertbot/internal/enrichment"
+	"alertbot/internal/fingerprint"
 	"alertbot/internal/notification"
 	"alertbot/internal/repository"
 	"alertbot/internal/websocket"
@@ -19,6 +22,17 @@ type Services struct {
 	AlertGroup       AlertGroupService
 	Inhibition       InhibitionService
 	Settings         SettingsService
+	SavedView        SavedViewService
+	Auth             AuthService
+	Incident         IncidentService
+	Topology         TopologyService
+	Relabel          RelabelService
+	Alertmanager     AlertmanagerService
+	Config           ConfigService
+	Heartbeat        HeartbeatService
+
+	// Ingestion queues received alerts; nil when they are ingested inline
+	Ingestion *IngestionQueue
 }
 
 type ServiceDependencies struct {
@@ -27,8 +41,13 @@ type ServiceDependencies struct {
 	Config              *config.Config
 	RuleEngine          *engine.RuleEngine
 	DeduplicationEngine *engine.DeduplicationEngine
+	TopologyEngine      *engine.TopologyEngine
+	RelabelEngine       *engine.RelabelEngine
 	NotificationManager *notification.NotificationManager
 	WebSocketHub        *websocket.Hub
+	Fingerprinter       *fingerprint.Strategy
+	Enricher            *enrichment.Enricher
+	Cluster             *cluster.Node
 }
 
 func NewServices(deps ServiceDependencies) *Services {
@@ -42,19 +61,71 @@ func NewServices(deps ServiceDependencies) *Services {
 		deps.DeduplicationEngine = engine.NewDeduplicationEngine(deps.Repositories, deps.Logger)
 	}
 	
+	// Initialize topology engine if not provided; causal correlation follows it
+	if deps.TopologyEngine == nil {
+		deps.TopologyEngine = engine.NewTopologyEngine(deps.Repositories, deps.Logger)
+	}
+	deps.DeduplicationEngine.SetTopology(deps.TopologyEngine)
+	
+	// Initialize relabel engine if not provided
+	if deps.RelabelEngine == nil {
+		deps.RelabelEngine = engine.NewRelabelEngine(deps.Repositories, deps.Logger)
+	}
+	
+	// Build the fingerprint strategy from config if not provided
+	if deps.Fingerprinter == nil {
+		if deps.Config != nil {
+			deps.Fingerprinter = fingerprint.New(deps.Config.Fingerprint.IncludeLabels, deps.Config.Fingerprint.ExcludeLabels)
+		} else {
+			deps.Fingerprinter = fingerprint.Default()
+		}
+	}
+	deps.DeduplicationEngine.SetFingerprinter(deps.Fingerprinter)
+	
+	// Build the enrichment rules from config if not provided
+	if deps.Enricher == nil && deps.Config != nil {
+		deps.Enricher = enrichment.New(deps.Config.Enrichment.Rules, deps.Logger)
+	}
+	
 	// Initialize notification manager if not provided
 	if deps.NotificationManager == nil {
 		deps.NotificationManager = notification.NewNotificationManager(deps.Logger)
 	}
 	
+	// Apply configured outbound limits and the persisted notification settings
+	if deps.Config != nil {
+		deps.NotificationManager.SetGlobalRateLimit(deps.Config.RateLimit.NotificationRPS, deps.Config.RateLimit.NotificationBurst)
+	}
+	if notificationConfig, err := deps.Repositories.Settings.GetNotificationConfig(); err != nil {
+		deps.Logger.WithError(err).Warn("Failed to load notification settings, using defaults")
+	} else {
+		deps.NotificationManager.ApplyConfig(notificationConfig)
+	}
+	
+	alerts := NewAlertService(deps)
+
+	var ingestion *IngestionQueue
+	if deps.Config != nil && deps.Config.Ingestion.Async {
+		ingestion = NewIngestionQueue(alerts, deps.Config.Ingestion, deps.Logger)
+	}
+
 	return &Services{
-		Alert:               NewAlertService(deps),
+		Alert:               alerts,
 		RoutingRule:         NewRoutingRuleService(deps),
 		NotificationChannel: NewNotificationChannelService(deps),
 		Silence:             NewSilenceService(deps),
 		Stats:               NewStatsService(deps), // Implemented in stats_service.go
 		AlertGroup:          NewAlertGroupService(deps.Repositories.AlertGroup, deps.Repositories.Alert, deps.Logger),
 		Inhibition:          NewInhibitionService(deps.Repositories.Inhibition, deps.Repositories.Alert, deps.Logger),
-		Settings:            NewSettingsService(deps.Repositories.Settings),
+		Settings:            NewSettingsService(deps.Repositories.Settings, deps.NotificationManager),
+		SavedView:           NewSavedViewService(deps.Repositories.SavedView, deps.Logger),
+		Auth:                NewAuthService(deps.Config, deps.Repositories.RevokedToken, deps.Logger),
+		Incident:            NewIncidentService(deps.Repositories, deps.Logger),
+		Topology:            NewTopologyService(deps.Repositories, deps.TopologyEngine),
+		Relabel:             NewRelabelService(deps.Repositories, deps.RelabelEngine, deps.Fingerprinter),
+		Alertmanager:        NewAlertmanagerService(deps.Repositories, deps.RuleEngine, deps.Logger),
+		Config:              NewConfigService(deps.Repositories, deps.RuleEngine, deps.Logger),
+		Heartbeat:           NewHeartbeatService(deps.Repositories, alerts, deps.Logger),
+		Ingestion:           ingestion,
 	}
 }
\ No newline at end of file
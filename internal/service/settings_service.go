@@ -2,18 +2,21 @@ package service
 
 import (
 	"alertbot/internal/models"
+	"alertbot/internal/notification"
 	"alertbot/internal/repository"
 	"net/url"
 	"time"
 )
 
 type settingsService struct {
-	repo repository.SettingsRepository
+	repo     repository.SettingsRepository
+	notifier *notification.NotificationManager
 }
 
-func NewSettingsService(repo repository.SettingsRepository) SettingsService {
+func NewSettingsService(repo repository.SettingsRepository, notifier *notification.NotificationManager) SettingsService {
 	return &settingsService{
-		repo: repo,
+		repo:     repo,
+		notifier: notifier,
 	}
 }
 
@@ -98,12 +101,35 @@ func (s *settingsService) UpdateNotificationConfig(config *models.NotificationCo
 	if config.RateLimit < 1 || config.RateLimit > 1000 {
 		return &ValidationError{Field: "rate_limit", Message: "Rate limit must be between 1 and 1000"}
 	}
+
+	if config.RateLimitPer == "" {
+		config.RateLimitPer = models.DefaultRateLimitPer
+	}
+	per, err := config.RateLimitInterval()
+	if err != nil || per < time.Second || per > 24*time.Hour {
+		return &ValidationError{Field: "rate_limit_per", Message: "Rate limit interval must be a duration between 1s and 24h"}
+	}
+
+	if config.RateLimitBurst == 0 {
+		config.RateLimitBurst = models.DefaultRateLimitBurst
+	}
+	if config.RateLimitBurst < 1 || config.RateLimitBurst > 1000 {
+		return &ValidationError{Field: "rate_limit_burst", Message: "Rate limit burst must be between 1 and 1000"}
+	}
 	
 	if config.BatchSize < 1 || config.BatchSize > 100 {
 		return &ValidationError{Field: "batch_size", Message: "Batch size must be between 1 and 100"}
 	}
 	
-	return s.repo.UpdateNotificationConfig(config)
+	if err := s.repo.UpdateNotificationConfig(config); err != nil {
+		return err
+	}
+	
+	// Take effect immediately on the running notification manager
+	if s.notifier != nil {
+		s.notifier.ApplyConfig(config)
+	}
+	return nil
 }
 
 // ValidationError represents a validation error
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSilenceCheckInterval is how often silenced alerts are re-evaluated
+const DefaultSilenceCheckInterval = 30 * time.Second
+
+// SilenceScheduler periodically releases alerts whose silences have ended
+type SilenceScheduler struct {
+	*leaderTicker
+
+	alerts AlertService
+	logger *logrus.Logger
+}
+
+func NewSilenceScheduler(alerts AlertService, logger *logrus.Logger, interval time.Duration) *SilenceScheduler {
+	if interval <= 0 {
+		interval = DefaultSilenceCheckInterval
+	}
+	s := &SilenceScheduler{
+		alerts: alerts,
+		logger: logger,
+	}
+	s.leaderTicker = newLeaderTicker("silence scheduler", logger, interval, s.check)
+	// Catch up on silences that ended while the server was down
+	s.leaderTicker.runOnStart = true
+	return s
+}
+
+func (s *SilenceScheduler) check(ctx context.Context) {
+	released, err := s.alerts.ReleaseExpiredSilences(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to release expired silences")
+		return
+	}
+	if released > 0 {
+		s.logger.WithField("released", released).Info("Released alerts from expired silences")
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"alertbot/internal/engine"
+	"alertbot/internal/models"
+	"alertbot/internal/repository"
+)
+
+type TopologyService interface {
+	GetGraph(ctx context.Context) (*models.TopologyGraph, error)
+	UpdateGraph(ctx context.Context, graph *models.TopologyGraph) error
+	// RootCause returns the suspected root cause of an alert from the dependency graph
+	RootCause(ctx context.Context, fingerprint string) (*models.RootCauseAnalysis, error)
+}
+
+type topologyService struct {
+	repos    *repository.Repositories
+	topology *engine.TopologyEngine
+}
+
+func NewTopologyService(repos *repository.Repositories, topology *engine.TopologyEngine) TopologyService {
+	return &topologyService{
+		repos:    repos,
+		topology: topology,
+	}
+}
+
+func (s *topologyService) GetGraph(ctx context.Context) (*models.TopologyGraph, error) {
+	return s.repos.Settings.GetTopologyGraph()
+}
+
+// UpdateGraph validates and stores the graph, then applies it on this replica
+// immediately; other replicas pick it up on their next refresh
+func (s *topologyService) UpdateGraph(ctx context.Context, graph *models.TopologyGraph) error {
+	if graph.Nodes == nil {
+		graph.Nodes = []models.TopologyNode{}
+	}
+	topology, err := engine.NewTopology(graph)
+	if err != nil {
+		return &ValidationError{Field: "nodes", Message: err.Error()}
+	}
+
+	if err := s.repos.Settings.UpdateTopologyGraph(graph); err != nil {
+		return fmt.Errorf("failed to save topology graph: %w", err)
+	}
+	s.topology.Set(topology)
+	return nil
+}
+
+func (s *topologyService) RootCause(ctx context.Context, fingerprint string) (*models.RootCauseAnalysis, error) {
+	alert, err := s.repos.Alert.GetByFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return s.topology.Analyze(ctx, alert)
+}
@@ -3,6 +3,7 @@ package websocket
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,23 +12,25 @@ import (
 )
 
 // NewClient creates a new WebSocket client
-func NewClient(hub *Hub, conn *websocket.Conn, userID uint, username, role string) *Client {
+func NewClient(hub *Hub, conn *websocket.Conn, session *Session) *Client {
 	clientID := uuid.New().String()
 	
 	client := &Client{
 		conn:         conn,
 		send:         make(chan *Message, 256),
+		done:         make(chan struct{}),
 		hub:          hub,
 		id:           clientID,
-		userID:       userID,
-		username:     username,
-		role:         role,
-		filters:      make(map[string]interface{}),
+		userID:       session.UserID,
+		username:     session.Username,
+		role:         session.Role,
+		session:      session,
+		subscriptions: make(map[string]*Subscription),
 		lastActivity: time.Now(),
 		logger: hub.logger.WithFields(logrus.Fields{
 			"client_id": clientID,
-			"user_id":   userID,
-			"username":  username,
+			"user_id":   session.UserID,
+			"username":  session.Username,
 		}),
 	}
 	
@@ -73,13 +76,14 @@ func (c *Client) writePump() {
 	
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.done:
+			// The client was closed by the hub
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+			
+		case message := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
 			
 			// Send the message
 			if err := c.conn.WriteJSON(message); err != nil {
@@ -117,6 +121,8 @@ func (c *Client) handleMessage(messageBytes []byte) {
 		c.handleSubscribe(message)
 	case "unsubscribe":
 		c.handleUnsubscribe(message)
+	case "resume":
+		c.handleResume(message)
 	case "ping":
 		c.handlePing()
 	case "get_filters":
@@ -126,70 +132,200 @@ func (c *Client) handleMessage(messageBytes []byte) {
 	}
 }
 
-// handleSubscribe handles subscription requests from clients
+// handleSubscribe handles subscription requests from clients.
+// Each subscribe message adds or replaces one subscription identified by "id".
 func (c *Client) handleSubscribe(message map[string]interface{}) {
-	filters, ok := message["filters"].(map[string]interface{})
-	if !ok {
-		c.logger.Error("Subscribe message missing or invalid filters")
-		return
+	id, _ := message["id"].(string)
+	if id == "" {
+		id = "default"
 	}
 	
-	// Update client filters
-	for key, value := range filters {
-		c.filters[key] = value
+	var sub *Subscription
+	var err error
+	if viewID, ok := message["view_id"].(float64); ok {
+		sub, err = c.subscriptionForView(id, uint(viewID))
+	} else {
+		filters, ok := message["filters"].(map[string]interface{})
+		if !ok {
+			filters = map[string]interface{}{}
+		}
+		sub, err = NewSubscription(id, filters)
+	}
+	if err != nil {
+		c.logger.WithError(err).WithField("subscription_id", id).Warn("Rejected subscription")
+		c.SendMessage("subscription_error", map[string]interface{}{
+			"id":    id,
+			"error": err.Error(),
+		})
+		return
 	}
 	
-	c.logger.WithField("filters", c.filters).Debug("Client subscription updated")
+	c.subMutex.Lock()
+	c.subscriptions[id] = sub
+	c.subMutex.Unlock()
+	
+	c.logger.WithField("subscription", sub).Debug("Client subscription updated")
 	
 	// Send confirmation
 	response := &Message{
 		Type: "subscription_updated",
 		Data: map[string]interface{}{
-			"filters": c.filters,
+			"subscription":  sub,
+			"subscriptions": c.Subscriptions(),
+			"epoch":         c.hub.bus.Epoch(),
+			"seq":           c.hub.bus.CurrentSeq(),
 		},
 		Timestamp: time.Now(),
 	}
 	
-	select {
-	case c.send <- response:
-	default:
+	if !c.trySend(response) {
 		c.logger.Warn("Failed to send subscription confirmation")
+		return
+	}
+	
+	// Reconnecting clients resume from their last epoch and sequence
+	// number, new ones get a snapshot of the alerts currently matching
+	if lastSeq, ok := message["last_seq"].(float64); ok {
+		epoch, _ := message["epoch"].(string)
+		c.replay(epoch, uint64(lastSeq))
+		return
+	}
+	if snapshot, ok := message["snapshot"].(bool); !ok || snapshot {
+		c.sendSnapshot(sub)
+	}
+}
+
+// subscriptionForView builds a subscription from a saved view the client may access
+func (c *Client) subscriptionForView(id string, viewID uint) (*Subscription, error) {
+	if c.hub.viewResolver == nil {
+		return nil, fmt.Errorf("saved views are not available")
+	}
+	
+	view, err := c.hub.viewResolver(viewID)
+	if err != nil {
+		return nil, fmt.Errorf("saved view %d not found", viewID)
+	}
+	if !view.VisibleTo(c.username) {
+		return nil, fmt.Errorf("saved view %d is not accessible", viewID)
 	}
+	
+	return SubscriptionFromView(id, view)
 }
 
-// handleUnsubscribe handles unsubscription requests from clients
+// handleUnsubscribe removes the subscription named by "id", or all of them
 func (c *Client) handleUnsubscribe(message map[string]interface{}) {
-	filterKeys, ok := message["filter_keys"].([]interface{})
-	if !ok {
-		// Clear all filters if no specific keys provided
-		c.filters = make(map[string]interface{})
+	c.subMutex.Lock()
+	if id, ok := message["id"].(string); ok && id != "" {
+		delete(c.subscriptions, id)
 	} else {
-		// Remove specific filter keys
-		for _, keyInterface := range filterKeys {
-			if key, ok := keyInterface.(string); ok {
-				delete(c.filters, key)
-			}
-		}
+		c.subscriptions = make(map[string]*Subscription)
 	}
+	c.subMutex.Unlock()
 	
-	c.logger.WithField("filters", c.filters).Debug("Client unsubscribed from filters")
+	c.logger.Debug("Client unsubscribed")
 	
 	// Send confirmation
 	response := &Message{
 		Type: "unsubscription_confirmed",
 		Data: map[string]interface{}{
-			"filters": c.filters,
+			"subscriptions": c.Subscriptions(),
 		},
 		Timestamp: time.Now(),
 	}
 	
-	select {
-	case c.send <- response:
-	default:
+	if !c.trySend(response) {
 		c.logger.Warn("Failed to send unsubscription confirmation")
 	}
 }
 
+// handleResume replays events missed since the given epoch and sequence number
+func (c *Client) handleResume(message map[string]interface{}) {
+	lastSeq, ok := message["last_seq"].(float64)
+	if !ok {
+		c.logger.Error("Resume message missing last_seq")
+		return
+	}
+	epoch, _ := message["epoch"].(string)
+	c.replay(epoch, uint64(lastSeq))
+}
+
+// replay sends retained messages after lastSeq that match the client's subscriptions.
+// If the position is from another bus epoch, such as before a restart or on
+// another replica, or the history no longer reaches back that far, the
+// client is told to resync and receives fresh snapshots instead.
+func (c *Client) replay(epoch string, lastSeq uint64) {
+	messages, ok := c.hub.messagesSince(epoch, lastSeq)
+	if ok && len(messages) < cap(c.send)-len(c.send) {
+		for _, message := range messages {
+			matched, send := c.hub.shouldSendToClient(c, message)
+			if !send {
+				continue
+			}
+			out := *message
+			out.Subscriptions = matched
+			if !c.trySend(&out) {
+				c.logger.Warn("Send buffer full during replay")
+				return
+			}
+		}
+		return
+	}
+	
+	c.SendMessage("resync_required", map[string]interface{}{
+		"last_epoch": epoch,
+		"last_seq":   lastSeq,
+		"epoch":      c.hub.bus.Epoch(),
+		"seq":        c.hub.bus.CurrentSeq(),
+	})
+	for _, sub := range c.Subscriptions() {
+		c.sendSnapshot(sub)
+	}
+}
+
+// sendSnapshot sends the alerts currently matching a subscription
+func (c *Client) sendSnapshot(sub *Subscription) {
+	if c.hub.alertLister == nil {
+		return
+	}
+	
+	// Take the sequence first so the client can discard older live events
+	seq := c.hub.bus.CurrentSeq()
+	
+	filters := c.session.Restrict(sub.AlertFilters())
+	filters.Size = snapshotLimit
+	alerts, total, err := c.hub.alertLister(filters)
+	if err != nil {
+		c.logger.WithError(err).WithField("subscription_id", sub.ID).Error("Failed to load subscription snapshot")
+		return
+	}
+	
+	if err := c.SendMessage("snapshot", map[string]interface{}{
+		"subscription_id": sub.ID,
+		"alerts":          alerts,
+		"total":           total,
+		"truncated":       total > int64(len(alerts)),
+		"epoch":           c.hub.bus.Epoch(),
+		"seq":             seq,
+	}); err != nil {
+		c.logger.WithError(err).Warn("Failed to send subscription snapshot")
+	}
+}
+
+// Subscriptions returns the client's subscriptions ordered by ID
+func (c *Client) Subscriptions() []*Subscription {
+	c.subMutex.RLock()
+	defer c.subMutex.RUnlock()
+	
+	subs := make([]*Subscription, 0, len(c.subscriptions))
+	for _, sub := range c.subscriptions {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].ID < subs[j].ID
+	})
+	return subs
+}
+
 // handlePing handles ping requests from clients
 func (c *Client) handlePing() {
 	response := &Message{
@@ -200,9 +336,7 @@ func (c *Client) handlePing() {
 		Timestamp: time.Now(),
 	}
 	
-	select {
-	case c.send <- response:
-	default:
+	if !c.trySend(response) {
 		c.logger.Warn("Failed to send pong response")
 	}
 }
@@ -212,14 +346,12 @@ func (c *Client) handleGetFilters() {
 	response := &Message{
 		Type: "current_filters",
 		Data: map[string]interface{}{
-			"filters": c.filters,
+			"subscriptions": c.Subscriptions(),
 		},
 		Timestamp: time.Now(),
 	}
 	
-	select {
-	case c.send <- response:
-	default:
+	if !c.trySend(response) {
 		c.logger.Warn("Failed to send current filters")
 	}
 }
@@ -242,14 +374,39 @@ func (c *Client) SendMessage(messageType string, data interface{}) error {
 		Timestamp: time.Now(),
 	}
 	
+	if !c.trySend(message) {
+		return fmt.Errorf("client send channel is full or closed")
+	}
+	return nil
+}
+
+// trySend queues a message without blocking. It fails once the client is
+// closed or while its buffer is full. c.send is never closed, so senders
+// on any goroutine cannot race with shutdown.
+func (c *Client) trySend(message *Message) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	
 	select {
 	case c.send <- message:
-		return nil
+		return true
+	case <-c.done:
+		return false
 	default:
-		return fmt.Errorf("client send channel is full")
+		return false
 	}
 }
 
+// close signals the write pump to stop; it is safe to call more than once
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
 // GetInfo returns client information
 func (c *Client) GetInfo() map[string]interface{} {
 	return map[string]interface{}{
@@ -257,7 +414,7 @@ func (c *Client) GetInfo() map[string]interface{} {
 		"user_id":       c.userID,
 		"username":      c.username,
 		"role":          c.role,
-		"filters":       c.filters,
+		"subscriptions": c.Subscriptions(),
 		"last_activity": c.lastActivity,
 	}
 }
\ No newline at end of file
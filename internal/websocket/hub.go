@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"alertbot/internal/events"
 	"alertbot/internal/models"
 
 	"github.com/gorilla/websocket"
@@ -23,8 +24,12 @@ type Hub struct {
 	// Unregister requests from clients
 	unregister chan *Client
 
-	// Broadcast messages to all clients
-	broadcast chan *Message
+	// Event bus shared with other streaming transports
+	bus      *events.Bus
+	listener *events.Listener
+
+	// Publishes broadcasts to the bus of every replica
+	broker events.Broker
 
 	// Logger for hub operations
 	logger *logrus.Logger
@@ -32,16 +37,43 @@ type Hub struct {
 	// Mutex for thread-safe operations
 	mutex sync.RWMutex
 
+	// Resolves saved views referenced by subscribe messages
+	viewResolver ViewResolver
+
+	// Loads currently matching alerts for subscription snapshots
+	alertLister AlertLister
+
+	// Reports whether a session token has been revoked
+	revocationChecker RevocationChecker
+
+
 	// Context for graceful shutdown
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
+// ViewResolver loads a saved view by ID
+type ViewResolver func(id uint) (*models.SavedView, error)
+
+// AlertLister lists alerts matching the given filters
+type AlertLister func(filters models.AlertFilters) ([]models.Alert, int64, error)
+
+// RevocationChecker reports whether the token with the given ID was revoked
+type RevocationChecker func(tokenID string) bool
+
 // Message represents a WebSocket message
 type Message struct {
 	Type      string      `json:"type"`
 	Data      interface{} `json:"data"`
 	Timestamp time.Time   `json:"timestamp"`
+
+	// Seq is a monotonically increasing number assigned to broadcast
+	// messages within the bus Epoch; clients resume from both
+	Epoch string `json:"epoch,omitempty"`
+	Seq   uint64 `json:"seq,omitempty"`
+
+	// Subscriptions lists the client's subscription IDs that matched the message
+	Subscriptions []string `json:"subscriptions,omitempty"`
 }
 
 // Client represents a WebSocket client connection
@@ -49,22 +81,31 @@ type Client struct {
 	// The WebSocket connection
 	conn *websocket.Conn
 
-	// Buffered channel of outbound messages
+	// Buffered channel of outbound messages, drained by the write pump.
+	// It is never closed; done signals shutdown instead.
 	send chan *Message
 
+	// Closed once when the client is closed
+	done      chan struct{}
+	closeOnce sync.Once
+
 	// Hub reference
 	hub *Hub
 
 	// Client ID for identification
 	id string
 
-	// User information (if authenticated)
+	// User information
 	userID   uint
 	username string
 	role     string
 
-	// Subscription filters
-	filters map[string]interface{}
+	// Authenticated session, used for scope filtering and expiry
+	session *Session
+
+	// Active subscriptions keyed by ID
+	subscriptions map[string]*Subscription
+	subMutex      sync.RWMutex
 
 	// Last activity time
 	lastActivity time.Time
@@ -84,35 +125,76 @@ const (
 	pingPeriod = (pongWait * 9) / 10
 
 	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	maxMessageSize = 4096
+
+	// Maximum number of alerts sent in a subscription snapshot
+	snapshotLimit = 100
+
+	// How often sessions are checked for expiry and revocation
+	sessionCheckPeriod = 15 * time.Second
+
+	// Close code sent when a session ends because its token is no longer valid
+	closeSessionEnded = 4001
 )
 
-// NewHub creates a new WebSocket hub
-func NewHub(logger *logrus.Logger) *Hub {
+// NewHub creates a new WebSocket hub that delivers events from the given bus
+func NewHub(logger *logrus.Logger, bus *events.Bus) *Hub {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	return &Hub{
 		clients:    make(map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		broadcast:  make(chan *Message, 256),
+		bus:        bus,
+		listener:   bus.Subscribe(256, false),
+		broker:     events.NewLocalBroker(bus),
 		logger:     logger,
 		ctx:        ctx,
 		cancel:     cancel,
 	}
 }
 
-// NewClient creates a new WebSocket client
-func (h *Hub) NewClient(conn *websocket.Conn, userID uint, username, role string) *Client {
-	return NewClient(h, conn, userID, username, role)
+// Bus returns the event bus the hub publishes to
+func (h *Hub) Bus() *events.Bus {
+	return h.bus
+}
+
+// SetBroker sets the broker broadcasts are published through, so clients
+// connected to other replicas receive them too
+func (h *Hub) SetBroker(broker events.Broker) {
+	h.broker = broker
+}
+
+// SetViewResolver sets the lookup used for view_id subscriptions
+func (h *Hub) SetViewResolver(resolver ViewResolver) {
+	h.viewResolver = resolver
+}
+
+// SetAlertLister sets the lookup used for subscription snapshots
+func (h *Hub) SetAlertLister(lister AlertLister) {
+	h.alertLister = lister
+}
+
+// SetRevocationChecker sets the lookup used to end sessions whose token was revoked
+func (h *Hub) SetRevocationChecker(checker RevocationChecker) {
+	h.revocationChecker = checker
+}
+
+// NewClient creates a new WebSocket client for an authenticated session
+func (h *Hub) NewClient(conn *websocket.Conn, session *Session) *Client {
+	return NewClient(h, conn, session)
 }
 
 // Run starts the hub and handles client registration/unregistration and broadcasting
 func (h *Hub) Run() {
 	defer h.cancel()
+	defer h.listener.Close()
 	
 	ticker := time.NewTicker(pingPeriod)
 	defer ticker.Stop()
+	
+	sessionTicker := time.NewTicker(sessionCheckPeriod)
+	defer sessionTicker.Stop()
 
 	for {
 		select {
@@ -126,11 +208,18 @@ func (h *Hub) Run() {
 		case client := <-h.unregister:
 			h.unregisterClient(client)
 			
-		case message := <-h.broadcast:
-			h.broadcastMessage(message)
+		case event, ok := <-h.listener.C:
+			if !ok {
+				h.logger.Warn("Event bus listener closed")
+				return
+			}
+			h.broadcastMessage(MessageFromEvent(event))
 			
 		case <-ticker.C:
 			h.pingClients()
+			
+		case <-sessionTicker.C:
+			h.expireSessions()
 		}
 	}
 }
@@ -154,13 +243,13 @@ func (h *Hub) registerClient(client *Client) {
 		Data: map[string]interface{}{
 			"client_id": client.id,
 			"server_time": time.Now(),
+			"epoch":     h.bus.Epoch(),
+			"seq":       h.bus.CurrentSeq(),
 		},
 		Timestamp: time.Now(),
 	}
 	
-	select {
-	case client.send <- welcomeMsg:
-	default:
+	if !client.trySend(welcomeMsg) {
 		h.closeClient(client)
 	}
 }
@@ -172,7 +261,7 @@ func (h *Hub) unregisterClient(client *Client) {
 	
 	if _, ok := h.clients[client]; ok {
 		delete(h.clients, client)
-		close(client.send)
+		client.close()
 		
 		h.logger.WithFields(logrus.Fields{
 			"client_id": client.id,
@@ -184,70 +273,98 @@ func (h *Hub) unregisterClient(client *Client) {
 
 // broadcastMessage broadcasts a message to all connected clients
 func (h *Hub) broadcastMessage(message *Message) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 	
 	for client := range h.clients {
-		// Check if client should receive this message based on filters
-		if h.shouldSendToClient(client, message) {
-			select {
-			case client.send <- message:
-			default:
-				h.closeClient(client)
-			}
+		// Check if client should receive this message based on its subscriptions
+		matched, ok := h.shouldSendToClient(client, message)
+		if !ok {
+			continue
+		}
+		
+		out := message
+		if len(matched) > 0 {
+			copied := *message
+			copied.Subscriptions = matched
+			out = &copied
+		}
+		
+		if !client.trySend(out) {
+			h.closeClient(client)
 		}
 	}
 }
 
 // shouldSendToClient determines if a message should be sent to a specific client
-func (h *Hub) shouldSendToClient(client *Client, message *Message) bool {
-	// If no filters are set, send all messages
-	if len(client.filters) == 0 {
-		return true
+// and returns the IDs of the subscriptions that matched it
+func (h *Hub) shouldSendToClient(client *Client, message *Message) ([]string, bool) {
+	return Route(client.session, client.Subscriptions(), message)
+}
+
+// messagesSince returns retained bus events after seq in epoch as messages
+func (h *Hub) messagesSince(epoch string, seq uint64) ([]*Message, bool) {
+	evts, ok := h.bus.Since(epoch, seq)
+	if !ok {
+		return nil, false
 	}
-	
-	// Apply filters based on message type and content
-	switch message.Type {
-	case "alert_created", "alert_updated", "alert_resolved":
-		// Check severity filter
-		if severityFilter, ok := client.filters["severity"].(string); ok {
-			if alert, ok := message.Data.(map[string]interface{}); ok {
-				if alertSeverity, ok := alert["severity"].(string); ok {
-					if severityFilter != alertSeverity {
-						return false
-					}
-				}
-			}
-		}
-		
-		// Check status filter
-		if statusFilter, ok := client.filters["status"].(string); ok {
-			if alert, ok := message.Data.(map[string]interface{}); ok {
-				if alertStatus, ok := alert["status"].(string); ok {
-					if statusFilter != alertStatus {
-						return false
-					}
-				}
-			}
-		}
+	messages := make([]*Message, len(evts))
+	for i, event := range evts {
+		messages[i] = MessageFromEvent(event)
 	}
-	
-	return true
+	return messages, true
 }
 
 // closeClient closes a client connection
 func (h *Hub) closeClient(client *Client) {
 	if _, ok := h.clients[client]; ok {
 		delete(h.clients, client)
-		close(client.send)
+		client.close()
 		client.conn.Close()
 	}
 }
 
+// expireSessions disconnects clients whose token expired or was revoked
+func (h *Hub) expireSessions() {
+	now := time.Now()
+	
+	h.mutex.RLock()
+	ended := make(map[*Client]string)
+	for client := range h.clients {
+		switch {
+		case client.session.Expired(now):
+			ended[client] = "token expired"
+		case h.revocationChecker != nil && client.session != nil && h.revocationChecker(client.session.TokenID):
+			ended[client] = "token revoked"
+		}
+	}
+	h.mutex.RUnlock()
+	
+	if len(ended) == 0 {
+		return
+	}
+	
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	
+	for client, reason := range ended {
+		h.logger.WithFields(logrus.Fields{
+			"client_id": client.id,
+			"username":  client.username,
+			"reason":    reason,
+		}).Info("Closing WebSocket session")
+		
+		client.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(closeSessionEnded, reason),
+			time.Now().Add(writeWait))
+		h.closeClient(client)
+	}
+}
+
 // pingClients sends ping messages to all clients to keep connections alive
 func (h *Hub) pingClients() {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 	
 	now := time.Now()
 	for client := range h.clients {
@@ -267,36 +384,24 @@ func (h *Hub) pingClients() {
 	}
 }
 
-// BroadcastAlertUpdate broadcasts an alert update to all connected clients
+// BroadcastAlertUpdate publishes an alert update to all streaming clients
 func (h *Hub) BroadcastAlertUpdate(alert *models.Alert, action string) {
-	message := &Message{
-		Type: fmt.Sprintf("alert_%s", action),
-		Data: map[string]interface{}{
-			"action": action,
-			"alert":  alert,
-		},
-		Timestamp: time.Now(),
-	}
-	
-	select {
-	case h.broadcast <- message:
-	default:
-		h.logger.Warn("Broadcast channel is full, dropping message")
-	}
+	h.publish(fmt.Sprintf("alert_%s", action), map[string]interface{}{
+		"action": action,
+		"alert":  alert,
+	})
 }
 
-// BroadcastSystemMessage broadcasts a system message to all connected clients
+// BroadcastSystemMessage publishes a system message to all streaming clients
 func (h *Hub) BroadcastSystemMessage(messageType string, data interface{}) {
-	message := &Message{
-		Type:      messageType,
-		Data:      data,
-		Timestamp: time.Now(),
-	}
-	
-	select {
-	case h.broadcast <- message:
-	default:
-		h.logger.Warn("Broadcast channel is full, dropping system message")
+	h.publish(messageType, data)
+}
+
+// publish sends an event through the broker; local clients receive it even
+// when the other replicas cannot be reached
+func (h *Hub) publish(eventType string, data interface{}) {
+	if err := h.broker.Publish(eventType, data); err != nil {
+		h.logger.WithError(err).WithField("event_type", eventType).Warn("Failed to publish event to other replicas")
 	}
 }
 
@@ -319,8 +424,9 @@ func (h *Hub) GetClients() []map[string]interface{} {
 			"user_id":       client.userID,
 			"username":      client.username,
 			"role":          client.role,
+			"expires_at":    client.session.ExpiresAt,
 			"last_activity": client.lastActivity,
-			"filters":       client.filters,
+			"subscriptions": client.Subscriptions(),
 		})
 	}
 	
@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+)
+
+// Session is the authenticated identity behind a streaming connection
+type Session struct {
+	UserID    uint
+	Username  string
+	Role      string
+	TokenID   string
+	ExpiresAt time.Time
+
+	// Scope restricts the alerts the user may see; empty means unrestricted
+	Scope matcher.Matchers
+}
+
+// NewSession builds a session and compiles the user's team and label scope.
+// Teams become a team=~"a|b" matcher ANDed with the scope expression.
+func NewSession(userID uint, username, role, tokenID string, expiresAt time.Time, teams []string, scope string) (*Session, error) {
+	session := &Session{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		TokenID:   tokenID,
+		ExpiresAt: expiresAt,
+	}
+
+	if role == "admin" {
+		return session, nil
+	}
+
+	ms, err := matcher.Parse(scope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user scope: %w", err)
+	}
+
+	if len(teams) > 0 {
+		quoted := make([]string, len(teams))
+		for i, team := range teams {
+			quoted[i] = regexp.QuoteMeta(team)
+		}
+		teamMatcher, err := matcher.New(matcher.MatchRegexp, "team", strings.Join(quoted, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid user teams: %w", err)
+		}
+		ms = append(ms, teamMatcher)
+	}
+
+	session.Scope = ms
+	return session, nil
+}
+
+// Allows reports whether the alert is within the session's scope
+func (s *Session) Allows(alert *models.Alert) bool {
+	if s == nil || len(s.Scope) == 0 {
+		return true
+	}
+	return s.Scope.MatchesJSONB(alert.Labels)
+}
+
+// Expired reports whether the session's token has expired
+func (s *Session) Expired(now time.Time) bool {
+	return s != nil && !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt)
+}
+
+// Restrict narrows repository filters to the session's scope
+func (s *Session) Restrict(filters models.AlertFilters) models.AlertFilters {
+	if s == nil || len(s.Scope) == 0 {
+		return filters
+	}
+
+	filters.Query = matcher.Join(s.Scope.String(), filters.Query)
+	return filters
+}
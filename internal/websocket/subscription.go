@@ -0,0 +1,228 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"alertbot/internal/events"
+	"alertbot/internal/matcher"
+	"alertbot/internal/models"
+)
+
+// Subscription selects the alert events a client wants to receive.
+// A client may hold several subscriptions; an event is delivered when
+// at least one of them matches.
+type Subscription struct {
+	ID       string   `json:"id"`
+	Query    string   `json:"query,omitempty"` // Label matcher expression
+	Status   string   `json:"status,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Types    []string `json:"types,omitempty"` // Alert event types, empty means all
+	ViewID   uint     `json:"view_id,omitempty"`
+
+	matchers matcher.Matchers
+}
+
+// subscriptionFilterKeys are the keys accepted in a subscribe message's filters object
+var subscriptionFilterKeys = map[string]bool{
+	"query":    true,
+	"status":   true,
+	"severity": true,
+	"types":    true,
+}
+
+// NewSubscription builds a subscription from a filters object.
+// Unknown keys are rejected instead of being ignored.
+func NewSubscription(id string, filters map[string]interface{}) (*Subscription, error) {
+	sub := &Subscription{ID: id}
+
+	for key, value := range filters {
+		if !subscriptionFilterKeys[key] {
+			return nil, fmt.Errorf("unsupported filter %q", key)
+		}
+
+		switch key {
+		case "types":
+			items, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("filter %q must be an array of strings", key)
+			}
+			for _, item := range items {
+				t, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("filter %q must be an array of strings", key)
+				}
+				sub.Types = append(sub.Types, t)
+			}
+		default:
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("filter %q must be a string", key)
+			}
+			switch key {
+			case "query":
+				sub.Query = str
+			case "status":
+				sub.Status = str
+			case "severity":
+				sub.Severity = str
+			}
+		}
+	}
+
+	if err := sub.compile(); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// SubscriptionFromView builds a subscription that mirrors a saved view
+func SubscriptionFromView(id string, view *models.SavedView) (*Subscription, error) {
+	sub := &Subscription{
+		ID:       id,
+		Query:    view.Query,
+		Status:   view.Status,
+		Severity: view.Severity,
+		ViewID:   view.ID,
+	}
+	if err := sub.compile(); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// compile parses the label query
+func (s *Subscription) compile() error {
+	ms, err := matcher.Parse(s.Query)
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	s.matchers = ms
+	return nil
+}
+
+// Matches reports whether the message is selected by this subscription.
+// Non-alert messages (system notices, snapshots) are always delivered.
+func (s *Subscription) Matches(message *Message) bool {
+	if !strings.HasPrefix(message.Type, "alert_") {
+		return true
+	}
+
+	if len(s.Types) > 0 && !containsString(s.Types, message.Type) {
+		return false
+	}
+
+	alert := alertFromMessage(message)
+	if alert == nil {
+		return false
+	}
+	return s.MatchesAlert(alert)
+}
+
+// MatchesAlert reports whether the alert satisfies the subscription filters
+func (s *Subscription) MatchesAlert(alert *models.Alert) bool {
+	if s.Status != "" && s.Status != alert.Status {
+		return false
+	}
+	if s.Severity != "" && s.Severity != alert.Severity {
+		return false
+	}
+	return s.matchers.MatchesJSONB(alert.Labels)
+}
+
+// AlertFilters returns repository filters selecting the same alerts, used for snapshots
+func (s *Subscription) AlertFilters() models.AlertFilters {
+	return models.AlertFilters{
+		Query:    s.Query,
+		Status:   s.Status,
+		Severity: s.Severity,
+		Sort:     "updated_at",
+		Order:    "desc",
+	}
+}
+
+// Route reports whether a message may be delivered to a stream with the given
+// session and subscriptions, and returns the IDs of the subscriptions that
+// matched. Alerts outside the session scope are never delivered; streams
+// without subscriptions receive everything else.
+func Route(session *Session, subs []*Subscription, message *Message) ([]string, bool) {
+	if strings.HasPrefix(message.Type, "alert_") {
+		if alert := alertFromMessage(message); alert == nil || !session.Allows(alert) {
+			return nil, false
+		}
+	}
+
+	if len(subs) == 0 {
+		return nil, true
+	}
+
+	var matched []string
+	for _, sub := range subs {
+		if sub.Matches(message) {
+			matched = append(matched, sub.ID)
+		}
+	}
+	return matched, len(matched) > 0
+}
+
+// MessageFromEvent converts a bus event into the streaming wire format
+func MessageFromEvent(event *events.Event) *Message {
+	return &Message{
+		Type:      event.Type,
+		Data:      event.Data,
+		Timestamp: event.Timestamp,
+		Epoch:     event.Epoch,
+		Seq:       event.Seq,
+	}
+}
+
+// DecodeEvent rebuilds event data received from another replica in the
+// shape BroadcastAlertUpdate publishes, so alert events can be routed
+func DecodeEvent(eventType string, data json.RawMessage) (interface{}, error) {
+	if !strings.HasPrefix(eventType, "alert_") {
+		var value interface{}
+		if len(data) == 0 {
+			return nil, nil
+		}
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+
+	var update struct {
+		Action string        `json:"action"`
+		Alert  *models.Alert `json:"alert"`
+	}
+	if err := json.Unmarshal(data, &update); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"action": update.Action,
+		"alert":  update.Alert,
+	}, nil
+}
+
+// alertFromMessage extracts the alert carried by an alert_* message
+func alertFromMessage(message *Message) *models.Alert {
+	data, ok := message.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	switch alert := data["alert"].(type) {
+	case *models.Alert:
+		return alert
+	case models.Alert:
+		return &alert
+	}
+	return nil
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
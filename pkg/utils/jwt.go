@@ -5,28 +5,62 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// ErrTicketNotAllowed is returned when a WebSocket ticket is presented as a bearer token
+var ErrTicketNotAllowed = errors.New("websocket ticket cannot be used as a bearer token")
+
+// TicketAudience marks short-lived tokens that may only be exchanged for a WebSocket session
+const TicketAudience = "ws-ticket"
+
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID   uint     `json:"user_id"`
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Teams    []string `json:"teams,omitempty"`
+	Scope    string   `json:"scope,omitempty"` // Label matcher expression limiting visible alerts
+
+	// Set on tickets: the session token they were issued from and its expiry
+	SessionID        string           `json:"sid,omitempty"`
+	SessionExpiresAt *jwt.NumericDate `json:"sexp,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID uint, username, role, secret string, expiration int) (string, error) {
-	claims := Claims{
+// IsTicket reports whether the claims belong to a WebSocket ticket rather than a session token
+func (c *Claims) IsTicket() bool {
+	for _, aud := range c.Audience {
+		if aud == TicketAudience {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClaims creates session claims with a unique token ID
+func NewClaims(userID uint, username, role string, ttl time.Duration) *Claims {
+	now := time.Now()
+	return &Claims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiration) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "alertbot",
 		},
 	}
+}
 
+func GenerateJWT(userID uint, username, role, secret string, expiration int) (string, error) {
+	return SignClaims(NewClaims(userID, username, role, time.Duration(expiration)*time.Hour), secret)
+}
+
+// SignClaims signs the claims with HS256
+func SignClaims(claims *Claims, secret string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
@@ -48,4 +82,4 @@ func ParseJWT(tokenString, secret string) (*Claims, error) {
 	}
 
 	return nil, errors.New("invalid token")
-}
\ No newline at end of file
+}
@@ -294,7 +294,15 @@ const Settings: React.FC = () => {
                 <InputNumber min={1} max={3600} style={{ width: '100%' }} />
               </Form.Item>
               
-              <Form.Item name="rate_limit" label="限流设置（每分钟最大发送数）" initialValue={100}>
+              <Form.Item name="rate_limit" label="单渠道限流（每个周期最大发送数）" initialValue={100}>
+                <InputNumber min={1} max={1000} style={{ width: '100%' }} />
+              </Form.Item>
+              
+              <Form.Item name="rate_limit_per" label="限流周期（如 1s、1m、1h）" initialValue="1m">
+                <Input />
+              </Form.Item>
+              
+              <Form.Item name="rate_limit_burst" label="突发上限（可连续发送数）" initialValue={10}>
                 <InputNumber min={1} max={1000} style={{ width: '100%' }} />
               </Form.Item>
               